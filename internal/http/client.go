@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"reflect"
 	"regexp"
@@ -13,6 +12,7 @@ import (
 
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
 
+	"github.com/newrelic/newrelic-client-go/internal/utils"
 	"github.com/newrelic/newrelic-client-go/internal/version"
 	"github.com/newrelic/newrelic-client-go/pkg/config"
 	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
@@ -46,7 +46,14 @@ type Client struct {
 
 	errorValue ErrorResponse
 
+	// checkRetry is the configured retry policy, which also decides whether
+	// errors read from a response body are retried.
+	checkRetry retryablehttp.CheckRetry
+
 	logger logging.Logger
+
+	// telemetry produces spans and metrics for requests, when configured.
+	telemetry *telemetry
 }
 
 // NewClient is used to create a new instance of Client.
@@ -65,6 +72,19 @@ func NewClient(cfg config.Config) Client {
 		c.Transport = http.DefaultTransport
 	}
 
+	if isIntercepted(cfg) {
+		c.Transport = &interceptorTransport{
+			transport: c.Transport,
+		}
+	}
+
+	if len(cfg.RateLimiters) > 0 {
+		c.Transport = &rateLimitedTransport{
+			transport: c.Transport,
+			limiters:  cfg.RateLimiters,
+		}
+	}
+
 	if cfg.UserAgent == "" {
 		cfg.UserAgent = defaultUserAgent
 	}
@@ -80,6 +100,34 @@ func NewClient(cfg config.Config) Client {
 	r.HTTPClient = &c
 	r.RetryMax = defaultRetryMax
 	r.CheckRetry = RetryPolicy
+	r.Backoff = ExponentialBackoff
+	r.ErrorHandler = retriesExhausted
+
+	if cfg.RetryMax != nil {
+		r.RetryMax = *cfg.RetryMax
+	}
+
+	if cfg.RetryWaitMin != nil {
+		r.RetryWaitMin = *cfg.RetryWaitMin
+	}
+
+	if cfg.RetryWaitMax != nil {
+		r.RetryWaitMax = *cfg.RetryWaitMax
+	}
+
+	if cfg.RetryJitter {
+		r.Backoff = JitteredExponentialBackoff
+	}
+
+	if cfg.CheckRetry != nil {
+		r.CheckRetry = customRetryPolicy(cfg.CheckRetry)
+	}
+
+	checkRetry := r.CheckRetry
+
+	if isIntercepted(cfg) {
+		r.CheckRetry = interceptorRetryPolicy(r, r.CheckRetry)
+	}
 
 	// Disable logging in go-retryablehttp since we are logging requests directly here
 	r.Logger = nil
@@ -94,10 +142,12 @@ func NewClient(cfg config.Config) Client {
 
 	client := Client{
 		authStrategy: &ClassicV2Authorizer{},
+		checkRetry:   checkRetry,
 		client:       r,
 		config:       cfg,
 		errorValue:   &DefaultErrorResponse{},
 		logger:       logger,
+		telemetry:    newTelemetry(cfg),
 	}
 
 	switch cfg.Compression {
@@ -278,56 +328,48 @@ func logNice(body string) string {
 	return newBody
 }
 
-// obfuscate receives a string, and replaces everything after the first 8
-// characters with an asterisk before returning the result.
-func obfuscate(input string) string {
-	result := make([]string, len(input))
-	parts := strings.Split(input, "")
-
-	for i, x := range parts {
-		if i < 8 {
-			result[i] = x
-		} else {
-			result[i] = "*"
+// logCleanHeaderMarshalJSON marshals the headers of a request for logging,
+// with the values of credential headers obfuscated.
+func logCleanHeaderMarshalJSON(header http.Header) ([]byte, error) {
+	return json.Marshal(utils.ObfuscateHeader(header))
+}
+
+// Do initiates an HTTP request as configured by the passed Request struct.
+func (c *Client) Do(req *Request) (*http.Response, error) {
+	if !isIntercepted(c.config) {
+		resp, err := c.do(req)
+		if err != nil {
+			withRequestDetails(err, req)
 		}
-	}
 
-	return strings.Join(result, "")
-}
+		return resp, err
+	}
 
-func logCleanHeaderMarshalJSON(header http.Header) ([]byte, error) {
-	h := http.Header{}
+	ir := newInterceptedRequest(c.config.Interceptors, req)
+	ctx := req.Context()
 
-	for k, values := range header {
-		if _, ok := h[k]; ok {
-			h[k] = make([]string, len(values))
-		}
+	finish := func(error) {}
+	if c.telemetry != nil {
+		ctx, finish = c.telemetry.startRequest(ctx, req, ir)
+	}
 
-		switch k {
-		case "Api-Key", "X-Api-Key", "X-Insert-Key":
-			newValues := []string{}
-			for _, v := range values {
-				newValues = append(newValues, obfuscate(v))
-			}
+	req.WithContext(context.WithValue(ctx, interceptedRequestContextKey{}, ir))
 
-			if len(newValues) > 0 {
-				h[k] = newValues
-			} else {
-				h[k] = values
-			}
-		default:
-			h[k] = values
-		}
+	resp, err := c.do(req)
+	if err != nil {
+		withRequestDetails(err, req)
+		ir.onError(err)
 	}
 
-	return json.Marshal(h)
+	finish(err)
+
+	return resp, err
 }
 
-// Do initiates an HTTP request as configured by the passed Request struct.
-func (c *Client) Do(req *Request) (*http.Response, error) {
+func (c *Client) do(req *Request) (*http.Response, error) {
 	var resp *http.Response
 	var errorValue ErrorResponse
-	var body []byte
+	var decoded *decodedResponse
 
 	c.logger.Debug("performing request", "method", req.method, "url", req.url)
 
@@ -335,7 +377,7 @@ func (c *Client) Do(req *Request) (*http.Response, error) {
 		var shouldRetry bool
 		var err error
 		errorValue = req.errorValue.New()
-		resp, body, shouldRetry, err = c.innerDo(req, errorValue, i)
+		resp, decoded, shouldRetry, err = c.innerDo(req, errorValue, i)
 
 		if serr, ok := err.(*nrErrors.MaxRetriesReached); ok {
 			return nil, serr
@@ -353,11 +395,7 @@ func (c *Client) Do(req *Request) (*http.Response, error) {
 	}
 
 	if !isResponseSuccess(resp) {
-		if errorValue.IsUnauthorized(resp) {
-			return nil, nrErrors.NewUnauthorizedError()
-		}
-
-		return nil, nrErrors.NewUnexpectedStatusCode(resp.StatusCode, errorValue.Error())
+		return nil, statusCodeError(resp, errorValue)
 	}
 
 	if errorValue.IsNotFound() {
@@ -365,22 +403,52 @@ func (c *Client) Do(req *Request) (*http.Response, error) {
 	}
 
 	if errorValue.Error() != "" {
+		return c.errorResponse(req, resp, decoded, errorValue)
+	}
+
+	if decoded.valueErr != nil {
+		return nil, decoded.valueErr
+	}
+
+	return resp, nil
+}
+
+// errorResponse returns the error for a response body that contained errors.
+// NerdGraph errors are returned as a structured errors.GraphQLErrors, and when
+// the request allows partial data, any data returned alongside the errors is
+// decoded into the response value.  If the data can't be decoded, the
+// NerdGraph errors are returned with the decode error as their cause.
+func (c *Client) errorResponse(req *Request, resp *http.Response, decoded *decodedResponse, errorValue ErrorResponse) (*http.Response, error) {
+	gqlResponse, ok := errorValue.(graphQLErrorsResponse)
+	if !ok {
 		return nil, errorValue
 	}
 
-	if req.value == nil {
-		return resp, nil
+	if decoded.data == nil {
+		return nil, nrErrors.NewGraphQLErrors(gqlResponse.GraphQLErrors(), errorValue)
 	}
 
-	jsonErr := json.Unmarshal(body, req.value)
-	if jsonErr != nil {
-		return nil, jsonErr
+	if err := decodeGraphQLData(decoded.data, req.value.(*graphQLResponse)); err != nil {
+		cause := fmt.Errorf("%s: %w", errorValue.Error(), err)
+		return nil, nrErrors.NewGraphQLErrors(gqlResponse.GraphQLErrors(), cause)
 	}
 
-	return resp, nil
+	gqlErrors := nrErrors.NewGraphQLErrors(gqlResponse.GraphQLErrors(), errorValue)
+	gqlErrors.PartialData = true
+
+	return resp, gqlErrors
 }
 
-func (c *Client) innerDo(req *Request, errorValue ErrorResponse, i int) (*http.Response, []byte, bool, error) {
+// traceEnabled reports whether the client's logger logs trace messages.
+func (c *Client) traceEnabled() bool {
+	if l, ok := c.logger.(logging.LevelEnabler); ok {
+		return l.IsLevelEnabled("trace")
+	}
+
+	return true
+}
+
+func (c *Client) innerDo(req *Request, errorValue ErrorResponse, i int) (*http.Response, *decodedResponse, bool, error) {
 	r, err := req.makeRequest()
 	if err != nil {
 		return nil, nil, false, err
@@ -417,51 +485,96 @@ func (c *Client) innerDo(req *Request, errorValue ErrorResponse, i int) (*http.R
 		c.logger.Debug(fmt.Sprintf("retrying request (attempt %d)", i), "method", req.method, "url", r.URL)
 	}
 
+	ir, intercepted := interceptedRequestFromContext(r.Context())
+	if intercepted {
+		ir.httpAttempts = 0
+	}
+
 	resp, retryErr := c.client.Do(r)
 	if retryErr != nil {
-		return resp, nil, false, retryErr
+		return resp, nil, false, contextError(r.Context(), retryErr)
 	}
 
+	req.requestID = resp.Header.Get(requestIDHeader)
+
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
 		return resp, nil, false, &nrErrors.NotFound{}
 	}
 
-	body, readErr := ioutil.ReadAll(resp.Body)
-
+	decoded, readErr := c.decodeResponse(req, resp, errorValue)
 	if readErr != nil {
-		return resp, body, false, readErr
+		return resp, nil, false, contextError(r.Context(), readErr)
 	}
 
 	logHeaders, err = json.Marshal(resp.Header)
 	if err != nil {
-		return resp, body, false, err
+		return resp, decoded, false, err
 	}
 
-	c.logger.Trace("request completed", "method", req.method, "url", r.URL, "status_code", resp.StatusCode, "headers", string(logHeaders), "body", string(body))
-
-	_ = json.Unmarshal(body, &errorValue)
+	c.logger.Trace("request completed", "method", req.method, "url", r.URL, "status_code", resp.StatusCode, "headers", string(logHeaders), "body", string(decoded.body))
 
 	if errorValue.IsNotFound() {
-		return resp, body, false, nrErrors.NewNotFound(errorValue.Error())
+		return resp, decoded, false, nrErrors.NewNotFound(errorValue.Error())
+	}
+
+	if errorValue.Error() == "" {
+		return resp, decoded, false, nil
 	}
 
-	if !errorValue.IsRetryableError() {
-		return resp, body, false, nil
+	retry, err := c.checkRetry(r.Context(), resp, errorValue)
+	if err != nil {
+		return resp, decoded, false, contextError(r.Context(), err)
+	}
+
+	if !retry {
+		return resp, decoded, false, nil
 	}
 
 	remain := c.client.RetryMax - i
 	if remain <= 0 {
 		c.logger.Debug(fmt.Sprintf("giving up after %d attempts", c.client.RetryMax), "method", req.method, "url", r.URL)
-		return resp, body, false, nrErrors.NewMaxRetriesReached(errorValue.Error())
+		return resp, decoded, false, nrErrors.NewMaxRetriesReached(errorValue.Error()).WithCause(errorValue)
+	}
+
+	if intercepted {
+		ir.onRetry(resp, errorValue)
 	}
 
 	wait := c.client.Backoff(c.client.RetryWaitMin, c.client.RetryWaitMax, i, resp)
 
-	time.Sleep(wait)
+	if err := sleepWithContext(r.Context(), wait); err != nil {
+		c.logger.Debug("request canceled while waiting to retry", "method", req.method, "url", r.URL)
+		return resp, decoded, false, err
+	}
+
+	return resp, decoded, true, nil
+}
+
+// sleepWithContext pauses for the given duration, returning early with a
+// RequestCanceled error if the context is done before the duration elapses.
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nrErrors.NewRequestCanceled(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// contextError returns a RequestCanceled error when the context is done,
+// so callers can tell cancellation apart from a failure of the request itself.
+// Otherwise the original error is returned unchanged.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nrErrors.NewRequestCanceled(ctxErr)
+	}
 
-	return resp, body, true, nil
+	return err
 }
 
 // Ensures the response status code falls within the
@@ -512,6 +625,7 @@ func (c *Client) NewNerdGraphRequest(query string, vars map[string]interface{},
 
 	req.SetAuthStrategy(&NerdGraphAuthorizer{})
 	req.SetErrorValue(&GraphQLErrorResponse{})
+	req.SetEndpointFamily(config.EndpointFamilies.NerdGraph)
 
 	return req, nil
 }
@@ -4,7 +4,9 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	stderrors "errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -48,6 +50,24 @@ func TestConfig(t *testing.T) {
 	require.Same(t, testTransport, c.config.HTTPTransport)
 }
 
+func TestConfigRetry(t *testing.T) {
+	t.Parallel()
+	retryMax := 7
+	retryWaitMin := 2 * time.Second
+	retryWaitMax := time.Minute
+
+	tc := mock.NewTestConfig(t, nil)
+	tc.RetryMax = &retryMax
+	tc.RetryWaitMin = &retryWaitMin
+	tc.RetryWaitMax = &retryWaitMax
+
+	c := NewClient(tc)
+
+	assert.Equal(t, retryMax, c.client.RetryMax)
+	assert.Equal(t, retryWaitMin, c.client.RetryWaitMin)
+	assert.Equal(t, retryWaitMax, c.client.RetryWaitMax)
+}
+
 func TestConfigDefaults(t *testing.T) {
 	t.Parallel()
 	tc := mock.NewTestConfig(t, nil)
@@ -415,3 +435,449 @@ func TestDelete(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestRequestWithContext(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, nil)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	req, err := c.NewRequest(http.MethodGet, c.config.Region().RestURL("path"), nil, nil, nil)
+	require.NoError(t, err)
+
+	req.WithContext(ctx)
+
+	assert.Equal(t, "value", req.Context().Value(ctxKey{}))
+}
+
+func TestContextCanceledBeforeRequest(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.GetWithContext(ctx, c.config.Region().RestURL("path"), nil, nil)
+
+	assert.Equal(t, 0, attempts)
+	assert.IsType(t, &errors.RequestCanceled{}, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestContextDeadlineExceededInFlight(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GetWithContext(ctx, c.config.Region().RestURL("path"), nil, nil)
+
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+	assert.IsType(t, &errors.RequestCanceled{}, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, err.(*errors.RequestCanceled).Timeout())
+}
+
+func TestContextCanceledDuringRetryBackoff(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		attempts++
+	}))
+
+	c.client.RetryWaitMin = 5 * time.Second
+	c.client.RetryWaitMax = 5 * time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.GetWithContext(ctx, c.config.Region().RestURL("path"), nil, nil)
+
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+	assert.Equal(t, 1, attempts)
+	assert.IsType(t, &errors.RequestCanceled{}, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestContextCanceledDuringNerdGraphRetrySleep(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors":[{"message": "some error", "extensions":{"errorClass":"TIMEOUT"}}]}`))
+		attempts++
+	}))
+
+	c.client.RetryWaitMin = 5 * time.Second
+	c.client.RetryWaitMax = 5 * time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := c.NerdGraphQueryWithContext(ctx, "query { actor { user { id } } }", nil, nil)
+
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+	assert.Equal(t, 1, attempts)
+	assert.IsType(t, &errors.RequestCanceled{}, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRetryMaxConfig(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		attempts++
+	}))
+
+	retryMax := 1
+	retryWait := time.Millisecond
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.RetryMax = &retryMax
+	tc.RetryWaitMin = &retryWait
+	tc.RetryWaitMax = &retryWait
+
+	c := NewClient(tc)
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestCustomCheckRetry(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+
+	retryWait := time.Millisecond
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.RetryWaitMin = &retryWait
+	tc.RetryWaitMax = &retryWait
+	tc.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		return resp != nil && resp.StatusCode == http.StatusConflict, nil
+	}
+
+	c := NewClient(tc)
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestCustomCheckRetryNerdGraphErrors(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors":[{"message": "some error", "extensions":{"errorClass":"TIMEOUT"}}]}`))
+	}))
+
+	var checkErrors []error
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if err != nil {
+			checkErrors = append(checkErrors, err)
+		}
+
+		return false, nil
+	}
+
+	c := NewClient(tc)
+
+	err := c.NerdGraphQuery("query { actor { user { id } } }", nil, nil)
+
+	assert.Equal(t, 1, attempts)
+	assert.False(t, stderrors.Is(err, errors.ErrMaxRetriesReached))
+
+	var gqlErrors *errors.GraphQLErrors
+	require.ErrorAs(t, err, &gqlErrors)
+
+	require.Len(t, checkErrors, 1)
+	var gqlResponse *GraphQLErrorResponse
+	require.ErrorAs(t, checkErrors[0], &gqlResponse)
+	assert.True(t, gqlResponse.IsRetryableError())
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+
+	c.client.RetryWaitMin = time.Millisecond
+	c.client.RetryWaitMax = time.Second
+
+	start := time.Now()
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Second))
+}
+
+const testGraphQLPartialResponse = `{
+	"data": {"actor": {"entities": [{"name": "first"}, null]}},
+	"errors": [{
+		"message": "entity not accessible",
+		"path": ["actor", "entities", 1],
+		"extensions": {"errorClass": "FORBIDDEN", "error_code": "ENTITY_ACCESS"}
+	}]
+}`
+
+type testGraphQLPartialData struct {
+	Actor struct {
+		Entities []*struct {
+			Name string `json:"name"`
+		} `json:"entities"`
+	} `json:"actor"`
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(testGraphQLPartialResponse))
+	}))
+
+	var data testGraphQLPartialData
+	err := c.NerdGraphQuery("query { actor { entities { name } } }", nil, &data)
+	require.Error(t, err)
+
+	var gqlErrors *errors.GraphQLErrors
+	require.ErrorAs(t, err, &gqlErrors)
+	assert.False(t, gqlErrors.PartialData)
+	assert.True(t, gqlErrors.HasErrorClass("FORBIDDEN"))
+	require.Len(t, gqlErrors.Errors, 1)
+	assert.Equal(t, []interface{}{"actor", "entities", 1}, gqlErrors.Errors[0].Path)
+	assert.Equal(t, "actor.entities.1", gqlErrors.Errors[0].PathString())
+	assert.Equal(t, "ENTITY_ACCESS", gqlErrors.Errors[0].ErrorCode)
+	assert.Contains(t, err.Error(), "entity not accessible")
+
+	var gqlResponse *GraphQLErrorResponse
+	assert.ErrorAs(t, err, &gqlResponse)
+	assert.Empty(t, data.Actor.Entities)
+}
+
+func TestGraphQLErrorsPartialData(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(testGraphQLPartialResponse))
+	}))
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.NerdGraphPartialData = true
+	c := NewClient(tc)
+
+	var data testGraphQLPartialData
+	err := c.NerdGraphQuery("query { actor { entities { name } } }", nil, &data)
+
+	var gqlErrors *errors.GraphQLErrors
+	require.ErrorAs(t, err, &gqlErrors)
+	assert.True(t, gqlErrors.PartialData)
+	require.Len(t, data.Actor.Entities, 2)
+	assert.Equal(t, "first", data.Actor.Entities[0].Name)
+	assert.Nil(t, data.Actor.Entities[1])
+}
+
+func TestGraphQLErrorsPartialDataDecodeError(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"actor": {"entities": "invalid"}}, "errors": [{"message": "some error"}]}`))
+	}))
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.NerdGraphPartialData = true
+	c := NewClient(tc)
+
+	var data testGraphQLPartialData
+	err := c.NerdGraphQuery("query { actor { entities { name } } }", nil, &data)
+
+	var gqlErrors *errors.GraphQLErrors
+	require.ErrorAs(t, err, &gqlErrors)
+	assert.False(t, gqlErrors.PartialData)
+	require.Len(t, gqlErrors.Errors, 1)
+	assert.Equal(t, "some error", gqlErrors.Errors[0].Message)
+	assert.Contains(t, err.Error(), "some error")
+
+	var decodeErr *json.UnmarshalTypeError
+	assert.ErrorAs(t, err, &decodeErr)
+}
+
+func TestGraphQLErrorsPartialDataNullData(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "some error"}]}`))
+	}))
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.NerdGraphPartialData = true
+	c := NewClient(tc)
+
+	var data testGraphQLPartialData
+	err := c.NerdGraphQuery("query { actor { entities { name } } }", nil, &data)
+
+	var gqlErrors *errors.GraphQLErrors
+	require.ErrorAs(t, err, &gqlErrors)
+	assert.False(t, gqlErrors.PartialData)
+	assert.Equal(t, "some error", err.Error())
+}
+
+func TestErrorRequestDetails(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc-123")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":{"title":"no access"}}`))
+	}))
+
+	url := c.config.Region().RestURL("path")
+	_, err := c.Get(url, nil, nil)
+
+	assert.True(t, stderrors.Is(err, errors.ErrForbidden))
+	assert.Equal(t, "403 response returned: no access", err.Error())
+
+	details, ok := errors.GetRequestDetails(err)
+	require.True(t, ok)
+	assert.Equal(t, http.MethodGet, details.Method)
+	assert.Equal(t, url, details.URL)
+	assert.Equal(t, "abc-123", details.RequestID)
+
+	var errorResponse *DefaultErrorResponse
+	assert.True(t, stderrors.As(err, &errorResponse))
+}
+
+func TestErrorTransportRequestDetails(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
+
+	retryMax := 1
+	retryWait := time.Millisecond
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.RetryMax = &retryMax
+	tc.RetryWaitMin = &retryWait
+	tc.RetryWaitMax = &retryWait
+
+	c := NewClient(tc)
+
+	url := c.config.Region().RestURL("path")
+	_, err := c.Get(url, nil, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GET "+url+" giving up after 2 attempt(s)")
+
+	details, ok := errors.GetRequestDetails(err)
+	require.True(t, ok)
+	assert.Equal(t, http.MethodGet, details.Method)
+	assert.Equal(t, url, details.URL)
+}
+
+func TestErrorRateLimitedRetriesExhausted(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("X-Request-Id", "abc-123")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	c.client.RetryWaitMin = time.Millisecond
+	c.client.RetryWaitMax = time.Millisecond
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	assert.Equal(t, 4, attempts)
+	assert.True(t, stderrors.Is(err, errors.ErrMaxRetriesReached))
+	assert.True(t, stderrors.Is(err, errors.ErrRateLimited))
+	assert.Equal(t, "maximum retries reached: giving up after 4 attempt(s): 429 response returned", err.Error())
+
+	var rateLimited *errors.RateLimited
+	require.True(t, stderrors.As(err, &rateLimited))
+	assert.Equal(t, http.MethodGet, rateLimited.Method)
+
+	details, ok := errors.GetRequestDetails(err)
+	require.True(t, ok)
+	assert.Equal(t, "abc-123", details.RequestID)
+}
+
+func TestErrorRateLimitedNotRetried(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		return false, nil
+	}
+
+	c := NewClient(tc)
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	var rateLimited *errors.RateLimited
+	require.True(t, stderrors.As(err, &rateLimited))
+	assert.Equal(t, 30*time.Second, rateLimited.RetryAfter())
+	assert.False(t, stderrors.Is(err, errors.ErrMaxRetriesReached))
+}
+
+func TestErrorNerdGraphMaxRetriesUnwrap(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors":[{"message": "some error", "extensions":{"errorClass":"TIMEOUT"}}]}`))
+	}))
+
+	c.client.RetryWaitMax = time.Millisecond
+
+	err := c.NerdGraphQuery("query { actor { user { id } } }", nil, nil)
+
+	assert.True(t, stderrors.Is(err, errors.ErrMaxRetriesReached))
+
+	var gqlResponse *GraphQLErrorResponse
+	require.True(t, stderrors.As(err, &gqlResponse))
+	assert.Equal(t, "TIMEOUT", gqlResponse.Errors[0].Extensions.ErrorClass)
+}
@@ -1,9 +1,12 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
 )
 
 // ErrorResponse provides an interface for obtaining
@@ -16,6 +19,85 @@ type ErrorResponse interface {
 	New() ErrorResponse
 }
 
+// graphQLErrorsResponse is implemented by error responses that carry NerdGraph
+// errors, which the client returns as a structured errors.GraphQLErrors.
+type graphQLErrorsResponse interface {
+	ErrorResponse
+	GraphQLErrors() []nrErrors.GraphQLError
+}
+
+// requestIDHeader is the response header carrying the ID New Relic assigned to a request.
+const requestIDHeader = "X-Request-Id"
+
+// statusCodeError returns the error for an unsuccessful response, wrapping the
+// error response read from its body, if any.
+func statusCodeError(resp *http.Response, errorValue ErrorResponse) error {
+	var cause error
+	var msg string
+	if errorValue != nil && errorValue.Error() != "" {
+		cause = errorValue
+		msg = errorValue.Error()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || (errorValue != nil && errorValue.IsUnauthorized(resp)):
+		return nrErrors.NewUnauthorizedError().WithCause(cause)
+	case resp.StatusCode == http.StatusForbidden:
+		return nrErrors.NewForbidden(msg).WithCause(cause)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait, _ := retryAfter(resp)
+		return nrErrors.NewRateLimited(wait, msg).WithCause(cause)
+	default:
+		return nrErrors.NewUnexpectedStatusCode(resp.StatusCode, msg).WithCause(cause)
+	}
+}
+
+// attemptsError is returned when a request fails without a response.  Its
+// request details are filled in by withRequestDetails, so the message names
+// the request that failed, as the retryable client's own error does.
+type attemptsError struct {
+	nrErrors.RequestDetails
+
+	attempts int
+	err      error
+}
+
+func (e *attemptsError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("giving up after %d attempt(s): %s", e.attempts, e.err)
+	}
+
+	return fmt.Sprintf("%s %s giving up after %d attempt(s): %s", e.Method, e.URL, e.attempts, e.err)
+}
+
+// Unwrap returns the error returned by the final attempt.
+func (e *attemptsError) Unwrap() error {
+	return e.err
+}
+
+// withRequestDetails records the method, URL and request ID of a request on
+// each error in the chain of err that embeds errors.RequestDetails.
+func withRequestDetails(err error, req *Request) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		e, ok := err.(interface {
+			Request() nrErrors.RequestDetails
+			SetRequest(nrErrors.RequestDetails)
+		})
+		if !ok {
+			continue
+		}
+
+		details := e.Request()
+		details.Method = req.method
+		details.URL = req.url
+		if details.RequestID == "" {
+			details.RequestID = req.requestID
+		}
+
+		e.SetRequest(details)
+	}
+}
+
 // DefaultErrorResponse represents the default error response from New Relic.
 type DefaultErrorResponse struct {
 	ErrorDetail ErrorDetail `json:"error"`
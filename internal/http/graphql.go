@@ -3,6 +3,8 @@ package http
 import (
 	"net/http"
 	"strings"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
 )
 
 type graphQLRequest struct {
@@ -16,8 +18,8 @@ type graphQLResponse struct {
 
 // GraphQLError represents a single error.
 type GraphQLError struct {
-	Message    string   `json:"message,omitempty"`
-	Path       []string `json:"path,omitempty"`
+	Message    string        `json:"message,omitempty"`
+	Path       []interface{} `json:"path,omitempty"`
 	Extensions struct {
 		ErrorClass string `json:"errorClass,omitempty"`
 		ErrorCode  string `json:"error_code,omitempty"`
@@ -44,6 +46,41 @@ func (r *GraphQLErrorResponse) Error() string {
 	return ""
 }
 
+// GraphQLErrors returns the structured errors contained in the response.
+func (r *GraphQLErrorResponse) GraphQLErrors() []nrErrors.GraphQLError {
+	errs := make([]nrErrors.GraphQLError, len(r.Errors))
+	for i, e := range r.Errors {
+		errs[i] = nrErrors.GraphQLError{
+			Message:    e.Message,
+			Path:       GraphQLErrorPath(e.Path),
+			ErrorClass: e.Extensions.ErrorClass,
+			ErrorCode:  e.Extensions.ErrorCode,
+			Code:       e.Extensions.Code,
+		}
+	}
+
+	return errs
+}
+
+// GraphQLErrorPath normalizes the path of a GraphQL error decoded from JSON,
+// converting list indices from float64 to int.
+func GraphQLErrorPath(path []interface{}) []interface{} {
+	if path == nil {
+		return nil
+	}
+
+	normalized := make([]interface{}, len(path))
+	for i, p := range path {
+		if f, ok := p.(float64); ok {
+			normalized[i] = int(f)
+		} else {
+			normalized[i] = p
+		}
+	}
+
+	return normalized
+}
+
 // IsNotFound determines if the error is due to a missing resource.
 func (r *GraphQLErrorResponse) IsNotFound() bool {
 	return false
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+)
+
+type interceptedRequestContextKey struct{}
+
+// isIntercepted reports whether requests need to be tracked across attempts,
+// either for the configured interceptors or for the client's telemetry.
+func isIntercepted(cfg config.Config) bool {
+	return len(cfg.Interceptors) > 0 || cfg.TracerProvider != nil || cfg.Meter != nil
+}
+
+// interceptedRequest tracks a single call to Client.Do across all of its
+// attempts, and dispatches each stage of the request to the interceptors.
+type interceptedRequest struct {
+	interceptors []config.Interceptor
+	info         config.RequestInfo
+
+	// attempts counts every attempt sent, while httpAttempts only counts the
+	// attempts sent by the current call to the retryable client, which is
+	// what its retry limit is checked against.
+	attempts     int
+	httpAttempts int
+
+	// retries and statusCode are kept for the client's telemetry.
+	retries    int
+	statusCode int
+}
+
+func newInterceptedRequest(interceptors []config.Interceptor, req *Request) *interceptedRequest {
+	ir := &interceptedRequest{
+		interceptors: interceptors,
+		info: config.RequestInfo{
+			EndpointFamily: req.endpointFamily,
+		},
+	}
+
+	if gql, ok := req.reqBody.(*graphQLRequest); ok {
+		ir.info.GraphQLQuery = gql.Query
+		ir.info.GraphQLVariables = gql.Variables
+	}
+
+	return ir
+}
+
+func interceptedRequestFromContext(ctx context.Context) (*interceptedRequest, bool) {
+	ir, ok := ctx.Value(interceptedRequestContextKey{}).(*interceptedRequest)
+	return ir, ok
+}
+
+func (ir *interceptedRequest) beforeRequest(req *http.Request) (*http.Request, error) {
+	ir.info.Request = req
+	ir.info.Attempt = ir.attempts
+	ir.attempts++
+	ir.httpAttempts++
+
+	for _, i := range ir.interceptors {
+		if i.BeforeRequest != nil {
+			if err := i.BeforeRequest(&ir.info); err != nil {
+				return nil, &interceptorAbort{err: err}
+			}
+		}
+	}
+
+	return ir.info.Request, nil
+}
+
+func (ir *interceptedRequest) afterResponse(resp *http.Response, duration time.Duration) {
+	ir.statusCode = resp.StatusCode
+
+	for _, i := range ir.interceptors {
+		if i.AfterResponse != nil {
+			i.AfterResponse(&ir.info, resp, duration)
+		}
+	}
+}
+
+func (ir *interceptedRequest) onRetry(resp *http.Response, err error) {
+	ir.retries++
+
+	for _, i := range ir.interceptors {
+		if i.OnRetry != nil {
+			i.OnRetry(&ir.info, resp, err)
+		}
+	}
+}
+
+func (ir *interceptedRequest) onError(err error) {
+	for _, i := range ir.interceptors {
+		if i.OnError != nil {
+			i.OnError(&ir.info, err)
+		}
+	}
+}
+
+// interceptorAbort is returned by the transport when a BeforeRequest hook
+// returns an error, so the retry policy knows not to retry the request.
+type interceptorAbort struct {
+	err error
+}
+
+func (e *interceptorAbort) Error() string {
+	return fmt.Sprintf("request aborted by interceptor: %s", e.err)
+}
+
+func (e *interceptorAbort) Unwrap() error {
+	return e.err
+}
+
+// interceptorTransport calls the BeforeRequest and AfterResponse hooks for
+// every attempt.  The request is cloned first so hooks are free to modify it.
+type interceptorTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *interceptorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ir, ok := interceptedRequestFromContext(req.Context())
+	if !ok {
+		return t.transport.RoundTrip(req)
+	}
+
+	req, err := ir.beforeRequest(req.Clone(req.Context()))
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	resp, err := t.transport.RoundTrip(req)
+	if err == nil {
+		ir.afterResponse(resp, time.Since(start))
+	}
+
+	return resp, err
+}
+
+// interceptorRetryPolicy wraps a retry policy to stop retrying requests aborted
+// by an interceptor, and to call the OnRetry hooks when a request will be retried.
+func interceptorRetryPolicy(client *retryablehttp.Client, checkRetry retryablehttp.CheckRetry) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		var abort *interceptorAbort
+		if errors.As(err, &abort) {
+			return false, abort.err
+		}
+
+		shouldRetry, checkErr := checkRetry(ctx, resp, err)
+
+		if ir, ok := interceptedRequestFromContext(ctx); ok && shouldRetry && ir.httpAttempts <= client.RetryMax {
+			ir.onRetry(resp, err)
+		}
+
+		return shouldRetry, checkErr
+	}
+}
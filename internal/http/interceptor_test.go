@@ -0,0 +1,210 @@
+//go:build unit
+// +build unit
+
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+func newInterceptedTestClient(t *testing.T, handler http.Handler, interceptors ...config.Interceptor) Client {
+	ts := httptest.NewServer(handler)
+	retryWait := time.Millisecond
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.RetryWaitMin = &retryWait
+	tc.RetryWaitMax = &retryWait
+	tc.Interceptors = interceptors
+
+	return NewClient(tc)
+}
+
+func TestInterceptorBeforeRequest(t *testing.T) {
+	t.Parallel()
+	var infos []config.RequestInfo
+
+	c := newInterceptedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "abc123", r.Header.Get("traceparent"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}), config.Interceptor{
+		BeforeRequest: func(info *config.RequestInfo) error {
+			info.Request.Header.Set("traceparent", "abc123")
+			infos = append(infos, *info)
+			return nil
+		},
+	})
+
+	vars := map[string]interface{}{"accountId": 12345}
+	err := c.NerdGraphQuery("query($accountId: Int!) { actor { account(id: $accountId) { id } } }", vars, nil)
+
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, config.EndpointFamilies.NerdGraph, infos[0].EndpointFamily)
+	assert.Contains(t, infos[0].GraphQLQuery, "account(id: $accountId)")
+	assert.Equal(t, vars, infos[0].GraphQLVariables)
+	assert.Equal(t, 0, infos[0].Attempt)
+	assert.Equal(t, http.MethodPost, infos[0].Request.Method)
+}
+
+func TestInterceptorRewriteRequest(t *testing.T) {
+	t.Parallel()
+	rewritten := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"custom":"rewritten"}`))
+	}))
+
+	c := newInterceptedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}), config.Interceptor{
+		BeforeRequest: func(info *config.RequestInfo) error {
+			u, err := url.Parse(rewritten.URL)
+			if err != nil {
+				return err
+			}
+
+			info.Request.URL.Host = u.Host
+			return nil
+		},
+	})
+
+	v := &CustomResponseValue{}
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, v)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "rewritten", v.Custom)
+}
+
+func TestInterceptorBeforeRequestAbort(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	retries := 0
+	var onError error
+	abortErr := errors.New("mutations are not allowed")
+
+	c := newInterceptedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+	}), config.Interceptor{
+		BeforeRequest: func(info *config.RequestInfo) error {
+			return abortErr
+		},
+		OnRetry: func(info *config.RequestInfo, resp *http.Response, err error) {
+			retries++
+		},
+		OnError: func(info *config.RequestInfo, err error) {
+			onError = err
+		},
+	})
+
+	_, err := c.Post(c.config.Region().RestURL("path"), nil, &struct{}{}, nil)
+
+	assert.ErrorIs(t, err, abortErr)
+	assert.Equal(t, err, onError)
+	assert.Equal(t, 0, attempts)
+	assert.Equal(t, 0, retries)
+}
+
+func TestInterceptorAfterResponse(t *testing.T) {
+	t.Parallel()
+	var statusCodes []int
+
+	c := newInterceptedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{}`))
+	}), config.Interceptor{
+		AfterResponse: func(info *config.RequestInfo, resp *http.Response, duration time.Duration) {
+			statusCodes = append(statusCodes, resp.StatusCode)
+			assert.GreaterOrEqual(t, int64(duration), int64(5*time.Millisecond))
+		},
+	})
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{http.StatusAccepted}, statusCodes)
+}
+
+func TestInterceptorOnRetryAndOnError(t *testing.T) {
+	t.Parallel()
+	var attempts []int
+	var retryStatusCodes []int
+	var onError error
+
+	c := newInterceptedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), config.Interceptor{
+		BeforeRequest: func(info *config.RequestInfo) error {
+			attempts = append(attempts, info.Attempt)
+			return nil
+		},
+		OnRetry: func(info *config.RequestInfo, resp *http.Response, err error) {
+			retryStatusCodes = append(retryStatusCodes, resp.StatusCode)
+		},
+		OnError: func(info *config.RequestInfo, err error) {
+			onError = err
+		},
+	})
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, err, onError)
+	assert.Equal(t, []int{0, 1, 2, 3}, attempts)
+	assert.Equal(t, []int{503, 503, 503}, retryStatusCodes)
+}
+
+func TestInterceptorOnRetryNerdGraph(t *testing.T) {
+	t.Parallel()
+	var retryErrors []string
+
+	c := newInterceptedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors":[{"message": "some error", "extensions":{"errorClass":"TIMEOUT"}}]}`))
+	}), config.Interceptor{
+		OnRetry: func(info *config.RequestInfo, resp *http.Response, err error) {
+			retryErrors = append(retryErrors, err.Error())
+		},
+	})
+
+	err := c.NerdGraphQuery("query { actor { user { id } } }", nil, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"some error", "some error", "some error"}, retryErrors)
+}
+
+func TestInterceptorChainOrder(t *testing.T) {
+	t.Parallel()
+	var calls []string
+
+	c := newInterceptedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}), config.Interceptor{
+		BeforeRequest: func(info *config.RequestInfo) error {
+			calls = append(calls, "first")
+			return nil
+		},
+	}, config.Interceptor{
+		BeforeRequest: func(info *config.RequestInfo) error {
+			calls = append(calls, "second")
+			return nil
+		},
+	})
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
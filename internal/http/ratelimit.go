@@ -0,0 +1,73 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+)
+
+type endpointFamilyContextKey struct{}
+
+// rateLimitedTransport waits on the rate limiter for a request's endpoint
+// family before handing the request to the underlying transport.  Since it
+// sits below the retry logic, every retry attempt is rate limited as well.
+type rateLimitedTransport struct {
+	transport http.RoundTripper
+	limiters  map[config.EndpointFamily]config.RateLimiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	family, _ := req.Context().Value(endpointFamilyContextKey{}).(config.EndpointFamily)
+
+	if limiter, ok := t.limiters[family]; ok && limiter != nil {
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.transport.RoundTrip(req)
+}
+
+// withEndpointFamily returns a copy of the context carrying the endpoint family
+// that the rate limited transport uses to pick a limiter.
+func withEndpointFamily(ctx context.Context, family config.EndpointFamily) context.Context {
+	if current, ok := ctx.Value(endpointFamilyContextKey{}).(config.EndpointFamily); ok && current == family {
+		return ctx
+	}
+
+	return context.WithValue(ctx, endpointFamilyContextKey{}, family)
+}
+
+// endpointFamily determines which endpoint family a URL belongs to by matching
+// it against the most specific base URL of the configured region.
+func endpointFamily(cfg *config.Config, url string) config.EndpointFamily {
+	reg := cfg.Region()
+
+	candidates := []struct {
+		family  config.EndpointFamily
+		baseURL string
+	}{
+		{config.EndpointFamilies.REST, reg.RestURL()},
+		{config.EndpointFamilies.REST, reg.InfrastructureURL()},
+		{config.EndpointFamilies.NerdGraph, reg.NerdGraphURL()},
+		{config.EndpointFamilies.Synthetics, reg.SyntheticsURL()},
+		{config.EndpointFamilies.InsightsIngest, reg.InsightsBaseURL()},
+		{config.EndpointFamilies.LogsIngest, reg.LogsURL()},
+		{config.EndpointFamilies.MetricsIngest, reg.MetricsURL()},
+		{config.EndpointFamilies.TracesIngest, reg.TracesURL()},
+	}
+
+	family := config.EndpointFamilies.REST
+	longest := 0
+
+	for _, c := range candidates {
+		if c.baseURL != "" && strings.HasPrefix(url, c.baseURL) && len(c.baseURL) > longest {
+			family = c.family
+			longest = len(c.baseURL)
+		}
+	}
+
+	return family
+}
@@ -0,0 +1,140 @@
+//go:build unit
+// +build unit
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+type countingLimiter struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls++
+
+	return l.err
+}
+
+func (l *countingLimiter) Calls() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.calls
+}
+
+func TestEndpointFamily(t *testing.T) {
+	t.Parallel()
+
+	tc := config.New()
+
+	cases := map[string]config.EndpointFamily{
+		tc.Region().RestURL("applications.json"):           config.EndpointFamilies.REST,
+		tc.Region().InfrastructureURL("alerts/conditions"): config.EndpointFamilies.REST,
+		tc.Region().NerdGraphURL():                         config.EndpointFamilies.NerdGraph,
+		tc.Region().SyntheticsURL("/v3/monitors"):          config.EndpointFamilies.Synthetics,
+		tc.Region().InsightsURL(12345):                     config.EndpointFamilies.InsightsIngest,
+		tc.Region().LogsURL():                              config.EndpointFamilies.LogsIngest,
+		tc.Region().MetricsURL():                           config.EndpointFamilies.MetricsIngest,
+		tc.Region().TracesURL():                            config.EndpointFamilies.TracesIngest,
+		"https://example.com/unknown":                      config.EndpointFamilies.REST,
+	}
+
+	for url, expected := range cases {
+		assert.Equal(t, expected, endpointFamily(&tc, url), url)
+	}
+}
+
+func TestRateLimiterPerEndpointFamily(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+
+	restLimiter := &countingLimiter{}
+	nerdGraphLimiter := &countingLimiter{}
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.SetRateLimiter(config.EndpointFamilies.REST, restLimiter)
+	tc.SetRateLimiter(config.EndpointFamilies.NerdGraph, nerdGraphLimiter)
+
+	// Clients created from the same config share limiters
+	c1 := NewClient(tc)
+	c2 := NewClient(tc)
+
+	_, err := c1.Get(c1.config.Region().RestURL("path"), nil, nil)
+	assert.NoError(t, err)
+
+	err = c2.NerdGraphQuery("query { actor { user { id } } }", nil, nil)
+	assert.NoError(t, err)
+
+	err = c1.NerdGraphQuery("query { actor { user { id } } }", nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, restLimiter.Calls())
+	assert.Equal(t, 2, nerdGraphLimiter.Calls())
+}
+
+func TestRateLimiterAppliesToRetries(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		attempts++
+	}))
+
+	limiter := &countingLimiter{}
+	retryWait := time.Millisecond
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.RetryWaitMin = &retryWait
+	tc.RetryWaitMax = &retryWait
+	tc.SetRateLimiter(config.EndpointFamilies.REST, limiter)
+
+	c := NewClient(tc)
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, 4, attempts)
+	assert.Equal(t, 4, limiter.Calls())
+}
+
+func TestRateLimiterContextCanceled(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+	}))
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.SetRateLimiter(config.EndpointFamilies.REST, &countingLimiter{err: context.Canceled})
+
+	c := NewClient(tc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.GetWithContext(ctx, c.config.Region().RestURL("path"), nil, nil)
+
+	assert.Equal(t, 0, attempts)
+	assert.IsType(t, &errors.RequestCanceled{}, err)
+}
@@ -23,6 +23,10 @@ type Request struct {
 	authStrategy RequestAuthorizer
 	errorValue   ErrorResponse
 	request      *retryablehttp.Request
+
+	endpointFamily config.EndpointFamily
+	partialData    bool
+	requestID      string
 }
 
 // NewRequest creates a new Request struct.
@@ -47,6 +51,8 @@ func (c *Client) NewRequest(method string, url string, params interface{}, reqBo
 	// Make a copy of the client's config
 	cfg := c.config
 	req.config = cfg
+	req.endpointFamily = endpointFamily(&cfg, url)
+	req.partialData = cfg.NerdGraphPartialData
 
 	if reqBody != nil {
 		switch val := reqBody.(type) {
@@ -86,9 +92,15 @@ func (c *Client) NewRequest(method string, url string, params interface{}, reqBo
 	return req, nil
 }
 
-// WithContext sets the context of the underlying request.
+// WithContext sets the context of the underlying request.  Cancelling the
+// context aborts the request, including any retries that are pending.
 func (r *Request) WithContext(ctx context.Context) {
-	r.request.WithContext(ctx)
+	r.request = r.request.WithContext(ctx)
+}
+
+// Context returns the context of the underlying request.
+func (r *Request) Context() context.Context {
+	return r.request.Context()
 }
 
 // SetHeader sets a header on the underlying request.
@@ -118,6 +130,17 @@ func (r *Request) SetErrorValue(e ErrorResponse) {
 	r.errorValue = e
 }
 
+// SetEndpointFamily sets the endpoint family used to rate limit the request.
+func (r *Request) SetEndpointFamily(family config.EndpointFamily) {
+	r.endpointFamily = family
+}
+
+// SetPartialData sets whether data returned alongside NerdGraph errors is
+// decoded into the response value, rather than discarded.
+func (r *Request) SetPartialData(partialData bool) {
+	r.partialData = partialData
+}
+
 // SetServiceName sets the service name for the request.
 func (r *Request) SetServiceName(serviceName string) {
 	serviceName = fmt.Sprintf("%s|%s", serviceName, defaultServiceName)
@@ -126,6 +149,7 @@ func (r *Request) SetServiceName(serviceName string) {
 
 func (r *Request) makeRequest() (*retryablehttp.Request, error) {
 	r.authStrategy.AuthorizeRequest(r, &r.config)
+	r.request = r.request.WithContext(withEndpointFamily(r.request.Context(), r.endpointFamily))
 
 	err := r.setQueryParams()
 	if err != nil {
@@ -0,0 +1,217 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+// maxPooledResponseBuffer is the largest response buffer kept for reuse, so
+// that one unusually large response doesn't hold on to memory indefinitely.
+const maxPooledResponseBuffer = 16 << 20
+
+// responseBuffers holds the buffers response bodies are read into, so the
+// memory for large responses is reused between requests.
+var responseBuffers = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
+
+// decodedResponse describes the result of decoding a response body.
+type decodedResponse struct {
+	// body is a copy of the response body, which is only kept when trace logging is enabled.
+	body []byte
+
+	// valueErr is the error decoding the body into the response value, if any.
+	valueErr error
+
+	// data is a copy of the data member of a successful NerdGraph response
+	// that also contained errors, kept so that it can be decoded into the
+	// response value once the errors are known to be returned with partial
+	// data.
+	data []byte
+}
+
+// decodeResponse reads a response body once, into a reusable buffer sized from
+// the Content-Length header, and decodes it.  The members of a NerdGraph
+// response are split in a single pass, which decodes the errors member into
+// the error value, and the data member is then decoded into the response value
+// only when it is needed.  Other responses are decoded into the error value,
+// then into the response value only for successful responses without errors.
+// Either way, the response value is left untouched when the request fails.
+func (c *Client) decodeResponse(req *Request, resp *http.Response, errorValue ErrorResponse) (*decodedResponse, error) {
+	limit := c.config.MaxResponseSize
+	if limit > 0 && resp.ContentLength > limit {
+		return nil, nrErrors.NewResponseTooLarge(limit)
+	}
+
+	buf := responseBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledResponseBuffer {
+			buf.Reset()
+			responseBuffers.Put(buf)
+		}
+	}()
+
+	buf.Reset()
+	if resp.ContentLength > 0 {
+		// Leave room for the read that finds the end of the body.
+		buf.Grow(int(resp.ContentLength) + bytes.MinRead)
+	}
+
+	if _, err := buf.ReadFrom(&limitedReader{r: resp.Body, limit: limit, remaining: limit}); err != nil {
+		return nil, err
+	}
+
+	body := buf.Bytes()
+	decoded := &decodedResponse{}
+
+	if c.traceEnabled() {
+		decoded.body = append([]byte(nil), body...)
+	}
+
+	var value interface{}
+	if isResponseSuccess(resp) && !errorValue.IsNotFound() {
+		value = req.value
+	}
+
+	env, isEnvelope := value.(*graphQLResponse)
+	if _, ok := errorValue.(graphQLErrorsResponse); ok && (value == nil || isEnvelope) {
+		decodeGraphQLResponse(body, env, errorValue, req.partialData, decoded)
+		return decoded, nil
+	}
+
+	_ = json.Unmarshal(body, errorValue)
+
+	if value != nil && !errorValue.IsNotFound() && errorValue.Error() == "" {
+		decoded.valueErr = json.Unmarshal(body, decodeTarget(value))
+	}
+
+	return decoded, nil
+}
+
+// decodeGraphQLResponse decodes a NerdGraph response body.  The body is split
+// into its members in a single pass, which decodes the errors member into the
+// error value, unless the error value decodes the whole body itself, and
+// leaves the data member in place.  The data is decoded into the response
+// envelope, if there is one, when there are no errors, and copied to be
+// decoded later when it is returned with errors as partial data.
+func decodeGraphQLResponse(body []byte, env *graphQLResponse, errorValue ErrorResponse, partialData bool, decoded *decodedResponse) {
+	var response struct {
+		Data   interface{} `json:"data"`
+		Errors interface{} `json:"errors"`
+	}
+
+	var data rawMember
+	response.Data = &data
+	response.Errors = &graphQLErrorsMember{errorValue: errorValue}
+
+	if _, ok := errorValue.(json.Unmarshaler); ok {
+		_ = json.Unmarshal(body, errorValue)
+		response.Errors = &rawMember{}
+	}
+
+	err := json.Unmarshal(body, &response)
+
+	if env == nil || errorValue.IsNotFound() {
+		return
+	}
+
+	switch {
+	case err != nil:
+		decoded.valueErr = err
+	case errorValue.Error() == "":
+		if data != nil {
+			decoded.valueErr = decodeGraphQLData(data, env)
+		}
+	case partialData && data != nil:
+		decoded.data = append([]byte(nil), data...)
+	}
+}
+
+// decodeGraphQLData decodes the data member of a NerdGraph response into the
+// response envelope.  The envelope is copied, so a null data member never
+// detaches the caller's response value from it.
+func decodeGraphQLData(data []byte, env *graphQLResponse) error {
+	target := *env
+	return json.Unmarshal(data, &target.Data)
+}
+
+// graphQLErrorsMember decodes the errors member of a NerdGraph response into
+// the error value.
+type graphQLErrorsMember struct {
+	errorValue ErrorResponse
+}
+
+func (m *graphQLErrorsMember) UnmarshalJSON(value []byte) error {
+	doc := make([]byte, 0, len(value)+len(`{"errors":}`))
+	doc = append(doc, `{"errors":`...)
+	doc = append(doc, value...)
+	doc = append(doc, '}')
+
+	_ = json.Unmarshal(doc, m.errorValue)
+
+	return nil
+}
+
+// rawMember holds the value of a member of a response body without decoding
+// it.  Unlike json.RawMessage, the value isn't copied, so it is only valid
+// while the body is.  A null value leaves it nil.
+type rawMember []byte
+
+func (m *rawMember) UnmarshalJSON(value []byte) error {
+	*m = value
+	return nil
+}
+
+// decodeTarget returns the value a response body is decoded into.  The
+// NerdGraph response envelope is copied, so a null "data" member never detaches
+// the caller's response value from it.
+func decodeTarget(value interface{}) interface{} {
+	if env, ok := value.(*graphQLResponse); ok {
+		target := *env
+		return &target
+	}
+
+	return value
+}
+
+// limitedReader reads from r, failing with an errors.ResponseTooLarge once
+// more than limit bytes have been read.  A limit of zero means no limit.
+type limitedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	err       error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	if l.limit <= 0 {
+		return l.r.Read(p)
+	}
+
+	// Read one byte past the limit, to tell a body of exactly limit bytes
+	// apart from one that is too large.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		l.err = nrErrors.NewResponseTooLarge(l.limit)
+		return int(l.remaining), l.err
+	}
+
+	l.remaining -= int64(n)
+
+	return n, err
+}
@@ -0,0 +1,413 @@
+//go:build unit
+// +build unit
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/logging"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+// unmarshalerErrorResponse is an error value with its own UnmarshalJSON,
+// which sees the whole body.
+type unmarshalerErrorResponse struct {
+	GraphQLErrorResponse
+	members int
+}
+
+func (r *unmarshalerErrorResponse) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	r.members = len(members)
+	return json.Unmarshal(data, &r.GraphQLErrorResponse)
+}
+
+func TestDecodeResponseErrorUnmarshaler(t *testing.T) {
+	t.Parallel()
+
+	c := NewClient(config.New())
+	req := &Request{value: &graphQLResponse{Data: &struct{}{}}}
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"data": {}, "errors": [{"message": "x"}], "extensions": {}}`)),
+	}
+	errorValue := &unmarshalerErrorResponse{}
+
+	decoded, err := c.decodeResponse(req, resp, errorValue)
+	require.NoError(t, err)
+
+	assert.Nil(t, decoded.data)
+	assert.Equal(t, 3, errorValue.members)
+	assert.Equal(t, "x", errorValue.Error())
+}
+
+func TestDecodeResponsePartialData(t *testing.T) {
+	t.Parallel()
+
+	c := NewClient(config.New())
+
+	cases := map[string]bool{
+		`{"data": {"value": "x"}, "errors": [{"message": "x"}]}`: true,
+		`{"errors": [{"message": "x"}], "data": {}}`:             true,
+		`{"data": null, "errors": [{"message": "x"}]}`:           false,
+		`{"errors": [{"message": "x"}]}`:                         false,
+	}
+
+	for body, hasData := range cases {
+		var data struct {
+			Value string `json:"value"`
+		}
+
+		req := &Request{value: &graphQLResponse{Data: &data}, partialData: true}
+		resp := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body))}
+		errorValue := &GraphQLErrorResponse{}
+
+		decoded, err := c.decodeResponse(req, resp, errorValue)
+		require.NoError(t, err)
+
+		assert.Equal(t, "x", errorValue.Error(), body)
+		assert.Equal(t, hasData, decoded.data != nil, body)
+
+		// The data is only decoded into the response value by errorResponse.
+		assert.Empty(t, data.Value, body)
+	}
+}
+
+func TestDecodeResponseGraphQLMembers(t *testing.T) {
+	t.Parallel()
+
+	c := NewClient(config.New())
+
+	var data struct {
+		Value string `json:"value"`
+	}
+
+	req := &Request{value: &graphQLResponse{Data: &data}}
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"extensions": {"cost": 1}, "Data": {"value": "x"}, "errors": null}`)),
+	}
+	errorValue := &GraphQLErrorResponse{}
+
+	decoded, err := c.decodeResponse(req, resp, errorValue)
+	require.NoError(t, err)
+	require.NoError(t, decoded.valueErr)
+
+	assert.Empty(t, errorValue.Error())
+	assert.Equal(t, "x", data.Value)
+
+	// A body that isn't valid JSON fails to decode into the response value.
+	req = &Request{value: &graphQLResponse{Data: &data}}
+	resp = &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"data": {`))}
+
+	decoded, err = c.decodeResponse(req, resp, &GraphQLErrorResponse{})
+	require.NoError(t, err)
+	assert.Error(t, decoded.valueErr)
+}
+
+func TestLimitedReader(t *testing.T) {
+	t.Parallel()
+
+	r := &limitedReader{r: strings.NewReader("12345"), limit: 5, remaining: 5}
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "12345", string(b))
+
+	r = &limitedReader{r: strings.NewReader("123456"), limit: 5, remaining: 5}
+	_, err = ioutil.ReadAll(r)
+	assert.True(t, stderrors.Is(err, errors.ErrResponseTooLarge))
+
+	r = &limitedReader{r: strings.NewReader("123456")}
+	b, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", string(b))
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"value": "` + strings.Repeat("x", 1024) + `"}}`))
+	}))
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.MaxResponseSize = 512
+	c := NewClient(tc)
+
+	var data map[string]interface{}
+	err := c.NerdGraphQuery("query { value }", nil, &data)
+
+	var tooLarge *errors.ResponseTooLarge
+	require.True(t, stderrors.As(err, &tooLarge))
+	assert.Equal(t, int64(512), tooLarge.Limit())
+	assert.Equal(t, "response body exceeds the maximum size of 512 bytes", err.Error())
+}
+
+func TestResponseErrorAfterData(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"value": "x"}, "errors": [{"message": "some error"}]}`))
+	}))
+
+	var data struct {
+		Value string `json:"value"`
+	}
+	err := c.NerdGraphQuery("query { value }", nil, &data)
+
+	assert.EqualError(t, err, "some error")
+	assert.Empty(t, data.Value)
+}
+
+func TestResponseRetryAfterNullData(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		if attempts == 1 {
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "timeout", "extensions": {"errorClass": "TIMEOUT"}}]}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"value": "x"}}`))
+	}))
+
+	c.client.RetryWaitMax = time.Millisecond
+
+	var data struct {
+		Value string `json:"value"`
+	}
+	err := c.NerdGraphQuery("query { value }", nil, &data)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, "x", data.Value)
+}
+
+func TestResponseErrorWithSuccessStatus(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"value": "x", "error": {"title": "some error"}}`))
+	}))
+
+	var data struct {
+		Value string `json:"value"`
+	}
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, &data)
+
+	assert.EqualError(t, err, "some error")
+	assert.Empty(t, data.Value)
+}
+
+func TestResponseValueKeptOnError(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"value": "x", "error": {"title": "some error"}}`))
+	}))
+
+	data := struct {
+		Value string `json:"value"`
+		Other string `json:"other"`
+	}{
+		Other: "set by caller",
+	}
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, &data)
+
+	assert.EqualError(t, err, "some error")
+	assert.Empty(t, data.Value)
+	assert.Equal(t, "set by caller", data.Other)
+}
+
+// traceLogger records the fields of trace messages, and doesn't report its level.
+type traceLogger struct {
+	logging.MockLogger
+
+	mu     sync.Mutex
+	traces map[string][]interface{}
+}
+
+func (l *traceLogger) Trace(msg string, fields ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.traces[msg] = fields
+}
+
+func TestResponseTraceBodyCustomLogger(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"value": "x"}`))
+	}))
+
+	logger := &traceLogger{
+		MockLogger: *logging.NewMockLogger(t),
+		traces:     map[string][]interface{}{},
+	}
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.LogLevel = "info"
+	tc.Logger = logger
+	c := NewClient(tc)
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+	require.NoError(t, err)
+
+	fields := logger.traces["request completed"]
+	require.NotEmpty(t, fields)
+	assert.Equal(t, `{"value": "x"}`, fields[len(fields)-1])
+}
+
+func TestResponseTraceBodyLevel(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"value": "x"}`))
+	}))
+
+	logger := logging.NewLogrusLogger()
+	logger.SetLevel("debug")
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.LogLevel = "trace"
+	tc.Logger = logger
+	c := NewClient(tc)
+	assert.False(t, c.traceEnabled())
+
+	logger.SetLevel("trace")
+	assert.True(t, c.traceEnabled())
+}
+
+func TestResponseEmptyBody(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var data struct{}
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, &data)
+	assert.EqualError(t, err, "unexpected end of JSON input")
+
+	_, err = c.Get(c.config.Region().RestURL("path"), nil, nil)
+	assert.NoError(t, err)
+}
+
+// benchmarkResponse is a NerdGraph response of several megabytes, with an
+// error after the data when withError is set.
+func benchmarkResponse(b *testing.B, withError bool) []byte {
+	type entity struct {
+		GUID string            `json:"guid"`
+		Name string            `json:"name"`
+		Tags map[string]string `json:"tags"`
+	}
+
+	entities := make([]entity, 20000)
+	for i := range entities {
+		entities[i] = entity{
+			GUID: fmt.Sprintf("MTIzNDU2fEFQTXxBUFBMSUNBVElPTnw%08d", i),
+			Name: fmt.Sprintf("entity-%d", i),
+			Tags: map[string]string{"env": "production", "team": "observability"},
+		}
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{"actor": map[string]interface{}{"entitySearch": map[string]interface{}{"results": map[string]interface{}{"entities": entities}}}},
+	}
+
+	if withError {
+		response["errors"] = []map[string]string{{"message": "some error"}}
+	}
+
+	body, err := json.Marshal(response)
+	require.NoError(b, err)
+
+	return body
+}
+
+type benchmarkEntities struct {
+	Actor struct {
+		EntitySearch struct {
+			Results struct {
+				Entities []struct {
+					GUID string            `json:"guid"`
+					Name string            `json:"name"`
+					Tags map[string]string `json:"tags"`
+				} `json:"entities"`
+			} `json:"results"`
+		} `json:"entitySearch"`
+	} `json:"actor"`
+}
+
+var benchmarkCases = map[string]bool{"data": false, "error": true}
+
+// BenchmarkDecodeResponseBuffered measures reading the whole body, then
+// unmarshaling it into the error value, and into the response value when it
+// has no errors.
+func BenchmarkDecodeResponseBuffered(b *testing.B) {
+	for name, withError := range benchmarkCases {
+		body := benchmarkResponse(b, withError)
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+
+			for i := 0; i < b.N; i++ {
+				buf, err := ioutil.ReadAll(bytes.NewReader(body))
+				require.NoError(b, err)
+
+				errorValue := &GraphQLErrorResponse{}
+				_ = json.Unmarshal(buf, errorValue)
+
+				if errorValue.Error() == "" {
+					value := &graphQLResponse{Data: &benchmarkEntities{}}
+					require.NoError(b, json.Unmarshal(buf, value))
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDecodeResponse measures decodeResponse, which reads the body into a
+// reused buffer, splits it into its members in a single pass, and decodes the
+// data only when there are no errors.
+func BenchmarkDecodeResponse(b *testing.B) {
+	c := NewClient(config.New())
+
+	for name, withError := range benchmarkCases {
+		body := benchmarkResponse(b, withError)
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+
+			for i := 0; i < b.N; i++ {
+				req := &Request{value: &graphQLResponse{Data: &benchmarkEntities{}}}
+				resp := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(body))}
+				errorValue := &GraphQLErrorResponse{}
+
+				decoded, err := c.decodeResponse(req, resp, errorValue)
+				require.NoError(b, err)
+				require.NoError(b, decoded.valueErr)
+			}
+		})
+	}
+}
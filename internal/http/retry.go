@@ -3,9 +3,18 @@ package http
 import (
 	"context"
 	"crypto/x509"
+	"errors"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
 )
 
 var (
@@ -21,13 +30,21 @@ var (
 )
 
 // RetryPolicy provides a callback for retryablehttp's CheckRetry, which
-// will retry on connection errors and server errors.
+// will retry on connection errors and server errors.  It is also called with
+// the error response read from a response body, such as NerdGraph errors
+// returned with a successful status code, which are retried when the error
+// response reports them as retryable.
 func RetryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
 	// do not retry on context.Canceled or context.DeadlineExceeded
 	if ctx.Err() != nil {
 		return false, ctx.Err()
 	}
 
+	var errorResponse ErrorResponse
+	if errors.As(err, &errorResponse) {
+		return errorResponse.IsRetryableError(), nil
+	}
+
 	if err != nil {
 		if v, ok := err.(*url.Error); ok {
 			// Don't retry if the error was due to too many redirects.
@@ -67,3 +84,139 @@ func RetryPolicy(ctx context.Context, resp *http.Response, err error) (bool, err
 
 	return false, nil
 }
+
+// RetryableBatchError reports whether sending a batch of data to an ingest API,
+// such as a batch of events or logs, that failed with err may succeed when sent
+// again.  Batches rejected for their content or the credentials used can never
+// be sent.
+func RetryableBatchError(err error) bool {
+	if errors.Is(err, nrErrors.ErrUnauthorized) || errors.Is(err, nrErrors.ErrForbidden) || errors.Is(err, nrErrors.ErrInvalidInput) {
+		return false
+	}
+
+	// Errors for a response, such as RateLimited, carry its status code.
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode()
+		return code >= http.StatusInternalServerError || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+// customRetryPolicy wraps a caller-supplied retry policy so that requests
+// whose context is done are never retried, regardless of the policy.
+func customRetryPolicy(checkRetry config.CheckRetry) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		return checkRetry(ctx, resp, err)
+	}
+}
+
+// retriesExhausted provides a callback for retryablehttp's ErrorHandler, which
+// is called when a request fails.  When retries are exhausted on an
+// unsuccessful response, a MaxRetriesReached wrapping the error for the final
+// response is returned.  Otherwise the error is wrapped with the number of
+// attempts, and the details of the request once the client returns it.
+func retriesExhausted(resp *http.Response, err error, attempts int) (*http.Response, error) {
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	if err != nil {
+		return nil, &attemptsError{attempts: attempts, err: err}
+	}
+
+	if resp == nil {
+		return nil, nrErrors.NewMaxRetriesReachedf("giving up after %d attempt(s)", attempts)
+	}
+
+	cause := statusCodeError(resp, nil)
+	maxRetries := nrErrors.NewMaxRetriesReachedf("giving up after %d attempt(s): %s", attempts, cause).WithCause(cause)
+	maxRetries.SetRequest(nrErrors.RequestDetails{RequestID: resp.Header.Get(requestIDHeader)})
+
+	return nil, maxRetries
+}
+
+// ExponentialBackoff provides a callback for retryablehttp's Backoff, which
+// waits exponentially longer based on the attempt number, limited by the
+// provided minimum and maximum durations.  A Retry-After header sent with a
+// 429 or 503 response takes precedence over the computed wait, but is still
+// limited by the maximum duration.
+func ExponentialBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if wait, ok := retryAfter(resp); ok {
+		return capWait(wait, max)
+	}
+
+	return exponentialWait(min, max, attemptNum)
+}
+
+// JitteredExponentialBackoff provides a callback for retryablehttp's Backoff,
+// which picks a random wait between the minimum duration and the exponential
+// wait for the attempt number.  Randomizing the wait keeps many clients that
+// were rate limited at the same time from retrying in lockstep.  A Retry-After
+// header sent with a 429 or 503 response takes precedence over the computed
+// wait, but is still limited by the maximum duration.
+func JitteredExponentialBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if wait, ok := retryAfter(resp); ok {
+		return capWait(wait, max)
+	}
+
+	ceiling := exponentialWait(min, max, attemptNum)
+	if ceiling <= min {
+		return ceiling
+	}
+
+	return min + time.Duration(rand.Int63n(int64(ceiling-min)))
+}
+
+func capWait(wait, max time.Duration) time.Duration {
+	if wait > max {
+		return max
+	}
+
+	return wait
+}
+
+func exponentialWait(min, max time.Duration, attemptNum int) time.Duration {
+	return retryablehttp.DefaultBackoff(min, max, attemptNum, nil)
+}
+
+// retryAfter parses the Retry-After header of 429 and 503 responses, which
+// can either be a number of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+
+		return wait, true
+	}
+
+	return 0, false
+}
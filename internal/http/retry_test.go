@@ -0,0 +1,161 @@
+//go:build unit
+// +build unit
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	min := 100 * time.Millisecond
+	max := time.Second
+
+	assert.Equal(t, 100*time.Millisecond, ExponentialBackoff(min, max, 0, nil))
+	assert.Equal(t, 200*time.Millisecond, ExponentialBackoff(min, max, 1, nil))
+	assert.Equal(t, 800*time.Millisecond, ExponentialBackoff(min, max, 3, nil))
+	assert.Equal(t, max, ExponentialBackoff(min, max, 10, nil))
+}
+
+func TestJitteredExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	min := 100 * time.Millisecond
+	max := time.Second
+
+	for attempt := 0; attempt < 10; attempt++ {
+		ceiling := ExponentialBackoff(min, max, attempt, nil)
+
+		for i := 0; i < 50; i++ {
+			wait := JitteredExponentialBackoff(min, max, attempt, nil)
+
+			assert.GreaterOrEqual(t, int64(wait), int64(min))
+			assert.LessOrEqual(t, int64(wait), int64(ceiling))
+		}
+	}
+}
+
+func TestBackoffRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	min := 100 * time.Millisecond
+	max := time.Minute
+
+	cases := map[string]struct {
+		StatusCode int
+		RetryAfter string
+		Expected   time.Duration
+	}{
+		"too many requests": {
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: "30",
+			Expected:   30 * time.Second,
+		},
+		"service unavailable": {
+			StatusCode: http.StatusServiceUnavailable,
+			RetryAfter: "5",
+			Expected:   5 * time.Second,
+		},
+		"date in the past": {
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: "Wed, 21 Oct 2015 07:28:00 GMT",
+			Expected:   0,
+		},
+		"invalid header": {
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: "soon",
+			Expected:   min,
+		},
+		"ignored for other status codes": {
+			StatusCode: http.StatusBadGateway,
+			RetryAfter: "30",
+			Expected:   min,
+		},
+	}
+
+	for name, tc := range cases {
+		resp := &http.Response{
+			StatusCode: tc.StatusCode,
+			Header:     http.Header{},
+		}
+		resp.Header.Set("Retry-After", tc.RetryAfter)
+
+		assert.Equal(t, tc.Expected, ExponentialBackoff(min, max, 0, resp), name)
+		assert.Equal(t, tc.Expected, JitteredExponentialBackoff(min, max, 0, resp), name)
+	}
+}
+
+func TestBackoffRetryAfterCapped(t *testing.T) {
+	t.Parallel()
+
+	min := 100 * time.Millisecond
+	max := time.Second
+
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{},
+	}
+	resp.Header.Set("Retry-After", "30")
+
+	assert.Equal(t, max, ExponentialBackoff(min, max, 0, resp))
+	assert.Equal(t, max, JitteredExponentialBackoff(min, max, 0, resp))
+}
+
+func TestBackoffRetryAfterDate(t *testing.T) {
+	t.Parallel()
+
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{},
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+
+	wait := ExponentialBackoff(time.Millisecond, 2*time.Minute, 0, resp)
+
+	assert.Greater(t, int64(wait), int64(50*time.Second))
+	assert.LessOrEqual(t, int64(wait), int64(time.Minute))
+}
+
+func TestCustomRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := customRetryPolicy(func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		return true, nil
+	})
+
+	retry, err := policy(context.Background(), &http.Response{StatusCode: http.StatusBadRequest}, nil)
+	assert.True(t, retry)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	retry, err = policy(ctx, &http.Response{StatusCode: http.StatusBadRequest}, nil)
+	assert.False(t, retry)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRetryableBatchError(t *testing.T) {
+	t.Parallel()
+
+	// Rate limited batches are retried, like server errors.
+	assert.True(t, RetryableBatchError(nrErrors.NewRateLimited(0, "")))
+	assert.True(t, RetryableBatchError(nrErrors.NewMaxRetriesReached("").WithCause(nrErrors.NewRateLimited(0, ""))))
+	assert.True(t, RetryableBatchError(nrErrors.NewUnexpectedStatusCode(http.StatusServiceUnavailable, "")))
+	assert.True(t, RetryableBatchError(nrErrors.NewUnexpectedStatusCode(http.StatusRequestTimeout, "")))
+	assert.True(t, RetryableBatchError(errors.New("connection reset")))
+
+	assert.False(t, RetryableBatchError(nrErrors.NewUnexpectedStatusCode(http.StatusBadRequest, "")))
+	assert.False(t, RetryableBatchError(nrErrors.NewForbidden("")))
+	assert.False(t, RetryableBatchError(nrErrors.NewUnauthorizedError()))
+}
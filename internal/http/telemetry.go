@@ -0,0 +1,191 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/newrelic/newrelic-client-go/internal/version"
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+const (
+	instrumentationName = "github.com/newrelic/newrelic-client-go"
+
+	endpointFamilyKey   = attribute.Key("newrelic.endpoint_family")
+	retryCountKey       = attribute.Key("newrelic.retry_count")
+	errorClassKey       = attribute.Key("error.class")
+	graphQLOperationKey = attribute.Key("graphql.operation.name")
+	graphQLTypeKey      = attribute.Key("graphql.operation.type")
+)
+
+var (
+	// graphQLOperationRe captures the operation type, the optional operation
+	// name, and the first root field of a GraphQL document.
+	graphQLOperationRe = regexp.MustCompile(`^\s*(query|mutation|subscription)?\s*([_A-Za-z][_0-9A-Za-z]*)?[^{]*\{\s*([_A-Za-z][_0-9A-Za-z]*)`)
+)
+
+// telemetry produces OpenTelemetry spans, and metrics recorded through the
+// configured meter, for requests made by the client.
+type telemetry struct {
+	tracer trace.Tracer
+	meter  config.Meter
+}
+
+// newTelemetry returns nil unless a tracer provider or meter is configured, in
+// which case the missing one falls back to a no-op implementation.
+func newTelemetry(cfg config.Config) *telemetry {
+	if cfg.TracerProvider == nil && cfg.Meter == nil {
+		return nil
+	}
+
+	tracerProvider := cfg.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = trace.NewNoopTracerProvider()
+	}
+
+	meter := cfg.Meter
+	if meter == nil {
+		meter = noopMeter{}
+	}
+
+	return &telemetry{
+		tracer: tracerProvider.Tracer(instrumentationName, trace.WithInstrumentationVersion(version.Version)),
+		meter:  meter,
+	}
+}
+
+// noopMeter is a config.Meter that records nothing.
+type noopMeter struct{}
+
+func (noopMeter) AddInt64(context.Context, config.Metric, int64, ...attribute.KeyValue) {}
+
+func (noopMeter) RecordFloat64(context.Context, config.Metric, float64, ...attribute.KeyValue) {}
+
+// startRequest starts a span for the request and returns the context carrying
+// it, along with a function that records the outcome once the request is done.
+func (t *telemetry) startRequest(ctx context.Context, req *Request, ir *interceptedRequest) (context.Context, func(error)) {
+	attrs := []attribute.KeyValue{
+		endpointFamilyKey.String(req.endpointFamily.String()),
+		semconv.HTTPMethodKey.String(req.method),
+	}
+
+	spanName := fmt.Sprintf("%s %s", req.method, req.endpointFamily)
+
+	if ir.info.GraphQLQuery != "" {
+		opType, opName := graphQLOperation(ir.info.GraphQLQuery)
+		attrs = append(attrs, graphQLTypeKey.String(opType), graphQLOperationKey.String(opName))
+		spanName = fmt.Sprintf("%s %s %s", req.endpointFamily, opType, opName)
+	}
+
+	ctx, span := t.tracer.Start(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(semconv.HTTPURLKey.String(req.url)),
+	)
+
+	start := time.Now()
+
+	return ctx, func(err error) {
+		if ir.statusCode > 0 {
+			attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(ir.statusCode))
+		}
+
+		span.SetAttributes(retryCountKey.Int(ir.retries))
+
+		t.meter.AddInt64(ctx, config.MetricRequests, 1, attrs...)
+		t.meter.RecordFloat64(ctx, config.MetricDuration, float64(time.Since(start))/float64(time.Millisecond), attrs...)
+
+		if ir.retries > 0 {
+			t.meter.AddInt64(ctx, config.MetricRetries, int64(ir.retries), attrs...)
+		}
+
+		if err != nil {
+			class := errorClassKey.String(errorClass(err))
+
+			t.meter.AddInt64(ctx, config.MetricErrors, 1, append(attrs, class)...)
+
+			span.SetAttributes(class)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.SetAttributes(attrs...)
+		span.End()
+	}
+}
+
+// graphQLOperation returns the type of a GraphQL operation, and its name.
+// Anonymous operations are named after their first root field.
+func graphQLOperation(query string) (string, string) {
+	matches := graphQLOperationRe.FindStringSubmatch(query)
+	if matches == nil {
+		return "query", ""
+	}
+
+	opType := matches[1]
+	if opType == "" {
+		opType = "query"
+	}
+
+	opName := matches[2]
+	if opName == "" {
+		opName = matches[3]
+	}
+
+	return opType, opName
+}
+
+// errorClass groups errors returned by the client into a small set of
+// classes, suitable for use as a metric attribute.
+func errorClass(err error) string {
+	var (
+		canceled     *nrErrors.RequestCanceled
+		unauthorized *nrErrors.UnauthorizedError
+		forbidden    *nrErrors.Forbidden
+		rateLimited  *nrErrors.RateLimited
+		tooLarge     *nrErrors.ResponseTooLarge
+		notFound     *nrErrors.NotFound
+		maxRetries   *nrErrors.MaxRetriesReached
+		statusCode   *nrErrors.UnexpectedStatusCode
+		graphQL      *nrErrors.GraphQLErrors
+		graphQLResp  *GraphQLErrorResponse
+		response     ErrorResponse
+	)
+
+	switch {
+	case errors.As(err, &canceled):
+		if canceled.Timeout() {
+			return "DeadlineExceeded"
+		}
+		return "Canceled"
+	case errors.As(err, &maxRetries):
+		return "MaxRetriesReached"
+	case errors.As(err, &unauthorized):
+		return "Unauthorized"
+	case errors.As(err, &forbidden):
+		return "Forbidden"
+	case errors.As(err, &rateLimited):
+		return "RateLimited"
+	case errors.As(err, &tooLarge):
+		return "ResponseTooLarge"
+	case errors.As(err, &notFound):
+		return "NotFound"
+	case errors.As(err, &statusCode):
+		return "UnexpectedStatusCode"
+	case errors.As(err, &graphQL), errors.As(err, &graphQLResp):
+		return "GraphQLError"
+	case errors.As(err, &response):
+		return "APIError"
+	default:
+		return "Error"
+	}
+}
@@ -0,0 +1,204 @@
+//go:build unit
+// +build unit
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+// measurement is a value recorded by a testMeter.
+type measurement struct {
+	Value float64
+	Attrs map[attribute.Key]attribute.Value
+}
+
+// testMeter is a config.Meter keeping the values recorded for each metric.
+type testMeter struct {
+	mu           sync.Mutex
+	measurements map[string][]measurement
+}
+
+func (m *testMeter) AddInt64(ctx context.Context, metric config.Metric, incr int64, attrs ...attribute.KeyValue) {
+	m.RecordFloat64(ctx, metric, float64(incr), attrs...)
+}
+
+func (m *testMeter) RecordFloat64(_ context.Context, metric config.Metric, value float64, attrs ...attribute.KeyValue) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.measurements == nil {
+		m.measurements = map[string][]measurement{}
+	}
+
+	m.measurements[metric.Name] = append(m.measurements[metric.Name], measurement{Value: value, Attrs: attributes(attrs)})
+}
+
+func (m *testMeter) Measurements() map[string][]measurement {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.measurements
+}
+
+func newInstrumentedTestClient(t *testing.T, handler http.Handler) (Client, *tracetest.SpanRecorder, *testMeter) {
+	ts := httptest.NewServer(handler)
+	retryWait := time.Millisecond
+
+	spans := tracetest.NewSpanRecorder()
+	meter := &testMeter{}
+
+	tc := mock.NewTestConfig(t, ts)
+	tc.RetryWaitMin = &retryWait
+	tc.RetryWaitMax = &retryWait
+	tc.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	tc.Meter = meter
+
+	return NewClient(tc), spans, meter
+}
+
+func attributes(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+
+	return m
+}
+
+func TestTelemetryNerdGraphSpan(t *testing.T) {
+	t.Parallel()
+	c, spans, meter := newInstrumentedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+
+	err := c.NerdGraphQuery(`mutation($accountId: Int!) { alertsPolicyCreate(accountId: $accountId) { id } }`, nil, nil)
+	require.NoError(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+
+	span := ended[0]
+	attrs := attributes(span.Attributes())
+
+	assert.Equal(t, "nerdgraph mutation alertsPolicyCreate", span.Name())
+	assert.Equal(t, "nerdgraph", attrs["newrelic.endpoint_family"].AsString())
+	assert.Equal(t, "mutation", attrs["graphql.operation.type"].AsString())
+	assert.Equal(t, "alertsPolicyCreate", attrs["graphql.operation.name"].AsString())
+	assert.Equal(t, "POST", attrs["http.method"].AsString())
+	assert.Equal(t, int64(200), attrs["http.status_code"].AsInt64())
+	assert.Equal(t, int64(0), attrs["newrelic.retry_count"].AsInt64())
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	m := meter.Measurements()
+	require.Len(t, m["newrelic.client.requests"], 1)
+	require.Len(t, m["newrelic.client.duration"], 1)
+	assert.Empty(t, m["newrelic.client.retries"])
+	assert.Empty(t, m["newrelic.client.errors"])
+	assert.Equal(t, float64(1), m["newrelic.client.requests"][0].Value)
+}
+
+func TestTelemetryRetriesAndErrors(t *testing.T) {
+	t.Parallel()
+	c, spans, meter := newInstrumentedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+	require.Error(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+
+	span := ended[0]
+	attrs := attributes(span.Attributes())
+
+	assert.Equal(t, "GET rest", span.Name())
+	assert.Equal(t, int64(503), attrs["http.status_code"].AsInt64())
+	assert.Equal(t, int64(3), attrs["newrelic.retry_count"].AsInt64())
+	assert.Equal(t, "MaxRetriesReached", attrs["error.class"].AsString())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	m := meter.Measurements()
+	require.Len(t, m["newrelic.client.retries"], 1)
+	require.Len(t, m["newrelic.client.errors"], 1)
+	assert.Equal(t, float64(3), m["newrelic.client.retries"][0].Value)
+	assert.Equal(t, "MaxRetriesReached", m["newrelic.client.errors"][0].Attrs["error.class"].AsString())
+}
+
+func TestTelemetryErrorClass(t *testing.T) {
+	t.Parallel()
+	c, spans, _ := newInstrumentedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	_, err := c.Get(c.config.Region().RestURL("path"), nil, nil)
+	require.Error(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+
+	attrs := attributes(ended[0].Attributes())
+	assert.Equal(t, "Unauthorized", attrs["error.class"].AsString())
+}
+
+func TestTelemetryDisabled(t *testing.T) {
+	t.Parallel()
+	c := NewTestAPIClient(t, nil)
+
+	assert.Nil(t, c.telemetry)
+}
+
+func TestGraphQLOperation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Query string
+		Type  string
+		Name  string
+	}{
+		{`query { actor { user { id } } }`, "query", "actor"},
+		{`{ actor { user { id } } }`, "query", "actor"},
+		{`query GetUser { actor { user { id } } }`, "query", "GetUser"},
+		{`mutation($accountId: Int!) { dashboardCreate(accountId: $accountId) { errors { description } } }`, "mutation", "dashboardCreate"},
+		{`  mutation DeleteDashboard($guid: EntityGuid!) { dashboardDelete(guid: $guid) { status } }`, "mutation", "DeleteDashboard"},
+		{``, "query", ""},
+	}
+
+	for _, tc := range cases {
+		opType, opName := graphQLOperation(tc.Query)
+
+		assert.Equal(t, tc.Type, opType, tc.Query)
+		assert.Equal(t, tc.Name, opName, tc.Query)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Canceled", errorClass(errors.NewRequestCanceled(nil)))
+	assert.Equal(t, "NotFound", errorClass(errors.NewNotFound("")))
+	assert.Equal(t, "Unauthorized", errorClass(errors.NewUnauthorizedError()))
+	assert.Equal(t, "MaxRetriesReached", errorClass(errors.NewMaxRetriesReached("")))
+	assert.Equal(t, "UnexpectedStatusCode", errorClass(errors.NewUnexpectedStatusCode(400, "")))
+	assert.Equal(t, "GraphQLError", errorClass(&GraphQLErrorResponse{}))
+	assert.Equal(t, "APIError", errorClass(&DefaultErrorResponse{}))
+}
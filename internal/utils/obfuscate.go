@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+)
+
+// obfuscatedHeaders are the headers that carry API keys.
+var obfuscatedHeaders = []string{"Api-Key", "X-Api-Key", "X-Insert-Key", "X-License-Key"}
+
+// Obfuscate masks all but the first 8 characters of a secret value,
+// e.g. "NRAK-ABC**********".
+func Obfuscate(input string) string {
+	result := make([]string, len(input))
+	parts := strings.Split(input, "")
+
+	for i, x := range parts {
+		if i < 8 {
+			result[i] = x
+		} else {
+			result[i] = "*"
+		}
+	}
+
+	return strings.Join(result, "")
+}
+
+// ObfuscateHeader returns a copy of the header with the values of the
+// headers that carry API keys obfuscated.
+func ObfuscateHeader(header http.Header) http.Header {
+	h := http.Header{}
+
+	for k, values := range header {
+		if !isObfuscatedHeader(k) || len(values) == 0 {
+			h[k] = values
+			continue
+		}
+
+		newValues := make([]string, len(values))
+		for i, v := range values {
+			newValues[i] = Obfuscate(v)
+		}
+
+		h[k] = newValues
+	}
+
+	return h
+}
+
+func isObfuscatedHeader(key string) bool {
+	for _, h := range obfuscatedHeaders {
+		if key == h {
+			return true
+		}
+	}
+
+	return false
+}
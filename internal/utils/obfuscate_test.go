@@ -0,0 +1,39 @@
+//go:build unit
+// +build unit
+
+package utils
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscate(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "NRAK-012********", Obfuscate("NRAK-0123456789A"))
+	assert.Equal(t, "short", Obfuscate("short"))
+	assert.Equal(t, "", Obfuscate(""))
+}
+
+func TestObfuscateHeader(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("Api-Key", "NRAK-0123456789A")
+	header.Set("X-Insert-Key", "NRII-0123456789A")
+	header.Set("X-License-Key", "0123456789ABCDEF")
+	header.Set("Content-Type", "application/json")
+
+	result := ObfuscateHeader(header)
+
+	assert.Equal(t, "NRAK-012********", result.Get("Api-Key"))
+	assert.Equal(t, "NRII-012********", result.Get("X-Insert-Key"))
+	assert.Equal(t, "01234567********", result.Get("X-License-Key"))
+	assert.Equal(t, "application/json", result.Get("Content-Type"))
+
+	// The original header is left untouched.
+	assert.Equal(t, "NRAK-0123456789A", header.Get("Api-Key"))
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/newrelic/newrelic-client-go/pkg/accounts"
 	"github.com/newrelic/newrelic-client-go/pkg/alerts"
@@ -21,14 +22,17 @@ import (
 	"github.com/newrelic/newrelic-client-go/pkg/installevents"
 	"github.com/newrelic/newrelic-client-go/pkg/logging"
 	"github.com/newrelic/newrelic-client-go/pkg/logs"
+	"github.com/newrelic/newrelic-client-go/pkg/metrics"
 	"github.com/newrelic/newrelic-client-go/pkg/nerdgraph"
 	"github.com/newrelic/newrelic-client-go/pkg/nerdstorage"
 	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
 	"github.com/newrelic/newrelic-client-go/pkg/nrqldroprules"
 	"github.com/newrelic/newrelic-client-go/pkg/plugins"
+	"github.com/newrelic/newrelic-client-go/pkg/ratelimit"
 	"github.com/newrelic/newrelic-client-go/pkg/region"
 	"github.com/newrelic/newrelic-client-go/pkg/servicelevel"
 	"github.com/newrelic/newrelic-client-go/pkg/synthetics"
+	"github.com/newrelic/newrelic-client-go/pkg/traces"
 	"github.com/newrelic/newrelic-client-go/pkg/workloads"
 )
 
@@ -46,6 +50,7 @@ type NewRelic struct {
 	EventsToMetrics eventstometrics.EventsToMetrics
 	InstallEvents   installevents.Installevents
 	Logs            logs.Logs
+	Metrics         metrics.Metrics
 	NerdGraph       nerdgraph.NerdGraph
 	NerdStorage     nerdstorage.NerdStorage
 	Nrdb            nrdb.Nrdb
@@ -53,6 +58,7 @@ type NewRelic struct {
 	Plugins         plugins.Plugins
 	ServiceLevel    servicelevel.Servicelevel
 	Synthetics      synthetics.Synthetics
+	Traces          traces.Traces
 	Workloads       workloads.Workloads
 
 	config config.Config
@@ -94,6 +100,7 @@ func New(opts ...ConfigOption) (*NewRelic, error) {
 		EventsToMetrics: eventstometrics.New(cfg),
 		InstallEvents:   installevents.New(cfg),
 		Logs:            logs.New(cfg),
+		Metrics:         metrics.New(cfg),
 		NerdGraph:       nerdgraph.New(cfg),
 		NerdStorage:     nerdstorage.New(cfg),
 		Nrdb:            nrdb.New(cfg),
@@ -101,6 +108,7 @@ func New(opts ...ConfigOption) (*NewRelic, error) {
 		Plugins:         plugins.New(cfg),
 		ServiceLevel:    servicelevel.New(cfg),
 		Synthetics:      synthetics.New(cfg),
+		Traces:          traces.New(cfg),
 		Workloads:       workloads.New(cfg),
 	}
 
@@ -208,6 +216,148 @@ func ConfigHTTPTransport(transport http.RoundTripper) ConfigOption {
 	}
 }
 
+// ConfigRetryMax sets the maximum number of times a failed request is retried.
+// Setting this to zero disables retries.
+func ConfigRetryMax(retryMax int) ConfigOption {
+	return func(cfg *config.Config) error {
+		if retryMax < 0 {
+			return errors.New("retry max can not be negative")
+		}
+
+		cfg.RetryMax = &retryMax
+		return nil
+	}
+}
+
+// ConfigRetryWait sets the minimum and maximum time to wait between retries.
+// The wait grows exponentially with each attempt, bounded by these values.
+func ConfigRetryWait(min time.Duration, max time.Duration) ConfigOption {
+	return func(cfg *config.Config) error {
+		if min < 0 || max < 0 {
+			return errors.New("retry wait can not be negative")
+		}
+
+		if min > max {
+			return errors.New("retry wait min can not be greater than retry wait max")
+		}
+
+		cfg.RetryWaitMin = &min
+		cfg.RetryWaitMax = &max
+		return nil
+	}
+}
+
+// ConfigRetryJitter toggles randomized jitter on the backoff between retries.
+func ConfigRetryJitter(jitter bool) ConfigOption {
+	return func(cfg *config.Config) error {
+		cfg.RetryJitter = jitter
+		return nil
+	}
+}
+
+// ConfigCheckRetry sets the policy used to decide whether a failed request
+// should be retried, replacing the default policy.
+func ConfigCheckRetry(checkRetry config.CheckRetry) ConfigOption {
+	return func(cfg *config.Config) error {
+		if checkRetry != nil {
+			cfg.CheckRetry = checkRetry
+			return nil
+		}
+
+		return errors.New("check retry can not be nil")
+	}
+}
+
+// ConfigRateLimit limits requests to an endpoint family to requestsPerSecond
+// on average, with bursts of up to burst requests.  The limit is shared by all
+// of the API clients created by New.
+func ConfigRateLimit(family config.EndpointFamily, requestsPerSecond float64, burst int) ConfigOption {
+	return func(cfg *config.Config) error {
+		if requestsPerSecond <= 0 {
+			return errors.New("rate limit must be greater than zero")
+		}
+
+		if burst <= 0 {
+			return errors.New("rate limit burst must be greater than zero")
+		}
+
+		cfg.SetRateLimiter(family, ratelimit.NewLimiter(requestsPerSecond, burst))
+		return nil
+	}
+}
+
+// ConfigRateLimiter sets a custom rate limiter for an endpoint family.
+// The limiter is shared by all of the API clients created by New.
+func ConfigRateLimiter(family config.EndpointFamily, limiter config.RateLimiter) ConfigOption {
+	return func(cfg *config.Config) error {
+		if limiter != nil {
+			cfg.SetRateLimiter(family, limiter)
+			return nil
+		}
+
+		return errors.New("rate limiter can not be nil")
+	}
+}
+
+// ConfigMaxResponseSize sets the maximum size in bytes of a response body the
+// client reads.  Larger responses fail with an errors.ResponseTooLarge.
+func ConfigMaxResponseSize(size int64) ConfigOption {
+	return func(cfg *config.Config) error {
+		if size < 0 {
+			return errors.New("max response size can not be negative")
+		}
+
+		cfg.MaxResponseSize = size
+		return nil
+	}
+}
+
+// ConfigNerdGraphPartialData sets whether data returned alongside NerdGraph
+// errors is decoded into the response, rather than discarded.  The errors are
+// still returned, as an errors.GraphQLErrors with PartialData set.
+func ConfigNerdGraphPartialData(partialData bool) ConfigOption {
+	return func(cfg *config.Config) error {
+		cfg.NerdGraphPartialData = partialData
+		return nil
+	}
+}
+
+// ConfigInterceptors adds interceptors that are called at each stage of every
+// request made by the client, in the order they are provided.
+func ConfigInterceptors(interceptors ...config.Interceptor) ConfigOption {
+	return func(cfg *config.Config) error {
+		cfg.Interceptors = append(cfg.Interceptors, interceptors...)
+		return nil
+	}
+}
+
+// ConfigTracerProvider enables OpenTelemetry tracing of every REST and NerdGraph
+// request made by the client, using spans from the given tracer provider.
+func ConfigTracerProvider(tracerProvider trace.TracerProvider) ConfigOption {
+	return func(cfg *config.Config) error {
+		if tracerProvider != nil {
+			cfg.TracerProvider = tracerProvider
+			return nil
+		}
+
+		return errors.New("tracer provider can not be nil")
+	}
+}
+
+// ConfigMeter enables metrics for every REST and NerdGraph request made by the
+// client, recorded through the given meter.  See config.Meter for an adapter
+// for the OpenTelemetry metric API.
+func ConfigMeter(meter config.Meter) ConfigOption {
+	return func(cfg *config.Config) error {
+		if meter != nil {
+			cfg.Meter = meter
+			return nil
+		}
+
+		return errors.New("meter can not be nil")
+	}
+}
+
 // ConfigUserAgent sets the HTTP UserAgent for API requests.
 func ConfigUserAgent(ua string) ConfigOption {
 	return func(cfg *config.Config) error {
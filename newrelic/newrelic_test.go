@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"testing"
@@ -11,9 +12,12 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/newrelic/newrelic-client-go/pkg/config"
 	"github.com/newrelic/newrelic-client-go/pkg/logging"
+	"github.com/newrelic/newrelic-client-go/pkg/ratelimit"
 )
 
 var testAPIkey = "asdf1234"
@@ -141,6 +145,150 @@ func TestNew_optionTransport(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestNew_optionRetryMax(t *testing.T) {
+	t.Parallel()
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigRetryMax(-1))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "retry max can not be negative")
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigRetryMax(0))
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	assert.Equal(t, 0, *nr.config.RetryMax)
+}
+
+func TestNew_optionRetryWait(t *testing.T) {
+	t.Parallel()
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigRetryWait(-time.Second, time.Second))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "retry wait can not be negative")
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigRetryWait(time.Minute, time.Second))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "retry wait min can not be greater than retry wait max")
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigRetryWait(time.Second, time.Minute), ConfigRetryJitter(true))
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	assert.Equal(t, time.Second, *nr.config.RetryWaitMin)
+	assert.Equal(t, time.Minute, *nr.config.RetryWaitMax)
+	assert.True(t, nr.config.RetryJitter)
+}
+
+func TestNew_optionMaxResponseSize(t *testing.T) {
+	t.Parallel()
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigMaxResponseSize(-1))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "max response size can not be negative")
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigMaxResponseSize(1<<20))
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	assert.Equal(t, int64(1<<20), nr.config.MaxResponseSize)
+}
+
+func TestNew_optionCheckRetry(t *testing.T) {
+	t.Parallel()
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigCheckRetry(nil))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "check retry can not be nil")
+
+	checkRetry := func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		return false, nil
+	}
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigCheckRetry(checkRetry))
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	assert.NotNil(t, nr.config.CheckRetry)
+}
+
+func TestNew_optionRateLimit(t *testing.T) {
+	t.Parallel()
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigRateLimit(config.EndpointFamilies.NerdGraph, 0, 1))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "rate limit must be greater than zero")
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigRateLimit(config.EndpointFamilies.NerdGraph, 1, 0))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "rate limit burst must be greater than zero")
+
+	nr, err = New(
+		ConfigPersonalAPIKey(testAPIkey),
+		ConfigRateLimit(config.EndpointFamilies.NerdGraph, 10, 5),
+		ConfigRateLimit(config.EndpointFamilies.Synthetics, 1, 1),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	assert.Len(t, nr.config.RateLimiters, 2)
+}
+
+func TestNew_optionRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigRateLimiter(config.EndpointFamilies.REST, nil))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "rate limiter can not be nil")
+
+	limiter := ratelimit.NewLimiter(1, 1)
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigRateLimiter(config.EndpointFamilies.REST, limiter))
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	assert.Same(t, limiter, nr.config.RateLimiters[config.EndpointFamilies.REST])
+}
+
+func TestNew_optionInterceptors(t *testing.T) {
+	t.Parallel()
+
+	first := config.Interceptor{OnError: func(info *config.RequestInfo, err error) {}}
+	second := config.Interceptor{OnRetry: func(info *config.RequestInfo, resp *http.Response, err error) {}}
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigInterceptors(first), ConfigInterceptors(second))
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	require.Len(t, nr.config.Interceptors, 2)
+	assert.NotNil(t, nr.config.Interceptors[0].OnError)
+	assert.NotNil(t, nr.config.Interceptors[1].OnRetry)
+}
+
+func TestNew_optionTracerProvider(t *testing.T) {
+	t.Parallel()
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigTracerProvider(nil))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "tracer provider can not be nil")
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigTracerProvider(trace.NewNoopTracerProvider()))
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	assert.NotNil(t, nr.config.TracerProvider)
+}
+
+type testMeter struct{}
+
+func (testMeter) AddInt64(context.Context, config.Metric, int64, ...attribute.KeyValue) {}
+
+func (testMeter) RecordFloat64(context.Context, config.Metric, float64, ...attribute.KeyValue) {}
+
+func TestNew_optionMeter(t *testing.T) {
+	t.Parallel()
+
+	nr, err := New(ConfigPersonalAPIKey(testAPIkey), ConfigMeter(nil))
+	assert.Nil(t, nr)
+	assert.EqualError(t, err, "meter can not be nil")
+
+	nr, err = New(ConfigPersonalAPIKey(testAPIkey), ConfigMeter(testMeter{}))
+	require.NoError(t, err)
+	require.NotNil(t, nr)
+	assert.NotNil(t, nr.config.Meter)
+}
+
 func TestNew_optionUserAgent(t *testing.T) {
 	t.Parallel()
 
@@ -2,10 +2,13 @@ package alerts
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
 	nrhttp "github.com/newrelic/newrelic-client-go/internal/http"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
 )
 
 // NerdGraphQueryWithContext works similarly to the default client's NerdGraphQueryWithContext but with a custom error
@@ -42,6 +45,57 @@ type GraphQLErrorResponse struct {
 			} `json:"validationErrors"`
 		} `json:"extensions"`
 	} `json:"errors"`
+
+	// paths are the error paths as decoded, with list indices as numbers.
+	paths [][]interface{}
+}
+
+// graphQLError is an element of GraphQLErrorResponse.Errors.
+type graphQLError = struct {
+	Message    string   `json:"message"`
+	Path       []string `json:"path"`
+	Extensions struct {
+		Code             string `json:"code"`
+		ErrorClass       string `json:"errorClass"`
+		ErrorCode        string `json:"error_code,omitempty"`
+		ValidationErrors []struct {
+			Name   string `json:"name"`
+			Reason string `json:"reason"`
+		} `json:"validationErrors"`
+	} `json:"extensions"`
+}
+
+// UnmarshalJSON decodes the response.  Error paths mix field names with list
+// indices, so the indices are kept as strings in Path, and as numbers in the
+// paths returned by GraphQLErrors.
+func (r *GraphQLErrorResponse) UnmarshalJSON(data []byte) error {
+	var response struct {
+		Errors []struct {
+			graphQLError
+			Path []interface{} `json:"path"`
+		} `json:"errors"`
+	}
+
+	if err := json.Unmarshal(data, &response); err != nil {
+		return err
+	}
+
+	r.Errors = make([]graphQLError, len(response.Errors))
+	r.paths = make([][]interface{}, len(response.Errors))
+
+	for i, e := range response.Errors {
+		r.Errors[i] = e.graphQLError
+		r.paths[i] = nrhttp.GraphQLErrorPath(e.Path)
+
+		if e.Path != nil {
+			r.Errors[i].Path = make([]string, len(e.Path))
+			for j, p := range r.paths[i] {
+				r.Errors[i].Path[j] = fmt.Sprint(p)
+			}
+		}
+	}
+
+	return nil
 }
 
 func (r *GraphQLErrorResponse) Error() string {
@@ -58,6 +112,32 @@ func (r *GraphQLErrorResponse) Error() string {
 	return ""
 }
 
+// GraphQLErrors returns the structured errors contained in the response.
+func (r *GraphQLErrorResponse) GraphQLErrors() []errors.GraphQLError {
+	errs := make([]errors.GraphQLError, len(r.Errors))
+	for i, e := range r.Errors {
+		var path []interface{}
+		if i < len(r.paths) {
+			path = r.paths[i]
+		} else if e.Path != nil {
+			path = make([]interface{}, len(e.Path))
+			for j, p := range e.Path {
+				path[j] = p
+			}
+		}
+
+		errs[i] = errors.GraphQLError{
+			Message:    e.Message,
+			Path:       path,
+			ErrorClass: e.Extensions.ErrorClass,
+			ErrorCode:  e.Extensions.ErrorCode,
+			Code:       e.Extensions.Code,
+		}
+	}
+
+	return errs
+}
+
 func (r *GraphQLErrorResponse) IsNotFound() bool {
 	for _, err := range r.Errors {
 		if strings.Contains(err.Message, "Not Found") {
@@ -0,0 +1,35 @@
+//go:build unit
+// +build unit
+
+package alerts
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLErrorResponsePath(t *testing.T) {
+	t.Parallel()
+
+	var resp GraphQLErrorResponse
+	err := json.Unmarshal([]byte(`{"errors": [
+		{"message": "Not Found", "path": ["actor", "entities", 1, "name"], "extensions": {"errorClass": "TIMEOUT"}},
+		{"message": "no path"}
+	]}`), &resp)
+	require.NoError(t, err)
+
+	require.Len(t, resp.Errors, 2)
+	assert.Equal(t, []string{"actor", "entities", "1", "name"}, resp.Errors[0].Path)
+	assert.Equal(t, "TIMEOUT", resp.Errors[0].Extensions.ErrorClass)
+	assert.Nil(t, resp.Errors[1].Path)
+	assert.True(t, resp.IsRetryableError())
+
+	errs := resp.GraphQLErrors()
+	require.Len(t, errs, 2)
+	assert.Equal(t, []interface{}{"actor", "entities", 1, "name"}, errs[0].Path)
+	assert.Equal(t, "Not Found", errs[0].Message)
+	assert.Nil(t, errs[1].Path)
+}
@@ -2,9 +2,12 @@
 package config
 
 import (
+	"context"
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/newrelic/newrelic-client-go/internal/version"
 	"github.com/newrelic/newrelic-client-go/pkg/logging"
 	"github.com/newrelic/newrelic-client-go/pkg/region"
@@ -37,6 +40,49 @@ type Config struct {
 	// HTTPTransport allows customization of the client's underlying transport.
 	HTTPTransport http.RoundTripper
 
+	// RetryMax is the maximum number of times a failed request is retried.
+	RetryMax *int
+
+	// RetryWaitMin is the minimum time to wait before retrying a request.
+	RetryWaitMin *time.Duration
+
+	// RetryWaitMax is the maximum time to wait before retrying a request.
+	RetryWaitMax *time.Duration
+
+	// RetryJitter toggles randomized jitter on the exponential backoff between retries,
+	// which spreads out retries from many clients hitting the same rate limit.
+	RetryJitter bool
+
+	// CheckRetry allows customization of the policy used to decide whether a
+	// failed request should be retried.  If nil, the client's default policy is used.
+	CheckRetry CheckRetry
+
+	// RateLimiters throttles outgoing requests per endpoint family.  Every attempt,
+	// including retries, waits on the limiter for its family before it is sent.
+	// Clients created from copies of the same Config share these limiters.
+	RateLimiters map[EndpointFamily]RateLimiter
+
+	// MaxResponseSize is the maximum size in bytes of a response body the client
+	// reads.  Larger responses fail with an errors.ResponseTooLarge.  Zero means
+	// there is no limit.
+	MaxResponseSize int64
+
+	// NerdGraphPartialData toggles decoding the data returned alongside NerdGraph
+	// errors into the response value, instead of discarding it.  The errors are
+	// still returned as an errors.GraphQLErrors with PartialData set.
+	NerdGraphPartialData bool
+
+	// Interceptors are called, in order, at each stage of every request made by the client.
+	Interceptors []Interceptor
+
+	// TracerProvider enables OpenTelemetry tracing of requests made by the client.
+	// Each request produces a client span, covering all of its retries.
+	TracerProvider trace.TracerProvider
+
+	// Meter enables metrics for requests made by the client, counting
+	// requests, retries and errors, and recording request latency.
+	Meter Meter
+
 	// Compression used in sending data in HTTP requests.
 	Compression CompressionType
 
@@ -57,6 +103,29 @@ type Config struct {
 	Logger logging.Logger
 }
 
+// CheckRetry is called after each HTTP response or transport error to decide
+// whether the request should be retried.  It is called again with the error
+// response read from the body when a response carries errors, such as
+// NerdGraph errors returned with a 200 status code.  Returning a non-nil
+// error stops retrying and surfaces that error to the caller.
+type CheckRetry func(ctx context.Context, resp *http.Response, err error) (bool, error)
+
+// RateLimiter blocks until a request is permitted to proceed, or returns an
+// error if the context is done first.  A golang.org/x/time/rate.Limiter
+// satisfies this interface, as does ratelimit.Limiter.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// SetRateLimiter configures the rate limiter used for requests to an endpoint family
+func (c *Config) SetRateLimiter(family EndpointFamily, limiter RateLimiter) {
+	if c.RateLimiters == nil {
+		c.RateLimiters = map[EndpointFamily]RateLimiter{}
+	}
+
+	c.RateLimiters[family] = limiter
+}
+
 // New creates a default configuration and returns it
 func New() Config {
 	reg, _ := region.Get(region.Default)
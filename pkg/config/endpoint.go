@@ -0,0 +1,28 @@
+package config
+
+// EndpointFamily groups the New Relic API endpoints that share a rate limit.
+type EndpointFamily string
+
+// EndpointFamilies is an Enum of the endpoint families requests are grouped into
+var EndpointFamilies = struct {
+	REST           EndpointFamily
+	NerdGraph      EndpointFamily
+	Synthetics     EndpointFamily
+	InsightsIngest EndpointFamily
+	LogsIngest     EndpointFamily
+	MetricsIngest  EndpointFamily
+	TracesIngest   EndpointFamily
+}{
+	REST:           "rest",
+	NerdGraph:      "nerdgraph",
+	Synthetics:     "synthetics",
+	InsightsIngest: "insights",
+	LogsIngest:     "logs",
+	MetricsIngest:  "metrics",
+	TracesIngest:   "traces",
+}
+
+// String returns the name of the endpoint family
+func (f EndpointFamily) String() string {
+	return string(f)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"net/http"
+	"time"
+)
+
+// Interceptor is a set of hooks called at each stage of every request made by
+// the client.  Any of the hooks may be nil.  Hooks are called synchronously on
+// the goroutine making the request, so they should return quickly.
+type Interceptor struct {
+	// BeforeRequest is called before each attempt is sent, including retries.
+	// It may modify info.Request, for example to add tracing headers or to
+	// point the request at a test server.  Returning an error aborts the
+	// request without retrying.
+	BeforeRequest func(info *RequestInfo) error
+
+	// AfterResponse is called after each attempt receives a response, along
+	// with how long the attempt took.  It must not read or close the response body.
+	AfterResponse func(info *RequestInfo, resp *http.Response, duration time.Duration)
+
+	// OnRetry is called when a failed attempt is about to be retried.  Either
+	// resp or err may be nil depending on how the attempt failed.
+	OnRetry func(info *RequestInfo, resp *http.Response, err error)
+
+	// OnError is called once when a request fails, with the error returned to the caller.
+	OnError func(info *RequestInfo, err error)
+}
+
+// RequestInfo describes a request made by the client, as seen by an Interceptor.
+type RequestInfo struct {
+	// Request is the HTTP request for the current attempt.  It is nil if the
+	// request failed before the first attempt was sent.
+	Request *http.Request
+
+	// EndpointFamily is the family of New Relic APIs the request is sent to.
+	EndpointFamily EndpointFamily
+
+	// GraphQLQuery is the query sent with a NerdGraph request.
+	GraphQLQuery string
+
+	// GraphQLVariables are the variables sent with a NerdGraph request.
+	GraphQLVariables map[string]interface{}
+
+	// Attempt is the zero-based number of the current attempt.
+	Attempt int
+}
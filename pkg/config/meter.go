@@ -0,0 +1,113 @@
+package config
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Meter records metrics for requests made by the client.  It is the client's
+// own interface, rather than a metrics library's, so any library can be
+// adapted to it without the client depending on the library's version.  An
+// adapter for OpenTelemetry's metric API creates an instrument for each of
+// Metrics from a meter, and adds or records to the instrument for the metric.
+// Methods are called synchronously on the goroutine making the request, so
+// they should return quickly.
+//
+// An adapter for the stable OpenTelemetry metric API,
+// go.opentelemetry.io/otel/metric v1, might look like:
+//
+//	type otelMeter struct {
+//		counters   map[string]metric.Int64Counter
+//		histograms map[string]metric.Float64Histogram
+//	}
+//
+//	func newOTelMeter(provider metric.MeterProvider) (*otelMeter, error) {
+//		meter := provider.Meter("github.com/newrelic/newrelic-client-go")
+//		m := &otelMeter{
+//			counters:   map[string]metric.Int64Counter{},
+//			histograms: map[string]metric.Float64Histogram{},
+//		}
+//
+//		for _, mt := range config.Metrics {
+//			var err error
+//			if mt == config.MetricDuration {
+//				m.histograms[mt.Name], err = meter.Float64Histogram(mt.Name,
+//					metric.WithDescription(mt.Description), metric.WithUnit(mt.Unit))
+//			} else {
+//				m.counters[mt.Name], err = meter.Int64Counter(mt.Name,
+//					metric.WithDescription(mt.Description), metric.WithUnit(mt.Unit))
+//			}
+//			if err != nil {
+//				return nil, err
+//			}
+//		}
+//
+//		return m, nil
+//	}
+//
+//	func (m *otelMeter) AddInt64(ctx context.Context, mt config.Metric, incr int64, attrs ...attribute.KeyValue) {
+//		if counter, ok := m.counters[mt.Name]; ok {
+//			counter.Add(ctx, incr, metric.WithAttributes(attrs...))
+//		}
+//	}
+//
+//	func (m *otelMeter) RecordFloat64(ctx context.Context, mt config.Metric, value float64, attrs ...attribute.KeyValue) {
+//		if histogram, ok := m.histograms[mt.Name]; ok {
+//			histogram.Record(ctx, value, metric.WithAttributes(attrs...))
+//		}
+//	}
+//
+// and is passed to the client with newrelic.ConfigMeter.
+type Meter interface {
+	// AddInt64 adds incr to the counter for the metric.
+	AddInt64(ctx context.Context, metric Metric, incr int64, attrs ...attribute.KeyValue)
+
+	// RecordFloat64 records value in the histogram for the metric.
+	RecordFloat64(ctx context.Context, metric Metric, value float64, attrs ...attribute.KeyValue)
+}
+
+// Metric describes a metric recorded by the client through a Meter.
+type Metric struct {
+	// Name of the metric, following OpenTelemetry's naming conventions.
+	Name string
+
+	// Description of the metric.
+	Description string
+
+	// Unit of the metric's values, in UCUM, or empty for counts.
+	Unit string
+}
+
+// The metrics recorded by the client.
+var (
+	// MetricRequests counts the requests made to New Relic APIs.
+	MetricRequests = Metric{
+		Name:        "newrelic.client.requests",
+		Description: "Number of requests made to New Relic APIs",
+	}
+
+	// MetricRetries counts the times requests to New Relic APIs were retried.
+	MetricRetries = Metric{
+		Name:        "newrelic.client.retries",
+		Description: "Number of times requests to New Relic APIs were retried",
+	}
+
+	// MetricErrors counts the requests to New Relic APIs that failed.
+	MetricErrors = Metric{
+		Name:        "newrelic.client.errors",
+		Description: "Number of requests to New Relic APIs that failed",
+	}
+
+	// MetricDuration is a histogram of the duration of requests to New Relic
+	// APIs, including retries.
+	MetricDuration = Metric{
+		Name:        "newrelic.client.duration",
+		Description: "Duration of requests to New Relic APIs, including retries",
+		Unit:        "ms",
+	}
+
+	// Metrics are all the metrics recorded by the client.  MetricDuration is
+	// the only histogram, the others are counters.
+	Metrics = []Metric{MetricRequests, MetricRetries, MetricErrors, MetricDuration}
+)
@@ -2,10 +2,77 @@
 package errors
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// Sentinel errors matched by the error types in this package, allowing callers
+// to branch on the kind of error with errors.Is, e.g.
+//
+//	if errors.Is(err, errors.ErrNotFound) {
+//		...
+//	}
+var (
+	ErrNotFound             error = sentinel("resource not found")
+	ErrUnexpectedStatusCode error = sentinel("unexpected status code")
+	ErrUnauthorized         error = sentinel("unauthorized")
+	ErrForbidden            error = sentinel("forbidden")
+	ErrRateLimited          error = sentinel("rate limited")
+	ErrMaxRetriesReached    error = sentinel("maximum retries reached")
+	ErrInvalidInput         error = sentinel("invalid input")
+	ErrRequestCanceled      error = sentinel("request canceled")
+	ErrResponseTooLarge     error = sentinel("response too large")
+	ErrPayloadTooLarge      error = sentinel("payload too large")
+)
+
+type sentinel string
+
+func (e sentinel) Error() string {
+	return string(e)
+}
+
+// RequestDetails describes the request that resulted in an error.  It is
+// embedded in the error types of this package, and is populated for errors
+// returned by the client.
+type RequestDetails struct {
+	// Method is the HTTP method of the request.
+	Method string
+
+	// URL is the URL of the request, without query parameters.
+	URL string
+
+	// RequestID is the request ID returned in the response headers, if any.
+	RequestID string
+}
+
+// Request returns the details of the request that resulted in the error.
+func (d *RequestDetails) Request() RequestDetails {
+	return *d
+}
+
+// SetRequest sets the details of the request that resulted in the error.
+func (d *RequestDetails) SetRequest(details RequestDetails) {
+	*d = details
+}
+
+// GetRequestDetails returns the details of the request that resulted in err,
+// and whether any were found.
+func GetRequestDetails(err error) (RequestDetails, bool) {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		if e, ok := err.(interface{ Request() RequestDetails }); ok {
+			if details := e.Request(); details != (RequestDetails{}) {
+				return details, true
+			}
+		}
+	}
+
+	return RequestDetails{}, false
+}
+
 // NewNotFound returns a new instance of NotFound with an optional custom error message.
 func NewNotFound(err string) *NotFound {
 	e := NotFound{
@@ -23,7 +90,10 @@ func NewNotFoundf(format string, args ...interface{}) *NotFound {
 
 // NotFound is returned when the target resource cannot be located.
 type NotFound struct {
-	err string
+	RequestDetails
+
+	err   string
+	cause error
 }
 
 func (e *NotFound) Error() string {
@@ -34,6 +104,22 @@ func (e *NotFound) Error() string {
 	return e.err
 }
 
+// WithCause sets the underlying error that caused the NotFound.
+func (e *NotFound) WithCause(cause error) *NotFound {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns the underlying error that caused the NotFound, if any.
+func (e *NotFound) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the target is ErrNotFound.
+func (e *NotFound) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 // NewUnexpectedStatusCode returns a new instance of UnexpectedStatusCode
 // with an optional custom message.
 func NewUnexpectedStatusCode(statusCode int, err string) *UnexpectedStatusCode {
@@ -52,8 +138,11 @@ func NewUnexpectedStatusCodef(statusCode int, format string, args ...interface{}
 // UnexpectedStatusCode is returned when an unexpected status code is returned
 // from New Relic's APIs.
 type UnexpectedStatusCode struct {
+	RequestDetails
+
 	err        string
 	statusCode int
+	cause      error
 }
 
 func (e *UnexpectedStatusCode) Error() string {
@@ -66,6 +155,27 @@ func (e *UnexpectedStatusCode) Error() string {
 	return msg
 }
 
+// StatusCode returns the HTTP status code of the response.
+func (e *UnexpectedStatusCode) StatusCode() int {
+	return e.statusCode
+}
+
+// WithCause sets the underlying error that caused the UnexpectedStatusCode.
+func (e *UnexpectedStatusCode) WithCause(cause error) *UnexpectedStatusCode {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns the underlying error that caused the UnexpectedStatusCode, if any.
+func (e *UnexpectedStatusCode) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the target is ErrUnexpectedStatusCode.
+func (e *UnexpectedStatusCode) Is(target error) bool {
+	return target == ErrUnexpectedStatusCode
+}
+
 // NewUnauthorizedError returns a new instance of UnauthorizedError
 // with an optional custom message.
 func NewUnauthorizedError() *UnauthorizedError {
@@ -78,8 +188,11 @@ func NewUnauthorizedError() *UnauthorizedError {
 // UnauthorizedError is returned when a 401 HTTP status code is returned
 // from New Relic's APIs.
 type UnauthorizedError struct {
+	RequestDetails
+
 	err        string
 	statusCode int
+	cause      error
 }
 
 func (e *UnauthorizedError) Error() string {
@@ -92,6 +205,154 @@ func (e *UnauthorizedError) Error() string {
 	return msg
 }
 
+// WithCause sets the underlying error that caused the UnauthorizedError.
+func (e *UnauthorizedError) WithCause(cause error) *UnauthorizedError {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns the underlying error that caused the UnauthorizedError, if any.
+func (e *UnauthorizedError) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the target is ErrUnauthorized.
+func (e *UnauthorizedError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+// NewForbidden returns a new instance of Forbidden with an optional custom message.
+func NewForbidden(err string) *Forbidden {
+	return &Forbidden{
+		err: err,
+	}
+}
+
+// Forbidden is returned when a 403 HTTP status code is returned
+// from New Relic's APIs.
+type Forbidden struct {
+	RequestDetails
+
+	err   string
+	cause error
+}
+
+func (e *Forbidden) Error() string {
+	msg := fmt.Sprintf("%d response returned", http.StatusForbidden)
+
+	if e.err != "" {
+		msg += fmt.Sprintf(": %s", e.err)
+	}
+
+	return msg
+}
+
+// WithCause sets the underlying error that caused the Forbidden.
+func (e *Forbidden) WithCause(cause error) *Forbidden {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns the underlying error that caused the Forbidden, if any.
+func (e *Forbidden) Unwrap() error {
+	return e.cause
+}
+
+// StatusCode returns the HTTP status code of the response, which is always 403.
+func (e *Forbidden) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// Is reports whether the target is ErrForbidden, or ErrUnexpectedStatusCode,
+// which 403 responses were returned as before Forbidden was added.
+func (e *Forbidden) Is(target error) bool {
+	return target == ErrForbidden || target == ErrUnexpectedStatusCode
+}
+
+// As sets target to an UnexpectedStatusCode for the response, if target is an
+// **UnexpectedStatusCode, so callers still expecting one get it.
+func (e *Forbidden) As(target interface{}) bool {
+	return asUnexpectedStatusCode(target, e.StatusCode(), e.err, e.RequestDetails, e.cause)
+}
+
+// NewRateLimited returns a new instance of RateLimited with the time to wait
+// before retrying, if known, and an optional custom message.
+func NewRateLimited(retryAfter time.Duration, err string) *RateLimited {
+	return &RateLimited{
+		err:        err,
+		retryAfter: retryAfter,
+	}
+}
+
+// RateLimited is returned when a 429 HTTP status code is returned
+// from New Relic's APIs.
+type RateLimited struct {
+	RequestDetails
+
+	err        string
+	retryAfter time.Duration
+	cause      error
+}
+
+func (e *RateLimited) Error() string {
+	msg := fmt.Sprintf("%d response returned", http.StatusTooManyRequests)
+
+	if e.err != "" {
+		msg += fmt.Sprintf(": %s", e.err)
+	}
+
+	return msg
+}
+
+// RetryAfter returns the time to wait before retrying, as requested by the
+// Retry-After response header, or zero if none was given.
+func (e *RateLimited) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// WithCause sets the underlying error that caused the RateLimited.
+func (e *RateLimited) WithCause(cause error) *RateLimited {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns the underlying error that caused the RateLimited, if any.
+func (e *RateLimited) Unwrap() error {
+	return e.cause
+}
+
+// StatusCode returns the HTTP status code of the response, which is always 429.
+func (e *RateLimited) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+// Is reports whether the target is ErrRateLimited, or ErrUnexpectedStatusCode,
+// which 429 responses were returned as before RateLimited was added.
+func (e *RateLimited) Is(target error) bool {
+	return target == ErrRateLimited || target == ErrUnexpectedStatusCode
+}
+
+// As sets target to an UnexpectedStatusCode for the response, if target is an
+// **UnexpectedStatusCode, so callers still expecting one get it.
+func (e *RateLimited) As(target interface{}) bool {
+	return asUnexpectedStatusCode(target, e.StatusCode(), e.err, e.RequestDetails, e.cause)
+}
+
+// asUnexpectedStatusCode sets target to an UnexpectedStatusCode with the given
+// details, if target is an **UnexpectedStatusCode.
+func asUnexpectedStatusCode(target interface{}, statusCode int, err string, details RequestDetails, cause error) bool {
+	t, ok := target.(**UnexpectedStatusCode)
+	if !ok {
+		return false
+	}
+
+	e := NewUnexpectedStatusCode(statusCode, err).WithCause(cause)
+	e.RequestDetails = details
+	*t = e
+
+	return true
+}
+
 // NewMaxRetriesReached returns a new instance of MaxRetriesReached with an optional custom error message.
 func NewMaxRetriesReached(err string) *MaxRetriesReached {
 	e := MaxRetriesReached{
@@ -109,13 +370,32 @@ func NewMaxRetriesReachedf(format string, args ...interface{}) *MaxRetriesReache
 
 // MaxRetriesReached is returned when the target resource cannot be located.
 type MaxRetriesReached struct {
-	err string
+	RequestDetails
+
+	err   string
+	cause error
 }
 
 func (e *MaxRetriesReached) Error() string {
 	return fmt.Sprintf("maximum retries reached: %s", e.err)
 }
 
+// WithCause sets the error returned by the final attempt.
+func (e *MaxRetriesReached) WithCause(cause error) *MaxRetriesReached {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns the error returned by the final attempt, if any.
+func (e *MaxRetriesReached) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the target is ErrMaxRetriesReached.
+func (e *MaxRetriesReached) Is(target error) bool {
+	return target == ErrMaxRetriesReached
+}
+
 // NewInvalidInput returns a new instance of InvalidInput with an optional custom error message.
 func NewInvalidInput(err string) *InvalidInput {
 	e := InvalidInput{
@@ -133,7 +413,10 @@ func NewInvalidInputf(format string, args ...interface{}) *InvalidInput {
 
 // InvalidInput is returned when the user input is invalid.
 type InvalidInput struct {
-	err string
+	RequestDetails
+
+	err   string
+	cause error
 }
 
 func (e *InvalidInput) Error() string {
@@ -143,3 +426,235 @@ func (e *InvalidInput) Error() string {
 
 	return e.err
 }
+
+// WithCause sets the underlying error that caused the InvalidInput.
+func (e *InvalidInput) WithCause(cause error) *InvalidInput {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns the underlying error that caused the InvalidInput, if any.
+func (e *InvalidInput) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the target is ErrInvalidInput.
+func (e *InvalidInput) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+// NewRequestCanceled returns a new instance of RequestCanceled wrapping
+// the context error that caused the request to be abandoned.
+func NewRequestCanceled(err error) *RequestCanceled {
+	e := RequestCanceled{
+		err: err,
+	}
+
+	return &e
+}
+
+// RequestCanceled is returned when a request is abandoned because its
+// context was canceled or its deadline was exceeded.
+type RequestCanceled struct {
+	RequestDetails
+
+	err error
+}
+
+func (e *RequestCanceled) Error() string {
+	if e.err == nil {
+		return "request canceled"
+	}
+
+	return fmt.Sprintf("request canceled: %s", e.err)
+}
+
+// Unwrap returns the context error that caused the request to be abandoned,
+// allowing callers to check for context.Canceled or context.DeadlineExceeded.
+func (e *RequestCanceled) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrRequestCanceled.
+func (e *RequestCanceled) Is(target error) bool {
+	return target == ErrRequestCanceled
+}
+
+// Timeout reports whether the request was abandoned because its deadline was exceeded.
+func (e *RequestCanceled) Timeout() bool {
+	return stderrors.Is(e.err, context.DeadlineExceeded)
+}
+
+// NewResponseTooLarge returns a new instance of ResponseTooLarge for a
+// response larger than the limit in bytes.
+func NewResponseTooLarge(limit int64) *ResponseTooLarge {
+	return &ResponseTooLarge{
+		limit: limit,
+	}
+}
+
+// ResponseTooLarge is returned when a response body is larger than the
+// configured maximum response size.
+type ResponseTooLarge struct {
+	RequestDetails
+
+	limit int64
+}
+
+func (e *ResponseTooLarge) Error() string {
+	return fmt.Sprintf("response body exceeds the maximum size of %d bytes", e.limit)
+}
+
+// Limit returns the maximum response size in bytes that was exceeded.
+func (e *ResponseTooLarge) Limit() int64 {
+	return e.limit
+}
+
+// Is reports whether the target is ErrResponseTooLarge.
+func (e *ResponseTooLarge) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// NewPayloadTooLarge returns a new instance of PayloadTooLarge for an item of
+// the given size in bytes, which is larger than the limit.
+func NewPayloadTooLarge(size int, limit int) *PayloadTooLarge {
+	return &PayloadTooLarge{
+		size:  size,
+		limit: limit,
+	}
+}
+
+// PayloadTooLarge is returned when an item, such as a custom event or log
+// entry, is too large to ever be sent within the payload size limit of the
+// API it is sent to.
+type PayloadTooLarge struct {
+	size  int
+	limit int
+}
+
+func (e *PayloadTooLarge) Error() string {
+	return fmt.Sprintf("payload of %d bytes exceeds the maximum size of %d bytes", e.size, e.limit)
+}
+
+// Size returns the size in bytes of the item that was rejected.
+func (e *PayloadTooLarge) Size() int {
+	return e.size
+}
+
+// Limit returns the maximum payload size in bytes that was exceeded.
+func (e *PayloadTooLarge) Limit() int {
+	return e.limit
+}
+
+// Is reports whether the target is ErrPayloadTooLarge or ErrInvalidInput.
+func (e *PayloadTooLarge) Is(target error) bool {
+	return target == ErrPayloadTooLarge || target == ErrInvalidInput
+}
+
+// GraphQLError is a single error returned in a NerdGraph response.
+type GraphQLError struct {
+	// Message describes the error.
+	Message string
+
+	// Path is the path to the response field the error applies to, made up
+	// of field names (string) and list indices (int).
+	Path []interface{}
+
+	// ErrorClass is the errorClass extension, e.g. "TIMEOUT" or "FORBIDDEN".
+	ErrorClass string
+
+	// ErrorCode is the error_code extension, e.g. "BAD_API_KEY".
+	ErrorCode string
+
+	// Code is the code extension, e.g. "BAD_USER_INPUT".
+	Code string
+}
+
+// PathString returns the path of the error joined with dots, e.g. "actor.account.nrql".
+func (e GraphQLError) PathString() string {
+	parts := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		parts[i] = fmt.Sprint(p)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// NewGraphQLErrors returns a new instance of GraphQLErrors for the errors in
+// a NerdGraph response.  The cause is the error response the errors were read
+// from, and provides the error message.
+func NewGraphQLErrors(errs []GraphQLError, cause error) *GraphQLErrors {
+	return &GraphQLErrors{
+		Errors: errs,
+		cause:  cause,
+	}
+}
+
+// GraphQLErrors is returned when a NerdGraph response contains errors.
+type GraphQLErrors struct {
+	RequestDetails
+
+	// Errors are the individual errors in the response.
+	Errors []GraphQLError
+
+	// PartialData is true when the response also contained data, which
+	// was decoded into the response value despite the errors.
+	PartialData bool
+
+	cause error
+}
+
+func (e *GraphQLErrors) Error() string {
+	if e.cause != nil {
+		return e.cause.Error()
+	}
+
+	messages := []string{}
+	for _, err := range e.Errors {
+		if err.Message != "" {
+			messages = append(messages, err.Message)
+		}
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+// Unwrap returns the error response the errors were read from.
+func (e *GraphQLErrors) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the target is ErrForbidden and any of the errors have the
+// FORBIDDEN errorClass, or the target is ErrUnauthorized and any of the errors
+// have the BAD_API_KEY error_code.
+func (e *GraphQLErrors) Is(target error) bool {
+	switch target {
+	case ErrForbidden:
+		return e.HasErrorClass("FORBIDDEN")
+	case ErrUnauthorized:
+		for _, err := range e.Errors {
+			if err.ErrorCode == "BAD_API_KEY" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// WithErrorClass returns the errors that have the given errorClass.
+func (e *GraphQLErrors) WithErrorClass(errorClass string) []GraphQLError {
+	matches := []GraphQLError{}
+	for _, err := range e.Errors {
+		if err.ErrorClass == errorClass {
+			matches = append(matches, err)
+		}
+	}
+
+	return matches
+}
+
+// HasErrorClass reports whether any of the errors have the given errorClass.
+func (e *GraphQLErrors) HasErrorClass(errorClass string) bool {
+	return len(e.WithErrorClass(errorClass)) > 0
+}
@@ -4,10 +4,15 @@
 package errors
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestErrorNotFound(t *testing.T) {
@@ -42,3 +47,185 @@ func TestErrorUnauthorized(t *testing.T) {
 	assert.Equal(t, 401, e.statusCode)
 	assert.True(t, strings.Contains(e.Error(), "Invalid credentials provided"))
 }
+
+func TestErrorRequestCanceled(t *testing.T) {
+	t.Parallel()
+
+	var e RequestCanceled
+
+	assert.Equal(t, "request canceled", e.Error())
+	assert.False(t, e.Timeout())
+
+	canceled := NewRequestCanceled(context.Canceled)
+
+	assert.Equal(t, "request canceled: context canceled", canceled.Error())
+	assert.ErrorIs(t, canceled, context.Canceled)
+	assert.False(t, canceled.Timeout())
+
+	deadline := NewRequestCanceled(context.DeadlineExceeded)
+
+	assert.ErrorIs(t, deadline, context.DeadlineExceeded)
+	assert.True(t, deadline.Timeout())
+
+	wrapped := NewRequestCanceled(fmt.Errorf("waiting to retry: %w", context.DeadlineExceeded))
+
+	assert.True(t, wrapped.Timeout())
+}
+
+func TestErrorGraphQLErrors(t *testing.T) {
+	t.Parallel()
+
+	e := NewGraphQLErrors([]GraphQLError{
+		{
+			Message:    "Access denied",
+			Path:       []interface{}{"actor", "account", 0, "nrql"},
+			ErrorClass: "FORBIDDEN",
+		},
+		{
+			Message:    "Timed out",
+			ErrorClass: "TIMEOUT",
+		},
+	}, nil)
+
+	assert.Equal(t, "Access denied, Timed out", e.Error())
+	assert.Equal(t, "actor.account.0.nrql", e.Errors[0].PathString())
+	assert.Equal(t, "", e.Errors[1].PathString())
+	assert.True(t, e.HasErrorClass("FORBIDDEN"))
+	assert.False(t, e.HasErrorClass("SERVER_ERROR"))
+	assert.Len(t, e.WithErrorClass("TIMEOUT"), 1)
+	assert.Nil(t, e.Unwrap())
+
+	cause := NewInvalidInput("custom message")
+	wrapped := NewGraphQLErrors(nil, cause)
+
+	assert.Equal(t, "custom message", wrapped.Error())
+	assert.ErrorIs(t, wrapped, cause)
+}
+
+func TestErrorForbidden(t *testing.T) {
+	t.Parallel()
+
+	e := NewForbidden("no access")
+
+	assert.Equal(t, "403 response returned: no access", e.Error())
+	assert.True(t, errors.Is(e, ErrForbidden))
+	assert.False(t, errors.Is(e, ErrUnauthorized))
+}
+
+func TestErrorRateLimited(t *testing.T) {
+	t.Parallel()
+
+	e := NewRateLimited(5*time.Second, "")
+
+	assert.Equal(t, "429 response returned", e.Error())
+	assert.Equal(t, 5*time.Second, e.RetryAfter())
+	assert.True(t, errors.Is(e, ErrRateLimited))
+}
+
+func TestErrorStatusCodes(t *testing.T) {
+	t.Parallel()
+
+	cases := map[int]error{
+		403: fmt.Errorf("wrapped: %w", NewForbidden("no access")),
+		429: fmt.Errorf("wrapped: %w", NewRateLimited(0, "slow down")),
+	}
+
+	for code, err := range cases {
+		// Callers checking for an unexpected status code still match them.
+		assert.True(t, errors.Is(err, ErrUnexpectedStatusCode), code)
+
+		var statusErr *UnexpectedStatusCode
+		require.True(t, errors.As(err, &statusErr), code)
+		assert.Equal(t, code, statusErr.StatusCode())
+		assert.Equal(t, err.Error(), "wrapped: "+statusErr.Error())
+
+		var coded interface{ StatusCode() int }
+		require.True(t, errors.As(err, &coded), code)
+		assert.Equal(t, code, coded.StatusCode())
+	}
+}
+
+func TestErrorPayloadTooLarge(t *testing.T) {
+	t.Parallel()
+
+	e := NewPayloadTooLarge(1000001, 1000000)
+
+	assert.Equal(t, "payload of 1000001 bytes exceeds the maximum size of 1000000 bytes", e.Error())
+	assert.Equal(t, 1000001, e.Size())
+	assert.Equal(t, 1000000, e.Limit())
+	assert.True(t, errors.Is(e, ErrPayloadTooLarge))
+	assert.True(t, errors.Is(e, ErrInvalidInput))
+}
+
+func TestErrorIs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		err      error
+		sentinel error
+	}{
+		{NewNotFound(""), ErrNotFound},
+		{NewUnexpectedStatusCode(500, ""), ErrUnexpectedStatusCode},
+		{NewUnauthorizedError(), ErrUnauthorized},
+		{NewForbidden(""), ErrForbidden},
+		{NewRateLimited(0, ""), ErrRateLimited},
+		{NewMaxRetriesReached(""), ErrMaxRetriesReached},
+		{NewInvalidInput(""), ErrInvalidInput},
+		{NewRequestCanceled(context.Canceled), ErrRequestCanceled},
+	}
+
+	for _, c := range cases {
+		wrapped := fmt.Errorf("wrapped: %w", c.err)
+
+		assert.True(t, errors.Is(wrapped, c.sentinel), c.sentinel.Error())
+		assert.Equal(t, c.sentinel == ErrInvalidInput, errors.Is(wrapped, ErrInvalidInput), c.sentinel.Error())
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New("cause")
+	rateLimited := NewRateLimited(0, "").WithCause(cause)
+	e := NewMaxRetriesReached("giving up").WithCause(rateLimited)
+
+	assert.Equal(t, "maximum retries reached: giving up", e.Error())
+	assert.True(t, errors.Is(e, ErrMaxRetriesReached))
+	assert.True(t, errors.Is(e, ErrRateLimited))
+	assert.True(t, errors.Is(e, cause))
+
+	var target *RateLimited
+	assert.True(t, errors.As(e, &target))
+	assert.Same(t, rateLimited, target)
+}
+
+func TestGetRequestDetails(t *testing.T) {
+	t.Parallel()
+
+	_, ok := GetRequestDetails(errors.New("plain"))
+	assert.False(t, ok)
+
+	_, ok = GetRequestDetails(NewNotFound(""))
+	assert.False(t, ok)
+
+	e := NewForbidden("")
+	e.SetRequest(RequestDetails{Method: "GET", URL: "https://api.newrelic.com/v2/path", RequestID: "abc"})
+
+	details, ok := GetRequestDetails(fmt.Errorf("wrapped: %w", e))
+	assert.True(t, ok)
+	assert.Equal(t, "GET", details.Method)
+	assert.Equal(t, "https://api.newrelic.com/v2/path", details.URL)
+	assert.Equal(t, "abc", details.RequestID)
+	assert.Equal(t, details, e.Request())
+}
+
+func TestErrorGraphQLErrorsIs(t *testing.T) {
+	t.Parallel()
+
+	e := NewGraphQLErrors([]GraphQLError{{Message: "denied", ErrorClass: "FORBIDDEN"}}, nil)
+	assert.True(t, errors.Is(e, ErrForbidden))
+	assert.False(t, errors.Is(e, ErrUnauthorized))
+
+	e = NewGraphQLErrors([]GraphQLError{{Message: "bad key", ErrorCode: "BAD_API_KEY"}}, nil)
+	assert.True(t, errors.Is(e, ErrUnauthorized))
+}
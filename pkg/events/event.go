@@ -0,0 +1,197 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+// Limits the Event API places on custom events.
+const (
+	MaxAttributes           = 255
+	MaxAttributeNameLength  = 255
+	MaxAttributeValueLength = 4096
+	MaxEventTypeLength      = 255
+)
+
+// eventTypePattern matches the characters allowed in an event type.
+var eventTypePattern = regexp.MustCompile(`^[A-Za-z0-9_:]+$`)
+
+// reservedWords are the NRQL words that can't be used as an event type.
+var reservedWords = map[string]bool{
+	"ago": true, "and": true, "as": true, "auto": true, "begin": true,
+	"begintime": true, "compare": true, "day": true, "days": true, "end": true,
+	"endtime": true, "explain": true, "facet": true, "from": true, "hour": true,
+	"hours": true, "in": true, "is": true, "like": true, "limit": true,
+	"minute": true, "minutes": true, "month": true, "months": true, "not": true,
+	"null": true, "offset": true, "or": true, "raw": true, "second": true,
+	"seconds": true, "select": true, "since": true, "timeseries": true,
+	"until": true, "week": true, "weeks": true, "where": true, "with": true,
+}
+
+// reservedAttributes are the attribute names set by the Event API, or by the
+// Event builder itself.
+var reservedAttributes = map[string]bool{
+	"accountId": true,
+	"appId":     true,
+	"eventType": true,
+	"timestamp": true,
+}
+
+// Event is a custom event built and validated against the Event API's rules
+// before it is sent.  It can be passed to CreateEvent or EnqueueEvent, which
+// validate it before any network call.
+type Event struct {
+	eventType  string
+	timestamp  time.Time
+	attributes map[string]interface{}
+}
+
+// NewEvent starts building a custom event of the given type.
+func NewEvent(eventType string) *Event {
+	return &Event{
+		eventType:  eventType,
+		attributes: map[string]interface{}{},
+	}
+}
+
+// WithTimestamp sets when the event occurred.  When not set, it is the time
+// the event is received.
+func (ev *Event) WithTimestamp(timestamp time.Time) *Event {
+	ev.timestamp = timestamp
+	return ev
+}
+
+// WithAttribute sets an attribute of the event.  Values must be strings,
+// booleans or numbers.
+func (ev *Event) WithAttribute(name string, value interface{}) *Event {
+	if ev.attributes == nil {
+		ev.attributes = map[string]interface{}{}
+	}
+
+	ev.attributes[name] = value
+	return ev
+}
+
+// WithAttributes sets attributes of the event.  Values must be strings,
+// booleans or numbers.
+func (ev *Event) WithAttributes(attributes map[string]interface{}) *Event {
+	if ev.attributes == nil {
+		ev.attributes = make(map[string]interface{}, len(attributes))
+	}
+
+	for name, value := range attributes {
+		ev.attributes[name] = value
+	}
+
+	return ev
+}
+
+// EventType returns the type of the event.
+func (ev *Event) EventType() string {
+	return ev.eventType
+}
+
+// Attributes returns a copy of the attributes of the event.
+func (ev *Event) Attributes() map[string]interface{} {
+	attributes := make(map[string]interface{}, len(ev.attributes))
+	for name, value := range ev.attributes {
+		attributes[name] = value
+	}
+
+	return attributes
+}
+
+// Validate checks the event against the Event API's rules, returning an
+// InvalidInput error describing every problem found.
+func (ev *Event) Validate() error {
+	problems := []string{}
+
+	switch {
+	case ev.eventType == "":
+		problems = append(problems, "eventType is required")
+	case len(ev.eventType) > MaxEventTypeLength:
+		problems = append(problems, fmt.Sprintf("eventType exceeds %d characters", MaxEventTypeLength))
+	case !eventTypePattern.MatchString(ev.eventType):
+		problems = append(problems, fmt.Sprintf("eventType %q may only contain letters, numbers, colons and underscores", ev.eventType))
+	case reservedWords[strings.ToLower(ev.eventType)]:
+		problems = append(problems, fmt.Sprintf("eventType %q is a reserved word", ev.eventType))
+	}
+
+	if len(ev.attributes) > MaxAttributes {
+		problems = append(problems, fmt.Sprintf("event has %d attributes, the limit is %d", len(ev.attributes), MaxAttributes))
+	}
+
+	names := make([]string, 0, len(ev.attributes))
+	for name := range ev.attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if problem := validateAttribute(name, ev.attributes[name]); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nrErrors.NewInvalidInputf("events: invalid event: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// validateAttribute returns the problem with an attribute, if any.
+func validateAttribute(name string, value interface{}) string {
+	switch {
+	case name == "":
+		return "attribute names can't be empty"
+	case len(name) > MaxAttributeNameLength:
+		return fmt.Sprintf("attribute name %q exceeds %d characters", name, MaxAttributeNameLength)
+	case reservedAttributes[name]:
+		return fmt.Sprintf("attribute name %q is reserved", name)
+	}
+
+	switch value := value.(type) {
+	case string:
+		if len(value) > MaxAttributeValueLength {
+			return fmt.Sprintf("value of attribute %q exceeds %d characters", name, MaxAttributeValueLength)
+		}
+	case bool, json.Number,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+	case nil:
+		return fmt.Sprintf("value of attribute %q is null", name)
+	default:
+		return fmt.Sprintf("value of attribute %q must be a string, boolean or number, not %T", name, value)
+	}
+
+	return ""
+}
+
+// MarshalJSON validates the event, then converts it into the Event API's
+// format.  It has a value receiver, so an Event nested in a slice or struct by
+// value is validated and converted as well.
+func (ev Event) MarshalJSON() ([]byte, error) {
+	if err := ev.Validate(); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]interface{}, len(ev.attributes)+2)
+	for name, value := range ev.attributes {
+		data[name] = value
+	}
+
+	data["eventType"] = ev.eventType
+	if !ev.timestamp.IsZero() {
+		data["timestamp"] = ev.timestamp.UnixNano() / int64(time.Millisecond)
+	}
+
+	return json.Marshal(data)
+}
@@ -0,0 +1,174 @@
+//go:build unit
+// +build unit
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+func TestEventMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	event := NewEvent("Purchase").
+		WithTimestamp(time.Unix(1600000000, 0)).
+		WithAttribute("amount", 123.45).
+		WithAttributes(map[string]interface{}{"currency": "USD", "gift": false})
+
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"eventType":"Purchase","timestamp":1600000000000,"amount":123.45,"currency":"USD","gift":false}`, string(data))
+
+	assert.Equal(t, "Purchase", event.EventType())
+	assert.Equal(t, map[string]interface{}{"amount": 123.45, "currency": "USD", "gift": false}, event.Attributes())
+}
+
+func TestEventMarshalJSONNested(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal([]Event{*NewEvent("Purchase").WithAttribute("amount", 1)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"eventType":"Purchase","amount":1}]`, string(data))
+
+	_, err = json.Marshal(struct{ Event Event }{Event: *NewEvent("bad type")})
+	assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput))
+}
+
+func TestEventZeroValue(t *testing.T) {
+	t.Parallel()
+
+	var event Event
+	event.WithAttribute("amount", 1)
+
+	var other Event
+	other.WithAttributes(map[string]interface{}{"currency": "USD"})
+
+	assert.Equal(t, map[string]interface{}{"amount": 1}, event.Attributes())
+	assert.Equal(t, map[string]interface{}{"currency": "USD"}, other.Attributes())
+
+	_, err := json.Marshal(event)
+	assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput))
+}
+
+func TestEventValidate(t *testing.T) {
+	t.Parallel()
+
+	tooMany := map[string]interface{}{}
+	for i := 0; i <= MaxAttributes; i++ {
+		tooMany[fmt.Sprintf("attr%d", i)] = i
+	}
+
+	cases := map[string]*Event{
+		"no event type":       NewEvent(""),
+		"long event type":     NewEvent(strings.Repeat("a", MaxEventTypeLength+1)),
+		"event type chars":    NewEvent("Purchase Event"),
+		"reserved event type": NewEvent("Select"),
+		"too many attributes": NewEvent("Purchase").WithAttributes(tooMany),
+		"empty name":          NewEvent("Purchase").WithAttribute("", 1),
+		"long name":           NewEvent("Purchase").WithAttribute(strings.Repeat("a", MaxAttributeNameLength+1), 1),
+		"reserved name":       NewEvent("Purchase").WithAttribute("accountId", 1),
+		"long value":          NewEvent("Purchase").WithAttribute("a", strings.Repeat("a", MaxAttributeValueLength+1)),
+		"null value":          NewEvent("Purchase").WithAttribute("a", nil),
+		"nested object":       NewEvent("Purchase").WithAttribute("a", map[string]interface{}{"b": 1}),
+		"nested array":        NewEvent("Purchase").WithAttribute("a", []int{1}),
+		"unsupported type":    NewEvent("Purchase").WithAttribute("a", time.Now()),
+	}
+
+	for name, event := range cases {
+		err := event.Validate()
+		assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput), name)
+
+		_, err = json.Marshal(event)
+		assert.Error(t, err, name)
+	}
+
+	assert.NoError(t, NewEvent("Purchase:Completed_2").WithAttribute("a", json.Number("1")).Validate())
+
+	// Every problem is described.
+	err := NewEvent("bad type").WithAttribute("appId", 1).WithAttribute("b", []int{}).Validate()
+	assert.Equal(t, `events: invalid event: eventType "bad type" may only contain letters, numbers, colons and underscores; `+
+		`attribute name "appId" is reserved; value of attribute "b" must be a string, boolean or number, not []int`, err.Error())
+}
+
+func TestCreateEventValidatesEvents(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		insightsResponse(w, http.StatusOK)
+	})
+
+	require.NoError(t, client.CreateEvent(1, NewEvent("Purchase").WithAttribute("amount", 1)))
+	require.NoError(t, client.CreateEvent(1, *NewEvent("Purchase").WithAttribute("amount", 1)))
+
+	err := client.CreateEvent(1, NewEvent("Purchase").WithAttribute("nested", map[string]interface{}{}))
+	assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput))
+
+	var nilEvent *Event
+	assert.True(t, errors.Is(client.CreateEvent(1, nilEvent), nrErrors.ErrInvalidInput))
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestCreateEventChecksEventType(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		insightsResponse(w, http.StatusOK)
+	})
+
+	invalid := []interface{}{
+		`{"name":"eventType"}`,
+		`{"eventType":1}`,
+		`{"eventType":""}`,
+		`{"data":{"eventType":"Purchase"}}`,
+		[]byte(`[{"eventType":"Purchase"},{"name":"eventType"}]`),
+		`[]`,
+		`not json, but eventType`,
+		map[string]interface{}{"name": "eventType"},
+	}
+
+	for _, event := range invalid {
+		err := client.CreateEvent(1, event)
+		assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput), fmt.Sprint(event))
+	}
+
+	require.NoError(t, client.CreateEvent(1, `{"amount":1,"eventType":"Purchase"}`))
+	require.NoError(t, client.CreateEvent(1, map[string]interface{}{"eventType": "Purchase"}))
+	require.NoError(t, client.CreateEvent(1, []byte(`[{"eventType":"Purchase"},{"eventType":"Refund"}]`)))
+	require.NoError(t, client.CreateEvent(1, []map[string]interface{}{{"eventType": "Purchase"}}))
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestEnqueueEventRejectsArrays(t *testing.T) {
+	t.Parallel()
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		insightsResponse(w, http.StatusOK)
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	defer func() { _ = client.Close(ctx) }()
+
+	err := client.EnqueueEvent(ctx, `[{"eventType":"Purchase"}]`)
+	assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput))
+}
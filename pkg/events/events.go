@@ -1,40 +1,75 @@
 package events
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/newrelic/newrelic-client-go/internal/http"
 	"github.com/newrelic/newrelic-client-go/pkg/config"
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
 	"github.com/newrelic/newrelic-client-go/pkg/logging"
+	"github.com/newrelic/newrelic-client-go/pkg/spool"
 )
 
 const (
-	DefaultBatchWorkers = 1
-	DefaultBatchSize    = 900
-	DefaultBatchTimeout = 60 * time.Second
+	DefaultBatchWorkers      = 1
+	DefaultBatchSize         = 900
+	DefaultBatchTimeout      = 60 * time.Second
+	DefaultBatchRetries      = 3
+	DefaultBatchRetryWaitMin = 1 * time.Second
+	DefaultBatchRetryWaitMax = 30 * time.Second
+	DefaultBatchMaxInFlight  = 10
 )
 
+// MaxPayloadSize is the maximum size in bytes of a payload accepted by the
+// Event API.  It limits the size of a single event, and of the batches events
+// are sent in when queued in batch mode.
+const MaxPayloadSize = 1000000
+
 // Events is used to send custom events to NRDB.
 type Events struct {
-	client http.Client
-	config config.Config
-	logger logging.Logger
+	client      http.Client
+	batchClient http.Client
+	config      config.Config
+	logger      logging.Logger
 
 	// For queue based event handling
 	accountID  int
-	eventQueue chan []byte
+	eventQueue chan queuedEvent
 	eventTimer *time.Timer
+	eventSpool *spool.Spool
 	flushQueue []chan bool
 
+	// For delivery of batches
+	sendSlots chan struct{}
+	sends     *sync.WaitGroup
+	stats     *batchStats
+
+	// For closing batch mode
+	batchMu     *sync.Mutex
+	cancelBatch context.CancelFunc
+	enqueues    *sync.WaitGroup
+	workers     *sync.WaitGroup
+	stopping    chan struct{}
+	draining    chan struct{}
+	stopped     chan struct{}
+
 	// These have defaults
-	batchWorkers int
-	batchSize    int
-	batchTimeout time.Duration
+	batchWorkers        int
+	batchSize           int
+	batchTimeout        time.Duration
+	batchRetries        int
+	batchRetryWaitMin   time.Duration
+	batchRetryWaitMax   time.Duration
+	batchMaxInFlight    int
+	batchMaxPayloadSize int
+	batchErrorHandler   BatchErrorHandler
+	batchSpoolConfig    *spool.Config
+	batchOverflowPolicy OverflowPolicy
 }
 
 // New is used to create a new Events client instance.
@@ -44,13 +79,29 @@ func New(cfg config.Config) Events {
 	client := http.NewClient(cfg)
 	client.SetAuthStrategy(&http.InsightsInsertKeyAuthorizer{})
 
+	// Batch mode retries batches itself, so the client it sends them with
+	// doesn't retry them as well.
+	noRetries := 0
+	batchCfg := cfg
+	batchCfg.RetryMax = &noRetries
+
+	batchClient := http.NewClient(batchCfg)
+	batchClient.SetAuthStrategy(&http.InsightsInsertKeyAuthorizer{})
+
 	pkg := Events{
-		client:       client,
-		config:       cfg,
-		logger:       cfg.GetLogger(),
-		batchWorkers: DefaultBatchWorkers,
-		batchSize:    DefaultBatchSize,
-		batchTimeout: DefaultBatchTimeout,
+		client:              client,
+		batchClient:         batchClient,
+		config:              cfg,
+		logger:              cfg.GetLogger(),
+		batchMu:             &sync.Mutex{},
+		batchWorkers:        DefaultBatchWorkers,
+		batchSize:           DefaultBatchSize,
+		batchTimeout:        DefaultBatchTimeout,
+		batchRetries:        DefaultBatchRetries,
+		batchRetryWaitMin:   DefaultBatchRetryWaitMin,
+		batchRetryWaitMax:   DefaultBatchRetryWaitMax,
+		batchMaxInFlight:    DefaultBatchMaxInFlight,
+		batchMaxPayloadSize: MaxPayloadSize,
 	}
 
 	return pkg
@@ -63,13 +114,17 @@ func (e *Events) CreateEvent(accountID int, event interface{}) error {
 
 // CreateEventWithContext reports a custom event to New Relic.
 func (e *Events) CreateEventWithContext(ctx context.Context, accountID int, event interface{}) error {
-	jsonData, err := e.marshalEvent(event)
+	// The Event API accepts an array of events in a single request.
+	jsonData, err := e.marshalEvent(event, true)
 	if err != nil {
 		return err
 	}
 	if jsonData == nil {
 		return errors.New("events: CreateEvent marhal returned nil data")
 	}
+	if len(*jsonData) > MaxPayloadSize {
+		return nrErrors.NewPayloadTooLarge(len(*jsonData), MaxPayloadSize)
+	}
 
 	resp := &createEventResponse{}
 
@@ -85,11 +140,20 @@ func (e *Events) CreateEventWithContext(ctx context.Context, accountID int, even
 	return nil
 }
 
-// marshalEvent converts the event interface into a JSON []byte
-func (e *Events) marshalEvent(event interface{}) (*[]byte, error) {
+// marshalEvent converts the event interface into a JSON []byte.  An Event is
+// validated first, other events need only be a JSON object with a string
+// eventType field, or when arrays are allowed, an array of them.
+func (e *Events) marshalEvent(event interface{}, arrays bool) (*[]byte, error) {
 	var jsonData []byte
 
 	switch event := event.(type) {
+	case Event:
+		return marshalTypedEvent(&event)
+	case *Event:
+		if event == nil {
+			return nil, nrErrors.NewInvalidInput("events: event is nil, nothing to do")
+		}
+		return marshalTypedEvent(event)
 	case []byte:
 		jsonData = event
 	case string:
@@ -98,12 +162,77 @@ func (e *Events) marshalEvent(event interface{}) (*[]byte, error) {
 		var err error
 		jsonData, err = json.Marshal(event)
 		if err != nil {
-			return nil, fmt.Errorf("error marshaling event data: %s", err.Error())
+			return nil, nrErrors.NewInvalidInputf("error marshaling event data: %s", err.Error()).WithCause(err)
+		}
+	}
+
+	if arrays && isJSONArray(jsonData) {
+		if err := checkEventTypes(jsonData); err != nil {
+			return nil, err
+		}
+
+		return &jsonData, nil
+	}
+
+	if err := checkEventType(jsonData); err != nil {
+		return nil, err
+	}
+
+	return &jsonData, nil
+}
+
+// isJSONArray reports whether JSON data is an array.
+func isJSONArray(jsonData []byte) bool {
+	trimmed := bytes.TrimLeft(jsonData, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// checkEventTypes checks that event data is an array of events that each pass
+// checkEventType.
+func checkEventTypes(jsonData []byte) error {
+	var events []json.RawMessage
+	if err := json.Unmarshal(jsonData, &events); err != nil {
+		return nrErrors.NewInvalidInputf("event data must be a JSON array of objects: %s", err.Error()).WithCause(err)
+	}
+
+	if len(events) == 0 {
+		return nrErrors.NewInvalidInput("event data must contain at least one event")
+	}
+
+	for _, event := range events {
+		if err := checkEventType(event); err != nil {
+			return err
 		}
 	}
 
-	if !strings.Contains(string(jsonData), "eventType") {
-		return nil, fmt.Errorf("event data must contain eventType field. %s", jsonData)
+	return nil
+}
+
+// checkEventType checks that event data is a JSON object with a string
+// eventType member at its top level.
+func checkEventType(jsonData []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(jsonData, &members); err != nil {
+		return nrErrors.NewInvalidInputf("event data must be a JSON object: %s", err.Error()).WithCause(err)
+	}
+
+	var eventType string
+	if raw, ok := members["eventType"]; !ok || json.Unmarshal(raw, &eventType) != nil || eventType == "" {
+		return nrErrors.NewInvalidInputf("event data must contain eventType field. %s", jsonData)
+	}
+
+	return nil
+}
+
+// marshalTypedEvent validates an Event, then converts it into a JSON []byte.
+func marshalTypedEvent(event *Event) (*[]byte, error) {
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(event)
+	if err != nil {
+		return nil, nrErrors.NewInvalidInputf("error marshaling event data: %s", err.Error()).WithCause(err)
 	}
 
 	return &jsonData, nil
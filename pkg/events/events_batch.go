@@ -4,12 +4,29 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	nrhttp "github.com/newrelic/newrelic-client-go/internal/http"
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/spool"
 )
 
 // BatchMode enables the Events client to accept, queue, and post
-// Events on behalf of the consuming application
+// Events on behalf of the consuming application.  Batch mode runs until
+// ctx is done, discarding any events still queued, or until Close is
+// called, which sends them first.  Once closed, batch mode can be
+// enabled again.
+//
+// When spooling with BatchConfigSpool, the events left in the spool are
+// queued again first, and events in batches that failed to send are queued
+// again while batch mode runs.
 func (e *Events) BatchMode(ctx context.Context, accountID int, opts ...BatchConfigOption) (err error) {
+	e.batchMu.Lock()
+	defer e.batchMu.Unlock()
+
 	if e.eventQueue != nil {
 		return errors.New("the Events client is already in batch mode")
 	}
@@ -23,13 +40,33 @@ func (e *Events) BatchMode(ctx context.Context, accountID int, opts ...BatchConf
 		}
 	}
 
+	if e.batchSpoolConfig != nil {
+		if e.eventSpool, err = spool.Open(*e.batchSpoolConfig); err != nil {
+			return err
+		}
+	}
+
+	// Batch mode stops when ctx is done, or Close gives up waiting for it.
+	ctx, e.cancelBatch = context.WithCancel(ctx)
+
 	e.accountID = accountID
-	e.eventQueue = make(chan []byte, e.batchSize)
+	e.eventQueue = make(chan queuedEvent, e.batchSize)
 	e.flushQueue = make([]chan bool, e.batchWorkers)
 	e.eventTimer = time.NewTimer(e.batchTimeout)
+	e.sendSlots = make(chan struct{}, e.batchMaxInFlight)
+	e.sends = &sync.WaitGroup{}
+	e.stats = &batchStats{}
+	e.enqueues = &sync.WaitGroup{}
+	e.workers = &sync.WaitGroup{}
+	e.stopping = make(chan struct{})
+	e.draining = make(chan struct{})
+	e.stopped = make(chan struct{})
 
 	// Handle timer based flushing
+	e.workers.Add(1)
 	go func() {
+		defer e.workers.Done()
+
 		err := e.watchdog(ctx)
 		if err != nil {
 			e.logger.Error("watchdog returned error", "error", err)
@@ -40,7 +77,10 @@ func (e *Events) BatchMode(ctx context.Context, accountID int, opts ...BatchConf
 	for x := range e.flushQueue {
 		e.flushQueue[x] = make(chan bool, 1)
 
+		e.workers.Add(1)
 		go func(id int) {
+			defer e.workers.Done()
+
 			err := e.batchWorker(ctx, id)
 			if err != nil {
 				e.logger.Error("batch worker returned error", "error", err)
@@ -48,9 +88,94 @@ func (e *Events) BatchMode(ctx context.Context, accountID int, opts ...BatchConf
 		}(x)
 	}
 
+	// Queue the events left in the spool, as if they were being queued now,
+	// and the events released by failed batches as they fail.
+	if e.eventSpool != nil {
+		e.enqueues.Add(1)
+		go func() {
+			defer e.enqueues.Done()
+
+			e.replaySpool(ctx)
+		}()
+	}
+
+	return nil
+}
+
+// Close stops batch mode once every queued event has been sent.  Events can no
+// longer be queued once Close is called, the events already queued are sent,
+// and Close waits for every batch to be sent, or for ctx to be done.  If ctx is
+// done first, the batches being sent are cancelled and the events left are
+// discarded.  An error is returned if any events could not be sent, not
+// counting events released to the spool, which are sent once batch mode is
+// enabled again.  Batch mode can be enabled again once closed.
+func (e *Events) Close(ctx context.Context) error {
+	e.batchMu.Lock()
+
+	if e.eventQueue == nil {
+		e.batchMu.Unlock()
+		return errors.New("queueing not enabled for this client")
+	}
+
+	stats, stopped, cancelBatch := e.stats, e.stopped, e.cancelBatch
+
+	select {
+	case <-e.stopping:
+	default:
+		e.logger.Debug("closing batch mode")
+		close(e.stopping)
+		go e.shutdown()
+	}
+
+	e.batchMu.Unlock()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		cancelBatch()
+		return ctx.Err()
+	}
+
+	failed := atomic.LoadInt64(&stats.failed)
+	dropped := atomic.LoadInt64(&stats.dropped)
+
+	if failed > 0 || dropped > 0 {
+		return fmt.Errorf("events: %d events could not be sent and %d were dropped", failed, dropped)
+	}
+
 	return nil
 }
 
+// shutdown waits for events being queued, has the workers send the events
+// left in the queue, waits for every batch to be sent, then leaves batch mode.
+func (e *Events) shutdown() {
+	e.enqueues.Wait()
+	close(e.draining)
+
+	e.workers.Wait()
+	e.sends.Wait()
+
+	// Workers stopped by their context being done may have left events.
+	e.dropEvents(e.drainQueue())
+	e.eventTimer.Stop()
+
+	if e.eventSpool != nil {
+		if err := e.eventSpool.Close(); err != nil {
+			e.logger.Error("failed to close event spool", "error", err)
+		}
+	}
+
+	e.batchMu.Lock()
+	defer e.batchMu.Unlock()
+
+	e.cancelBatch()
+
+	e.eventQueue = nil
+	e.eventSpool = nil
+	e.flushQueue = nil
+	close(e.stopped)
+}
+
 type BatchConfigOption func(*Events) error
 
 // BatchConfigWorkers sets how many background workers will process
@@ -95,15 +220,199 @@ func BatchConfigTimeout(seconds int) BatchConfigOption {
 	}
 }
 
+// BatchConfigMaxPayloadSize is the maximum size in bytes of the payload a
+// batch of events is sent in, before compression.  If adding an event to a
+// batch would take it over this size, the batch is sent first.  Events too
+// large to be sent on their own are rejected when queued.  It can't exceed
+// MaxPayloadSize, which is the default.
+func BatchConfigMaxPayloadSize(size int) BatchConfigOption {
+	return func(e *Events) error {
+		if size <= emptyPayloadSize || size > MaxPayloadSize {
+			return errors.New("events: invalid max payload size specified")
+		}
+
+		e.batchMaxPayloadSize = size
+		return nil
+	}
+}
+
+// BatchConfigRetries is how many times sending a batch of events is retried
+// when it fails, before the batch is passed to the error handler.  Batches
+// rejected for their content or the credentials used are not retried.  Batches
+// are sent without the client's own retries, set by config.Config.RetryMax, so
+// a batch is sent at most count+1 times.
+func BatchConfigRetries(count int) BatchConfigOption {
+	return func(e *Events) error {
+		if count < 0 {
+			return errors.New("events: invalid retry count specified")
+		}
+
+		e.batchRetries = count
+		return nil
+	}
+}
+
+// BatchConfigRetryWait is the minimum and maximum amount of time to wait
+// before retrying to send a batch of events, which grows exponentially with
+// each attempt.  A longer wait requested by New Relic when rate limiting
+// takes precedence.
+func BatchConfigRetryWait(min, max time.Duration) BatchConfigOption {
+	return func(e *Events) error {
+		if min <= 0 || max < min {
+			return errors.New("events: invalid retry wait specified")
+		}
+
+		e.batchRetryWaitMin = min
+		e.batchRetryWaitMax = max
+		return nil
+	}
+}
+
+// BatchConfigMaxInFlight is how many batches of events can be sent to New
+// Relic at once.  Once the limit is reached, workers wait for a send to
+// complete before sending another batch, and events queue up behind them.
+func BatchConfigMaxInFlight(count int) BatchConfigOption {
+	return func(e *Events) error {
+		if count <= 0 {
+			return errors.New("events: invalid max in flight specified")
+		}
+
+		e.batchMaxInFlight = count
+		return nil
+	}
+}
+
+// OverflowPolicy is what EnqueueEvent does when the queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the queue, or for the context passed to
+	// EnqueueEvent to be done.  It is the default.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the event being queued.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest events in the queue to make room
+	// for the event being queued.
+	OverflowDropOldest
+
+	// OverflowFail returns ErrQueueFull without queueing the event.
+	OverflowFail
+)
+
+// ErrQueueFull is returned by EnqueueEvent when the queue is full and the
+// overflow policy is OverflowFail.
+var ErrQueueFull = errors.New("events: queue is full")
+
+// BatchConfigOverflowPolicy sets what EnqueueEvent does when the queue is
+// full.  Every policy but OverflowBlock returns without waiting, and events
+// discarded are counted as dropped.
+func BatchConfigOverflowPolicy(policy OverflowPolicy) BatchConfigOption {
+	return func(e *Events) error {
+		if policy < OverflowBlock || policy > OverflowFail {
+			return errors.New("events: invalid overflow policy specified")
+		}
+
+		e.batchOverflowPolicy = policy
+		return nil
+	}
+}
+
+// BatchErrorHandler is called with the events of a batch, each marshaled to
+// JSON, that could not be sent to New Relic, and the error for the last
+// attempt to send them.  It can be used to log, persist or requeue them.
+type BatchErrorHandler func(events [][]byte, err error)
+
+// BatchConfigErrorHandler sets the handler called with batches of events that
+// could not be sent to New Relic once retries are exhausted.
+func BatchConfigErrorHandler(handler BatchErrorHandler) BatchConfigOption {
+	return func(e *Events) error {
+		e.batchErrorHandler = handler
+		return nil
+	}
+}
+
+// BatchConfigSpool writes queued events to a disk-backed spool, so they
+// survive restarts and outages.  Events are removed from the spool once they
+// are sent, or rejected by New Relic.  Events in batches that could not be sent
+// once retries were exhausted are queued again after the spool's replay
+// interval, which backs off while they keep failing.  Events left in the
+// spool, such as those dropped when batch mode's context was done, are queued
+// again when batch mode is next enabled with the spool.  Events may be sent
+// more than once as a result.
+func BatchConfigSpool(cfg spool.Config) BatchConfigOption {
+	return func(e *Events) error {
+		if cfg.Dir == "" {
+			return errors.New("events: invalid spool directory specified")
+		}
+
+		e.batchSpoolConfig = &cfg
+		return nil
+	}
+}
+
+// BatchStats are delivery statistics for the events queued in batch mode.
+type BatchStats struct {
+	// Queued is the number of events waiting to be sent, including events
+	// in batches being sent.
+	Queued int64
+
+	// QueueDepth is the number of events in the queue, waiting to be added to
+	// a batch.
+	QueueDepth int64
+
+	// Sent is the number of events sent to New Relic.
+	Sent int64
+
+	// Failed is the number of events in batches that could not be sent, which
+	// were passed to the error handler.
+	Failed int64
+
+	// Spooled is the number of events in batches that could not be sent, which
+	// were released to the spool to be sent again, rather than failing.
+	Spooled int64
+
+	// Dropped is the number of events discarded without being sent, such as
+	// events still queued when batch mode's context is done.
+	Dropped int64
+}
+
+type batchStats struct {
+	queued  int64
+	sent    int64
+	failed  int64
+	spooled int64
+	dropped int64
+}
+
+// BatchStats returns the delivery statistics for the events queued in batch mode.
+func (e *Events) BatchStats() BatchStats {
+	e.batchMu.Lock()
+	stats, queue := e.stats, e.eventQueue
+	e.batchMu.Unlock()
+
+	if stats == nil {
+		return BatchStats{}
+	}
+
+	return BatchStats{
+		Queued:     atomic.LoadInt64(&stats.queued),
+		QueueDepth: int64(len(queue)),
+		Sent:       atomic.LoadInt64(&stats.sent),
+		Failed:     atomic.LoadInt64(&stats.failed),
+		Spooled:    atomic.LoadInt64(&stats.spooled),
+		Dropped:    atomic.LoadInt64(&stats.dropped),
+	}
+}
+
 // EnqueueEventContext handles the queueing. Only works in batch mode. If you wish to be able to avoid blocking
 // forever until the event can be queued, provide a ctx with a deadline or timeout as this function will
-// bail when ctx.Done() is closed and return and error.
+// bail when ctx.Done() is closed and return and error.  What happens when the queue is full is set by
+// BatchConfigOverflowPolicy.
 func (e *Events) EnqueueEvent(ctx context.Context, event interface{}) (err error) {
-	if e.eventQueue == nil {
-		return errors.New("queueing not enabled for this client")
-	}
-
-	jsonData, err := e.marshalEvent(event)
+	// Batches are arrays of events, so each queued event must be a single event.
+	jsonData, err := e.marshalEvent(event, false)
 	if err != nil {
 		return err
 	}
@@ -111,26 +420,189 @@ func (e *Events) EnqueueEvent(ctx context.Context, event interface{}) (err error
 		return errors.New("events: EnqueueEvent marhal returned nil data")
 	}
 
+	e.batchMu.Lock()
+
+	if e.eventQueue == nil {
+		e.batchMu.Unlock()
+		return errors.New("queueing not enabled for this client")
+	}
+
+	// An event that can't fit in a batch on its own can never be sent.
+	if size := emptyPayloadSize + len(*jsonData); size > e.batchMaxPayloadSize {
+		e.batchMu.Unlock()
+		return nrErrors.NewPayloadTooLarge(size, e.batchMaxPayloadSize)
+	}
+
+	select {
+	case <-e.stopping:
+		e.batchMu.Unlock()
+		return errors.New("events: batch mode is closing")
+	default:
+	}
+
+	queue, stopping, stats, eventSpool, policy := e.eventQueue, e.stopping, e.stats, e.eventSpool, e.batchOverflowPolicy
+	e.enqueues.Add(1)
+	defer e.enqueues.Done()
+
+	e.batchMu.Unlock()
+
+	item := queuedEvent{data: *jsonData}
+	if eventSpool != nil {
+		if item.record, err = eventSpool.Append(item.data); err != nil {
+			return err
+		}
+	}
+
+	atomic.AddInt64(&stats.queued, 1)
+
 	select {
-	case e.eventQueue <- *jsonData:
+	case queue <- item:
 		return nil
-	case <-ctx.Done():
-		e.logger.Trace("EnqueueEvent: exiting per context Done")
-		return ctx.Err()
+	default:
+	}
+
+	switch policy {
+	case OverflowDropNewest:
+		e.dropOverflow(item)
+		return nil
+	case OverflowDropOldest:
+		e.displaceOldest(queue, item)
+		return nil
+	case OverflowFail:
+		err = ErrQueueFull
+	default:
+		select {
+		case queue <- item:
+			return nil
+		case <-stopping:
+			err = errors.New("events: batch mode is closing")
+		case <-ctx.Done():
+			e.logger.Trace("EnqueueEvent: exiting per context Done")
+			err = ctx.Err()
+		}
+	}
+
+	atomic.AddInt64(&stats.queued, -1)
+
+	// The caller is told the event wasn't queued, so it isn't replayed.
+	if eventSpool != nil {
+		eventSpool.Ack(item.record)
+	}
+
+	return err
+}
+
+// displaceOldest queues an event in a full queue, discarding the oldest events
+// in the queue to make room for it.
+func (e *Events) displaceOldest(queue chan queuedEvent, item queuedEvent) {
+	for {
+		select {
+		case queue <- item:
+			return
+		default:
+		}
+
+		select {
+		case oldest := <-queue:
+			e.dropOverflow(oldest)
+		default:
+		}
 	}
 }
 
+// dropOverflow accounts for an event discarded because the queue is full,
+// removing it from the spool.
+func (e *Events) dropOverflow(item queuedEvent) {
+	if e.eventSpool != nil {
+		e.eventSpool.Ack(item.record)
+	}
+
+	e.dropEvents(1)
+}
+
+// replaySpool queues the events left in the spool, then keeps queueing the
+// events released by batches that failed to send, waiting the spool's replay
+// interval between replays.  The interval doubles, up to the maximum, while
+// each replay finds events to queue, and is reset once one doesn't.  It runs
+// until batch mode is closing or ctx is done, which Close waits for.  Events
+// not queued are left in the spool.
+func (e *Events) replaySpool(ctx context.Context) {
+	cfg := e.eventSpool.Config()
+	wait := cfg.ReplayInterval
+
+	// The events left in the spool are queued before Close sends the queue.
+	e.replaySpooled(ctx, nil)
+
+	for {
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-e.stopping:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		if e.replaySpooled(ctx, e.stopping) == 0 {
+			wait = cfg.ReplayInterval
+		} else if wait = 2 * wait; wait > cfg.MaxReplayInterval {
+			wait = cfg.MaxReplayInterval
+		}
+	}
+}
+
+// replaySpooled queues the events the spool replays, returning how many were
+// queued, until stopping is closed or ctx is done.
+func (e *Events) replaySpooled(ctx context.Context, stopping <-chan struct{}) int {
+	replayed := 0
+
+	err := e.eventSpool.Replay(func(data []byte, record spool.Record) bool {
+		atomic.AddInt64(&e.stats.queued, 1)
+
+		select {
+		case e.eventQueue <- queuedEvent{data: data, record: record}:
+			replayed++
+			return true
+		case <-stopping:
+		case <-ctx.Done():
+		}
+
+		atomic.AddInt64(&e.stats.queued, -1)
+		return false
+	})
+	if err != nil {
+		e.logger.Error("failed to replay spooled events", "error", err)
+	}
+
+	if replayed > 0 {
+		e.logger.Debug("replayed spooled events", "count", replayed)
+	}
+
+	return replayed
+}
+
 // Flush gives the user a way to manually flush the queue in the foreground.
 // This is also used by watchdog when the timer expires.
 func (e *Events) Flush() error {
-	if e.flushQueue == nil {
+	e.batchMu.Lock()
+	flushQueue := e.flushQueue
+	e.batchMu.Unlock()
+
+	if flushQueue == nil {
 		return errors.New("queueing not enabled for this client")
 	}
 
 	e.logger.Debug("flushing events")
 
-	for x := range e.flushQueue {
-		e.flushQueue[x] <- true
+	for x := range flushQueue {
+		// A worker with a flush pending doesn't need another.
+		select {
+		case flushQueue[x] <- true:
+		default:
+		}
 	}
 
 	return nil
@@ -149,30 +621,103 @@ func (e *Events) batchWorker(ctx context.Context, id int) (err error) {
 		return errors.New("batchWorker: invalid worker id specified")
 	}
 
-	eventBuf := make([][]byte, e.batchSize)
-	count := 0
+	batch := &eventBatch{
+		events:  make([][]byte, e.batchSize),
+		records: make([]spool.Record, e.batchSize),
+		size:    emptyPayloadSize,
+	}
 
 	for {
 		select {
 		case item := <-e.eventQueue:
-			eventBuf[count] = item
-			count++
-			if count >= e.batchSize {
-				e.grabAndConsumeEvents(count, eventBuf)
-				count = 0
-			}
+			e.batchEvent(ctx, batch, item)
 		case <-e.flushQueue[id]:
-			if count > 0 {
-				e.grabAndConsumeEvents(count, eventBuf)
-				count = 0
+			if batch.count > 0 {
+				e.sendBatch(ctx, batch)
+			}
+		case <-e.draining:
+			// Events queued when ctx is done are discarded, even when closing.
+			if err := ctx.Err(); err != nil {
+				e.dropEvents(batch.count + e.drainQueue())
+				return err
 			}
+
+			e.logger.Trace("batchWorker draining queue", "id", id)
+			e.drainWorker(ctx, batch)
+			return nil
 		case <-ctx.Done():
-			e.logger.Trace("batchWorker[", id, "]: exiting per context Done")
+			e.logger.Trace("batchWorker exiting per context Done", "id", id)
+			e.dropEvents(batch.count + e.drainQueue())
 			return ctx.Err()
 		}
 	}
 }
 
+// emptyPayloadSize is the size of the JSON array a batch is sent in.
+const emptyPayloadSize = len("[]")
+
+// queuedEvent is an event in the queue, marshaled to JSON, along with its
+// record in the spool when spooling.
+type queuedEvent struct {
+	data   []byte
+	record spool.Record
+}
+
+// eventBatch is the batch of events a worker is building, along with the
+// size of the payload it is sent in.
+type eventBatch struct {
+	events  [][]byte
+	records []spool.Record
+	count   int
+	size    int
+}
+
+// batchEvent adds an event to a worker's batch.  The batch is sent first if
+// the event would take its payload over the maximum size, and is sent once
+// it holds the maximum number of events.
+func (e *Events) batchEvent(ctx context.Context, batch *eventBatch, item queuedEvent) {
+	if batch.count > 0 && batch.size+len(",")+len(item.data) > e.batchMaxPayloadSize {
+		e.sendBatch(ctx, batch)
+	}
+
+	if batch.count > 0 {
+		batch.size += len(",")
+	}
+
+	batch.events[batch.count] = item.data
+	batch.records[batch.count] = item.record
+	batch.count++
+	batch.size += len(item.data)
+
+	if batch.count >= e.batchSize {
+		e.sendBatch(ctx, batch)
+	}
+}
+
+// sendBatch sends the events in a worker's batch, leaving it empty.
+func (e *Events) sendBatch(ctx context.Context, batch *eventBatch) {
+	e.grabAndConsumeEvents(ctx, batch.count, batch.events, batch.records)
+	batch.count = 0
+	batch.size = emptyPayloadSize
+}
+
+// drainWorker sends the events a worker has read along with the events left
+// in the queue, once batch mode is closing and no more events can be queued.
+func (e *Events) drainWorker(ctx context.Context, batch *eventBatch) {
+	for {
+		select {
+		case item := <-e.eventQueue:
+			e.batchEvent(ctx, batch, item)
+		default:
+			if batch.count > 0 {
+				e.sendBatch(ctx, batch)
+			}
+
+			return
+		}
+	}
+}
+
 //
 // watchdog has a Timer that will send the results once the
 // it has expired.
@@ -190,6 +735,9 @@ func (e *Events) watchdog(ctx context.Context) (err error) {
 				return
 			}
 			e.eventTimer.Reset(e.batchTimeout)
+		case <-e.draining:
+			e.logger.Trace("watchdog exiting: batch mode closing")
+			return nil
 		case <-ctx.Done():
 			e.logger.Trace("watchdog exiting: context finished")
 			return ctx.Err()
@@ -198,22 +746,128 @@ func (e *Events) watchdog(ctx context.Context) (err error) {
 }
 
 // grabAndConsumeEvents makes a copy of the event handles,
-// and asynchronously writes those events in its own goroutine.
-func (e *Events) grabAndConsumeEvents(count int, eventBuf [][]byte) {
+// and asynchronously writes those events in its own goroutine,
+// once fewer than the maximum number of batches are in flight.
+func (e *Events) grabAndConsumeEvents(ctx context.Context, count int, eventBuf [][]byte, recordBuf []spool.Record) {
 	saved := make([][]byte, count)
+	records := make([]spool.Record, count)
 	for i := 0; i < count; i++ {
 		saved[i] = eventBuf[i]
+		records[i] = recordBuf[i]
 		eventBuf[i] = nil
 	}
 
-	go func(count int, saved [][]byte) {
-		if sendErr := e.sendEvents(saved[0:count]); sendErr != nil {
-			e.logger.Error("failed to send events")
+	select {
+	case e.sendSlots <- struct{}{}:
+	case <-ctx.Done():
+		e.dropEvents(count)
+		return
+	}
+
+	e.sends.Add(1)
+
+	go func(saved [][]byte, records []spool.Record) {
+		defer func() {
+			<-e.sendSlots
+			e.sends.Done()
+		}()
+
+		e.deliverEvents(ctx, saved, records)
+	}(saved, records)
+}
+
+// deliverEvents sends a batch of events, retrying failures, and accounts for
+// the result, passing batches that could not be sent to the error handler.
+// Events that were sent, or can never be sent, are removed from the spool, and
+// the others are released to be replayed, and counted as spooled rather than
+// failed.
+func (e *Events) deliverEvents(ctx context.Context, events [][]byte, records []spool.Record) {
+	count := int64(len(events))
+
+	err := e.sendEventsWithRetries(ctx, events)
+
+	atomic.AddInt64(&e.stats.queued, -count)
+
+	if e.eventSpool != nil && err != nil && nrhttp.RetryableBatchError(err) {
+		e.eventSpool.Release(records...)
+		atomic.AddInt64(&e.stats.spooled, count)
+		e.logger.Warn("failed to send events, released them to the spool", "error", err, "count", count)
+
+		return
+	}
+
+	if e.eventSpool != nil {
+		e.eventSpool.Ack(records...)
+	}
+
+	if err != nil {
+		atomic.AddInt64(&e.stats.failed, count)
+		e.logger.Error("failed to send events", "error", err, "count", count)
+
+		if e.batchErrorHandler != nil {
+			e.batchErrorHandler(events, err)
 		}
-	}(count, saved)
+
+		return
+	}
+
+	atomic.AddInt64(&e.stats.sent, count)
+}
+
+// sendEventsWithRetries sends a batch of events, retrying with an exponential
+// backoff until it is sent, the retries are exhausted, or ctx is done.
+func (e *Events) sendEventsWithRetries(ctx context.Context, events [][]byte) error {
+	for attempt := 0; ; attempt++ {
+		err := e.sendEvents(ctx, events)
+		if err == nil || attempt >= e.batchRetries || !nrhttp.RetryableBatchError(err) {
+			return err
+		}
+
+		wait := nrhttp.ExponentialBackoff(e.batchRetryWaitMin, e.batchRetryWaitMax, attempt, nil)
+
+		var rateLimited *nrErrors.RateLimited
+		if errors.As(err, &rateLimited) && rateLimited.RetryAfter() > wait {
+			wait = rateLimited.RetryAfter()
+		}
+
+		e.logger.Debug("retrying failed batch of events", "error", err, "attempt", attempt+1, "wait", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// drainQueue discards the events left in the queue, returning how many there were.
+func (e *Events) drainQueue() int {
+	count := 0
+
+	for {
+		select {
+		case <-e.eventQueue:
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+// dropEvents accounts for queued events discarded without being sent.
+func (e *Events) dropEvents(count int) {
+	if count == 0 {
+		return
+	}
+
+	atomic.AddInt64(&e.stats.queued, -int64(count))
+	atomic.AddInt64(&e.stats.dropped, int64(count))
+	e.logger.Warn("dropped queued events", "count", count)
 }
 
-func (e *Events) sendEvents(events [][]byte) error {
+func (e *Events) sendEvents(ctx context.Context, events [][]byte) error {
 	var buf bytes.Buffer
 
 	// Since we already marshalled all of the data into JSON, let's make a
@@ -230,7 +884,7 @@ func (e *Events) sendEvents(events [][]byte) error {
 
 	resp := &createEventResponse{}
 
-	_, err := e.client.Post(e.config.Region().InsightsURL(e.accountID), nil, buf.Bytes(), resp)
+	_, err := e.batchClient.PostWithContext(ctx, e.config.Region().InsightsURL(e.accountID), nil, buf.Bytes(), resp)
 
 	if err != nil {
 		return err
@@ -0,0 +1,690 @@
+//go:build unit
+// +build unit
+
+package events
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/spool"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+var testEvent = map[string]interface{}{"eventType": "Test", "amount": 1}
+
+func newTestBatchClient(t *testing.T, handler http.HandlerFunc) Events {
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	cfg := mock.NewTestConfig(t, ts)
+	cfg.Region().SetInsightsBaseURL(ts.URL)
+	cfg.InsightsInsertKey = "insertKey"
+
+	retryMax := 0
+	cfg.RetryMax = &retryMax
+
+	return New(cfg)
+}
+
+func insightsResponse(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if status == http.StatusOK {
+		_, _ = w.Write([]byte(`{"success": true, "uuid": "abc"}`))
+	}
+}
+
+func TestBatchRetriesFailedBatches(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			insightsResponse(w, http.StatusServiceUnavailable)
+			return
+		}
+
+		insightsResponse(w, http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := client.BatchMode(ctx, 1,
+		BatchConfigQueueSize(2),
+		BatchConfigRetries(3),
+		BatchConfigRetryWait(time.Millisecond, 5*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	assert.Equal(t, int64(1), client.BatchStats().Queued)
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+
+	assert.Eventually(t, func() bool {
+		return client.BatchStats().Sent == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, BatchStats{Sent: 2}, client.BatchStats())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestBatchRetriesRateLimitedBatches(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			insightsResponse(w, http.StatusTooManyRequests)
+			return
+		}
+
+		insightsResponse(w, http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := client.BatchMode(ctx, 1,
+		BatchConfigQueueSize(1),
+		BatchConfigRetries(1),
+		BatchConfigRetryWait(time.Millisecond, 5*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+
+	assert.Eventually(t, func() bool {
+		return client.BatchStats().Sent == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, BatchStats{Sent: 1}, client.BatchStats())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestBatchErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		insightsResponse(w, http.StatusBadRequest)
+	})
+
+	failed := make(chan [][]byte, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := client.BatchMode(ctx, 1,
+		BatchConfigQueueSize(2),
+		BatchConfigRetries(3),
+		BatchConfigRetryWait(time.Millisecond, 5*time.Millisecond),
+		BatchConfigErrorHandler(func(events [][]byte, err error) {
+			assert.Error(t, err)
+			failed <- events
+		}),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	require.NoError(t, client.EnqueueEvent(ctx, `{"eventType":"Test","amount":2}`))
+
+	select {
+	case events := <-failed:
+		require.Len(t, events, 2)
+		assert.JSONEq(t, `{"eventType":"Test","amount":2}`, string(events[1]))
+	case <-time.After(5 * time.Second):
+		t.Fatal("error handler not called")
+	}
+
+	// Bad requests aren't retried.
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	assert.Equal(t, BatchStats{Failed: 2}, client.BatchStats())
+}
+
+func TestBatchMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight int32
+	release := make(chan struct{})
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		<-release
+		atomic.AddInt32(&inFlight, -1)
+		insightsResponse(w, http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := client.BatchMode(ctx, 1,
+		BatchConfigQueueSize(1),
+		BatchConfigWorkers(3),
+		BatchConfigMaxInFlight(2),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	}
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&inFlight) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		return client.BatchStats().Sent == 5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+}
+
+func TestBatchDroppedOnCancel(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	requests := 0
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		insightsResponse(w, http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		return client.BatchStats().Dropped == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, BatchStats{Dropped: 2}, client.BatchStats())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Zero(t, requests)
+}
+
+// countEvents returns the number of events in a request to the Insights API.
+func countEvents(t *testing.T, r *http.Request) int {
+	body := io.Reader(r.Body)
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+
+		body = gz
+	}
+
+	events := []map[string]interface{}{}
+	assert.NoError(t, json.NewDecoder(body).Decode(&events))
+
+	return len(events)
+}
+
+func TestBatchClose(t *testing.T) {
+	t.Parallel()
+
+	var received int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, int32(countEvents(t, r)))
+		insightsResponse(w, http.StatusOK)
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigWorkers(2)))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	}
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&received))
+	assert.Equal(t, BatchStats{Sent: 3}, client.BatchStats())
+
+	assert.Error(t, client.EnqueueEvent(ctx, testEvent))
+	assert.Error(t, client.Flush())
+	assert.Error(t, client.Close(ctx))
+
+	// Batch mode can be enabled again once closed.
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	require.NoError(t, client.Close(ctx))
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&received))
+	assert.Equal(t, BatchStats{Sent: 1}, client.BatchStats())
+}
+
+func TestBatchCloseCancelsSends(t *testing.T) {
+	t.Parallel()
+
+	canceled := make(chan struct{})
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// The request is only cancelled once its body is read.
+		_, _ = ioutil.ReadAll(r.Body)
+
+		<-r.Context().Done()
+		close(canceled)
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	// Close waits for the batch being sent, cancelling it once ctx is done.
+	assert.Equal(t, context.DeadlineExceeded, client.Close(timeout))
+	assert.Error(t, client.EnqueueEvent(ctx, testEvent))
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch being sent wasn't cancelled")
+	}
+
+	require.Error(t, client.Close(ctx))
+	assert.Equal(t, BatchStats{Failed: 1}, client.BatchStats())
+}
+
+func TestBatchSendsWithoutClientRetries(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		insightsResponse(w, http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(ts.Close)
+
+	// The client retries requests, but not the batches batch mode retries.
+	cfg := mock.NewTestConfig(t, ts)
+	cfg.Region().SetInsightsBaseURL(ts.URL)
+	cfg.InsightsInsertKey = "insertKey"
+
+	retryMax := 3
+	retryWait := time.Millisecond
+	cfg.RetryMax = &retryMax
+	cfg.RetryWaitMin = &retryWait
+	cfg.RetryWaitMax = &retryWait
+
+	client := New(cfg)
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1,
+		BatchConfigRetries(1),
+		BatchConfigRetryWait(time.Millisecond, time.Millisecond),
+	))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	require.Error(t, client.Close(ctx))
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestBatchCloseReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		insightsResponse(w, http.StatusBadRequest)
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+
+	err := client.Close(ctx)
+	require.Error(t, err)
+	assert.Equal(t, "events: 2 events could not be sent and 0 were dropped", err.Error())
+}
+
+func TestBatchConfigOptions(t *testing.T) {
+	t.Parallel()
+
+	e := New(mock.NewTestConfig(t, nil))
+
+	assert.Error(t, BatchConfigRetries(-1)(&e))
+	assert.NoError(t, BatchConfigRetries(0)(&e))
+	assert.Error(t, BatchConfigRetryWait(0, time.Second)(&e))
+	assert.Error(t, BatchConfigRetryWait(time.Second, time.Millisecond)(&e))
+	assert.NoError(t, BatchConfigRetryWait(time.Millisecond, time.Second)(&e))
+	assert.Error(t, BatchConfigMaxInFlight(0)(&e))
+	assert.NoError(t, BatchConfigMaxInFlight(1)(&e))
+	assert.Error(t, BatchConfigMaxPayloadSize(len("[]"))(&e))
+	assert.Error(t, BatchConfigMaxPayloadSize(MaxPayloadSize+1)(&e))
+	assert.NoError(t, BatchConfigMaxPayloadSize(MaxPayloadSize)(&e))
+	assert.Error(t, BatchConfigOverflowPolicy(OverflowPolicy(-1))(&e))
+	assert.Error(t, BatchConfigOverflowPolicy(OverflowFail+1)(&e))
+	assert.NoError(t, BatchConfigOverflowPolicy(OverflowDropOldest)(&e))
+
+	assert.Equal(t, BatchStats{}, e.BatchStats())
+	assert.Error(t, e.Close(context.Background()))
+}
+
+func TestBatchSplitByPayloadSize(t *testing.T) {
+	t.Parallel()
+
+	var requests, received int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		atomic.AddInt32(&received, int32(countEvents(t, r)))
+		insightsResponse(w, http.StatusOK)
+	})
+
+	event := `{"eventType":"Test","amount":1}`
+	ctx := context.Background()
+
+	// Exactly two events fit in a payload: [event,event]
+	require.NoError(t, client.BatchMode(ctx, 1,
+		BatchConfigMaxPayloadSize(len("[]")+2*len(event)+len(",")),
+	))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, client.EnqueueEvent(ctx, event))
+	}
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&received))
+	assert.Equal(t, BatchStats{Sent: 5}, client.BatchStats())
+}
+
+func TestBatchRejectsOversizedEvents(t *testing.T) {
+	t.Parallel()
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		insightsResponse(w, http.StatusOK)
+	})
+
+	event := `{"eventType":"Test","amount":1}`
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1,
+		BatchConfigMaxPayloadSize(len("[]")+len(event)),
+	))
+
+	// An event that only just fits in a payload on its own is queued.
+	require.NoError(t, client.EnqueueEvent(ctx, event))
+
+	err := client.EnqueueEvent(ctx, `{"eventType":"Test","amount":10}`)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, nrErrors.ErrPayloadTooLarge))
+
+	var tooLarge *nrErrors.PayloadTooLarge
+	require.True(t, errors.As(err, &tooLarge))
+	assert.Equal(t, len("[]")+len(event)+1, tooLarge.Size())
+	assert.Equal(t, len("[]")+len(event), tooLarge.Limit())
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, BatchStats{Sent: 1}, client.BatchStats())
+}
+
+func TestCreateEventRejectsOversizedEvents(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		insightsResponse(w, http.StatusOK)
+	})
+
+	padding := strings.Repeat("a", MaxPayloadSize)
+	err := client.CreateEvent(1, map[string]interface{}{"eventType": "Test", "padding": padding})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, nrErrors.ErrPayloadTooLarge))
+	assert.Zero(t, atomic.LoadInt32(&requests))
+}
+
+func TestBatchSpoolReplaysUnsentEvents(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var status, received int32 = http.StatusServiceUnavailable, 0
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		code := int(atomic.LoadInt32(&status))
+		if code == http.StatusOK {
+			atomic.AddInt32(&received, int32(countEvents(t, r)))
+		}
+
+		insightsResponse(w, code)
+	})
+
+	ctx := context.Background()
+	opts := []BatchConfigOption{BatchConfigSpool(spool.Config{Dir: dir}), BatchConfigRetries(0)}
+
+	// Events that can't be sent during an outage are left in the spool.
+	require.NoError(t, client.BatchMode(ctx, 1, opts...))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	}
+
+	// They aren't reported as failures, since they'll be sent later.
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, BatchStats{Spooled: 3}, client.BatchStats())
+
+	// They're sent once batch mode is enabled again, as when restarting.
+	atomic.StoreInt32(&status, http.StatusOK)
+
+	require.NoError(t, client.BatchMode(ctx, 1, opts...))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	require.NoError(t, client.Close(ctx))
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&received))
+	assert.Equal(t, BatchStats{Sent: 4}, client.BatchStats())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestBatchSpoolReplaysFailedEventsWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var status, received, requests int32 = http.StatusServiceUnavailable, 0, 0
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		code := int(atomic.LoadInt32(&status))
+		if code == http.StatusOK {
+			atomic.AddInt32(&received, int32(countEvents(t, r)))
+		}
+
+		insightsResponse(w, code)
+	})
+
+	ctx := context.Background()
+	cfg := spool.Config{Dir: dir, ReplayInterval: 5 * time.Millisecond, MaxReplayInterval: 20 * time.Millisecond}
+
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigSpool(cfg), BatchConfigRetries(0), BatchConfigQueueSize(3)))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	}
+
+	// Events in batches that fail during an outage are sent again while it
+	// lasts, and once it's over, without restarting batch mode.
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&requests) >= 3 }, 5*time.Second, time.Millisecond)
+	assert.Zero(t, atomic.LoadInt32(&received))
+
+	atomic.StoreInt32(&status, http.StatusOK)
+
+	require.Eventually(t, func() bool { return client.BatchStats().Sent == 3 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&received))
+	assert.Zero(t, client.BatchStats().Queued)
+	assert.Zero(t, client.eventSpool.Released())
+	assert.Zero(t, client.BatchStats().Failed)
+	assert.GreaterOrEqual(t, client.BatchStats().Spooled, int64(3))
+
+	require.NoError(t, client.Close(ctx))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestBatchSpoolRemovesRejectedEvents(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var requests int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		insightsResponse(w, http.StatusBadRequest)
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigSpool(spool.Config{Dir: dir})))
+	require.NoError(t, client.EnqueueEvent(ctx, testEvent))
+	require.Error(t, client.Close(ctx))
+
+	// Events rejected by New Relic aren't replayed.
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigSpool(spool.Config{Dir: dir})))
+	require.NoError(t, client.Close(ctx))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Error(t, BatchConfigSpool(spool.Config{})(&client))
+}
+
+// newUnstartedBatch returns a client in batch mode whose workers aren't
+// running, so the events queued stay in its queue of the given size.
+func newUnstartedBatch(t *testing.T, size int, policy OverflowPolicy) Events {
+	e := New(mock.NewTestConfig(t, nil))
+
+	e.eventQueue = make(chan queuedEvent, size)
+	e.stats = &batchStats{}
+	e.enqueues = &sync.WaitGroup{}
+	e.stopping = make(chan struct{})
+	e.batchOverflowPolicy = policy
+
+	return e
+}
+
+// queuedAmounts returns the amounts of the events in the queue, in order.
+func queuedAmounts(t *testing.T, e Events) []float64 {
+	amounts := []float64{}
+
+	for len(e.eventQueue) > 0 {
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal((<-e.eventQueue).data, &event))
+		amounts = append(amounts, event["amount"].(float64))
+	}
+
+	return amounts
+}
+
+func TestBatchOverflowPolicies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	event := func(amount int) map[string]interface{} {
+		return map[string]interface{}{"eventType": "Test", "amount": amount}
+	}
+
+	e := newUnstartedBatch(t, 2, OverflowDropNewest)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, e.EnqueueEvent(ctx, event(i)))
+	}
+	assert.Equal(t, BatchStats{Queued: 2, QueueDepth: 2, Dropped: 1}, e.BatchStats())
+	assert.Equal(t, []float64{1, 2}, queuedAmounts(t, e))
+
+	e = newUnstartedBatch(t, 2, OverflowDropOldest)
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, e.EnqueueEvent(ctx, event(i)))
+	}
+	assert.Equal(t, BatchStats{Queued: 2, QueueDepth: 2, Dropped: 2}, e.BatchStats())
+	assert.Equal(t, []float64{3, 4}, queuedAmounts(t, e))
+
+	e = newUnstartedBatch(t, 2, OverflowFail)
+	for i := 1; i <= 2; i++ {
+		require.NoError(t, e.EnqueueEvent(ctx, event(i)))
+	}
+	assert.Equal(t, ErrQueueFull, e.EnqueueEvent(ctx, event(3)))
+	assert.Equal(t, BatchStats{Queued: 2, QueueDepth: 2}, e.BatchStats())
+
+	e = newUnstartedBatch(t, 1, OverflowBlock)
+	require.NoError(t, e.EnqueueEvent(ctx, event(1)))
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, e.EnqueueEvent(timeout, event(2)))
+	assert.Equal(t, BatchStats{Queued: 1, QueueDepth: 1}, e.BatchStats())
+}
+
+func TestBatchOverflowDropsSpooledEvents(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := spool.Open(spool.Config{Dir: dir})
+	require.NoError(t, err)
+
+	e := newUnstartedBatch(t, 1, OverflowDropNewest)
+	e.eventSpool = s
+
+	require.NoError(t, e.EnqueueEvent(context.Background(), testEvent))
+	require.NoError(t, e.EnqueueEvent(context.Background(), testEvent))
+
+	// Once the event still queued is sent, nothing is left in the spool.
+	s.Ack((<-e.eventQueue).record)
+	require.NoError(t, s.Close())
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -124,7 +124,7 @@ func TestIntegrationEvents_marshalEvent(t *testing.T) {
 	client := newIntegrationTestClient(t)
 
 	for _, event := range testEvents {
-		data, err := client.marshalEvent(event.Event)
+		data, err := client.marshalEvent(event.Event, true)
 		if event.err == nil {
 			assert.NoError(t, err)
 		} else {
@@ -27,8 +27,13 @@ func Example_batch() {
 	// Initialize the client.
 	client := New(cfg)
 
-	// Start batch mode
-	if err := client.BatchMode(context.Background(), accountID); err != nil {
+	// Start batch mode, logging any batches that can't be delivered once
+	// retries are exhausted.
+	errorHandler := func(events [][]byte, err error) {
+		log.Printf("failed to deliver %d events: %s", len(events), err)
+	}
+
+	if err := client.BatchMode(context.Background(), accountID, BatchConfigErrorHandler(errorHandler)); err != nil {
 		log.Fatal("error starting batch mode:", err)
 	}
 
@@ -45,8 +50,8 @@ func Example_batch() {
 		log.Fatal("error posting custom event:", err)
 	}
 
-	// Force flush events
-	if err := client.Flush(); err != nil {
-		log.Fatal("error flushing event queue:", err)
+	// Send the queued events and leave batch mode.
+	if err := client.Close(context.Background()); err != nil {
+		log.Fatal("error closing event queue:", err)
 	}
 }
@@ -0,0 +1,19 @@
+/*
+Package logforwarder ships application logs to New Relic through the Logs
+client's batch mode, so a separate log forwarder isn't needed.
+
+A Forwarder adds the attributes that link log entries to an entity and to the
+trace they were logged in, and queues them without blocking the application.
+Adapters are provided for logrus (a Hook), zap (a Core) and, on Go 1.21 and
+later, log/slog (a Handler).
+
+Authentication
+
+You will need a valid License key, or an Insights insert key, to communicate
+with the backend New Relic API that provides this functionality.  See the API
+key documentation below for more information on how to locate these keys:
+
+https://docs.newrelic.com/docs/apis/get-started/intro-apis/types-new-relic-api-keys
+
+*/
+package logforwarder
@@ -0,0 +1,52 @@
+//go:build integration
+// +build integration
+
+package logforwarder
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/logs"
+)
+
+func Example_logrus() {
+	// Initialize the client configuration.  A New Relic License Key is
+	// required to communicate with the backend API.
+	cfg := config.New()
+	cfg.LicenseKey = os.Getenv("NEW_RELIC_LICENSE_KEY")
+
+	// Initialize the Logs client, and start batch mode.
+	client := logs.New(cfg)
+
+	if err := client.BatchMode(context.Background(), 0); err != nil {
+		log.Fatal("error starting batch mode:", err)
+	}
+
+	// Create a forwarder linking the logs to an entity.
+	forwarder, err := New(context.Background(), &client, Config{
+		EntityGUID: os.Getenv("NEW_RELIC_ENTITY_GUID"),
+	})
+	if err != nil {
+		log.Fatal("error creating forwarder:", err)
+	}
+
+	// Forward the application's logs.
+	logger := logrus.New()
+	logger.AddHook(NewLogrusHook(forwarder))
+
+	logger.WithField("order.id", 42).Info("checkout complete")
+
+	// Queue the logs forwarded, then send them and leave batch mode.
+	if err := forwarder.Close(context.Background()); err != nil {
+		log.Fatal("error closing forwarder:", err)
+	}
+
+	if err := client.Close(context.Background()); err != nil {
+		log.Fatal("error closing logs batch mode:", err)
+	}
+}
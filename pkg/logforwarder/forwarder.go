@@ -0,0 +1,256 @@
+package logforwarder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/newrelic/newrelic-client-go/pkg/logs"
+)
+
+const (
+	DefaultBufferSize   = 1000
+	DefaultBlockTimeout = 100 * time.Millisecond
+)
+
+// BackpressurePolicy is what the Forwarder does with a log entry when its
+// buffer is full.
+type BackpressurePolicy int
+
+const (
+	// BackpressureDrop drops the log entry without waiting, so logging never
+	// blocks the application.
+	BackpressureDrop BackpressurePolicy = iota
+
+	// BackpressureBlock waits up to the block timeout for room in the buffer,
+	// then drops the log entry.
+	BackpressureBlock
+)
+
+// TraceContextFunc returns the IDs of the trace and span active in ctx, if
+// there is one.
+type TraceContextFunc func(ctx context.Context) (traceID, spanID string)
+
+// Config configures a Forwarder.
+type Config struct {
+	// EntityGUID is the GUID of the entity the logs belong to, sent as the
+	// entity.guid attribute.
+	EntityGUID string
+
+	// Hostname is sent as the hostname attribute.  It defaults to the name of
+	// the host reported by the kernel.
+	Hostname string
+
+	// Attributes are added to every log entry.
+	Attributes map[string]interface{}
+
+	// BufferSize is how many log entries can wait to be queued in batch mode.
+	BufferSize int
+
+	// Policy is what to do with log entries when the buffer is full.
+	Policy BackpressurePolicy
+
+	// BlockTimeout is how long BackpressureBlock waits for room in the buffer.
+	BlockTimeout time.Duration
+
+	// TraceContext returns the trace.id and span.id attributes of log entries
+	// logged with a context.  It defaults to the OpenTelemetry span in the
+	// context.
+	TraceContext TraceContextFunc
+}
+
+// Forwarder queues log entries in a Logs client's batch mode on behalf of the
+// logging adapters, adding entity linking attributes to each one.
+type Forwarder struct {
+	client *logs.Logs
+	config Config
+
+	entries chan logs.LogEntry
+	dropped int64
+
+	mu      sync.RWMutex
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// New creates a Forwarder queueing log entries in the client's batch mode,
+// which must already be enabled, until ctx is done or the Forwarder is closed.
+// Closing the Forwarder doesn't close batch mode.
+func New(ctx context.Context, client *logs.Logs, cfg Config) (*Forwarder, error) {
+	if client == nil {
+		return nil, errors.New("logforwarder: invalid logs client specified")
+	}
+
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	if cfg.BufferSize < 0 {
+		return nil, errors.New("logforwarder: invalid buffer size specified")
+	}
+
+	if cfg.BlockTimeout == 0 {
+		cfg.BlockTimeout = DefaultBlockTimeout
+	}
+	if cfg.BlockTimeout < 0 {
+		return nil, errors.New("logforwarder: invalid block timeout specified")
+	}
+
+	if cfg.Policy != BackpressureDrop && cfg.Policy != BackpressureBlock {
+		return nil, errors.New("logforwarder: invalid backpressure policy specified")
+	}
+
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+
+	if cfg.TraceContext == nil {
+		cfg.TraceContext = otelTraceContext
+	}
+
+	f := &Forwarder{
+		client:  client,
+		config:  cfg,
+		entries: make(chan logs.LogEntry, cfg.BufferSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go f.forward(ctx)
+
+	return f, nil
+}
+
+// otelTraceContext returns the IDs of the OpenTelemetry span in ctx.
+func otelTraceContext(ctx context.Context) (string, string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", ""
+	}
+
+	return sc.TraceID().String(), sc.SpanID().String()
+}
+
+// Forward queues a log entry logged with ctx, which may be nil, adding the
+// entity linking attributes.  It reports whether the entry was accepted; it is
+// dropped when the buffer is full, as set by the backpressure policy, or once
+// the Forwarder is closed.
+func (f *Forwarder) Forward(ctx context.Context, entry logs.LogEntry) bool {
+	entry.Attributes = f.attributes(ctx, entry.Attributes)
+
+	// Hold the read lock so Close can't close the buffer while sending.
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	select {
+	case <-f.closing:
+		atomic.AddInt64(&f.dropped, 1)
+		return false
+	default:
+	}
+
+	select {
+	case f.entries <- entry:
+		return true
+	default:
+	}
+
+	if f.config.Policy == BackpressureBlock {
+		timer := time.NewTimer(f.config.BlockTimeout)
+		defer timer.Stop()
+
+		select {
+		case f.entries <- entry:
+			return true
+		case <-timer.C:
+		}
+	}
+
+	atomic.AddInt64(&f.dropped, 1)
+	return false
+}
+
+// attributes returns the attributes of a log entry with the configured and
+// entity linking attributes added, without changing the entry's attributes.
+func (f *Forwarder) attributes(ctx context.Context, entryAttributes map[string]interface{}) map[string]interface{} {
+	attributes := make(map[string]interface{}, len(f.config.Attributes)+len(entryAttributes)+4)
+
+	for k, v := range f.config.Attributes {
+		attributes[k] = v
+	}
+
+	if f.config.EntityGUID != "" {
+		attributes["entity.guid"] = f.config.EntityGUID
+	}
+	if f.config.Hostname != "" {
+		attributes["hostname"] = f.config.Hostname
+	}
+
+	if ctx != nil {
+		if traceID, spanID := f.config.TraceContext(ctx); traceID != "" {
+			attributes["trace.id"] = traceID
+			if spanID != "" {
+				attributes["span.id"] = spanID
+			}
+		}
+	}
+
+	for k, v := range entryAttributes {
+		attributes[k] = v
+	}
+
+	return attributes
+}
+
+// Dropped returns the number of log entries dropped by the Forwarder.
+func (f *Forwarder) Dropped() int64 {
+	return atomic.LoadInt64(&f.dropped)
+}
+
+// Close stops the Forwarder once the log entries it has accepted have been
+// queued in batch mode, or ctx is done.  It returns an error if any log
+// entries were dropped.
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.mu.Lock()
+	select {
+	case <-f.closing:
+	default:
+		close(f.closing)
+		close(f.entries)
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if dropped := f.Dropped(); dropped > 0 {
+		return fmt.Errorf("logforwarder: %d log entries were dropped", dropped)
+	}
+
+	return nil
+}
+
+// forward queues the log entries in the buffer in batch mode until the buffer
+// is closed, or ctx is done, dropping the rest.
+func (f *Forwarder) forward(ctx context.Context) {
+	defer close(f.done)
+
+	for entry := range f.entries {
+		if err := ctx.Err(); err != nil {
+			atomic.AddInt64(&f.dropped, 1)
+			continue
+		}
+
+		if err := f.client.EnqueueLogEntry(ctx, entry); err != nil {
+			atomic.AddInt64(&f.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+//go:build unit
+// +build unit
+
+package logforwarder
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/newrelic/newrelic-client-go/pkg/logs"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+// testServer records the log entries sent to it.
+type testServer struct {
+	mu      sync.Mutex
+	entries []map[string]interface{}
+}
+
+func (s *testServer) Entries() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]map[string]interface{}(nil), s.entries...)
+}
+
+// newTestForwarder returns a Forwarder queueing log entries in the batch mode
+// of a Logs client sending them to a test server.
+func newTestForwarder(t *testing.T, cfg Config) (*Forwarder, *testServer) {
+	server := &testServer{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+
+		// Log entries are sent in blocks of the Log API's detailed format.
+		blocks := []struct {
+			Logs []map[string]interface{} `json:"logs"`
+		}{}
+		assert.NoError(t, json.NewDecoder(body).Decode(&blocks))
+
+		server.mu.Lock()
+		for _, block := range blocks {
+			server.entries = append(server.entries, block.Logs...)
+		}
+		server.mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(ts.Close)
+
+	client := logs.New(mock.NewTestConfig(t, ts))
+	require.NoError(t, client.BatchMode(context.Background(), 0))
+
+	if cfg.Hostname == "" {
+		cfg.Hostname = "test-host"
+	}
+
+	f, err := New(context.Background(), &client, cfg)
+	require.NoError(t, err)
+
+	return f, server
+}
+
+// closeTestForwarder closes the Forwarder, then batch mode, so every log
+// entry accepted has been sent.
+func closeTestForwarder(t *testing.T, f *Forwarder) {
+	require.NoError(t, f.Close(context.Background()))
+	require.NoError(t, f.client.Close(context.Background()))
+}
+
+func TestForward(t *testing.T) {
+	t.Parallel()
+
+	f, server := newTestForwarder(t, Config{
+		EntityGUID: "MXxBUE18QVBQTElDQVRJT058MQ",
+		Attributes: map[string]interface{}{"service.name": "checkout"},
+	})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	attributes := map[string]interface{}{"order.id": 42}
+	assert.True(t, f.Forward(ctx, logs.LogEntry{Message: "traced", Level: "info", Attributes: attributes}))
+	assert.True(t, f.Forward(context.Background(), logs.LogEntry{Message: "untraced"}))
+
+	// The entry's attributes are left untouched.
+	assert.Equal(t, map[string]interface{}{"order.id": 42}, attributes)
+
+	closeTestForwarder(t, f)
+
+	entries := server.Entries()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, map[string]interface{}{
+		"message": "traced",
+		"attributes": map[string]interface{}{
+			"entity.guid":  "MXxBUE18QVBQTElDQVRJT058MQ",
+			"hostname":     "test-host",
+			"service.name": "checkout",
+			"trace.id":     "4bf92f3577b34da6a3ce929d0e0e4736",
+			"span.id":      "00f067aa0ba902b7",
+			"order.id":     float64(42),
+			"level":        "info",
+		},
+	}, entries[0])
+
+	assert.NotContains(t, entries[1]["attributes"], "trace.id")
+	assert.Zero(t, f.Dropped())
+
+	// Entries are dropped once the Forwarder is closed.
+	assert.False(t, f.Forward(context.Background(), logs.LogEntry{Message: "late"}))
+	assert.Equal(t, int64(1), f.Dropped())
+}
+
+// newUnstartedForwarder returns a Forwarder that doesn't read its buffer until
+// forward is called.
+func newUnstartedForwarder(t *testing.T, cfg Config) *Forwarder {
+	client := logs.New(mock.NewTestConfig(t, nil))
+
+	cfg.TraceContext = otelTraceContext
+
+	return &Forwarder{
+		client:  &client,
+		config:  cfg,
+		entries: make(chan logs.LogEntry, cfg.BufferSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func TestForwardDropPolicy(t *testing.T) {
+	t.Parallel()
+
+	f := newUnstartedForwarder(t, Config{BufferSize: 1})
+
+	assert.True(t, f.Forward(context.Background(), logs.LogEntry{Message: "buffered"}))
+	assert.False(t, f.Forward(context.Background(), logs.LogEntry{Message: "dropped"}))
+	assert.Equal(t, int64(1), f.Dropped())
+
+	// Entries left in the buffer once ctx is done are dropped.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	go f.forward(ctx)
+
+	err := f.Close(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "logforwarder: 2 log entries were dropped", err.Error())
+}
+
+func TestForwardBlockPolicy(t *testing.T) {
+	t.Parallel()
+
+	f := newUnstartedForwarder(t, Config{BufferSize: 1, Policy: BackpressureBlock, BlockTimeout: 10 * time.Millisecond})
+
+	assert.True(t, f.Forward(context.Background(), logs.LogEntry{Message: "buffered"}))
+	assert.False(t, f.Forward(context.Background(), logs.LogEntry{Message: "timed out"}))
+	assert.Equal(t, int64(1), f.Dropped())
+
+	// The client isn't in batch mode, so every entry read is dropped, which
+	// keeps making room in the buffer for blocked entries.
+	f.config.BlockTimeout = time.Minute
+	go f.forward(context.Background())
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, f.Forward(context.Background(), logs.LogEntry{Message: "blocked"}))
+	}
+
+	require.Error(t, f.Close(context.Background()))
+	assert.Equal(t, int64(7), f.Dropped())
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	client := logs.New(mock.NewTestConfig(t, nil))
+	ctx := context.Background()
+
+	cases := map[string]Config{
+		"buffer size":   {BufferSize: -1},
+		"block timeout": {BlockTimeout: -1},
+		"policy":        {Policy: BackpressurePolicy(5)},
+	}
+
+	for name, cfg := range cases {
+		_, err := New(ctx, &client, cfg)
+		assert.Error(t, err, name)
+	}
+
+	_, err := New(ctx, nil, Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package logforwarder
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/newrelic/newrelic-client-go/pkg/logs"
+)
+
+// LogrusHook is a logrus Hook forwarding log entries to New Relic.
+type LogrusHook struct {
+	forwarder *Forwarder
+	levels    []logrus.Level
+}
+
+// NewLogrusHook creates a logrus Hook forwarding entries at the given levels,
+// or at every level when none are given.
+func NewLogrusHook(forwarder *Forwarder, levels ...logrus.Level) *LogrusHook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+
+	return &LogrusHook{
+		forwarder: forwarder,
+		levels:    levels,
+	}
+}
+
+// Levels returns the levels of the entries the hook forwards.
+func (h *LogrusHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire forwards a log entry, along with its fields.  It never returns an
+// error, so logging isn't interrupted when the entry is dropped.
+func (h *LogrusHook) Fire(entry *logrus.Entry) error {
+	attributes := make(map[string]interface{}, len(entry.Data))
+	for k, v := range entry.Data {
+		attributes[k] = attributeValue(v)
+	}
+
+	h.forwarder.Forward(entry.Context, logs.LogEntry{
+		Timestamp:  entry.Time,
+		Message:    entry.Message,
+		Level:      entry.Level.String(),
+		Attributes: attributes,
+	})
+
+	return nil
+}
+
+// attributeValue converts a field's value to one of the types of attribute
+// values the Log API accepts.
+func attributeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
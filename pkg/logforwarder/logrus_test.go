@@ -0,0 +1,43 @@
+//go:build unit
+// +build unit
+
+package logforwarder
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogrusHook(t *testing.T) {
+	t.Parallel()
+
+	f, server := newTestForwarder(t, Config{})
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(NewLogrusHook(f, logrus.WarnLevel, logrus.ErrorLevel))
+
+	logger.Info("not forwarded")
+	logger.WithFields(logrus.Fields{"order.id": 42}).WithError(errors.New("declined")).Warn("payment failed")
+
+	closeTestForwarder(t, f)
+
+	entries := server.Entries()
+	require.Len(t, entries, 1)
+
+	assert.Equal(t, "payment failed", entries[0]["message"])
+	assert.NotZero(t, entries[0]["timestamp"])
+	assert.Equal(t, map[string]interface{}{
+		"hostname": "test-host",
+		"level":    "warning",
+		"order.id": float64(42),
+		"error":    "declined",
+	}, entries[0]["attributes"])
+
+	assert.Equal(t, logrus.AllLevels, NewLogrusHook(f).Levels())
+}
@@ -0,0 +1,127 @@
+//go:build go1.21
+// +build go1.21
+
+package logforwarder
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/newrelic/newrelic-client-go/pkg/logs"
+)
+
+// SlogHandler is a log/slog Handler forwarding log records to New Relic.
+type SlogHandler struct {
+	forwarder *Forwarder
+	level     slog.Leveler
+	attrs     map[string]interface{}
+	group     string
+}
+
+// NewSlogHandler creates a log/slog Handler forwarding records at level or
+// above.  A nil level forwards records at slog.LevelInfo or above.
+func NewSlogHandler(forwarder *Forwarder, level slog.Leveler) *SlogHandler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+
+	return &SlogHandler{
+		forwarder: forwarder,
+		level:     level,
+		attrs:     map[string]interface{}{},
+	}
+}
+
+// Enabled reports whether records at the level are forwarded.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle forwards a log record, along with its attributes, linking it to the
+// trace active in ctx.  It never returns an error, so logging isn't
+// interrupted when the record is dropped.
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	attributes := make(map[string]interface{}, len(h.attrs)+record.NumAttrs())
+	for k, v := range h.attrs {
+		attributes[k] = v
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(attributes, h.group, attr)
+		return true
+	})
+
+	h.forwarder.Forward(ctx, logs.LogEntry{
+		Timestamp:  record.Time,
+		Message:    record.Message,
+		Level:      strings.ToLower(record.Level.String()),
+		Attributes: attributes,
+	})
+
+	return nil
+}
+
+// WithAttrs returns a copy of the handler adding attributes to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	for _, attr := range attrs {
+		addSlogAttr(clone.attrs, h.group, attr)
+	}
+
+	return clone
+}
+
+// WithGroup returns a copy of the handler qualifying the names of the
+// attributes added afterwards with the group's name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := h.clone()
+	clone.group = groupKey(h.group, name)
+
+	return clone
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	clone := *h
+	clone.attrs = make(map[string]interface{}, len(h.attrs))
+	for k, v := range h.attrs {
+		clone.attrs[k] = v
+	}
+
+	return &clone
+}
+
+// addSlogAttr adds an attribute to attributes, flattening groups into names
+// joined by dots.
+func addSlogAttr(attributes map[string]interface{}, group string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		for _, member := range value.Group() {
+			addSlogAttr(attributes, groupKey(group, attr.Key), member)
+		}
+
+		return
+	}
+
+	if attr.Key == "" {
+		return
+	}
+
+	attributes[groupKey(group, attr.Key)] = attributeValue(value.Any())
+}
+
+func groupKey(group, key string) string {
+	if group == "" {
+		return key
+	}
+	if key == "" {
+		return group
+	}
+
+	return group + "." + key
+}
@@ -0,0 +1,52 @@
+//go:build unit && go1.21
+// +build unit,go1.21
+
+package logforwarder
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSlogHandler(t *testing.T) {
+	t.Parallel()
+
+	f, server := newTestForwarder(t, Config{})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	logger := slog.New(NewSlogHandler(f, slog.LevelWarn)).With("service.name", "checkout")
+
+	logger.InfoContext(ctx, "not forwarded")
+	logger.WithGroup("payment").WarnContext(ctx, "payment failed",
+		slog.Int("order.id", 42),
+		slog.Group("card", slog.String("brand", "visa")),
+	)
+
+	closeTestForwarder(t, f)
+
+	entries := server.Entries()
+	require.Len(t, entries, 1)
+
+	assert.Equal(t, "payment failed", entries[0]["message"])
+	assert.NotZero(t, entries[0]["timestamp"])
+	assert.Equal(t, map[string]interface{}{
+		"hostname":           "test-host",
+		"level":              "warn",
+		"service.name":       "checkout",
+		"payment.order.id":   float64(42),
+		"payment.card.brand": "visa",
+		"trace.id":           "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span.id":            "00f067aa0ba902b7",
+	}, entries[0]["attributes"])
+}
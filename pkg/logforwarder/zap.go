@@ -0,0 +1,90 @@
+package logforwarder
+
+import (
+	"context"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/newrelic/newrelic-client-go/pkg/logs"
+)
+
+// ZapCore is a zap Core forwarding log entries to New Relic.
+type ZapCore struct {
+	zapcore.LevelEnabler
+
+	forwarder *Forwarder
+	fields    []zapcore.Field
+	ctx       context.Context
+}
+
+// NewZapCore creates a zap Core forwarding entries at the levels enabled by
+// enabler.  It can be combined with other cores with zapcore.NewTee.
+func NewZapCore(forwarder *Forwarder, enabler zapcore.LevelEnabler) *ZapCore {
+	return &ZapCore{
+		LevelEnabler: enabler,
+		forwarder:    forwarder,
+	}
+}
+
+// WithContext returns a copy of the core linking the entries it forwards to
+// the trace active in ctx.
+func (c *ZapCore) WithContext(ctx context.Context) *ZapCore {
+	clone := *c
+	clone.ctx = ctx
+
+	return &clone
+}
+
+// With returns a copy of the core adding fields to every entry.
+func (c *ZapCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+
+	return &clone
+}
+
+// Check adds the core to the checked entry when its level is enabled.
+func (c *ZapCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+// Write forwards a log entry, along with its fields.  It never returns an
+// error, so logging isn't interrupted when the entry is dropped.
+func (c *ZapCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	attributes := make(map[string]interface{}, len(enc.Fields)+1)
+	for k, v := range enc.Fields {
+		attributes[k] = attributeValue(v)
+	}
+
+	if entry.LoggerName != "" {
+		attributes["logger.name"] = entry.LoggerName
+	}
+
+	c.forwarder.Forward(c.ctx, logs.LogEntry{
+		Timestamp:  entry.Time,
+		Message:    entry.Message,
+		Level:      entry.Level.String(),
+		Attributes: attributes,
+	})
+
+	return nil
+}
+
+// Sync does nothing, as entries are sent by the Logs client's batch mode.
+func (c *ZapCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build unit
+// +build unit
+
+package logforwarder
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestZapCore(t *testing.T) {
+	t.Parallel()
+
+	f, server := newTestForwarder(t, Config{})
+
+	logger := zap.New(NewZapCore(f, zapcore.WarnLevel)).Named("payments").With(zap.String("service.name", "checkout"))
+
+	logger.Info("not forwarded")
+	logger.Warn("payment failed", zap.Int("order.id", 42), zap.Error(errors.New("declined")))
+	require.NoError(t, logger.Sync())
+
+	closeTestForwarder(t, f)
+
+	entries := server.Entries()
+	require.Len(t, entries, 1)
+
+	assert.Equal(t, "payment failed", entries[0]["message"])
+	assert.NotZero(t, entries[0]["timestamp"])
+	assert.Equal(t, map[string]interface{}{
+		"hostname":     "test-host",
+		"level":        "warn",
+		"logger.name":  "payments",
+		"service.name": "checkout",
+		"order.id":     float64(42),
+		"error":        "declined",
+	}, entries[0]["attributes"])
+}
+
+func TestZapCoreWithContext(t *testing.T) {
+	t.Parallel()
+
+	f, server := newTestForwarder(t, Config{})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	core := NewZapCore(f, zapcore.InfoLevel)
+	zap.New(core.WithContext(ctx)).Info("traced")
+	zap.New(core).Info("untraced")
+
+	closeTestForwarder(t, f)
+
+	entries := server.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entries[0]["attributes"].(map[string]interface{})["trace.id"])
+	assert.NotContains(t, entries[1]["attributes"], "trace.id")
+}
@@ -9,3 +9,10 @@ type Logger interface {
 	Trace(string, ...interface{})
 	SetLevel(string)
 }
+
+// LevelEnabler is implemented by loggers that can report whether messages at
+// a level are logged, so callers can skip preparing messages that would be
+// dropped.  Loggers that don't implement it are assumed to log every level.
+type LevelEnabler interface {
+	IsLevelEnabled(levelName string) bool
+}
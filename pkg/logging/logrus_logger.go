@@ -55,6 +55,16 @@ func (l LogrusLogger) SetLevel(levelName string) {
 	l.logger.SetLevel(level)
 }
 
+// IsLevelEnabled reports whether messages at the given level are logged.
+func (l LogrusLogger) IsLevelEnabled(levelName string) bool {
+	level, err := log.ParseLevel(levelName)
+	if err != nil {
+		return false
+	}
+
+	return l.logger.IsLevelEnabled(level)
+}
+
 // LogJSON determines whether or not to format the logs as JSON.
 func (l LogrusLogger) SetLogJSON(value bool) {
 	if value {
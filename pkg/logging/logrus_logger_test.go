@@ -48,3 +48,18 @@ func TestLogrusLogger_interface(t *testing.T) {
 	var l Logger = NewLogrusLogger()
 	l.Info("testing")
 }
+
+func TestIsLevelEnabled(t *testing.T) {
+	t.Parallel()
+	l := NewLogrusLogger()
+
+	l.SetLevel("debug")
+	assert.True(t, l.IsLevelEnabled("debug"))
+	assert.False(t, l.IsLevelEnabled("trace"))
+	assert.False(t, l.IsLevelEnabled("notalevel"))
+
+	l.SetLevel("trace")
+	assert.True(t, l.IsLevelEnabled("trace"))
+
+	var _ LevelEnabler = l
+}
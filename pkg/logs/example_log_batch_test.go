@@ -53,10 +53,8 @@ func Example_basic() {
 		}
 	}
 
-	// Hack to stop the test from ending BEFORE the queue flushes
-	time.Sleep((BatchTimeoutSeconds * 2) * time.Second)
-	// Force flush
-	if err := client.Flush(); err != nil {
-		log.Fatal("error flushing log queue: ", err)
+	// Send the queued log entries and leave batch mode.
+	if err := client.Close(context.Background()); err != nil {
+		log.Fatal("error closing log queue: ", err)
 	}
 }
@@ -0,0 +1,226 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+// Limits the Log API places on the attributes of a log entry, including the
+// attributes of its common block.
+const (
+	MaxAttributes           = 255
+	MaxAttributeNameLength  = 255
+	MaxAttributeValueLength = 4094
+)
+
+// LogEntry is a log entry in the Log API's detailed format.  It can be passed
+// to CreateLogEntry or EnqueueLogEntry, or sent in a LogBatch.
+type LogEntry struct {
+	// Timestamp is when the entry was logged.  When zero, the timestamp of
+	// the common block is used, or the time the entry is received.
+	Timestamp time.Time
+
+	// Message is the log message.
+	Message string
+
+	// Level is the severity of the entry, sent as the level attribute.
+	Level string
+
+	// Attributes are the attributes of the entry.
+	Attributes map[string]interface{}
+}
+
+// LogCommon holds the timestamp and attributes shared by the entries of a
+// LogBatch.
+type LogCommon struct {
+	Timestamp  time.Time
+	Attributes map[string]interface{}
+}
+
+// LogBatch is a group of log entries sharing a common block, which keeps the
+// attributes they share out of each entry.
+type LogBatch struct {
+	Common *LogCommon
+	Logs   []LogEntry
+}
+
+type logEntryJSON struct {
+	Timestamp  int64                  `json:"timestamp,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type logCommonJSON struct {
+	Timestamp  int64                  `json:"timestamp,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type logBatchJSON struct {
+	Common *LogCommon `json:"common,omitempty"`
+	Logs   []LogEntry `json:"logs"`
+}
+
+// MarshalJSON converts the log entry into the Log API's detailed format.
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	attributes := e.Attributes
+	if e.Level != "" {
+		attributes = make(map[string]interface{}, len(e.Attributes)+1)
+		for k, v := range e.Attributes {
+			attributes[k] = v
+		}
+
+		attributes["level"] = e.Level
+	}
+
+	return json.Marshal(logEntryJSON{
+		Timestamp:  unixMilli(e.Timestamp),
+		Message:    e.Message,
+		Attributes: attributes,
+	})
+}
+
+// MarshalJSON converts the common block into the Log API's detailed format.
+func (c LogCommon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(logCommonJSON{
+		Timestamp:  unixMilli(c.Timestamp),
+		Attributes: c.Attributes,
+	})
+}
+
+// MarshalJSON converts the batch into the Log API's detailed format.
+func (b LogBatch) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]logBatchJSON{{Common: b.Common, Logs: b.Logs}})
+}
+
+// Validate checks the log entry against the Log API's limits on attributes,
+// counting the attributes of the common block it is sent with, which may be
+// nil.
+func (e LogEntry) Validate(common *LogCommon) error {
+	count := len(e.Attributes)
+	if e.Level != "" {
+		count++
+	}
+	if common != nil {
+		count += len(common.Attributes)
+	}
+
+	if count > MaxAttributes {
+		return errors.NewInvalidInputf("logs: log entry has %d attributes, the limit is %d", count, MaxAttributes)
+	}
+
+	return validateAttributes(e.Attributes)
+}
+
+// Validate checks the entries of the batch, along with its common block,
+// against the Log API's limits on attributes.
+func (b LogBatch) Validate() error {
+	if len(b.Logs) == 0 {
+		return errors.NewInvalidInput("logs: log batch has no log entries")
+	}
+
+	if b.Common != nil {
+		if err := validateAttributes(b.Common.Attributes); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range b.Logs {
+		if err := entry.Validate(b.Common); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAttributes checks the length of attribute names and string values.
+func validateAttributes(attributes map[string]interface{}) error {
+	for name, value := range attributes {
+		if name == "" || len(name) > MaxAttributeNameLength {
+			return errors.NewInvalidInputf("logs: attribute name %q must be 1 to %d characters", name, MaxAttributeNameLength)
+		}
+
+		if s, ok := value.(string); ok && len(s) > MaxAttributeValueLength {
+			return errors.NewInvalidInputf("logs: value of attribute %q exceeds %d characters", name, MaxAttributeValueLength)
+		}
+	}
+
+	return nil
+}
+
+// GroupLogEntries groups log entries into batches by the values of the given
+// attributes, which are moved into the common block of each batch.  Entries
+// missing any of the attributes are grouped together with a common block
+// holding the attributes they do have.  Batches are in the order their first
+// entry appears.
+func GroupLogEntries(entries []LogEntry, keys ...string) []LogBatch {
+	batches := []LogBatch{}
+	index := map[string]int{}
+
+	for _, entry := range entries {
+		common := map[string]interface{}{}
+		attributes := make(map[string]interface{}, len(entry.Attributes))
+
+		for k, v := range entry.Attributes {
+			attributes[k] = v
+		}
+
+		for _, key := range keys {
+			if v, ok := attributes[key]; ok {
+				common[key] = v
+				delete(attributes, key)
+			}
+		}
+
+		entry.Attributes = attributes
+
+		groupKey := commonKey(common)
+		i, ok := index[groupKey]
+		if !ok {
+			i = len(batches)
+			index[groupKey] = i
+
+			batch := LogBatch{}
+			if len(common) > 0 {
+				batch.Common = &LogCommon{Attributes: common}
+			}
+
+			batches = append(batches, batch)
+		}
+
+		batches[i].Logs = append(batches[i].Logs, entry)
+	}
+
+	return batches
+}
+
+// commonKey identifies the attributes of a common block, independent of the
+// order of the map.
+func commonKey(attributes map[string]interface{}) string {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	key := ""
+	for _, k := range keys {
+		key += fmt.Sprintf("%q=%#v;", k, attributes[k])
+	}
+
+	return key
+}
+
+// unixMilli returns the time in milliseconds since the epoch, or zero for the
+// zero time, so it can be omitted.
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,258 @@
+//go:build unit
+// +build unit
+
+package logs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+// decodePayloads returns a handler decoding each payload sent to the Log API.
+func decodePayloads(t *testing.T, mu *sync.Mutex, payloads *[]interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+
+		var payload interface{}
+		assert.NoError(t, json.NewDecoder(body).Decode(&payload))
+
+		mu.Lock()
+		*payloads = append(*payloads, payload)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func TestLogEntryMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	entry := LogEntry{
+		Timestamp:  time.Unix(1600000000, 0),
+		Message:    "checkout complete",
+		Level:      "info",
+		Attributes: map[string]interface{}{"order.id": 42},
+	}
+
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"timestamp":1600000000000,"message":"checkout complete","attributes":{"order.id":42,"level":"info"}}`, string(data))
+
+	// The entry's attributes are left untouched.
+	assert.Equal(t, map[string]interface{}{"order.id": 42}, entry.Attributes)
+
+	data, err = json.Marshal(LogBatch{
+		Common: &LogCommon{Attributes: map[string]interface{}{"service": "checkout"}},
+		Logs:   []LogEntry{{Message: "a"}},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"common":{"attributes":{"service":"checkout"}},"logs":[{"message":"a"}]}]`, string(data))
+}
+
+func TestLogEntryValidate(t *testing.T) {
+	t.Parallel()
+
+	tooMany := map[string]interface{}{}
+	for i := 0; i < MaxAttributes; i++ {
+		tooMany[fmt.Sprintf("attr%d", i)] = i
+	}
+
+	common := &LogCommon{Attributes: map[string]interface{}{"service": "checkout"}}
+
+	assert.NoError(t, LogEntry{Message: "a", Attributes: tooMany}.Validate(nil))
+	assert.Error(t, LogEntry{Message: "a", Attributes: tooMany}.Validate(common))
+	assert.Error(t, LogEntry{Message: "a", Level: "info", Attributes: tooMany}.Validate(nil))
+
+	cases := map[string]LogEntry{
+		"empty name":  {Attributes: map[string]interface{}{"": 1}},
+		"long name":   {Attributes: map[string]interface{}{strings.Repeat("a", MaxAttributeNameLength+1): 1}},
+		"long string": {Attributes: map[string]interface{}{"a": strings.Repeat("a", MaxAttributeValueLength+1)}},
+	}
+
+	for name, entry := range cases {
+		err := entry.Validate(nil)
+		assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput), name)
+	}
+
+	assert.Error(t, LogBatch{}.Validate())
+	assert.Error(t, LogBatch{Common: common, Logs: []LogEntry{{Attributes: tooMany}}}.Validate())
+	assert.Error(t, LogBatch{Common: &LogCommon{Attributes: map[string]interface{}{"": 1}}, Logs: []LogEntry{{}}}.Validate())
+	assert.NoError(t, LogBatch{Common: common, Logs: []LogEntry{{Message: "a"}}}.Validate())
+}
+
+func TestGroupLogEntries(t *testing.T) {
+	t.Parallel()
+
+	entries := []LogEntry{
+		{Message: "a", Attributes: map[string]interface{}{"service": "checkout", "host": "1"}},
+		{Message: "b", Attributes: map[string]interface{}{"service": "cart", "host": "1"}},
+		{Message: "c", Attributes: map[string]interface{}{"service": "checkout", "host": "2"}},
+		{Message: "d"},
+	}
+
+	batches := GroupLogEntries(entries, "service")
+
+	assert.Equal(t, []LogBatch{
+		{
+			Common: &LogCommon{Attributes: map[string]interface{}{"service": "checkout"}},
+			Logs: []LogEntry{
+				{Message: "a", Attributes: map[string]interface{}{"host": "1"}},
+				{Message: "c", Attributes: map[string]interface{}{"host": "2"}},
+			},
+		},
+		{
+			Common: &LogCommon{Attributes: map[string]interface{}{"service": "cart"}},
+			Logs:   []LogEntry{{Message: "b", Attributes: map[string]interface{}{"host": "1"}}},
+		},
+		{
+			Logs: []LogEntry{{Message: "d", Attributes: map[string]interface{}{}}},
+		},
+	}, batches)
+
+	// The entries passed in are left untouched.
+	assert.Equal(t, "checkout", entries[0].Attributes["service"])
+}
+
+func TestCreateLogBatches(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var payloads []interface{}
+	client := newTestBatchClient(t, decodePayloads(t, &mu, &payloads))
+
+	batches := GroupLogEntries([]LogEntry{
+		{Message: "a", Attributes: map[string]interface{}{"service": "checkout"}},
+		{Message: "b", Attributes: map[string]interface{}{"service": "cart"}},
+	}, "service")
+
+	require.NoError(t, client.CreateLogBatches(batches...))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, payloads, 1)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"common": map[string]interface{}{"attributes": map[string]interface{}{"service": "checkout"}},
+			"logs":   []interface{}{map[string]interface{}{"message": "a"}},
+		},
+		map[string]interface{}{
+			"common": map[string]interface{}{"attributes": map[string]interface{}{"service": "cart"}},
+			"logs":   []interface{}{map[string]interface{}{"message": "b"}},
+		},
+	}, payloads[0])
+
+	assert.True(t, errors.Is(client.CreateLogBatches(), nrErrors.ErrInvalidInput))
+	assert.True(t, errors.Is(client.CreateLogBatches(LogBatch{}), nrErrors.ErrInvalidInput))
+
+	oversized := LogBatch{Logs: []LogEntry{{Message: incompressible(t, MaxPayloadSize*3/2)}}}
+	assert.True(t, errors.Is(client.CreateLogBatches(oversized), nrErrors.ErrPayloadTooLarge))
+
+	invalid := LogEntry{Attributes: map[string]interface{}{"": 1}}
+	assert.True(t, errors.Is(client.CreateLogEntry(invalid), nrErrors.ErrInvalidInput))
+	assert.True(t, errors.Is(client.CreateLogEntry(&invalid), nrErrors.ErrInvalidInput))
+	assert.Len(t, payloads, 1)
+}
+
+// rawPayloads returns a handler recording the body of each payload sent to
+// the Log API, as it was sent.
+func rawPayloads(t *testing.T, mu *sync.Mutex, payloads *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+
+		data, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		*payloads = append(*payloads, string(data))
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func TestLogEntryPayloadWithoutCommon(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var payloads []string
+	client := newTestBatchClient(t, rawPayloads(t, &mu, &payloads))
+
+	entry := LogEntry{Message: "a", Level: "info", Attributes: map[string]interface{}{"host": "web-1"}}
+
+	require.NoError(t, client.CreateLogEntry(entry))
+	require.NoError(t, client.CreateLogEntry(&entry))
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueLogEntry(ctx, entry))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	require.NoError(t, client.Close(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// A LogEntry is always sent in a block of the detailed format, so its
+	// attributes aren't nested.  Other log entries are sent as they are.
+	block := `{"logs":[{"message":"a","attributes":{"host":"web-1","level":"info"}}]}`
+
+	assert.Equal(t, []string{
+		"[" + block + "]",
+		"[" + block + "]",
+		"[" + block + `,{"level":"info","message":"test"}]`,
+	}, payloads)
+}
+
+func TestCreateLogEntryRawPayload(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var payloads []string
+	client := newTestBatchClient(t, rawPayloads(t, &mu, &payloads))
+
+	payload := `[{"common":{"attributes":{"host":"web-1"}},"logs":[{"message":"a"}]}]`
+
+	// A payload that is already JSON is posted as it is.
+	require.NoError(t, client.CreateLogEntry([]byte(payload)))
+	require.NoError(t, client.CreateLogEntry(json.RawMessage(payload)))
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueLogEntry(ctx, []byte(`{"message":"b"}`)))
+	assert.True(t, errors.Is(client.EnqueueLogEntry(ctx, []byte(`{"message":`)), nrErrors.ErrInvalidInput))
+	require.NoError(t, client.Close(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{payload, payload, `[{"message":"b"}]`}, payloads)
+}
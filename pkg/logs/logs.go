@@ -1,12 +1,18 @@
 package logs
 
 import (
-	"errors"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"sync"
 	"time"
 
 	"github.com/newrelic/newrelic-client-go/internal/http"
 	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
 	"github.com/newrelic/newrelic-client-go/pkg/logging"
+	"github.com/newrelic/newrelic-client-go/pkg/spool"
 )
 
 // Logs is used to send log data to the New Relic Log API
@@ -17,6 +23,12 @@ const (
 	DefaultBatchTimeout = 60 * time.Second
 )
 
+// MaxPayloadSize is the maximum size in bytes of a payload accepted by the Log
+// API, once compressed.  Payloads are always sent gzipped, so it limits the
+// compressed size of a single log entry, and of the batches log entries are
+// sent in when queued in batch mode.
+const MaxPayloadSize = 1000000
+
 type Logs struct {
 	client http.Client
 	config config.Config
@@ -24,14 +36,35 @@ type Logs struct {
 
 	// For queue based log handling
 	accountID  int
-	logQueue   chan interface{}
+	logQueue   chan queuedLog
 	logTimer   *time.Timer
+	logSpool   *spool.Spool
 	flushQueue []chan bool
 
+	// For delivery of batches
+	sends *sync.WaitGroup
+	stats *batchStats
+
+	// For closing batch mode
+	batchMu     *sync.Mutex
+	cancelBatch context.CancelFunc
+	enqueues    *sync.WaitGroup
+	workers     *sync.WaitGroup
+	stopping    chan struct{}
+	draining    chan struct{}
+	stopped     chan struct{}
+
 	// These have defaults
 	batchWorkers int
 	batchSize    int
 	batchTimeout time.Duration
+
+	batchMaxPayloadSize int
+	batchSpoolConfig    *spool.Config
+	batchCommon         *LogCommon
+	batchCommonData     []byte
+	batchEmptySize      int
+	batchOverflowPolicy OverflowPolicy
 }
 
 // New is used to create a new Logs client instance.
@@ -49,21 +82,40 @@ func New(cfg config.Config) Logs {
 		client:       client,
 		config:       cfg,
 		logger:       cfg.GetLogger(),
+		batchMu:      &sync.Mutex{},
 		batchWorkers: DefaultBatchWorkers,
 		batchSize:    DefaultBatchSize,
 		batchTimeout: DefaultBatchTimeout,
+
+		batchMaxPayloadSize: MaxPayloadSize,
 	}
 
 	return pkg
 }
 
 // CreateLogEntry reports a log entry to New Relic.
-// It's up to the caller to send a valid Log API payload, no checking done here
+// It's up to the caller to send a valid Log API payload, no checking done here,
+// unless the log entry is a LogEntry or LogBatch, which are validated first.
+// A LogEntry is sent in a batch of its own, in the Log API's detailed format.
 func (l *Logs) CreateLogEntry(logEntry interface{}) error {
-	if logEntry == nil {
-		return errors.New("logs: CreateLogEntry: logEntry is nil, nothing to do")
+	switch entry := logEntry.(type) {
+	case LogEntry:
+		logEntry = LogBatch{Logs: []LogEntry{entry}}
+	case *LogEntry:
+		if entry != nil {
+			logEntry = LogBatch{Logs: []LogEntry{*entry}}
+		}
+	}
+
+	jsonData, err := marshalLogEntry(logEntry, nil)
+	if err != nil {
+		return err
 	}
-	_, err := l.client.Post(l.config.Region().LogsURL(), nil, logEntry, nil)
+	if size := payloadSize(jsonData, MaxPayloadSize); size > MaxPayloadSize {
+		return errors.NewPayloadTooLarge(size, MaxPayloadSize)
+	}
+
+	_, err = l.client.Post(l.config.Region().LogsURL(), nil, jsonData, nil)
 
 	// If no error is returned then the call succeeded
 	if err != nil {
@@ -72,3 +124,154 @@ func (l *Logs) CreateLogEntry(logEntry interface{}) error {
 
 	return nil
 }
+
+// CreateLogBatches reports batches of log entries to New Relic in a single
+// payload, each with its own common block.  Batches are validated against the
+// Log API's limits before they are sent.
+func (l *Logs) CreateLogBatches(batches ...LogBatch) error {
+	if len(batches) == 0 {
+		return errors.NewInvalidInput("logs: CreateLogBatches: no batches, nothing to do")
+	}
+
+	blocks := make([]logBatchJSON, len(batches))
+	for i, batch := range batches {
+		if err := batch.Validate(); err != nil {
+			return err
+		}
+
+		blocks[i] = logBatchJSON{Common: batch.Common, Logs: batch.Logs}
+	}
+
+	jsonData, err := json.Marshal(blocks)
+	if err != nil {
+		return errors.NewInvalidInputf("logs: error marshaling log batches: %s", err.Error()).WithCause(err)
+	}
+	if size := payloadSize(jsonData, MaxPayloadSize); size > MaxPayloadSize {
+		return errors.NewPayloadTooLarge(size, MaxPayloadSize)
+	}
+
+	_, err = l.client.Post(l.config.Region().LogsURL(), nil, jsonData, nil)
+
+	return err
+}
+
+// marshalLogEntry converts the log entry into a JSON []byte, validating a
+// LogEntry or LogBatch first.  A LogEntry counts the attributes of the common
+// block it is sent with, which may be nil.  A []byte or json.RawMessage is
+// already JSON, and is returned unchanged.
+func marshalLogEntry(logEntry interface{}, common *LogCommon) ([]byte, error) {
+	if logEntry == nil {
+		return nil, errors.NewInvalidInput("logs: logEntry is nil, nothing to do")
+	}
+
+	var err error
+
+	switch entry := logEntry.(type) {
+	case []byte:
+		return entry, nil
+	case json.RawMessage:
+		return entry, nil
+	case LogEntry:
+		err = entry.Validate(common)
+	case *LogEntry:
+		if entry == nil {
+			return nil, errors.NewInvalidInput("logs: logEntry is nil, nothing to do")
+		}
+		err = entry.Validate(common)
+	case LogBatch:
+		err = entry.Validate()
+	case *LogBatch:
+		if entry == nil {
+			return nil, errors.NewInvalidInput("logs: logEntry is nil, nothing to do")
+		}
+		err = entry.Validate()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(logEntry)
+	if err != nil {
+		return nil, errors.NewInvalidInputf("logs: error marshaling log entry: %s", err.Error()).WithCause(err)
+	}
+
+	return jsonData, nil
+}
+
+// payloadSize returns the size of a payload to check against the limit, which
+// applies to the payload once gzipped.  A payload within the limit before
+// compression is within it after, so only larger payloads are compressed to
+// measure them.
+func payloadSize(data []byte, limit int) int {
+	if len(data) <= limit {
+		return len(data)
+	}
+
+	var compressed countingWriter
+
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write(data)
+	_ = gz.Close()
+
+	return compressed.n
+}
+
+// countingWriter discards the data written to it, counting its size.
+type countingWriter struct {
+	n int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+// isLogEntry reports whether the log entry is a LogEntry, which is marshaled
+// in the Log API's detailed format.
+func isLogEntry(logEntry interface{}) bool {
+	switch entry := logEntry.(type) {
+	case LogEntry:
+		return true
+	case *LogEntry:
+		return entry != nil
+	default:
+		return false
+	}
+}
+
+// logEntryBlock wraps a LogEntry, already marshaled to JSON, in a block of the
+// Log API's detailed format.  Sent on its own, the Log API would take it for
+// the simple format, and store its attributes nested under "attributes".
+func logEntryBlock(data []byte) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"logs":[`)
+	buf.Write(data)
+	buf.WriteString(`]}`)
+
+	return buf.Bytes()
+}
+
+// buildPayload joins log entries, already marshaled to JSON, into a payload,
+// in a block with the common block when there is one.  Without one, LogEntry
+// values are queued already wrapped in a block by logEntryBlock.
+func buildPayload(common []byte, logs [][]byte) []byte {
+	var buf bytes.Buffer
+
+	if common == nil {
+		buf.WriteString("[")
+		buf.Write(bytes.Join(logs, []byte(",")))
+		buf.WriteString("]")
+
+		return buf.Bytes()
+	}
+
+	buf.WriteString(`[{"common":`)
+	buf.Write(common)
+	buf.WriteString(`,"logs":[`)
+	buf.Write(bytes.Join(logs, []byte(",")))
+	buf.WriteString(`]}]`)
+
+	return buf.Bytes()
+}
@@ -1,15 +1,33 @@
 package logs
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	nrhttp "github.com/newrelic/newrelic-client-go/internal/http"
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/spool"
 )
 
 // BatchMode enables the Logs client to accept, queue, and post
-// Logs on behalf of the consuming application
+// Logs on behalf of the consuming application.  Batch mode runs until
+// ctx is done, discarding any logs still queued, or until Close is
+// called, which sends them first.  Once closed, batch mode can be
+// enabled again.
+//
+// When spooling with BatchConfigSpool, the logs left in the spool are
+// queued again first, and logs in batches that failed to send are queued
+// again while batch mode runs.
 func (e *Logs) BatchMode(ctx context.Context, accountID int, opts ...BatchConfigOption) (err error) {
+	e.batchMu.Lock()
+	defer e.batchMu.Unlock()
+
 	if e.logQueue != nil {
 		return errors.New("the Logs client is already in batch mode")
 	}
@@ -23,16 +41,43 @@ func (e *Logs) BatchMode(ctx context.Context, accountID int, opts ...BatchConfig
 		}
 	}
 
+	e.batchCommonData = nil
+	if e.batchCommon != nil {
+		if e.batchCommonData, err = json.Marshal(e.batchCommon); err != nil {
+			return fmt.Errorf("logs: invalid common block specified: %w", err)
+		}
+	}
+	e.batchEmptySize = len(buildPayload(e.batchCommonData, nil))
+
+	if e.batchSpoolConfig != nil {
+		if e.logSpool, err = spool.Open(*e.batchSpoolConfig); err != nil {
+			return err
+		}
+	}
+
+	// Batch mode stops when ctx is done, or Close gives up waiting for it.
+	ctx, e.cancelBatch = context.WithCancel(ctx)
+
 	e.accountID = accountID
-	e.logQueue = make(chan interface{}, e.batchSize)
+	e.logQueue = make(chan queuedLog, e.batchSize)
 	e.flushQueue = make([]chan bool, e.batchWorkers)
 	e.logTimer = time.NewTimer(e.batchTimeout)
+	e.sends = &sync.WaitGroup{}
+	e.stats = &batchStats{}
+	e.enqueues = &sync.WaitGroup{}
+	e.workers = &sync.WaitGroup{}
+	e.stopping = make(chan struct{})
+	e.draining = make(chan struct{})
+	e.stopped = make(chan struct{})
 
 	// Handle timer based flushing
+	e.workers.Add(1)
 	go func() {
+		defer e.workers.Done()
+
 		err := e.watchdog(ctx)
 		if err != nil {
-			e.logger.Error(fmt.Sprintf("watchdog returned error: %v", err))
+			e.logger.Error("watchdog returned error", "error", err)
 		}
 	}()
 
@@ -40,18 +85,160 @@ func (e *Logs) BatchMode(ctx context.Context, accountID int, opts ...BatchConfig
 	for x := range e.flushQueue {
 		e.flushQueue[x] = make(chan bool, 1)
 
+		e.workers.Add(1)
 		go func(id int) {
+			defer e.workers.Done()
+
 			e.logger.Trace("inside anonymous function")
 			err := e.batchWorker(ctx, id)
 			if err != nil {
-				e.logger.Error(fmt.Sprintf("batch worker returned error: %v", err))
+				e.logger.Error("batch worker returned error", "error", err)
 			}
 		}(x)
 	}
 
+	// Queue the logs left in the spool, as if they were being queued now,
+	// and the logs released by failed batches as they fail.
+	if e.logSpool != nil {
+		e.enqueues.Add(1)
+		go func() {
+			defer e.enqueues.Done()
+
+			e.replaySpool(ctx)
+		}()
+	}
+
 	return nil
 }
 
+// BatchStats are delivery statistics for the logs queued in batch mode.
+type BatchStats struct {
+	// Queued is the number of log entries waiting to be sent, including
+	// entries in batches being sent.
+	Queued int64
+
+	// QueueDepth is the number of log entries in the queue, waiting to be
+	// added to a batch.
+	QueueDepth int64
+
+	// Sent is the number of log entries sent to New Relic.
+	Sent int64
+
+	// Failed is the number of log entries in batches that could not be sent.
+	Failed int64
+
+	// Spooled is the number of log entries in batches that could not be sent,
+	// which were released to the spool to be sent again, rather than failing.
+	Spooled int64
+
+	// Dropped is the number of log entries discarded without being sent, such
+	// as entries discarded by the overflow policy, or still queued when batch
+	// mode's context is done.
+	Dropped int64
+}
+
+type batchStats struct {
+	queued  int64
+	sent    int64
+	failed  int64
+	spooled int64
+	dropped int64
+}
+
+// BatchStats returns the delivery statistics for the logs queued in batch mode.
+func (e *Logs) BatchStats() BatchStats {
+	e.batchMu.Lock()
+	stats, queue := e.stats, e.logQueue
+	e.batchMu.Unlock()
+
+	if stats == nil {
+		return BatchStats{}
+	}
+
+	return BatchStats{
+		Queued:     atomic.LoadInt64(&stats.queued),
+		QueueDepth: int64(len(queue)),
+		Sent:       atomic.LoadInt64(&stats.sent),
+		Failed:     atomic.LoadInt64(&stats.failed),
+		Spooled:    atomic.LoadInt64(&stats.spooled),
+		Dropped:    atomic.LoadInt64(&stats.dropped),
+	}
+}
+
+// Close stops batch mode once every queued log entry has been sent.  Logs can
+// no longer be queued once Close is called, the logs already queued are sent,
+// and Close waits for every batch to be sent, or for ctx to be done.  If ctx is
+// done first, the batches being sent are cancelled and the logs left are
+// discarded.  An error is returned if any logs could not be sent, not counting
+// logs released to the spool, which are sent once batch mode is enabled again.
+// Batch mode can be enabled again once closed.
+func (e *Logs) Close(ctx context.Context) error {
+	e.batchMu.Lock()
+
+	if e.logQueue == nil {
+		e.batchMu.Unlock()
+		return errors.New("queueing not enabled for this client")
+	}
+
+	stats, stopped, cancelBatch := e.stats, e.stopped, e.cancelBatch
+
+	select {
+	case <-e.stopping:
+	default:
+		e.logger.Debug("closing batch mode")
+		close(e.stopping)
+		go e.shutdown()
+	}
+
+	e.batchMu.Unlock()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		cancelBatch()
+		return ctx.Err()
+	}
+
+	failed := atomic.LoadInt64(&stats.failed)
+	dropped := atomic.LoadInt64(&stats.dropped)
+
+	if failed > 0 || dropped > 0 {
+		return fmt.Errorf("logs: %d log entries could not be sent and %d were dropped", failed, dropped)
+	}
+
+	return nil
+}
+
+// shutdown waits for logs being queued, has the workers send the logs left in
+// the queue, waits for every batch to be sent, then leaves batch mode.
+func (e *Logs) shutdown() {
+	e.enqueues.Wait()
+	close(e.draining)
+
+	e.workers.Wait()
+	e.sends.Wait()
+
+	// Workers stopped by their context being done may have left logs.
+	e.dropLogs(e.drainQueue())
+	e.logTimer.Stop()
+
+	if e.logSpool != nil {
+		if err := e.logSpool.Close(); err != nil {
+			e.logger.Error("failed to close log spool", "error", err)
+		}
+	}
+
+	e.batchMu.Lock()
+	defer e.batchMu.Unlock()
+
+	e.cancelBatch()
+
+	e.logQueue = nil
+	e.logSpool = nil
+	e.flushQueue = nil
+	close(e.stopped)
+}
+
 type BatchConfigOption func(*Logs) error
 
 // BatchConfigWorkers sets how many background workers will process
@@ -82,6 +269,42 @@ func BatchConfigQueueSize(size int) BatchConfigOption {
 	}
 }
 
+// BatchConfigMaxPayloadSize is the maximum size in bytes of the payload a
+// batch of logs is sent in, once compressed.  If adding a log entry to a batch
+// could take it over this size, the batch is sent first.  The compressed size
+// of a batch is estimated as it is built, erring on the large side, so batches
+// may be sent a little below it.  Log entries too large to be sent on their own
+// are rejected when queued.  It can't exceed MaxPayloadSize, which is the
+// default.
+func BatchConfigMaxPayloadSize(size int) BatchConfigOption {
+	return func(e *Logs) error {
+		if size <= emptyPayloadSize || size > MaxPayloadSize {
+			return errors.New("logs: invalid max payload size specified")
+		}
+
+		e.batchMaxPayloadSize = size
+		return nil
+	}
+}
+
+// BatchConfigSpool writes queued logs to a disk-backed spool, so they survive
+// restarts and outages.  Logs are removed from the spool once they are sent,
+// or rejected by New Relic.  Logs in batches that could not be sent are queued
+// again after the spool's replay interval, which backs off while they keep
+// failing.  Logs left in the spool, such as those dropped when batch mode's
+// context was done, are queued again when batch mode is next enabled with the
+// spool.  Logs may be sent more than once as a result.
+func BatchConfigSpool(cfg spool.Config) BatchConfigOption {
+	return func(e *Logs) error {
+		if cfg.Dir == "" {
+			return errors.New("logs: invalid spool directory specified")
+		}
+
+		e.batchSpoolConfig = &cfg
+		return nil
+	}
+}
+
 // BatchConfigTimeout is the maximum amount of time to queue logs
 // before sending to New Relic.  If this is reached before the Size
 // limit, the queue is flushed.
@@ -96,35 +319,297 @@ func BatchConfigTimeout(seconds int) BatchConfigOption {
 	}
 }
 
+// OverflowPolicy is what EnqueueLogEntry does when the queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the queue, or for the context passed to
+	// EnqueueLogEntry to be done.  It is the default.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the log entry being queued.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest log entries in the queue to make
+	// room for the log entry being queued.
+	OverflowDropOldest
+
+	// OverflowFail returns ErrQueueFull without queueing the log entry.
+	OverflowFail
+)
+
+// ErrQueueFull is returned by EnqueueLogEntry when the queue is full and the
+// overflow policy is OverflowFail.
+var ErrQueueFull = errors.New("logs: queue is full")
+
+// BatchConfigOverflowPolicy sets what EnqueueLogEntry does when the queue is
+// full.  Every policy but OverflowBlock returns without waiting, and log
+// entries discarded are counted as dropped.
+func BatchConfigOverflowPolicy(policy OverflowPolicy) BatchConfigOption {
+	return func(e *Logs) error {
+		if policy < OverflowBlock || policy > OverflowFail {
+			return errors.New("logs: invalid overflow policy specified")
+		}
+
+		e.batchOverflowPolicy = policy
+		return nil
+	}
+}
+
+// BatchConfigCommonAttributes sets the attributes of the common block logs
+// queued in batch mode are sent with, which apply to every log entry.  A
+// LogEntry queued is validated counting these attributes.
+func BatchConfigCommonAttributes(attributes map[string]interface{}) BatchConfigOption {
+	return func(e *Logs) error {
+		if err := validateAttributes(attributes); err != nil {
+			return err
+		}
+		if len(attributes) > MaxAttributes {
+			return errors.New("logs: invalid common attributes specified")
+		}
+
+		e.batchCommon = &LogCommon{Attributes: attributes}
+		return nil
+	}
+}
+
 // EnqueueLogEntry handles the queueing. Only works in batch mode. If you wish to be able to avoid blocking
 // forever until the log can be queued, provide a ctx with a deadline or timeout as this function will
-// bail when ctx.Done() is closed and return and error.
+// bail when ctx.Done() is closed and return and error.  What happens when the queue is full is set by
+// BatchConfigOverflowPolicy.
 func (e *Logs) EnqueueLogEntry(ctx context.Context, msg interface{}) (err error) {
-	if e.logQueue == nil {
-		return errors.New("queueing not enabled for this client")
+	switch msg.(type) {
+	case LogBatch, *LogBatch:
+		return nrErrors.NewInvalidInput("logs: log batches can't be queued, use CreateLogBatches")
+	}
+
+	// The log entry is marshaled outside the lock, using the settings of the
+	// batch mode it is queued in, so calls from many goroutines don't wait on
+	// each other.  Should batch mode be restarted meanwhile, it's marshaled
+	// again with the new settings.
+	var jsonData []byte
+	var queue chan queuedLog
+
+	for {
+		e.batchMu.Lock()
+		queue = e.logQueue
+		common, emptySize, maxSize := e.batchCommon, e.batchEmptySize, e.batchMaxPayloadSize
+		e.batchMu.Unlock()
+
+		if queue == nil {
+			return errors.New("queueing not enabled for this client")
+		}
+
+		if jsonData, err = marshalQueuedLog(msg, common, emptySize, maxSize); err != nil {
+			return err
+		}
+
+		e.batchMu.Lock()
+		if e.logQueue == queue {
+			break
+		}
+		e.batchMu.Unlock()
+	}
+
+	select {
+	case <-e.stopping:
+		e.batchMu.Unlock()
+		return errors.New("logs: batch mode is closing")
+	default:
+	}
+
+	stopping, stats, logSpool, policy := e.stopping, e.stats, e.logSpool, e.batchOverflowPolicy
+	e.enqueues.Add(1)
+	defer e.enqueues.Done()
+
+	e.batchMu.Unlock()
+
+	item := queuedLog{data: jsonData}
+	if logSpool != nil {
+		if item.record, err = logSpool.Append(item.data); err != nil {
+			return err
+		}
 	}
 
+	atomic.AddInt64(&stats.queued, 1)
+
 	select {
-	case e.logQueue <- msg:
+	case queue <- item:
 		e.logger.Trace("EnqueueLogEntry: log entry queued ")
 		return nil
-	case <-ctx.Done():
-		e.logger.Trace("EnqueueLogEntry: exiting per context Done")
-		return ctx.Err()
+	default:
+	}
+
+	switch policy {
+	case OverflowDropNewest:
+		e.dropOverflow(item)
+		return nil
+	case OverflowDropOldest:
+		e.displaceOldest(queue, item)
+		return nil
+	case OverflowFail:
+		err = ErrQueueFull
+	default:
+		select {
+		case queue <- item:
+			e.logger.Trace("EnqueueLogEntry: log entry queued ")
+			return nil
+		case <-stopping:
+			err = errors.New("logs: batch mode is closing")
+		case <-ctx.Done():
+			e.logger.Trace("EnqueueLogEntry: exiting per context Done")
+			err = ctx.Err()
+		}
+	}
+
+	atomic.AddInt64(&stats.queued, -1)
+
+	// The caller is told the log entry wasn't queued, so it isn't replayed.
+	if logSpool != nil {
+		logSpool.Ack(item.record)
 	}
+
+	return err
+}
+
+// marshalQueuedLog marshals a log entry to be queued in batch mode with the
+// given common block, checking that it fits in a batch on its own.
+func marshalQueuedLog(msg interface{}, common *LogCommon, emptySize int, maxSize int) ([]byte, error) {
+	jsonData, err := marshalLogEntry(msg, common)
+	if err != nil {
+		return nil, err
+	}
+
+	// A payload passed as JSON is sent within a batch, so it must be valid.
+	if !json.Valid(jsonData) {
+		return nil, nrErrors.NewInvalidInput("logs: log entry is not valid JSON")
+	}
+
+	// Without a common block, a LogEntry is sent in a block of its own.
+	if common == nil && isLogEntry(msg) {
+		jsonData = logEntryBlock(jsonData)
+	}
+
+	// A log entry that can't fit in a batch on its own can never be sent.
+	if size := emptySize + payloadSize(jsonData, maxSize-emptySize); size > maxSize {
+		return nil, nrErrors.NewPayloadTooLarge(size, maxSize)
+	}
+
+	return jsonData, nil
+}
+
+// displaceOldest queues a log entry in a full queue, discarding the oldest
+// entries in the queue to make room for it.
+func (e *Logs) displaceOldest(queue chan queuedLog, item queuedLog) {
+	for {
+		select {
+		case queue <- item:
+			return
+		default:
+		}
+
+		select {
+		case oldest := <-queue:
+			e.dropOverflow(oldest)
+		default:
+		}
+	}
+}
+
+// dropOverflow accounts for a log entry discarded because the queue is full,
+// removing it from the spool.
+func (e *Logs) dropOverflow(item queuedLog) {
+	if e.logSpool != nil {
+		e.logSpool.Ack(item.record)
+	}
+
+	e.dropLogs(1)
+}
+
+// replaySpool queues the logs left in the spool, then keeps queueing the logs
+// released by batches that failed to send, waiting the spool's replay
+// interval between replays.  The interval doubles, up to the maximum, while
+// each replay finds logs to queue, and is reset once one doesn't.  It runs
+// until batch mode is closing or ctx is done, which Close waits for.  Logs not
+// queued are left in the spool.
+func (e *Logs) replaySpool(ctx context.Context) {
+	cfg := e.logSpool.Config()
+	wait := cfg.ReplayInterval
+
+	// The logs left in the spool are queued before Close sends the queue.
+	e.replaySpooled(ctx, nil)
+
+	for {
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-e.stopping:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		if e.replaySpooled(ctx, e.stopping) == 0 {
+			wait = cfg.ReplayInterval
+		} else if wait = 2 * wait; wait > cfg.MaxReplayInterval {
+			wait = cfg.MaxReplayInterval
+		}
+	}
+}
+
+// replaySpooled queues the logs the spool replays, returning how many were
+// queued, until stopping is closed or ctx is done.
+func (e *Logs) replaySpooled(ctx context.Context, stopping <-chan struct{}) int {
+	replayed := 0
+
+	err := e.logSpool.Replay(func(data []byte, record spool.Record) bool {
+		atomic.AddInt64(&e.stats.queued, 1)
+
+		select {
+		case e.logQueue <- queuedLog{data: data, record: record}:
+			replayed++
+			return true
+		case <-stopping:
+		case <-ctx.Done():
+		}
+
+		atomic.AddInt64(&e.stats.queued, -1)
+		return false
+	})
+	if err != nil {
+		e.logger.Error("failed to replay spooled logs", "error", err)
+	}
+
+	if replayed > 0 {
+		e.logger.Debug("replayed spooled logs", "count", replayed)
+	}
+
+	return replayed
 }
 
 // Flush gives the user a way to manually flush the queue in the foreground.
 // This is also used by watchdog when the timer expires.
 func (e *Logs) Flush() error {
-	if e.flushQueue == nil {
+	e.batchMu.Lock()
+	flushQueue := e.flushQueue
+	e.batchMu.Unlock()
+
+	if flushQueue == nil {
 		return errors.New("queueing not enabled for this client")
 	}
 
 	e.logger.Debug("flushing queues")
-	for x := range e.flushQueue {
-		e.logger.Trace(fmt.Sprintf("flushing logs queue: %d", x))
-		e.flushQueue[x] <- true
+	for x := range flushQueue {
+		e.logger.Trace("flushing logs queue", "id", x)
+
+		// A worker with a flush pending doesn't need another.
+		select {
+		case flushQueue[x] <- true:
+		default:
+		}
 	}
 
 	return nil
@@ -142,30 +627,198 @@ func (e *Logs) batchWorker(ctx context.Context, id int) (err error) {
 		return errors.New("batchWorker: invalid worker id specified")
 	}
 
-	logBuf := make([]interface{}, e.batchSize)
-	count := 0
+	batch := &logBatch{
+		logs:    make([][]byte, e.batchSize),
+		records: make([]spool.Record, e.batchSize),
+		size:    e.batchEmptySize,
+	}
+	batch.compressed.reset()
 
 	for {
 		select {
 		case item := <-e.logQueue:
-			logBuf[count] = item
-			count++
-			if count >= e.batchSize {
-				e.grabAndConsumeLogs(count, logBuf)
-				count = 0
-			}
+			e.batchLog(ctx, batch, item)
 		case <-e.flushQueue[id]:
-			if count > 0 {
-				e.grabAndConsumeLogs(count, logBuf)
-				count = 0
+			if batch.count > 0 {
+				e.sendBatch(ctx, batch)
+			}
+		case <-e.draining:
+			// Logs queued when ctx is done are discarded, even when closing.
+			if err := ctx.Err(); err != nil {
+				e.dropLogs(batch.count + e.drainQueue())
+				return err
 			}
+
+			e.logger.Trace("batchWorker draining queue", "id", id)
+			e.drainWorker(ctx, batch)
+			return nil
 		case <-ctx.Done():
-			e.logger.Trace("batchWorker[", id, "]: exiting per context Done")
+			e.logger.Trace("batchWorker exiting per context Done", "id", id)
+			e.dropLogs(batch.count + e.drainQueue())
 			return ctx.Err()
 		}
 	}
 }
 
+// emptyPayloadSize is the size of the JSON array a batch is sent in, without a
+// common block.
+const emptyPayloadSize = len("[]")
+
+// queuedLog is a log entry in the queue, marshaled to JSON, along with its
+// record in the spool when spooling.
+type queuedLog struct {
+	data   []byte
+	record spool.Record
+}
+
+// logBatch is the batch of log entries a worker is building, along with the
+// size of the payload it is sent in, before and after compression.
+type logBatch struct {
+	logs       [][]byte
+	records    []spool.Record
+	count      int
+	size       int
+	compressed payloadEstimate
+}
+
+// payloadSize returns the size of the batch's payload to check against the
+// maximum payload size, with extra bytes added to it.  The limit applies to the
+// payload once compressed, but a payload within it before compression is
+// within it after.
+func (b *logBatch) payloadSize(emptySize int, extra int) int {
+	compressed := emptySize + b.compressed.size() + extra
+	if b.size+extra < compressed {
+		return b.size + extra
+	}
+
+	return compressed
+}
+
+// batchLog adds a log entry to a worker's batch.  The batch is sent first if
+// the entry could take its payload over the maximum size, and is sent once it
+// holds the maximum number of entries.
+func (e *Logs) batchLog(ctx context.Context, batch *logBatch, item queuedLog) {
+	if batch.count > 0 && batch.payloadSize(e.batchEmptySize, len(separator)+len(item.data)) > e.batchMaxPayloadSize {
+		e.sendBatch(ctx, batch)
+	}
+
+	if batch.count > 0 {
+		batch.size += len(separator)
+		batch.compressed.add(separator)
+	}
+
+	batch.logs[batch.count] = item.data
+	batch.records[batch.count] = item.record
+	batch.count++
+	batch.size += len(item.data)
+	batch.compressed.add(item.data)
+
+	if batch.count >= e.batchSize {
+		e.sendBatch(ctx, batch)
+	}
+}
+
+// sendBatch sends the log entries in a worker's batch, leaving it empty.
+func (e *Logs) sendBatch(ctx context.Context, batch *logBatch) {
+	e.grabAndConsumeLogs(ctx, batch.count, batch.logs, batch.records)
+	batch.count = 0
+	batch.size = e.batchEmptySize
+	batch.compressed.reset()
+}
+
+// separator separates the log entries in a payload.
+var separator = []byte(",")
+
+// payloadEstimateFlushSize is how many bytes of log entries are added to a
+// payloadEstimate between flushes of its compressor.
+const payloadEstimateFlushSize = 32 * 1024
+
+// payloadEstimate estimates the gzipped size of the log entries in a batch by
+// compressing them as they are added, without keeping the compressed data.
+// The compressor is flushed every payloadEstimateFlushSize bytes, and entries
+// added since the last flush are counted uncompressed, so the estimate doesn't
+// fall short of the size the entries compress to when the batch is sent.
+type payloadEstimate struct {
+	gz         *gzip.Writer
+	compressed countingWriter
+	pending    int
+}
+
+// add compresses data into the estimate.
+func (p *payloadEstimate) add(data []byte) {
+	_, _ = p.gz.Write(data)
+	p.pending += len(data)
+
+	if p.pending >= payloadEstimateFlushSize {
+		_ = p.gz.Flush()
+		p.pending = 0
+	}
+}
+
+// size returns the estimated compressed size, allowing for gzip's header and
+// trailer.
+func (p *payloadEstimate) size() int {
+	return gzipOverhead + p.compressed.n + p.pending
+}
+
+// reset empties the estimate.
+func (p *payloadEstimate) reset() {
+	p.compressed.n = 0
+	p.pending = 0
+
+	if p.gz == nil {
+		p.gz = gzip.NewWriter(&p.compressed)
+		return
+	}
+
+	p.gz.Reset(&p.compressed)
+}
+
+// gzipOverhead is the size of the header and trailer gzip adds to data.
+const gzipOverhead = 18
+
+// drainWorker sends the logs a worker has read along with the logs left in
+// the queue, once batch mode is closing and no more logs can be queued.
+func (e *Logs) drainWorker(ctx context.Context, batch *logBatch) {
+	for {
+		select {
+		case item := <-e.logQueue:
+			e.batchLog(ctx, batch, item)
+		default:
+			if batch.count > 0 {
+				e.sendBatch(ctx, batch)
+			}
+
+			return
+		}
+	}
+}
+
+// drainQueue discards the logs left in the queue, returning how many there were.
+func (e *Logs) drainQueue() int {
+	count := 0
+
+	for {
+		select {
+		case <-e.logQueue:
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+// dropLogs accounts for queued logs discarded without being sent.
+func (e *Logs) dropLogs(count int) {
+	if count == 0 {
+		return
+	}
+
+	atomic.AddInt64(&e.stats.queued, -int64(count))
+	atomic.AddInt64(&e.stats.dropped, int64(count))
+	e.logger.Warn("dropped queued logs", "count", count)
+}
+
 //
 // watchdog has a Timer that will send the results once the
 // it has expired.
@@ -184,6 +837,9 @@ func (e *Logs) watchdog(ctx context.Context) (err error) {
 				return
 			}
 			e.logTimer.Reset(e.batchTimeout)
+		case <-e.draining:
+			e.logger.Trace("watchdog exiting: batch mode closing")
+			return nil
 		case <-ctx.Done():
 			e.logger.Trace("watchdog exiting: context finished")
 			return ctx.Err()
@@ -192,25 +848,56 @@ func (e *Logs) watchdog(ctx context.Context) (err error) {
 }
 
 // grabAndConsumeLogs makes a copy of the log handles,
-// and asynchronously writes those logs in its own goroutine.
-func (e *Logs) grabAndConsumeLogs(count int, logBuf []interface{}) {
+// and asynchronously writes those logs in its own goroutine, until ctx is done.
+func (e *Logs) grabAndConsumeLogs(ctx context.Context, count int, logBuf [][]byte, recordBuf []spool.Record) {
 	e.logger.Trace("grabAndConsumeLogs")
-	saved := make([]interface{}, count)
+	saved := make([][]byte, count)
+	records := make([]spool.Record, count)
 	for i := 0; i < count; i++ {
 		saved[i] = logBuf[i]
+		records[i] = recordBuf[i]
 		logBuf[i] = nil
 	}
 
-	go func(count int, saved []interface{}) {
-		if sendErr := e.sendLogs(saved[0:count]); sendErr != nil {
-			e.logger.Error("failed to send logs")
+	e.sends.Add(1)
+
+	go func(count int, saved [][]byte, records []spool.Record) {
+		defer e.sends.Done()
+
+		sendErr := e.sendLogs(ctx, saved[0:count])
+
+		atomic.AddInt64(&e.stats.queued, -int64(count))
+
+		// Logs that were sent, or can never be sent, are removed from the
+		// spool, and the others are released to be replayed, and counted as
+		// spooled rather than failed.
+		if e.logSpool != nil && sendErr != nil && nrhttp.RetryableBatchError(sendErr) {
+			e.logSpool.Release(records...)
+			atomic.AddInt64(&e.stats.spooled, int64(count))
+			e.logger.Warn("failed to send logs, released them to the spool", "error", sendErr, "count", count)
+
+			return
+		}
+
+		if e.logSpool != nil {
+			e.logSpool.Ack(records...)
 		}
-	}(count, saved)
+
+		if sendErr != nil {
+			atomic.AddInt64(&e.stats.failed, int64(count))
+			e.logger.Error("failed to send logs", "error", sendErr, "count", count)
+			return
+		}
+
+		atomic.AddInt64(&e.stats.sent, int64(count))
+	}(count, saved, records)
 }
 
-func (e *Logs) sendLogs(logs []interface{}) error {
-	e.logger.Trace(fmt.Sprintf("sendLogs: entry count: %d", len(logs)))
-	_, err := e.client.Post(e.config.Region().LogsURL(), nil, logs, nil)
+func (e *Logs) sendLogs(ctx context.Context, logs [][]byte) error {
+	e.logger.Trace("sendLogs", "count", len(logs))
+
+	// The log entries are already marshaled to JSON, so join them into a payload.
+	_, err := e.client.PostWithContext(ctx, e.config.Region().LogsURL(), nil, buildPayload(e.batchCommonData, logs), nil)
 
 	if err != nil {
 		return err
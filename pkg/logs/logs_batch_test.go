@@ -0,0 +1,625 @@
+//go:build unit
+// +build unit
+
+package logs
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/spool"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+var testLogEntry = map[string]interface{}{"message": "test", "level": "info"}
+
+func newTestBatchClient(t *testing.T, handler http.HandlerFunc) Logs {
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	cfg := mock.NewTestConfig(t, ts)
+
+	retryMax := 0
+	cfg.RetryMax = &retryMax
+
+	return New(cfg)
+}
+
+// countLogs returns the number of log entries in a request to the Log API.
+func countLogs(t *testing.T, r *http.Request) int {
+	body := io.Reader(r.Body)
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+
+		body = gz
+	}
+
+	logs := []map[string]interface{}{}
+	assert.NoError(t, json.NewDecoder(body).Decode(&logs))
+
+	return len(logs)
+}
+
+func TestBatchClose(t *testing.T) {
+	t.Parallel()
+
+	var received int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, int32(countLogs(t, r)))
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigWorkers(2), BatchConfigQueueSize(2)))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	}
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&received))
+
+	assert.Error(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	assert.Error(t, client.Flush())
+	assert.Error(t, client.Close(ctx))
+
+	// Batch mode can be enabled again once closed.
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	require.NoError(t, client.Close(ctx))
+
+	assert.Equal(t, int32(6), atomic.LoadInt32(&received))
+}
+
+func TestBatchCloseCancelsSends(t *testing.T) {
+	t.Parallel()
+
+	canceled := make(chan struct{})
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// The request is only cancelled once its body is read.
+		_, _ = ioutil.ReadAll(r.Body)
+
+		<-r.Context().Done()
+		close(canceled)
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigQueueSize(1)))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	// Close waits for the batch being sent, cancelling it once ctx is done.
+	assert.Equal(t, context.DeadlineExceeded, client.Close(timeout))
+	assert.Error(t, client.EnqueueLogEntry(ctx, testLogEntry))
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch being sent wasn't cancelled")
+	}
+
+	require.Error(t, client.Close(ctx))
+	assert.Equal(t, int64(1), client.BatchStats().Failed)
+}
+
+func TestBatchModeContextCancelsSends(t *testing.T) {
+	t.Parallel()
+
+	sending := make(chan struct{})
+	canceled := make(chan struct{})
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		close(sending)
+
+		<-r.Context().Done()
+		close(canceled)
+	})
+
+	ctx, cancelBatch := context.WithCancel(context.Background())
+
+	// A batch of one log entry is sent as soon as it is queued.
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigQueueSize(1)))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+
+	<-sending
+	cancelBatch()
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch being sent wasn't cancelled")
+	}
+
+	require.Error(t, client.Close(context.Background()))
+}
+
+func TestBatchCloseReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+
+	err := client.Close(ctx)
+	require.Error(t, err)
+	assert.Equal(t, "logs: 1 log entries could not be sent and 0 were dropped", err.Error())
+}
+
+func TestBatchDroppedOnCancel(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+
+	cancel()
+
+	err := client.Close(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "logs: 0 log entries could not be sent and 2 were dropped", err.Error())
+	assert.Zero(t, atomic.LoadInt32(&requests))
+}
+
+func TestBatchSplitByPayloadSize(t *testing.T) {
+	t.Parallel()
+
+	var requests, received int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		atomic.AddInt32(&received, int32(countLogs(t, r)))
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	entry, err := json.Marshal(testLogEntry)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Exactly two log entries fit in a payload: [entry,entry]
+	require.NoError(t, client.BatchMode(ctx, 1,
+		BatchConfigMaxPayloadSize(len("[]")+2*len(entry)+len(",")),
+	))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	}
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&received))
+}
+
+func TestBatchRejectsOversizedLogEntries(t *testing.T) {
+	t.Parallel()
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	entry := `{"message":"test"}`
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1,
+		BatchConfigMaxPayloadSize(len("[]")+len(entry)),
+	))
+
+	// A log entry that only just fits in a payload on its own is queued.
+	require.NoError(t, client.EnqueueLogEntry(ctx, json.RawMessage(entry)))
+
+	err := client.EnqueueLogEntry(ctx, json.RawMessage(`{"message":"test2"}`))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, nrErrors.ErrPayloadTooLarge))
+
+	assert.Error(t, client.EnqueueLogEntry(ctx, nil))
+
+	require.NoError(t, client.Close(ctx))
+}
+
+func TestCreateLogEntryRejectsOversizedLogEntries(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	err := client.CreateLogEntry(map[string]interface{}{"message": incompressible(t, MaxPayloadSize*3/2)})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, nrErrors.ErrPayloadTooLarge))
+
+	assert.True(t, errors.Is(client.CreateLogEntry(nil), nrErrors.ErrInvalidInput))
+	assert.Zero(t, atomic.LoadInt32(&requests))
+
+	// The limit applies to the payload once compressed.
+	require.NoError(t, client.CreateLogEntry(map[string]interface{}{"message": strings.Repeat("a", 2*MaxPayloadSize)}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestBatchCompressiblePayloadOverMaxPayloadSize(t *testing.T) {
+	t.Parallel()
+
+	var requests, received int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		atomic.AddInt32(&received, int32(countLogs(t, r)))
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	ctx := context.Background()
+	require.NoError(t, client.BatchMode(ctx, 1))
+
+	// The log entries add up to more than the maximum payload size, but are
+	// sent in a single payload, as they compress well within it.
+	entry := map[string]interface{}{"message": strings.Repeat("a", MaxPayloadSize/2)}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.EnqueueLogEntry(ctx, entry))
+	}
+
+	// A log entry that doesn't compress within it is rejected.
+	err := client.EnqueueLogEntry(ctx, map[string]interface{}{"message": incompressible(t, MaxPayloadSize*3/2)})
+	assert.True(t, errors.Is(err, nrErrors.ErrPayloadTooLarge))
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&received))
+}
+
+func TestBatchSplitByCompressedPayloadSize(t *testing.T) {
+	t.Parallel()
+
+	var requests, received int32
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		atomic.AddInt32(&received, int32(countLogs(t, r)))
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	ctx := context.Background()
+	require.NoError(t, client.BatchMode(ctx, 1))
+
+	// Log entries that don't compress are still split into payloads within
+	// the maximum size.
+	for i := 0; i < 3; i++ {
+		entry := map[string]interface{}{"message": incompressible(t, MaxPayloadSize*2/5)}
+		require.NoError(t, client.EnqueueLogEntry(ctx, entry))
+	}
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&received))
+}
+
+// incompressible returns a random string of the given length, which gzip
+// can't compress.
+func incompressible(t *testing.T, length int) string {
+	data := make([]byte, length)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(data)[:length]
+}
+
+// lockingLogEntry is a log entry that takes the batch lock of the client it's
+// queued in while it's marshaled.
+type lockingLogEntry struct {
+	client *Logs
+}
+
+func (l lockingLogEntry) MarshalJSON() ([]byte, error) {
+	l.client.batchMu.Lock()
+	defer l.client.batchMu.Unlock()
+
+	return []byte(`{"message":"test"}`), nil
+}
+
+func TestEnqueueLogEntryMarshalsOutsideLock(t *testing.T) {
+	t.Parallel()
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	ctx := context.Background()
+	require.NoError(t, client.BatchMode(ctx, 1))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.EnqueueLogEntry(ctx, lockingLogEntry{client: &client})
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("log entry was marshaled holding the batch lock")
+	}
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, BatchStats{Sent: 1}, client.BatchStats())
+}
+
+func TestBatchConfigOptions(t *testing.T) {
+	t.Parallel()
+
+	l := New(mock.NewTestConfig(t, nil))
+
+	assert.Error(t, BatchConfigMaxPayloadSize(len("[]"))(&l))
+	assert.Error(t, BatchConfigMaxPayloadSize(MaxPayloadSize+1)(&l))
+	assert.NoError(t, BatchConfigMaxPayloadSize(MaxPayloadSize)(&l))
+	assert.Error(t, BatchConfigCommonAttributes(map[string]interface{}{"": 1})(&l))
+	assert.NoError(t, BatchConfigCommonAttributes(map[string]interface{}{"service": "checkout"})(&l))
+	assert.Error(t, BatchConfigOverflowPolicy(OverflowPolicy(-1))(&l))
+	assert.Error(t, BatchConfigOverflowPolicy(OverflowFail+1)(&l))
+	assert.NoError(t, BatchConfigOverflowPolicy(OverflowDropOldest)(&l))
+}
+
+func TestBatchCommonAttributes(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var payloads []interface{}
+	client := newTestBatchClient(t, decodePayloads(t, &mu, &payloads))
+
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, 1,
+		BatchConfigCommonAttributes(map[string]interface{}{"service": "checkout"}),
+	))
+
+	require.NoError(t, client.EnqueueLogEntry(ctx, LogEntry{Message: "a", Level: "info"}))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+
+	// The common attributes count towards the limit of each log entry.
+	tooMany := map[string]interface{}{}
+	for i := 0; i < MaxAttributes; i++ {
+		tooMany[fmt.Sprintf("attr%d", i)] = i
+	}
+
+	err := client.EnqueueLogEntry(ctx, LogEntry{Message: "b", Attributes: tooMany})
+	assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput))
+
+	err = client.EnqueueLogEntry(ctx, LogBatch{Logs: []LogEntry{{Message: "c"}}})
+	assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput))
+
+	require.NoError(t, client.Close(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, payloads, 1)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"common": map[string]interface{}{"attributes": map[string]interface{}{"service": "checkout"}},
+			"logs": []interface{}{
+				map[string]interface{}{"message": "a", "attributes": map[string]interface{}{"level": "info"}},
+				map[string]interface{}{"message": "test", "level": "info"},
+			},
+		},
+	}, payloads[0])
+}
+
+func TestBatchSpoolReplaysUnsentLogs(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "logs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var status, received int32 = http.StatusServiceUnavailable, 0
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		code := int(atomic.LoadInt32(&status))
+		if code == http.StatusAccepted {
+			atomic.AddInt32(&received, int32(countLogs(t, r)))
+		}
+
+		w.WriteHeader(code)
+	})
+
+	ctx := context.Background()
+
+	// Logs that can't be sent during an outage are left in the spool.
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigSpool(spool.Config{Dir: dir})))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	}
+
+	// They aren't reported as failures, since they'll be sent later.
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, BatchStats{Spooled: 3}, client.BatchStats())
+
+	// They're sent once batch mode is enabled again, as when restarting.
+	atomic.StoreInt32(&status, http.StatusAccepted)
+
+	require.NoError(t, client.BatchMode(ctx, 1))
+	require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	require.NoError(t, client.Close(ctx))
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&received))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	assert.Error(t, BatchConfigSpool(spool.Config{})(&client))
+}
+
+func TestBatchSpoolReplaysFailedLogsWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "logs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var status, received, requests int32 = http.StatusServiceUnavailable, 0, 0
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		code := int(atomic.LoadInt32(&status))
+		if code == http.StatusAccepted {
+			atomic.AddInt32(&received, int32(countLogs(t, r)))
+		}
+
+		w.WriteHeader(code)
+	})
+
+	ctx := context.Background()
+	cfg := spool.Config{Dir: dir, ReplayInterval: 5 * time.Millisecond, MaxReplayInterval: 20 * time.Millisecond}
+
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigSpool(cfg), BatchConfigQueueSize(3)))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	}
+
+	// Logs in batches that fail during an outage are sent again while it
+	// lasts, and once it's over, without restarting batch mode.
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&requests) >= 3 }, 5*time.Second, time.Millisecond)
+	assert.Zero(t, atomic.LoadInt32(&received))
+
+	atomic.StoreInt32(&status, http.StatusAccepted)
+
+	require.Eventually(t, func() bool { return client.BatchStats().Sent == 3 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&received))
+	assert.Zero(t, client.BatchStats().Queued)
+	assert.Zero(t, client.logSpool.Released())
+	assert.Zero(t, client.BatchStats().Failed)
+	assert.GreaterOrEqual(t, client.BatchStats().Spooled, int64(3))
+
+	require.NoError(t, client.Close(ctx))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+// newUnstartedBatch returns a client in batch mode whose workers aren't
+// running, so the logs queued stay in its queue of the given size.
+func newUnstartedBatch(t *testing.T, size int, policy OverflowPolicy) Logs {
+	l := New(mock.NewTestConfig(t, nil))
+
+	l.logQueue = make(chan queuedLog, size)
+	l.stats = &batchStats{}
+	l.enqueues = &sync.WaitGroup{}
+	l.stopping = make(chan struct{})
+	l.batchEmptySize = emptyPayloadSize
+	l.batchOverflowPolicy = policy
+
+	return l
+}
+
+// queuedMessages returns the messages of the logs in the queue, in order.
+func queuedMessages(t *testing.T, l Logs) []string {
+	messages := []string{}
+
+	for len(l.logQueue) > 0 {
+		// Without a common block, each LogEntry is queued in a block of its own.
+		block := struct {
+			Logs []map[string]interface{} `json:"logs"`
+		}{}
+		require.NoError(t, json.Unmarshal((<-l.logQueue).data, &block))
+		require.Len(t, block.Logs, 1)
+		messages = append(messages, block.Logs[0]["message"].(string))
+	}
+
+	return messages
+}
+
+func TestBatchOverflowPolicies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	entry := func(message string) LogEntry {
+		return LogEntry{Message: message}
+	}
+
+	l := newUnstartedBatch(t, 2, OverflowDropNewest)
+	for _, message := range []string{"a", "b", "c"} {
+		require.NoError(t, l.EnqueueLogEntry(ctx, entry(message)))
+	}
+	assert.Equal(t, BatchStats{Queued: 2, QueueDepth: 2, Dropped: 1}, l.BatchStats())
+	assert.Equal(t, []string{"a", "b"}, queuedMessages(t, l))
+
+	l = newUnstartedBatch(t, 2, OverflowDropOldest)
+	for _, message := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, l.EnqueueLogEntry(ctx, entry(message)))
+	}
+	assert.Equal(t, BatchStats{Queued: 2, QueueDepth: 2, Dropped: 2}, l.BatchStats())
+	assert.Equal(t, []string{"c", "d"}, queuedMessages(t, l))
+
+	l = newUnstartedBatch(t, 2, OverflowFail)
+	for _, message := range []string{"a", "b"} {
+		require.NoError(t, l.EnqueueLogEntry(ctx, entry(message)))
+	}
+	assert.Equal(t, ErrQueueFull, l.EnqueueLogEntry(ctx, entry("c")))
+	assert.Equal(t, BatchStats{Queued: 2, QueueDepth: 2}, l.BatchStats())
+
+	l = newUnstartedBatch(t, 1, OverflowBlock)
+	require.NoError(t, l.EnqueueLogEntry(ctx, entry("a")))
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, l.EnqueueLogEntry(timeout, entry("b")))
+	assert.Equal(t, BatchStats{Queued: 1, QueueDepth: 1}, l.BatchStats())
+}
+
+func TestBatchStats(t *testing.T) {
+	t.Parallel()
+
+	client := newTestBatchClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	ctx := context.Background()
+
+	assert.Equal(t, BatchStats{}, client.BatchStats())
+
+	require.NoError(t, client.BatchMode(ctx, 1, BatchConfigQueueSize(2)))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.EnqueueLogEntry(ctx, testLogEntry))
+	}
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, BatchStats{Sent: 3}, client.BatchStats())
+}
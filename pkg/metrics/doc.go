@@ -0,0 +1,18 @@
+/*
+Package metrics provides a programmatic API for sending dimensional metrics to
+New Relic's Metric API.
+
+Gauge, count and summary metrics can be sent directly with CreateMetrics, or
+recorded in batch mode, where metrics with the same name and attributes are
+aggregated over each harvest period before they are sent.
+
+Authentication
+
+You will need a valid License key, or an Insights insert key, to communicate
+with the backend New Relic API that provides this functionality.  See the API
+key documentation below for more information on how to locate these keys:
+
+https://docs.newrelic.com/docs/apis/get-started/intro-apis/types-new-relic-api-keys
+
+*/
+package metrics
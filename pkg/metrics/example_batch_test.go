@@ -0,0 +1,51 @@
+//go:build integration
+// +build integration
+
+package metrics
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+)
+
+func Example_batch() {
+	// Initialize the client configuration.  A New Relic License Key is
+	// required to communicate with the backend API.
+	cfg := config.New()
+	cfg.LicenseKey = os.Getenv("NEW_RELIC_LICENSE_KEY")
+
+	// Initialize the client.
+	client := New(cfg)
+
+	// Start batch mode, with attributes common to every metric recorded.
+	common := BatchConfigCommonAttributes(map[string]interface{}{
+		"service.name": "example",
+	})
+
+	if err := client.BatchMode(context.Background(), common); err != nil {
+		log.Fatal("error starting batch mode:", err)
+	}
+
+	// Record metrics, which are aggregated over each harvest period.
+	attributes := map[string]interface{}{"endpoint": "/checkout"}
+
+	if err := client.RecordCount("requests", 1, attributes); err != nil {
+		log.Fatal("error recording count:", err)
+	}
+
+	if err := client.RecordSummary("request.duration", 0.125, attributes); err != nil {
+		log.Fatal("error recording summary:", err)
+	}
+
+	if err := client.RecordGauge("queue.length", 12, nil); err != nil {
+		log.Fatal("error recording gauge:", err)
+	}
+
+	// Send the recorded metrics and leave batch mode.
+	if err := client.Close(context.Background()); err != nil {
+		log.Fatal("error closing metrics batch mode:", err)
+	}
+}
@@ -0,0 +1,344 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/newrelic/newrelic-client-go/internal/http"
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/logging"
+)
+
+const (
+	DefaultHarvestPeriod = 5 * time.Second
+)
+
+// MaxPayloadSize is the maximum size in bytes of a payload accepted by the
+// Metric API.  It limits the size of the payloads metrics are sent in.
+const MaxPayloadSize = 1000000
+
+// Metrics is used to send dimensional metrics to the Metric API.
+type Metrics struct {
+	client http.Client
+	config config.Config
+	logger logging.Logger
+
+	// For aggregating recorded metrics
+	aggregates    map[string]Metric
+	harvestStart  time.Time
+	harvestTicker *time.Ticker
+	flushQueue    chan bool
+	stats         *batchStats
+
+	// For closing batch mode
+	batchMu  *sync.Mutex
+	workers  *sync.WaitGroup
+	stopping chan struct{}
+	stopped  chan struct{}
+
+	// These have defaults
+	harvestPeriod       time.Duration
+	batchMaxPayloadSize int
+	batchCommon         *Common
+	batchErrorHandler   BatchErrorHandler
+}
+
+// New is used to create a new Metrics client instance.
+func New(cfg config.Config) Metrics {
+	cfg.Compression = config.Compression.Gzip
+
+	client := http.NewClient(cfg)
+	if cfg.InsightsInsertKey != "" {
+		client.SetAuthStrategy(&http.LogsInsertKeyAuthorizer{})
+	} else {
+		client.SetAuthStrategy(&http.LicenseKeyAuthorizer{})
+	}
+
+	pkg := Metrics{
+		client:              client,
+		config:              cfg,
+		logger:              cfg.GetLogger(),
+		batchMu:             &sync.Mutex{},
+		harvestPeriod:       DefaultHarvestPeriod,
+		batchMaxPayloadSize: MaxPayloadSize,
+	}
+
+	return pkg
+}
+
+// Metric is a Gauge, Count or Summary metric.
+type Metric interface {
+	json.Marshaler
+
+	// MetricName returns the name of the metric.
+	MetricName() string
+
+	// MetricAttributes returns the attributes of the metric.
+	MetricAttributes() map[string]interface{}
+}
+
+// Gauge is a metric for a value at a point in time, such as a temperature.
+type Gauge struct {
+	Name       string
+	Value      float64
+	Timestamp  time.Time
+	Attributes map[string]interface{}
+}
+
+// Count is a metric for the number of occurrences of something over an
+// interval, such as the number of requests served.
+type Count struct {
+	Name       string
+	Value      float64
+	Timestamp  time.Time
+	Interval   time.Duration
+	Attributes map[string]interface{}
+}
+
+// Summary is a metric for the distribution of values over an interval, such
+// as the duration of the requests served.
+type Summary struct {
+	Name       string
+	Count      float64
+	Sum        float64
+	Min        float64
+	Max        float64
+	Timestamp  time.Time
+	Interval   time.Duration
+	Attributes map[string]interface{}
+}
+
+// Common holds the timestamp, interval and attributes shared by the metrics
+// sent in a payload, which are used when a metric doesn't have its own.
+type Common struct {
+	Timestamp  time.Time
+	Interval   time.Duration
+	Attributes map[string]interface{}
+}
+
+type metricJSON struct {
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Value      interface{}            `json:"value"`
+	Timestamp  int64                  `json:"timestamp,omitempty"`
+	Interval   int64                  `json:"interval.ms,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type summaryValueJSON struct {
+	Count float64 `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+type commonJSON struct {
+	Timestamp  int64                  `json:"timestamp,omitempty"`
+	Interval   int64                  `json:"interval.ms,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// MetricName returns the name of the metric.
+func (m Gauge) MetricName() string { return m.Name }
+
+// MetricAttributes returns the attributes of the metric.
+func (m Gauge) MetricAttributes() map[string]interface{} { return m.Attributes }
+
+// MarshalJSON marshals the metric in the format of the Metric API.
+func (m Gauge) MarshalJSON() ([]byte, error) {
+	return json.Marshal(metricJSON{
+		Name:       m.Name,
+		Type:       "gauge",
+		Value:      m.Value,
+		Timestamp:  unixMilliseconds(m.Timestamp),
+		Attributes: m.Attributes,
+	})
+}
+
+// MetricName returns the name of the metric.
+func (m Count) MetricName() string { return m.Name }
+
+// MetricAttributes returns the attributes of the metric.
+func (m Count) MetricAttributes() map[string]interface{} { return m.Attributes }
+
+// MarshalJSON marshals the metric in the format of the Metric API.
+func (m Count) MarshalJSON() ([]byte, error) {
+	return json.Marshal(metricJSON{
+		Name:       m.Name,
+		Type:       "count",
+		Value:      m.Value,
+		Timestamp:  unixMilliseconds(m.Timestamp),
+		Interval:   m.Interval.Milliseconds(),
+		Attributes: m.Attributes,
+	})
+}
+
+// MetricName returns the name of the metric.
+func (m Summary) MetricName() string { return m.Name }
+
+// MetricAttributes returns the attributes of the metric.
+func (m Summary) MetricAttributes() map[string]interface{} { return m.Attributes }
+
+// MarshalJSON marshals the metric in the format of the Metric API.
+func (m Summary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(metricJSON{
+		Name: m.Name,
+		Type: "summary",
+		Value: summaryValueJSON{
+			Count: m.Count,
+			Sum:   m.Sum,
+			Min:   m.Min,
+			Max:   m.Max,
+		},
+		Timestamp:  unixMilliseconds(m.Timestamp),
+		Interval:   m.Interval.Milliseconds(),
+		Attributes: m.Attributes,
+	})
+}
+
+// MarshalJSON marshals the common block in the format of the Metric API.
+func (c Common) MarshalJSON() ([]byte, error) {
+	return json.Marshal(commonJSON{
+		Timestamp:  unixMilliseconds(c.Timestamp),
+		Interval:   c.Interval.Milliseconds(),
+		Attributes: c.Attributes,
+	})
+}
+
+// unixMilliseconds returns the Unix time of t in milliseconds, or zero when t
+// is the zero time so the timestamp is omitted.
+func unixMilliseconds(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// CreateMetrics sends metrics to New Relic, along with the common block
+// shared by them, which may be nil.
+func (m *Metrics) CreateMetrics(common *Common, metrics ...Metric) error {
+	return m.CreateMetricsWithContext(context.Background(), common, metrics...)
+}
+
+// CreateMetricsWithContext sends metrics to New Relic, along with the common
+// block shared by them, which may be nil.
+func (m *Metrics) CreateMetricsWithContext(ctx context.Context, common *Common, metrics ...Metric) error {
+	if len(metrics) == 0 {
+		return errors.NewInvalidInput("metrics: CreateMetrics: no metrics, nothing to do")
+	}
+
+	commonData, err := marshalCommon(common)
+	if err != nil {
+		return err
+	}
+
+	data := make([][]byte, len(metrics))
+	for i, metric := range metrics {
+		if data[i], err = marshalMetric(metric, common); err != nil {
+			return err
+		}
+	}
+
+	payload := buildPayload(commonData, data)
+	if len(payload) > MaxPayloadSize {
+		return errors.NewPayloadTooLarge(len(payload), MaxPayloadSize)
+	}
+
+	return m.sendPayload(ctx, payload)
+}
+
+// marshalCommon converts the common block into a JSON []byte, or nil when
+// there is none.
+func marshalCommon(common *Common) ([]byte, error) {
+	if common == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(common)
+	if err != nil {
+		return nil, errors.NewInvalidInputf("metrics: error marshaling common block: %s", err.Error()).WithCause(err)
+	}
+
+	return data, nil
+}
+
+// marshalMetric validates the metric and converts it into a JSON []byte.
+// Counts and summaries must have an interval, of their own or from the
+// common block.
+func marshalMetric(metric Metric, common *Common) ([]byte, error) {
+	if metric == nil {
+		return nil, errors.NewInvalidInput("metrics: metric is nil")
+	}
+	if metric.MetricName() == "" {
+		return nil, errors.NewInvalidInput("metrics: metric name is required")
+	}
+
+	if interval, ok := metricInterval(metric); ok && interval <= 0 && (common == nil || common.Interval <= 0) {
+		return nil, errors.NewInvalidInputf("metrics: metric %q requires an interval", metric.MetricName())
+	}
+
+	data, err := json.Marshal(metric)
+	if err != nil {
+		return nil, errors.NewInvalidInputf("metrics: error marshaling metric %q: %s", metric.MetricName(), err.Error()).WithCause(err)
+	}
+
+	return data, nil
+}
+
+// metricInterval returns the interval of a metric, and whether the type of
+// metric has one.
+func metricInterval(metric Metric) (time.Duration, bool) {
+	switch metric := metric.(type) {
+	case Count:
+		return metric.Interval, true
+	case Summary:
+		return metric.Interval, true
+	default:
+		return 0, false
+	}
+}
+
+// buildPayload joins the metrics, already marshaled to JSON, into a payload
+// along with the common block.
+func buildPayload(common []byte, metrics [][]byte) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(`[{`)
+	if common != nil {
+		buf.WriteString(`"common":`)
+		buf.Write(common)
+		buf.WriteString(`,`)
+	}
+	buf.WriteString(`"metrics":[`)
+	buf.Write(bytes.Join(metrics, []byte(",")))
+	buf.WriteString(`]}]`)
+
+	return buf.Bytes()
+}
+
+// emptyPayloadSize returns the size of a payload without any metrics.
+func emptyPayloadSize(common []byte) int {
+	return len(buildPayload(common, nil))
+}
+
+type createMetricsResponse struct {
+	RequestID string `json:"requestId"`
+}
+
+func (m *Metrics) sendPayload(ctx context.Context, payload []byte) error {
+	resp := &createMetricsResponse{}
+
+	_, err := m.client.PostWithContext(ctx, m.config.Region().MetricsURL(), nil, payload, resp)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Trace("metrics sent", "requestId", resp.RequestID)
+
+	return nil
+}
@@ -0,0 +1,483 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+// BatchMode enables the Metrics client to record metrics on behalf of the
+// consuming application.  Metrics with the same name, type and attributes are
+// aggregated over each harvest period, then sent to New Relic.  Batch mode
+// runs until ctx is done, discarding any metrics not yet sent, or until Close
+// is called, which sends them first.  Once closed, batch mode can be enabled
+// again.
+func (m *Metrics) BatchMode(ctx context.Context, opts ...BatchConfigOption) (err error) {
+	m.batchMu.Lock()
+	defer m.batchMu.Unlock()
+
+	if m.aggregates != nil {
+		return errors.New("the Metrics client is already in batch mode")
+	}
+
+	// Loop through config options
+	for _, fn := range opts {
+		if nil != fn {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+	}
+
+	m.aggregates = map[string]Metric{}
+	m.harvestStart = time.Now()
+	m.harvestTicker = time.NewTicker(m.harvestPeriod)
+	m.flushQueue = make(chan bool, 1)
+	m.stats = &batchStats{}
+	m.workers = &sync.WaitGroup{}
+	m.stopping = make(chan struct{})
+	m.stopped = make(chan struct{})
+
+	m.workers.Add(1)
+	go func() {
+		defer m.workers.Done()
+
+		err := m.harvester(ctx)
+		if err != nil {
+			m.logger.Error("harvester returned error", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Close stops batch mode once the metrics recorded have been sent.  Metrics
+// can no longer be recorded once Close is called, the metrics aggregated so
+// far are sent, and Close waits for them to be sent, or for ctx to be done.
+// An error is returned if any metrics could not be sent.  Batch mode can be
+// enabled again once closed.
+func (m *Metrics) Close(ctx context.Context) error {
+	m.batchMu.Lock()
+
+	if m.aggregates == nil {
+		m.batchMu.Unlock()
+		return errors.New("queueing not enabled for this client")
+	}
+
+	stats, stopped := m.stats, m.stopped
+
+	select {
+	case <-m.stopping:
+	default:
+		m.logger.Debug("closing batch mode")
+		close(m.stopping)
+		go m.shutdown()
+	}
+
+	m.batchMu.Unlock()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	failed := atomic.LoadInt64(&stats.failed)
+	dropped := atomic.LoadInt64(&stats.dropped)
+
+	if failed > 0 || dropped > 0 {
+		return fmt.Errorf("metrics: %d metrics could not be sent and %d were dropped", failed, dropped)
+	}
+
+	return nil
+}
+
+// shutdown waits for the harvester to send the metrics aggregated so far,
+// then leaves batch mode.
+func (m *Metrics) shutdown() {
+	m.workers.Wait()
+	m.harvestTicker.Stop()
+
+	m.batchMu.Lock()
+	defer m.batchMu.Unlock()
+
+	// A harvester stopped by its context being done may have left metrics.
+	m.dropMetrics(len(m.aggregates))
+
+	m.aggregates = nil
+	m.flushQueue = nil
+	close(m.stopped)
+}
+
+type BatchConfigOption func(*Metrics) error
+
+// BatchConfigHarvestPeriod is the period metrics are aggregated over before
+// they are sent to New Relic.
+func BatchConfigHarvestPeriod(period time.Duration) BatchConfigOption {
+	return func(m *Metrics) error {
+		if period < time.Millisecond {
+			return errors.New("metrics: invalid harvest period specified")
+		}
+
+		m.harvestPeriod = period
+		return nil
+	}
+}
+
+// BatchConfigCommonAttributes sets the attributes sent in the common block of
+// every payload, which apply to every metric recorded in batch mode.
+func BatchConfigCommonAttributes(attributes map[string]interface{}) BatchConfigOption {
+	return func(m *Metrics) error {
+		if _, err := json.Marshal(attributes); err != nil {
+			return fmt.Errorf("metrics: invalid common attributes specified: %w", err)
+		}
+
+		m.batchCommon = &Common{Attributes: attributes}
+		return nil
+	}
+}
+
+// BatchConfigMaxPayloadSize is the maximum size in bytes of the payloads
+// metrics are sent in, before compression.  The metrics of a harvest are
+// split across as many payloads as needed.  It can't exceed MaxPayloadSize,
+// which is the default.
+func BatchConfigMaxPayloadSize(size int) BatchConfigOption {
+	return func(m *Metrics) error {
+		if size <= 0 || size > MaxPayloadSize {
+			return errors.New("metrics: invalid max payload size specified")
+		}
+
+		m.batchMaxPayloadSize = size
+		return nil
+	}
+}
+
+// BatchErrorHandler is called with the metrics of a payload that could not be
+// sent to New Relic, and the error for the attempt to send them.
+type BatchErrorHandler func(metrics []Metric, err error)
+
+// BatchConfigErrorHandler sets the handler called with the metrics that could
+// not be sent to New Relic.
+func BatchConfigErrorHandler(handler BatchErrorHandler) BatchConfigOption {
+	return func(m *Metrics) error {
+		m.batchErrorHandler = handler
+		return nil
+	}
+}
+
+// BatchStats are delivery statistics for the metrics recorded in batch mode,
+// counting each aggregated metric once.
+type BatchStats struct {
+	// Sent is the number of metrics sent to New Relic.
+	Sent int64
+
+	// Failed is the number of metrics that could not be sent, which were
+	// passed to the error handler.
+	Failed int64
+
+	// Dropped is the number of metrics discarded without being sent, such as
+	// metrics recorded when batch mode's context is done.
+	Dropped int64
+}
+
+type batchStats struct {
+	sent    int64
+	failed  int64
+	dropped int64
+}
+
+// BatchStats returns the delivery statistics for the metrics recorded in batch mode.
+func (m *Metrics) BatchStats() BatchStats {
+	m.batchMu.Lock()
+	stats := m.stats
+	m.batchMu.Unlock()
+
+	if stats == nil {
+		return BatchStats{}
+	}
+
+	return BatchStats{
+		Sent:    atomic.LoadInt64(&stats.sent),
+		Failed:  atomic.LoadInt64(&stats.failed),
+		Dropped: atomic.LoadInt64(&stats.dropped),
+	}
+}
+
+// RecordGauge records the value of a gauge, replacing the value recorded for
+// the gauge with the same name and attributes earlier in the harvest period.
+func (m *Metrics) RecordGauge(name string, value float64, attributes map[string]interface{}) error {
+	return m.EnqueueMetric(Gauge{Name: name, Value: value, Attributes: attributes})
+}
+
+// RecordCount adds a value to a count, which is summed with the values
+// recorded for the count with the same name and attributes over the harvest
+// period.
+func (m *Metrics) RecordCount(name string, value float64, attributes map[string]interface{}) error {
+	return m.EnqueueMetric(Count{Name: name, Value: value, Attributes: attributes})
+}
+
+// RecordSummary records a value in a summary, which holds the count, sum,
+// minimum and maximum of the values recorded for the summary with the same
+// name and attributes over the harvest period.
+func (m *Metrics) RecordSummary(name string, value float64, attributes map[string]interface{}) error {
+	return m.EnqueueMetric(Summary{Name: name, Count: 1, Sum: value, Min: value, Max: value, Attributes: attributes})
+}
+
+// EnqueueMetric aggregates a metric with the metrics of the same name, type
+// and attributes recorded over the harvest period.  Only works in batch mode.
+// The timestamp and interval of counts and summaries are set to the harvest
+// period's when they are sent.  Attributes must not be modified once recorded.
+func (m *Metrics) EnqueueMetric(metric Metric) error {
+	key, err := aggregateKey(metric)
+	if err != nil {
+		return err
+	}
+
+	if gauge, ok := metric.(Gauge); ok && gauge.Timestamp.IsZero() {
+		gauge.Timestamp = time.Now()
+		metric = gauge
+	}
+
+	m.batchMu.Lock()
+	defer m.batchMu.Unlock()
+
+	if m.aggregates == nil {
+		return errors.New("queueing not enabled for this client")
+	}
+
+	select {
+	case <-m.stopping:
+		return errors.New("metrics: batch mode is closing")
+	default:
+	}
+
+	m.aggregates[key] = aggregate(m.aggregates[key], metric)
+
+	return nil
+}
+
+// aggregateKey returns the key a metric is aggregated by, made of its type,
+// name and attributes.
+func aggregateKey(metric Metric) (string, error) {
+	if metric == nil {
+		return "", nrErrors.NewInvalidInput("metrics: metric is nil")
+	}
+	if metric.MetricName() == "" {
+		return "", nrErrors.NewInvalidInput("metrics: metric name is required")
+	}
+
+	// Maps are marshaled with their keys sorted, so equal attributes have
+	// equal keys.
+	attributes, err := json.Marshal(metric.MetricAttributes())
+	if err != nil {
+		return "", nrErrors.NewInvalidInputf("metrics: invalid attributes for metric %q: %s", metric.MetricName(), err.Error()).WithCause(err)
+	}
+
+	return fmt.Sprintf("%T\x00%s\x00%s", metric, metric.MetricName(), attributes), nil
+}
+
+// aggregate returns the result of aggregating a metric with the metric
+// aggregated so far, which is nil for the first metric recorded.
+func aggregate(aggregated Metric, metric Metric) Metric {
+	switch metric := metric.(type) {
+	case Count:
+		if count, ok := aggregated.(Count); ok {
+			metric.Value += count.Value
+		}
+
+		return metric
+	case Summary:
+		if summary, ok := aggregated.(Summary); ok {
+			metric.Count += summary.Count
+			metric.Sum += summary.Sum
+			metric.Min = math.Min(metric.Min, summary.Min)
+			metric.Max = math.Max(metric.Max, summary.Max)
+		}
+
+		return metric
+	default:
+		return metric
+	}
+}
+
+// Flush gives the user a way to manually send the metrics aggregated so far
+// in the background.  This is also done by the harvester every harvest period.
+func (m *Metrics) Flush() error {
+	m.batchMu.Lock()
+	flushQueue := m.flushQueue
+	m.batchMu.Unlock()
+
+	if flushQueue == nil {
+		return errors.New("queueing not enabled for this client")
+	}
+
+	m.logger.Debug("flushing metrics")
+
+	// A harvester with a flush pending doesn't need another.
+	select {
+	case flushQueue <- true:
+	default:
+	}
+
+	return nil
+}
+
+// harvester sends the metrics aggregated over each harvest period, or when
+// flushed, until batch mode is closing or ctx is done.
+func (m *Metrics) harvester(ctx context.Context) error {
+	for {
+		select {
+		case <-m.harvestTicker.C:
+			m.harvest(ctx)
+		case <-m.flushQueue:
+			m.harvest(ctx)
+		case <-m.stopping:
+			// Metrics recorded when ctx is done are discarded, even when closing.
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			m.logger.Trace("harvester exiting: batch mode closing")
+			m.harvest(ctx)
+			return nil
+		case <-ctx.Done():
+			m.logger.Trace("harvester exiting: context finished")
+			return ctx.Err()
+		}
+	}
+}
+
+// harvest sends the metrics aggregated since the last harvest, setting the
+// timestamp and interval of counts and summaries to the harvest period's.
+func (m *Metrics) harvest(ctx context.Context) {
+	now := time.Now()
+
+	m.batchMu.Lock()
+	aggregates, start := m.aggregates, m.harvestStart
+	m.aggregates = map[string]Metric{}
+	m.harvestStart = now
+	m.batchMu.Unlock()
+
+	if len(aggregates) == 0 {
+		return
+	}
+
+	// The Metric API requires an interval of at least a millisecond.
+	interval := now.Sub(start)
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+
+	keys := make([]string, 0, len(aggregates))
+	for key := range aggregates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	metrics := make([]Metric, 0, len(aggregates))
+	for _, key := range keys {
+		switch metric := aggregates[key].(type) {
+		case Count:
+			metric.Timestamp, metric.Interval = start, interval
+			metrics = append(metrics, metric)
+		case Summary:
+			metric.Timestamp, metric.Interval = start, interval
+			metrics = append(metrics, metric)
+		default:
+			metrics = append(metrics, metric)
+		}
+	}
+
+	m.sendMetrics(ctx, metrics)
+}
+
+// sendMetrics sends metrics in as many payloads as needed to keep each one
+// within the maximum payload size.
+func (m *Metrics) sendMetrics(ctx context.Context, metrics []Metric) {
+	common, err := marshalCommon(m.batchCommon)
+	if err != nil {
+		m.failMetrics(metrics, err)
+		return
+	}
+
+	emptySize := emptyPayloadSize(common)
+
+	batch := [][]byte{}
+	batchMetrics := []Metric{}
+	size := emptySize
+
+	send := func() {
+		if len(batch) > 0 {
+			m.sendBatch(ctx, common, batch, batchMetrics)
+		}
+
+		batch, batchMetrics, size = [][]byte{}, []Metric{}, emptySize
+	}
+
+	for _, metric := range metrics {
+		data, err := marshalMetric(metric, m.batchCommon)
+		if err != nil {
+			m.failMetrics([]Metric{metric}, err)
+			continue
+		}
+
+		// A metric that can't fit in a payload on its own can never be sent.
+		if emptySize+len(data) > m.batchMaxPayloadSize {
+			m.failMetrics([]Metric{metric}, nrErrors.NewPayloadTooLarge(emptySize+len(data), m.batchMaxPayloadSize))
+			continue
+		}
+
+		if len(batch) > 0 && size+len(",")+len(data) > m.batchMaxPayloadSize {
+			send()
+		}
+
+		if len(batch) > 0 {
+			size += len(",")
+		}
+
+		batch = append(batch, data)
+		batchMetrics = append(batchMetrics, metric)
+		size += len(data)
+	}
+
+	send()
+}
+
+// sendBatch sends a payload of metrics, and accounts for the result.
+func (m *Metrics) sendBatch(ctx context.Context, common []byte, batch [][]byte, metrics []Metric) {
+	if err := m.sendPayload(ctx, buildPayload(common, batch)); err != nil {
+		m.failMetrics(metrics, err)
+		return
+	}
+
+	atomic.AddInt64(&m.stats.sent, int64(len(metrics)))
+}
+
+// failMetrics accounts for metrics that could not be sent, passing them to
+// the error handler.
+func (m *Metrics) failMetrics(metrics []Metric, err error) {
+	atomic.AddInt64(&m.stats.failed, int64(len(metrics)))
+	m.logger.Error("failed to send metrics", "error", err, "count", len(metrics))
+
+	if m.batchErrorHandler != nil {
+		m.batchErrorHandler(metrics, err)
+	}
+}
+
+// dropMetrics accounts for aggregated metrics discarded without being sent.
+func (m *Metrics) dropMetrics(count int) {
+	if count == 0 {
+		return
+	}
+
+	atomic.AddInt64(&m.stats.dropped, int64(count))
+	m.logger.Warn("dropped recorded metrics", "count", count)
+}
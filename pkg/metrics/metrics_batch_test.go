@@ -0,0 +1,213 @@
+//go:build unit
+// +build unit
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+func TestBatchAggregatesMetrics(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx,
+		BatchConfigHarvestPeriod(time.Hour),
+		BatchConfigCommonAttributes(map[string]interface{}{"service": "test"}),
+	))
+
+	kitchen := map[string]interface{}{"room": "kitchen"}
+
+	require.NoError(t, client.RecordGauge("temperature", 20, kitchen))
+	require.NoError(t, client.RecordGauge("temperature", 21, map[string]interface{}{"room": "kitchen"}))
+	require.NoError(t, client.RecordGauge("temperature", 18, map[string]interface{}{"room": "hall"}))
+	require.NoError(t, client.RecordCount("requests", 1, nil))
+	require.NoError(t, client.RecordCount("requests", 2, nil))
+	require.NoError(t, client.RecordSummary("duration", 0.5, nil))
+	require.NoError(t, client.RecordSummary("duration", 0.25, nil))
+	require.NoError(t, client.RecordSummary("duration", 0.75, nil))
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, BatchStats{Sent: 4}, client.BatchStats())
+
+	payloads := testPayloads(t, server)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, map[string]interface{}{"attributes": map[string]interface{}{"service": "test"}}, payloads[0].Common)
+
+	metrics := map[string]map[string]interface{}{}
+	for _, metric := range payloads[0].Metrics {
+		key := metric["name"].(string)
+		if attributes, ok := metric["attributes"].(map[string]interface{}); ok {
+			key += "/" + attributes["room"].(string)
+		}
+
+		metrics[key] = metric
+	}
+
+	require.Len(t, metrics, 4)
+	assert.Equal(t, 21.0, metrics["temperature/kitchen"]["value"])
+	assert.Equal(t, 18.0, metrics["temperature/hall"]["value"])
+	assert.NotZero(t, metrics["temperature/hall"]["timestamp"])
+
+	assert.Equal(t, "count", metrics["requests"]["type"])
+	assert.Equal(t, 3.0, metrics["requests"]["value"])
+	assert.NotZero(t, metrics["requests"]["timestamp"])
+	assert.NotZero(t, metrics["requests"]["interval.ms"])
+
+	assert.Equal(t, map[string]interface{}{"count": 3.0, "sum": 1.5, "min": 0.25, "max": 0.75}, metrics["duration"]["value"])
+	assert.NotZero(t, metrics["duration"]["interval.ms"])
+
+	assert.Error(t, client.RecordGauge("temperature", 1, nil))
+	assert.Error(t, client.Flush())
+	assert.Error(t, client.Close(ctx))
+
+	// Batch mode can be enabled again once closed.
+	require.NoError(t, client.BatchMode(ctx))
+	require.NoError(t, client.RecordCount("requests", 1, nil))
+	require.NoError(t, client.Close(ctx))
+	assert.Len(t, server.Payloads(), 2)
+}
+
+func TestBatchFlush(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, BatchConfigHarvestPeriod(time.Hour)))
+	require.NoError(t, client.RecordCount("requests", 1, nil))
+	require.NoError(t, client.Flush())
+
+	assert.Eventually(t, func() bool {
+		return client.BatchStats().Sent == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Nothing is left to send.
+	require.NoError(t, client.Close(ctx))
+	assert.Len(t, server.Payloads(), 1)
+}
+
+func TestBatchHarvestPeriod(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newTestClient(t, http.StatusAccepted)
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, BatchConfigHarvestPeriod(10*time.Millisecond)))
+	require.NoError(t, client.RecordGauge("temperature", 1, nil))
+
+	assert.Eventually(t, func() bool {
+		return client.BatchStats().Sent == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.Close(ctx))
+}
+
+func TestBatchSplitByPayloadSize(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+	ctx := context.Background()
+
+	gauge := Gauge{Name: "a", Value: 1, Timestamp: time.Unix(1600000000, 0)}
+	data, err := gauge.MarshalJSON()
+	require.NoError(t, err)
+
+	// Exactly two gauges fit in a payload.
+	require.NoError(t, client.BatchMode(ctx,
+		BatchConfigHarvestPeriod(time.Hour),
+		BatchConfigMaxPayloadSize(emptyPayloadSize(nil)+2*len(data)+len(",")),
+	))
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		gauge.Name = name
+		require.NoError(t, client.EnqueueMetric(gauge))
+	}
+
+	require.NoError(t, client.Close(ctx))
+
+	payloads := testPayloads(t, server)
+	require.Len(t, payloads, 3)
+	assert.Len(t, payloads[0].Metrics, 2)
+	assert.Len(t, payloads[1].Metrics, 2)
+	assert.Len(t, payloads[2].Metrics, 1)
+	assert.Equal(t, BatchStats{Sent: 5}, client.BatchStats())
+}
+
+func TestBatchErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newTestClient(t, http.StatusBadRequest)
+	ctx := context.Background()
+
+	var failed []Metric
+	require.NoError(t, client.BatchMode(ctx,
+		BatchConfigErrorHandler(func(metrics []Metric, err error) {
+			assert.Error(t, err)
+			failed = append(failed, metrics...)
+		}),
+	))
+
+	require.NoError(t, client.RecordCount("requests", 1, nil))
+	require.NoError(t, client.RecordCount("requests", 1, nil))
+
+	err := client.Close(ctx)
+	require.Error(t, err)
+	assert.Equal(t, "metrics: 1 metrics could not be sent and 0 were dropped", err.Error())
+
+	require.Len(t, failed, 1)
+	assert.Equal(t, 2.0, failed[0].(Count).Value)
+}
+
+func TestBatchDroppedOnCancel(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, client.BatchMode(ctx, BatchConfigHarvestPeriod(time.Hour)))
+	require.NoError(t, client.RecordCount("requests", 1, nil))
+	require.NoError(t, client.RecordGauge("temperature", 1, nil))
+
+	cancel()
+
+	err := client.Close(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "metrics: 0 metrics could not be sent and 2 were dropped", err.Error())
+	assert.Empty(t, server.Payloads())
+}
+
+func TestBatchConfigOptions(t *testing.T) {
+	t.Parallel()
+
+	m := New(mock.NewTestConfig(t, nil))
+
+	assert.Error(t, BatchConfigHarvestPeriod(0)(&m))
+	assert.NoError(t, BatchConfigHarvestPeriod(time.Second)(&m))
+	assert.Error(t, BatchConfigMaxPayloadSize(0)(&m))
+	assert.Error(t, BatchConfigMaxPayloadSize(MaxPayloadSize+1)(&m))
+	assert.NoError(t, BatchConfigMaxPayloadSize(MaxPayloadSize)(&m))
+	assert.Error(t, BatchConfigCommonAttributes(map[string]interface{}{"bad": func() {}})(&m))
+
+	assert.Error(t, m.RecordGauge("temperature", 1, nil))
+	assert.Error(t, m.Flush())
+	assert.Error(t, m.Close(context.Background()))
+	assert.Equal(t, BatchStats{}, m.BatchStats())
+
+	require.NoError(t, m.BatchMode(context.Background()))
+	assert.Error(t, m.BatchMode(context.Background()))
+	assert.Error(t, m.RecordGauge("", 1, nil))
+	assert.Error(t, m.EnqueueMetric(nil))
+	require.NoError(t, m.Close(context.Background()))
+}
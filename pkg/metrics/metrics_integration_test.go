@@ -0,0 +1,40 @@
+//go:build integration
+// +build integration
+
+// Requires NEW_RELIC_LICENSE_KEY envvar (APM License Key)
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	nr "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+func TestIntegrationMetrics(t *testing.T) {
+	t.Parallel()
+
+	client := newIntegrationTestClient(t)
+
+	common := &Common{
+		Timestamp:  time.Now(),
+		Interval:   10 * time.Second,
+		Attributes: map[string]interface{}{"test": "TestIntegrationMetrics"},
+	}
+
+	err := client.CreateMetrics(common,
+		Gauge{Name: "newrelic-client-go.test.gauge", Value: 1},
+		Count{Name: "newrelic-client-go.test.count", Value: 2},
+		Summary{Name: "newrelic-client-go.test.summary", Count: 2, Sum: 3, Min: 1, Max: 2},
+	)
+	assert.NoError(t, err)
+}
+
+func newIntegrationTestClient(t *testing.T) Metrics {
+	tc := nr.NewIntegrationTestConfig(t)
+
+	return New(tc)
+}
@@ -0,0 +1,139 @@
+//go:build unit
+// +build unit
+
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+// testPayload is a payload received by the test server.
+type testPayload struct {
+	Common  map[string]interface{}   `json:"common"`
+	Metrics []map[string]interface{} `json:"metrics"`
+}
+
+func newTestClient(t *testing.T, status int) (Metrics, *mock.IngestServer) {
+	server := mock.NewIngestServer(t, status)
+	cfg := mock.NewTestConfig(t, server.Server)
+
+	retryMax := 0
+	cfg.RetryMax = &retryMax
+
+	return New(cfg), server
+}
+
+// testPayloads decodes the payloads received by the test server.
+func testPayloads(t *testing.T, server *mock.IngestServer) []testPayload {
+	payloads := []testPayload{}
+
+	for _, data := range server.Payloads() {
+		var batch []testPayload
+		require.NoError(t, json.Unmarshal(data, &batch))
+		payloads = append(payloads, batch...)
+	}
+
+	return payloads
+}
+
+func TestCreateMetrics(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	timestamp := time.Unix(1600000000, 0)
+	common := &Common{
+		Timestamp:  timestamp,
+		Interval:   10 * time.Second,
+		Attributes: map[string]interface{}{"host.name": "test"},
+	}
+
+	err := client.CreateMetrics(common,
+		Gauge{Name: "temperature", Value: 21.5, Attributes: map[string]interface{}{"room": "kitchen"}},
+		Count{Name: "requests", Value: 3},
+		Summary{Name: "duration", Count: 2, Sum: 0.5, Min: 0.1, Max: 0.4, Timestamp: timestamp, Interval: time.Second},
+	)
+	require.NoError(t, err)
+
+	payloads := testPayloads(t, server)
+	require.Len(t, payloads, 1)
+
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":   float64(1600000000000),
+		"interval.ms": float64(10000),
+		"attributes":  map[string]interface{}{"host.name": "test"},
+	}, payloads[0].Common)
+
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "temperature", "type": "gauge", "value": 21.5, "attributes": map[string]interface{}{"room": "kitchen"}},
+		{"name": "requests", "type": "count", "value": float64(3)},
+		{
+			"name":        "duration",
+			"type":        "summary",
+			"value":       map[string]interface{}{"count": float64(2), "sum": 0.5, "min": 0.1, "max": 0.4},
+			"timestamp":   float64(1600000000000),
+			"interval.ms": float64(1000),
+		},
+	}, payloads[0].Metrics)
+
+	assert.Equal(t, mock.LicenseKey, server.Header().Get("X-License-Key"))
+}
+
+func TestCreateMetricsInsertKey(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	cfg := client.config
+	cfg.InsightsInsertKey = "insertKey"
+	client = New(cfg)
+
+	require.NoError(t, client.CreateMetrics(nil, Gauge{Name: "temperature", Value: 1}))
+
+	assert.Equal(t, "insertKey", server.Header().Get("Api-Key"))
+}
+
+func TestCreateMetricsInvalid(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	cases := map[string][]Metric{
+		"no metrics":       {},
+		"nil metric":       {nil},
+		"no name":          {Gauge{Value: 1}},
+		"count interval":   {Count{Name: "requests", Value: 1}},
+		"summary interval": {Summary{Name: "duration", Count: 1}},
+		"attributes":       {Gauge{Name: "temperature", Attributes: map[string]interface{}{"bad": func() {}}}},
+	}
+
+	for name, metrics := range cases {
+		err := client.CreateMetrics(nil, metrics...)
+		assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput), name)
+	}
+
+	err := client.CreateMetrics(nil, Gauge{Name: strings.Repeat("a", MaxPayloadSize)})
+	assert.True(t, errors.Is(err, nrErrors.ErrPayloadTooLarge))
+
+	assert.Empty(t, server.Payloads())
+}
+
+func TestCreateMetricsError(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newTestClient(t, http.StatusForbidden)
+
+	err := client.CreateMetrics(nil, Gauge{Name: "temperature", Value: 1})
+	assert.True(t, errors.Is(err, nrErrors.ErrForbidden))
+}
@@ -3,9 +3,11 @@ package nerdgraph
 
 import (
 	"context"
+	stderrors "errors"
 
 	"github.com/newrelic/newrelic-client-go/internal/http"
 	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
 	"github.com/newrelic/newrelic-client-go/pkg/logging"
 )
 
@@ -39,10 +41,16 @@ func (n *NerdGraph) Query(query string, variables map[string]interface{}) (inter
 
 // QueryWithContext facilitates making a NerdGraph request with a raw GraphQL query. Variables may be provided
 // in the form of a map. The response's data structure will vary based on the query provided.
+// When partial data is enabled, any data returned alongside errors is returned with an errors.GraphQLErrors.
 func (n *NerdGraph) QueryWithContext(ctx context.Context, query string, variables map[string]interface{}) (interface{}, error) {
 	respBody := QueryResponse{}
 
 	if err := n.QueryWithResponseAndContext(ctx, query, variables, &respBody); err != nil {
+		var gqlErrors *errors.GraphQLErrors
+		if stderrors.As(err, &gqlErrors) && gqlErrors.PartialData {
+			return respBody, err
+		}
+
 		return nil, err
 	}
 
@@ -5,6 +5,25 @@ the New Relic Query Language are available here:
 
 https://docs.newrelic.com/docs/query-data/nrql-new-relic-query-language/getting-started/introduction-nrql
 
+Building queries
+
+Queries can be assembled with Select and the Query builder methods instead of
+concatenating strings, so attribute names and literals are always quoted
+correctly.  Existing NRQL can be parsed into a Query with ParseQuery, which
+uses the parser in the nrql package.
+
+Large time ranges
+
+NRDB returns at most 5000 rows per query.  QueryRange runs a query over a
+time range in windows, shrinking them where there are too many rows, and
+returns every row through a RowIterator.
+
+Long-running queries
+
+Queries that would outlast the client's timeout can be run asynchronously with
+QueryAsync, which returns an AsyncQuery to Poll or Wait on, or with
+QueryAsyncAndWait, which blocks until the results are available.
+
 Authentication
 
 You will need a valid Personal API key to communicate with the backend New Relic
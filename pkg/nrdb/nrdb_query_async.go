@@ -0,0 +1,214 @@
+package nrdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultAsyncRetryAfter is how long to wait between polls when NerdGraph
+// doesn't say.
+const defaultAsyncRetryAfter = time.Second
+
+// NRDBQueryProgress is the progress of an asynchronous NRQL query.
+type NRDBQueryProgress struct {
+	// Completed is true once the query's results are available.
+	Completed bool `json:"completed"`
+	// QueryID identifies the query when polling for its results.
+	QueryID string `json:"queryId,omitempty"`
+	// RetryAfter is the number of seconds to wait before polling again.
+	RetryAfter int `json:"retryAfter,omitempty"`
+	// RetryDeadline is the number of seconds the query can be polled for.
+	RetryDeadline int `json:"retryDeadline,omitempty"`
+}
+
+// AsyncQuery is a handle to a NRQL query running asynchronously, created by
+// QueryAsync.  Its results are fetched by polling with Poll, or by blocking
+// until they are available with Wait.
+type AsyncQuery struct {
+	nrdb *Nrdb
+
+	AccountID int
+	Query     NRQL
+	Progress  NRDBQueryProgress
+
+	// deadline is when the query can no longer be polled, if NerdGraph said.
+	deadline time.Time
+	result   *NRDBResultContainer
+}
+
+// QueryAsync starts a NRQL query in NerdGraph's async mode, which returns
+// when the query completes or after a short while, whichever is first, so
+// queries running longer than the client's timeout don't fail.
+func (n *Nrdb) QueryAsync(accountID int, query NRQL) (*AsyncQuery, error) {
+	return n.QueryAsyncWithContext(context.Background(), accountID, query)
+}
+
+// QueryAsyncWithContext starts a NRQL query in NerdGraph's async mode.
+func (n *Nrdb) QueryAsyncWithContext(ctx context.Context, accountID int, query NRQL) (*AsyncQuery, error) {
+	respBody := gqlNrqlAsyncQueryResponse{}
+
+	vars := map[string]interface{}{
+		"accountId": accountID,
+		"query":     query,
+	}
+
+	if err := n.client.NerdGraphQueryWithContext(ctx, gqlNrqlAsyncQuery, vars, &respBody); err != nil {
+		return nil, err
+	}
+
+	q := &AsyncQuery{
+		nrdb:      n,
+		AccountID: accountID,
+		Query:     query,
+	}
+
+	q.update(&respBody.Actor.Account.NRQL)
+
+	return q, nil
+}
+
+// QueryAsyncAndWait runs a NRQL query in NerdGraph's async mode, and blocks
+// until its results are available.
+func (n *Nrdb) QueryAsyncAndWait(accountID int, query NRQL) (*NRDBResultContainer, error) {
+	return n.QueryAsyncAndWaitWithContext(context.Background(), accountID, query)
+}
+
+// QueryAsyncAndWaitWithContext runs a NRQL query in NerdGraph's async mode,
+// and blocks until its results are available or ctx is done.
+func (n *Nrdb) QueryAsyncAndWaitWithContext(ctx context.Context, accountID int, query NRQL) (*NRDBResultContainer, error) {
+	q, err := n.QueryAsyncWithContext(ctx, accountID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.WaitWithContext(ctx)
+}
+
+// Done reports whether the query has completed.
+func (q *AsyncQuery) Done() bool {
+	return q.Progress.Completed
+}
+
+// Result returns the results of the query, or nil until it has completed.
+func (q *AsyncQuery) Result() *NRDBResultContainer {
+	return q.result
+}
+
+// Poll asks NerdGraph for the progress of the query once, fetching its
+// results if it has completed.  Polling a completed query does nothing.
+func (q *AsyncQuery) Poll() error {
+	return q.PollWithContext(context.Background())
+}
+
+// PollWithContext asks NerdGraph for the progress of the query once.
+func (q *AsyncQuery) PollWithContext(ctx context.Context) error {
+	if q.Done() {
+		return nil
+	}
+
+	respBody := gqlNrqlAsyncQueryResponse{}
+
+	vars := map[string]interface{}{
+		"accountId": q.AccountID,
+		"queryId":   q.Progress.QueryID,
+	}
+
+	if err := q.nrdb.client.NerdGraphQueryWithContext(ctx, gqlNrqlQueryProgressQuery, vars, &respBody); err != nil {
+		return err
+	}
+
+	q.update(&respBody.Actor.Account.NRQLQueryProgress)
+
+	return nil
+}
+
+// Wait polls the query until it completes, waiting as long between polls as
+// NerdGraph asks, and returns its results.  It fails if the query can't be
+// polled again before its retry deadline.
+func (q *AsyncQuery) Wait() (*NRDBResultContainer, error) {
+	return q.WaitWithContext(context.Background())
+}
+
+// WaitWithContext polls the query until it completes or ctx is done.
+func (q *AsyncQuery) WaitWithContext(ctx context.Context) (*NRDBResultContainer, error) {
+	for !q.Done() {
+		wait := time.Duration(q.Progress.RetryAfter) * time.Second
+		if wait <= 0 {
+			wait = defaultAsyncRetryAfter
+		}
+
+		if !q.deadline.IsZero() && time.Now().Add(wait).After(q.deadline) {
+			return nil, fmt.Errorf("nrdb: query %s didn't complete before its retry deadline", q.Progress.QueryID)
+		}
+
+		if err := sleepWithContext(ctx, wait); err != nil {
+			return nil, err
+		}
+
+		if err := q.PollWithContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return q.result, nil
+}
+
+// update records the progress of the query, and its results once complete.
+func (q *AsyncQuery) update(resp *nrqlAsyncResult) {
+	q.Progress = resp.QueryProgress
+
+	// A query completing within the first request has no progress to report.
+	if resp.QueryProgress.QueryID == "" && !resp.QueryProgress.Completed {
+		q.Progress.Completed = true
+	}
+
+	if q.Progress.RetryDeadline > 0 {
+		q.deadline = time.Now().Add(time.Duration(q.Progress.RetryDeadline) * time.Second)
+	}
+
+	if q.Progress.Completed {
+		result := resp.NRDBResultContainer
+		q.result = &result
+	}
+}
+
+func sleepWithContext(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+const (
+	gqlNrqlAsyncQuery = `query($query: Nrql!, $accountId: Int!) { actor { account(id: $accountId) { nrql(query: $query, async: true) {
+    currentResults otherResult previousResults results totalResult
+    metadata { eventTypes facets messages timeWindow { begin compareWith end since until } }
+    queryProgress { queryId completed retryAfter retryDeadline }
+  } } } }`
+
+	gqlNrqlQueryProgressQuery = `query($queryId: ID!, $accountId: Int!) { actor { account(id: $accountId) { nrqlQueryProgress(queryId: $queryId) {
+    currentResults otherResult previousResults results totalResult
+    metadata { eventTypes facets messages timeWindow { begin compareWith end since until } }
+    queryProgress { queryId completed retryAfter retryDeadline }
+  } } } }`
+)
+
+type nrqlAsyncResult struct {
+	NRDBResultContainer
+	QueryProgress NRDBQueryProgress `json:"queryProgress"`
+}
+
+type gqlNrqlAsyncQueryResponse struct {
+	Actor struct {
+		Account struct {
+			NRQL              nrqlAsyncResult
+			NRQLQueryProgress nrqlAsyncResult
+		}
+	}
+}
@@ -0,0 +1,145 @@
+//go:build unit
+// +build unit
+
+package nrdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+// newFakeAsyncQuery returns a client whose async NRQL queries complete after
+// the given number of polls, asking to be polled every retryAfter seconds.
+func newFakeAsyncQuery(t *testing.T, polls int, retryAfter int, retryDeadline int) (*testhelpers.FakeNerdGraph, Nrdb) {
+	fake := testhelpers.NewFakeNerdGraph(t)
+
+	progress := func(completed bool) map[string]interface{} {
+		result := map[string]interface{}{
+			"queryProgress": map[string]interface{}{
+				"queryId":       "4f1e2b3c",
+				"completed":     completed,
+				"retryAfter":    retryAfter,
+				"retryDeadline": retryDeadline,
+			},
+		}
+
+		if completed {
+			result["results"] = []NRDBResult{{"count": 1204.0}}
+		}
+
+		return result
+	}
+
+	fake.Handle("actor.account.nrql", func(req *testhelpers.FakeNerdGraphRequest) (interface{}, error) {
+		var async bool
+		if err := req.DecodeArgument("async", &async); err != nil {
+			return nil, err
+		}
+
+		require.True(t, async)
+
+		return progress(polls == 0), nil
+	})
+
+	fake.Handle("actor.account.nrqlQueryProgress", func(req *testhelpers.FakeNerdGraphRequest) (interface{}, error) {
+		var queryID string
+		if err := req.DecodeArgument("queryId", &queryID); err != nil {
+			return nil, err
+		}
+
+		require.Equal(t, "4f1e2b3c", queryID)
+
+		polls--
+
+		return progress(polls <= 0), nil
+	})
+
+	return fake, New(fake.Config(t))
+}
+
+func TestQueryAsyncCompleted(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeAsyncQuery(t, 0, 1, 60)
+
+	q, err := client.QueryAsync(1, "SELECT count(*) FROM Transaction")
+	require.NoError(t, err)
+
+	assert.True(t, q.Done())
+	assert.Equal(t, []NRDBResult{{"count": 1204.0}}, q.Result().Results)
+
+	// Polling or waiting for a completed query doesn't send requests.
+	require.NoError(t, q.Poll())
+
+	result, err := q.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, q.Result(), result)
+	assert.Len(t, fake.Requests(), 1)
+}
+
+func TestQueryAsyncPoll(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAsyncQuery(t, 2, 1, 60)
+
+	q, err := client.QueryAsync(1, "SELECT count(*) FROM Transaction SINCE 1 year ago")
+	require.NoError(t, err)
+
+	assert.False(t, q.Done())
+	assert.Nil(t, q.Result())
+	assert.Equal(t, NRDBQueryProgress{QueryID: "4f1e2b3c", RetryAfter: 1, RetryDeadline: 60}, q.Progress)
+
+	require.NoError(t, q.Poll())
+	assert.False(t, q.Done())
+
+	require.NoError(t, q.Poll())
+	assert.True(t, q.Done())
+	assert.Equal(t, []NRDBResult{{"count": 1204.0}}, q.Result().Results)
+}
+
+func TestQueryAsyncAndWait(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeAsyncQuery(t, 1, 1, 60)
+
+	start := time.Now()
+
+	result, err := client.QueryAsyncAndWait(1, "SELECT count(*) FROM Transaction SINCE 1 year ago")
+	require.NoError(t, err)
+
+	assert.Equal(t, []NRDBResult{{"count": 1204.0}}, result.Results)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Second))
+	assert.Len(t, fake.Requests(), 2)
+}
+
+func TestQueryAsyncWaitDeadline(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeAsyncQuery(t, 1, 30, 10)
+
+	q, err := client.QueryAsync(1, "SELECT count(*) FROM Transaction SINCE 1 year ago")
+	require.NoError(t, err)
+
+	_, err = q.Wait()
+	assert.EqualError(t, err, "nrdb: query 4f1e2b3c didn't complete before its retry deadline")
+	assert.Len(t, fake.Requests(), 1)
+}
+
+func TestQueryAsyncWaitCanceled(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAsyncQuery(t, 1, 30, 60)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.QueryAsyncAndWaitWithContext(ctx, 1, "SELECT count(*) FROM Transaction SINCE 1 year ago")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -0,0 +1,296 @@
+package nrdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+// DefaultRangeLimit is the number of rows QueryRange asks for in each time
+// window, the most NRDB returns for a query.
+const DefaultRangeLimit = 5000
+
+// RangeConfigOption configures a RowIterator.
+type RangeConfigOption func(*RowIterator) error
+
+// RangeConfigWindow sets the size of the first time window queried, and the
+// largest windows grow back to after being shrunk.  The default is the whole
+// time range.
+func RangeConfigWindow(window time.Duration) RangeConfigOption {
+	return func(it *RowIterator) error {
+		if window < time.Millisecond {
+			return errors.New("nrdb: invalid window specified")
+		}
+
+		it.window = window.Truncate(time.Millisecond)
+		it.maxWindow = it.window
+
+		return nil
+	}
+}
+
+// RangeConfigMinWindow sets the smallest time window queried.  A window this
+// small which still has more rows than the limit fails the iterator.  The
+// default is a millisecond, the resolution of SINCE and UNTIL.
+func RangeConfigMinWindow(window time.Duration) RangeConfigOption {
+	return func(it *RowIterator) error {
+		if window < time.Millisecond {
+			return errors.New("nrdb: invalid minimum window specified")
+		}
+
+		it.minWindow = window.Truncate(time.Millisecond)
+
+		return nil
+	}
+}
+
+// RangeConfigLimit sets the number of rows asked for in each time window.
+// Windows returning this many rows are assumed to be missing rows, and are
+// queried again in halves.
+func RangeConfigLimit(limit int) RangeConfigOption {
+	return func(it *RowIterator) error {
+		if limit <= 0 || limit > DefaultRangeLimit {
+			return errors.New("nrdb: invalid limit specified")
+		}
+
+		it.limit = limit
+
+		return nil
+	}
+}
+
+// RowIterator streams the rows of a query over a time range.  It is created
+// by QueryRange, and used like sql.Rows:
+//
+//	it, err := client.QueryRange(ctx, accountID, "SELECT * FROM Transaction", since, until)
+//	if err != nil {
+//		return err
+//	}
+//
+//	for it.Next() {
+//		row := it.Row()
+//		...
+//	}
+//
+//	return it.Err()
+type RowIterator struct {
+	nrdb      *Nrdb
+	ctx       context.Context
+	accountID int
+	query     *Query
+	until     time.Time
+
+	// start is the beginning of the next window queried.
+	start     time.Time
+	window    time.Duration
+	maxWindow time.Duration
+	minWindow time.Duration
+	limit     int
+
+	// boundary holds the rows already returned at start, which the next
+	// window can return again.
+	boundary map[string]bool
+
+	rows []NRDBResult
+	row  NRDBResult
+	err  error
+}
+
+// QueryRange runs a query returning events over a time range too long for a
+// single query, by splitting the range into windows queried in turn, oldest
+// first.  Windows returning as many rows as the limit are split in half and
+// queried again, and windows grow back as rows thin out.  Rows at the
+// boundary of two windows are only returned once.
+//
+// The query must not have SINCE, UNTIL, COMPARE WITH, TIMESERIES, FACET or
+// LIMIT clauses, which QueryRange sets itself or can't split.  No query is
+// run until the iterator's Next is called, and ctx is used by every query.
+func (n *Nrdb) QueryRange(ctx context.Context, accountID int, query NRQL, since time.Time, until time.Time, opts ...RangeConfigOption) (*RowIterator, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if clause := rangeUnsupportedClause(q); clause != "" {
+		return nil, nrErrors.NewInvalidInputf("nrdb: QueryRange can't run queries with a %s clause", clause)
+	}
+
+	since = since.Truncate(time.Millisecond)
+	until = until.Truncate(time.Millisecond)
+
+	if !since.Before(until) {
+		return nil, nrErrors.NewInvalidInput("nrdb: QueryRange needs since to be before until")
+	}
+
+	it := &RowIterator{
+		nrdb:      n,
+		ctx:       ctx,
+		accountID: accountID,
+		query:     q,
+		until:     until,
+		start:     since,
+		window:    until.Sub(since),
+		maxWindow: until.Sub(since),
+		minWindow: time.Millisecond,
+		limit:     DefaultRangeLimit,
+		boundary:  map[string]bool{},
+	}
+
+	for _, opt := range opts {
+		if err := opt(it); err != nil {
+			return nil, err
+		}
+	}
+
+	if it.minWindow > it.window {
+		it.minWindow = it.window
+	}
+
+	q.Limit(it.limit)
+
+	return it, nil
+}
+
+// rangeUnsupportedClause returns the first clause of q QueryRange can't run.
+func rangeUnsupportedClause(q *Query) string {
+	switch {
+	case q.since != nil:
+		return "SINCE"
+	case q.until != nil:
+		return "UNTIL"
+	case q.compareWith != nil:
+		return "COMPARE WITH"
+	case q.timeseries != nil:
+		return "TIMESERIES"
+	case len(q.facets) > 0:
+		return "FACET"
+	case q.limit != "":
+		return "LIMIT"
+	default:
+		return ""
+	}
+}
+
+// Next moves to the next row, querying the next time window when the rows
+// of the last one run out.  It returns false at the end of the time range,
+// or when a query fails or ctx is done, which Err reports.
+func (it *RowIterator) Next() bool {
+	for len(it.rows) == 0 {
+		if it.err != nil || !it.start.Before(it.until) {
+			return false
+		}
+
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+
+		it.err = it.queryWindow()
+	}
+
+	it.row, it.rows = it.rows[0], it.rows[1:]
+
+	return true
+}
+
+// Row returns the current row.
+func (it *RowIterator) Row() NRDBResult {
+	return it.row
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *RowIterator) Err() error {
+	return it.err
+}
+
+func (it *RowIterator) queryWindow() error {
+	for {
+		end := it.start.Add(it.window)
+		if end.After(it.until) {
+			end = it.until
+		}
+
+		it.query.Since(At(it.start)).Until(At(end))
+
+		resp, err := it.nrdb.QueryWithContext(it.ctx, it.accountID, it.query.NRQL())
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Results) >= it.limit {
+			span := end.Sub(it.start)
+			if span <= it.minWindow {
+				return fmt.Errorf("nrdb: more than %d rows between %s and %s, which can't be split further",
+					it.limit, it.start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano))
+			}
+
+			it.window = (span / 2).Truncate(time.Millisecond)
+			if it.window < it.minWindow {
+				it.window = it.minWindow
+			}
+
+			continue
+		}
+
+		it.rows = it.dedupe(resp.Results, end)
+		it.start = end
+
+		if len(resp.Results) < it.limit/2 && it.window < it.maxWindow {
+			it.window *= 2
+			if it.window > it.maxWindow {
+				it.window = it.maxWindow
+			}
+		}
+
+		return nil
+	}
+}
+
+// dedupe drops the rows the last window already returned, and remembers the
+// rows at the end of this window for the next.
+func (it *RowIterator) dedupe(results []NRDBResult, end time.Time) []NRDBResult {
+	startMillis := it.start.UnixNano() / int64(time.Millisecond)
+	endMillis := end.UnixNano() / int64(time.Millisecond)
+
+	rows := make([]NRDBResult, 0, len(results))
+	boundary := map[string]bool{}
+
+	for _, result := range results {
+		timestamp, err := result.Float("timestamp")
+		if err != nil {
+			rows = append(rows, result)
+			continue
+		}
+
+		key := rowKey(result)
+
+		if int64(timestamp) == startMillis && it.boundary[key] {
+			continue
+		}
+
+		if int64(timestamp) == endMillis {
+			boundary[key] = true
+		}
+
+		rows = append(rows, result)
+	}
+
+	it.boundary = boundary
+
+	return rows
+}
+
+// rowKey identifies a row by its attributes.  encoding/json sorts the keys
+// of maps, so equal rows have equal keys.
+func rowKey(result NRDBResult) string {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Sprint(result)
+	}
+
+	return string(data)
+}
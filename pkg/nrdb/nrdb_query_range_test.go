@@ -0,0 +1,203 @@
+//go:build unit
+// +build unit
+
+package nrdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/nrql"
+	"github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+var testRangeSince = time.Unix(1600000000, 0)
+
+// newFakeEvents returns a client whose NRQL queries return the events between
+// SINCE and UNTIL, both inclusive, newest first and cut off at the LIMIT, the
+// way NRDB returns raw events.
+func newFakeEvents(t *testing.T, events []NRDBResult) (*testhelpers.FakeNerdGraph, Nrdb) {
+	fake := testhelpers.NewFakeNerdGraph(t)
+
+	fake.Handle("actor.account.nrql", func(req *testhelpers.FakeNerdGraphRequest) (interface{}, error) {
+		var query string
+		if err := req.DecodeArgument("query", &query); err != nil {
+			return nil, err
+		}
+
+		q, err := nrql.Parse(query)
+		if err != nil {
+			return nil, err
+		}
+
+		since, _ := strconv.ParseFloat(q.Since.Time.Text, 64)
+		until, _ := strconv.ParseFloat(q.Until.Time.Text, 64)
+
+		results := []NRDBResult{}
+		for _, event := range events {
+			if timestamp := event["timestamp"].(float64); timestamp >= since && timestamp <= until {
+				results = append(results, event)
+			}
+		}
+
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i]["timestamp"].(float64) > results[j]["timestamp"].(float64)
+		})
+
+		if len(results) > q.Limit.Count {
+			results = results[:q.Limit.Count]
+		}
+
+		return map[string]interface{}{"results": results}, nil
+	})
+
+	return fake, New(fake.Config(t))
+}
+
+func testEvent(id int, offset time.Duration) NRDBResult {
+	return NRDBResult{
+		"id":        fmt.Sprintf("event-%d", id),
+		"timestamp": float64(testRangeSince.Add(offset).UnixNano() / int64(time.Millisecond)),
+	}
+}
+
+func TestQueryRange(t *testing.T) {
+	t.Parallel()
+
+	events := []NRDBResult{}
+	for i := 0; i < 90; i++ {
+		events = append(events, testEvent(i, time.Duration(i)*40*time.Second))
+	}
+
+	// A burst of events in a single millisecond, and events at the
+	// boundaries of the windows queried.
+	for i := 90; i < 96; i++ {
+		events = append(events, testEvent(i, 20*time.Minute))
+	}
+
+	events = append(events, testEvent(96, 30*time.Minute), testEvent(97, 45*time.Minute), testEvent(98, 0),
+		testEvent(99, 61*time.Minute))
+
+	fake, client := newFakeEvents(t, events)
+
+	it, err := client.QueryRange(context.Background(), 1, "SELECT * FROM Transaction WHERE appName = 'api'",
+		testRangeSince, testRangeSince.Add(time.Hour), RangeConfigLimit(10), RangeConfigWindow(15*time.Minute))
+	require.NoError(t, err)
+
+	seen := map[string]int{}
+	for it.Next() {
+		seen[it.Row()["id"].(string)]++
+	}
+
+	require.NoError(t, it.Err())
+
+	// Every event in the hour is returned once.  Event 99 is after it.
+	assert.Len(t, seen, 99)
+	for id, count := range seen {
+		assert.Equal(t, 1, count, id)
+	}
+
+	assert.NotContains(t, seen, "event-99")
+
+	// Each query asked for its window, and the limit.
+	requests := fake.Requests()
+	require.NotEmpty(t, requests)
+
+	first, err := nrql.Parse(requests[0].Arguments["query"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatInt(testRangeSince.UnixNano()/int64(time.Millisecond), 10), first.Since.Time.Text)
+	assert.Equal(t, 10, first.Limit.Count)
+	assert.Equal(t, "api", first.Where.Condition.(*nrql.Binary).Right.(*nrql.Literal).Value)
+}
+
+func TestQueryRangeTooManyRows(t *testing.T) {
+	t.Parallel()
+
+	events := []NRDBResult{}
+	for i := 0; i < 5; i++ {
+		events = append(events, testEvent(i, time.Minute))
+	}
+
+	_, client := newFakeEvents(t, events)
+
+	it, err := client.QueryRange(context.Background(), 1, "SELECT * FROM Log", testRangeSince, testRangeSince.Add(time.Hour),
+		RangeConfigLimit(5), RangeConfigMinWindow(time.Second))
+	require.NoError(t, err)
+
+	assert.False(t, it.Next())
+	require.Error(t, it.Err())
+	assert.Regexp(t, "^nrdb: more than 5 rows between .* and .*, which can't be split further$", it.Err().Error())
+}
+
+func TestQueryRangeCanceled(t *testing.T) {
+	t.Parallel()
+
+	events := []NRDBResult{}
+	for i := 0; i < 20; i++ {
+		events = append(events, testEvent(i, time.Duration(i)*time.Minute))
+	}
+
+	fake, client := newFakeEvents(t, events)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	it, err := client.QueryRange(ctx, 1, "SELECT * FROM Log", testRangeSince, testRangeSince.Add(time.Hour),
+		RangeConfigWindow(5*time.Minute))
+	require.NoError(t, err)
+
+	require.True(t, it.Next())
+	cancel()
+
+	// The rows of the window already queried, both ends included, are
+	// returned, then no more windows are.
+	rows := 1
+	for it.Next() {
+		rows++
+	}
+
+	assert.Equal(t, 6, rows)
+	assert.True(t, errors.Is(it.Err(), context.Canceled))
+	assert.Len(t, fake.Requests(), 1)
+}
+
+func TestQueryRangeInvalid(t *testing.T) {
+	t.Parallel()
+
+	client := New(testhelpers.NewTestConfig(t, nil))
+	since, until := testRangeSince, testRangeSince.Add(time.Hour)
+
+	cases := map[NRQL]string{
+		"SELECT * FROM Log SINCE 1 day ago":         "nrdb: QueryRange can't run queries with a SINCE clause",
+		"SELECT count(*) FROM Log TIMESERIES":       "nrdb: QueryRange can't run queries with a TIMESERIES clause",
+		"SELECT count(*) FROM Log FACET host":       "nrdb: QueryRange can't run queries with a FACET clause",
+		"SELECT * FROM Log LIMIT 10":                "nrdb: QueryRange can't run queries with a LIMIT clause",
+		"SELECT * FROM Log COMPARE WITH 1 week ago": "nrdb: QueryRange can't run queries with a COMPARE WITH clause",
+		"SELECT * FROM Log UNTIL now":               "nrdb: QueryRange can't run queries with a UNTIL clause",
+	}
+
+	for query, message := range cases {
+		_, err := client.QueryRange(context.Background(), 1, query, since, until)
+		assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput), string(query))
+		assert.Contains(t, err.Error(), message, string(query))
+	}
+
+	_, err := client.QueryRange(context.Background(), 1, "SELECT * FROM Log", until, since)
+	assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput))
+
+	_, err = client.QueryRange(context.Background(), 1, "SELECT * FROM", since, until)
+	assert.Error(t, err)
+
+	for _, opt := range []RangeConfigOption{RangeConfigLimit(0), RangeConfigLimit(DefaultRangeLimit + 1), RangeConfigWindow(0), RangeConfigMinWindow(time.Microsecond)} {
+		_, err = client.QueryRange(context.Background(), 1, "SELECT * FROM Log", since, until, opt)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,531 @@
+package nrdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys NRDB adds to the results of TIMESERIES, COMPARE WITH and FACET
+// queries.
+const (
+	ResultBeginTimeSeconds = "beginTimeSeconds"
+	ResultEndTimeSeconds   = "endTimeSeconds"
+	ResultComparison       = "comparison"
+	ResultFacet            = "facet"
+)
+
+// TimeseriesPoint is a value of one bucket of a TIMESERIES query.  Value is
+// NaN for buckets without data.
+type TimeseriesPoint struct {
+	BeginTime time.Time
+	EndTime   time.Time
+	Value     float64
+}
+
+// Value returns a value of the result.  Values nested in objects, such as a
+// single percentile, can be read by joining the keys with a dot, as in
+// "percentile.duration.95".
+func (r NRDBResult) Value(key string) (interface{}, bool) {
+	if value, ok := r[key]; ok {
+		return value, true
+	}
+
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return nil, false
+	}
+
+	parent, ok := r.Value(key[:i])
+	if !ok {
+		return nil, false
+	}
+
+	object, ok := parent.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := object[key[i+1:]]
+
+	return value, ok
+}
+
+// Float returns a numeric value of the result.  Null values are NaN.
+func (r NRDBResult) Float(key string) (float64, error) {
+	value, ok := r.Value(key)
+	if !ok {
+		return 0, fmt.Errorf("nrdb: result has no %q", key)
+	}
+
+	if value == nil {
+		return math.NaN(), nil
+	}
+
+	f, err := toFloat(value)
+	if err != nil {
+		return 0, fmt.Errorf("nrdb: result %q: %w", key, err)
+	}
+
+	return f, nil
+}
+
+// TimeWindow returns the bucket of a TIMESERIES result.
+func (r NRDBResult) TimeWindow() (begin time.Time, end time.Time, ok bool) {
+	beginSeconds, beginErr := toFloat(r[ResultBeginTimeSeconds])
+	endSeconds, endErr := toFloat(r[ResultEndTimeSeconds])
+
+	if beginErr != nil || endErr != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return secondsToTime(beginSeconds), secondsToTime(endSeconds), true
+}
+
+// Facet returns the facet of a FACET result, with one value for each
+// attribute in Metadata.Facets.
+func (r NRDBResult) Facet() []string {
+	switch facet := r[ResultFacet].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values := make([]string, len(facet))
+		for i, value := range facet {
+			values[i] = facetString(value)
+		}
+
+		return values
+	default:
+		return []string{facetString(facet)}
+	}
+}
+
+// FacetKey returns the facet of a FACET result as a single string, with the
+// values of queries faceting by more than one attribute joined by ", ".
+func (r NRDBResult) FacetKey() string {
+	return strings.Join(r.Facet(), ", ")
+}
+
+// Decode decodes the result into the struct out points to.  Fields are read
+// from the key in their `nrdb` struct tag, or their name, ignoring case, if
+// they have none.  A tag of "-" skips the field.  Keys may name nested values as Value
+// does.
+//
+// Numbers decode into numeric fields, and into time.Time fields as
+// milliseconds since the epoch, or as seconds with the "seconds" tag option:
+//
+//	type Bucket struct {
+//		Begin    time.Time `nrdb:"beginTimeSeconds,seconds"`
+//		Duration float64   `nrdb:"average.duration"`
+//		P95      *float64  `nrdb:"percentile.duration.95"`
+//		Facet    []string  `nrdb:"facet"`
+//	}
+//
+// Missing and null values leave fields unchanged.
+func (r NRDBResult) Decode(out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nrdb: can't decode a result into %T, a struct pointer is required", out)
+	}
+
+	return r.decodeStruct(v.Elem())
+}
+
+func (r NRDBResult) decodeStruct(v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key, seconds := r.fieldKey(field.Name), false
+
+		if tag, ok := field.Tag.Lookup("nrdb"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+
+			if parts[0] != "" {
+				key = parts[0]
+			}
+
+			for _, option := range parts[1:] {
+				seconds = seconds || option == "seconds"
+			}
+		}
+
+		value, ok := r.Value(key)
+		if !ok || value == nil {
+			continue
+		}
+
+		if err := decodeValue(v.Field(i), value, seconds); err != nil {
+			return fmt.Errorf("nrdb: can't decode %q into %s: %w", key, field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// fieldKey returns the key of a field without a tag: its name, or the first
+// key matching its name regardless of case.
+func (r NRDBResult) fieldKey(name string) string {
+	if _, ok := r[name]; ok {
+		return name
+	}
+
+	for key := range r {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+
+	return name
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func decodeValue(dst reflect.Value, value interface{}, seconds bool) error {
+	if dst.Kind() == reflect.Ptr {
+		target := reflect.New(dst.Type().Elem())
+		if err := decodeValue(target.Elem(), value, seconds); err != nil {
+			return err
+		}
+
+		dst.Set(target)
+
+		return nil
+	}
+
+	if dst.Type() == timeType {
+		return decodeTime(dst, value, seconds)
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %T", value)
+		}
+
+		dst.SetString(s)
+
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected a boolean, got %T", value)
+		}
+
+		dst.SetBool(b)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(value)
+		if err != nil {
+			return err
+		}
+
+		dst.SetFloat(f)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := toFloat(value)
+		if err != nil {
+			return err
+		}
+
+		if f != math.Trunc(f) || dst.OverflowInt(int64(f)) {
+			return fmt.Errorf("%v doesn't fit in %s", f, dst.Type())
+		}
+
+		dst.SetInt(int64(f))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, err := toFloat(value)
+		if err != nil {
+			return err
+		}
+
+		if f < 0 || f != math.Trunc(f) || dst.OverflowUint(uint64(f)) {
+			return fmt.Errorf("%v doesn't fit in %s", f, dst.Type())
+		}
+
+		dst.SetUint(uint64(f))
+
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(value))
+
+	default:
+		// Slices, maps and structs, such as uniques() and percentile()
+		// results, go through encoding/json.
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal(data, dst.Addr().Interface())
+	}
+
+	return nil
+}
+
+func decodeTime(dst reflect.Value, value interface{}, seconds bool) error {
+	if s, ok := value.(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+
+		dst.Set(reflect.ValueOf(t))
+
+		return nil
+	}
+
+	f, err := toFloat(value)
+	if err != nil {
+		return err
+	}
+
+	if seconds {
+		dst.Set(reflect.ValueOf(secondsToTime(f)))
+	} else {
+		dst.Set(reflect.ValueOf(time.Unix(0, int64(f)*int64(time.Millisecond))))
+	}
+
+	return nil
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch value := value.(type) {
+	case float64:
+		return value, nil
+	case float32:
+		return float64(value), nil
+	case int:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case json.Number:
+		return value.Float64()
+	case string:
+		// Numbers may be sent as strings, such as "NaN".
+		return strconv.ParseFloat(value, 64)
+	default:
+		return 0, fmt.Errorf("expected a number, got %T", value)
+	}
+}
+
+func secondsToTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func facetString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+// DecodeResults decodes the results of a query into the slice of structs out
+// points to.  Each result is decoded with NRDBResult.Decode.
+func (r *NRDBResultContainer) DecodeResults(out interface{}) error {
+	return decodeResults(r.Results, out)
+}
+
+// DecodeComparison decodes the current and previous results of a COMPARE
+// WITH query into the slices of structs current and previous point to.
+func (r *NRDBResultContainer) DecodeComparison(current interface{}, previous interface{}) error {
+	currentResults, previousResults := r.Comparison()
+
+	if err := decodeResults(currentResults, current); err != nil {
+		return err
+	}
+
+	return decodeResults(previousResults, previous)
+}
+
+func decodeResults(results []NRDBResult, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("nrdb: can't decode results into %T, a slice pointer is required", out)
+	}
+
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	decoded := reflect.MakeSlice(slice.Type(), 0, len(results))
+
+	for i, result := range results {
+		elem := reflect.New(elemType)
+		if err := result.Decode(elem.Interface()); err != nil {
+			return fmt.Errorf("result %d: %w", i, err)
+		}
+
+		decoded = reflect.Append(decoded, elem.Elem())
+	}
+
+	slice.Set(decoded)
+
+	return nil
+}
+
+// Comparison returns the current and previous results of a COMPARE WITH
+// query.  Queries without TIMESERIES return them in CurrentResults and
+// PreviousResults, and TIMESERIES queries mark each result with a
+// "comparison" of "current" or "previous".
+func (r *NRDBResultContainer) Comparison() (current []NRDBResult, previous []NRDBResult) {
+	if len(r.CurrentResults) > 0 || len(r.PreviousResults) > 0 {
+		return r.CurrentResults, r.PreviousResults
+	}
+
+	for _, result := range r.Results {
+		switch result[ResultComparison] {
+		case "current":
+			current = append(current, result)
+		case "previous":
+			previous = append(previous, result)
+		}
+	}
+
+	return current, previous
+}
+
+// Timeseries returns a value of each result of a TIMESERIES query, in time
+// order.  Results of a COMPARE WITH query are rejected, use
+// ComparisonTimeseries for them.
+func (r *NRDBResultContainer) Timeseries(key string) ([]TimeseriesPoint, error) {
+	return timeseries(r.Results, key)
+}
+
+// ComparisonTimeseries returns a value of each current and previous result of
+// a COMPARE WITH ... TIMESERIES query, each in time order.
+func (r *NRDBResultContainer) ComparisonTimeseries(key string) (current []TimeseriesPoint, previous []TimeseriesPoint, err error) {
+	currentResults, previousResults := r.Comparison()
+
+	if current, err = timeseries(withoutComparison(currentResults), key); err != nil {
+		return nil, nil, err
+	}
+
+	if previous, err = timeseries(withoutComparison(previousResults), key); err != nil {
+		return nil, nil, err
+	}
+
+	return current, previous, nil
+}
+
+// FacetTimeseries returns a value of each result of a FACET ... TIMESERIES
+// query, in time order, keyed by facet as NRDBResult.FacetKey.  Results of a
+// COMPARE WITH query are rejected.
+func (r *NRDBResultContainer) FacetTimeseries(key string) (map[string][]TimeseriesPoint, error) {
+	byFacet := map[string][]NRDBResult{}
+	for _, result := range r.Results {
+		facet := result.FacetKey()
+		byFacet[facet] = append(byFacet[facet], result)
+	}
+
+	series := make(map[string][]TimeseriesPoint, len(byFacet))
+
+	for facet, results := range byFacet {
+		points, err := timeseries(results, key)
+		if err != nil {
+			return nil, err
+		}
+
+		series[facet] = points
+	}
+
+	return series, nil
+}
+
+// timeseries returns a value of each result, sorted by the start of the
+// result's time window.
+func timeseries(results []NRDBResult, key string) ([]TimeseriesPoint, error) {
+	points := make([]TimeseriesPoint, 0, len(results))
+
+	for i, result := range results {
+		begin, end, ok := result.TimeWindow()
+		if !ok {
+			return nil, fmt.Errorf("nrdb: result %d isn't a TIMESERIES result", i)
+		}
+
+		// The current and previous results of a comparison share their time
+		// windows, so they can't be told apart once in a single series.
+		if _, ok := result[ResultComparison]; ok {
+			return nil, fmt.Errorf("nrdb: result %d is a COMPARE WITH result, use ComparisonTimeseries", i)
+		}
+
+		value, err := result.Float(key)
+		if err != nil {
+			return nil, err
+		}
+
+		points = append(points, TimeseriesPoint{BeginTime: begin, EndTime: end, Value: value})
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].BeginTime.Before(points[j].BeginTime)
+	})
+
+	return points, nil
+}
+
+// withoutComparison returns copies of the results without their comparison
+// marker.
+func withoutComparison(results []NRDBResult) []NRDBResult {
+	copies := make([]NRDBResult, len(results))
+
+	for i, result := range results {
+		copies[i] = make(NRDBResult, len(result))
+		for k, v := range result {
+			if k != ResultComparison {
+				copies[i][k] = v
+			}
+		}
+	}
+
+	return copies
+}
+
+// FacetResults returns the results of a FACET query keyed by facet, as
+// NRDBResult.FacetKey.
+func (r *NRDBResultContainer) FacetResults() map[string]NRDBResult {
+	facets := make(map[string]NRDBResult, len(r.Results))
+	for _, result := range r.Results {
+		facets[result.FacetKey()] = result
+	}
+
+	return facets
+}
+
+// FacetValues returns a numeric value of each result of a FACET query, keyed
+// by facet as NRDBResult.FacetKey.
+func (r *NRDBResultContainer) FacetValues(key string) (map[string]float64, error) {
+	values := make(map[string]float64, len(r.Results))
+
+	for _, result := range r.Results {
+		value, err := result.Float(key)
+		if err != nil {
+			return nil, err
+		}
+
+		values[result.FacetKey()] = value
+	}
+
+	return values, nil
+}
+
+// FacetAttributes returns the attributes a FACET query faceted by, which
+// name the values of NRDBResult.Facet.
+func (r *NRDBResultContainer) FacetAttributes() []string {
+	return r.Metadata.Facets
+}
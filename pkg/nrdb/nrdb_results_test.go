@@ -0,0 +1,303 @@
+//go:build unit
+// +build unit
+
+package nrdb
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Hand-written responses in the shapes NerdGraph's actor.account.nrql field
+// returns, for aggregate, TIMESERIES, FACET and COMPARE WITH queries.  They
+// are not recorded from New Relic.
+var (
+	testAggregateResponseJSON = `{
+		"results": [{"average.duration": 0.0235, "count": 1204, "percentile.duration": {"95": 0.081, "99": 0.214}, "uniques.host": ["web-1", "web-2"], "latest.timestamp": 1600000123456}],
+		"metadata": {"eventTypes": ["Transaction"], "facets": null, "messages": [], "timeWindow": {"begin": 1599996523000, "end": 1600000123000, "since": "60 MINUTES AGO", "until": "NOW"}}
+	}`
+
+	testTimeseriesResponseJSON = `{
+		"results": [
+			{"beginTimeSeconds": 1600000000, "endTimeSeconds": 1600000060, "average.duration": 0.021},
+			{"beginTimeSeconds": 1600000060, "endTimeSeconds": 1600000120, "average.duration": null},
+			{"beginTimeSeconds": 1600000120, "endTimeSeconds": 1600000180, "average.duration": 0.034}
+		],
+		"metadata": {"eventTypes": ["Transaction"], "timeWindow": {"begin": 1600000000000, "end": 1600000180000}}
+	}`
+
+	testFacetResponseJSON = `{
+		"results": [
+			{"facet": ["checkout", "web-1"], "appName": "checkout", "host": "web-1", "count": 312},
+			{"facet": ["checkout", "web-2"], "appName": "checkout", "host": "web-2", "count": 298},
+			{"facet": ["search", "web-1"], "appName": "search", "host": "web-1", "count": 41}
+		],
+		"otherResult": {"count": 7},
+		"totalResult": {"count": 658},
+		"metadata": {"eventTypes": ["Transaction"], "facets": ["appName", "host"]}
+	}`
+
+	testFacetTimeseriesResponseJSON = `{
+		"results": [
+			{"beginTimeSeconds": 1600000000, "endTimeSeconds": 1600000060, "facet": "checkout", "appName": "checkout", "count": 10},
+			{"beginTimeSeconds": 1600000000, "endTimeSeconds": 1600000060, "facet": "search", "appName": "search", "count": 3},
+			{"beginTimeSeconds": 1600000060, "endTimeSeconds": 1600000120, "facet": "checkout", "appName": "checkout", "count": 12},
+			{"beginTimeSeconds": 1600000060, "endTimeSeconds": 1600000120, "facet": "search", "appName": "search", "count": 0}
+		],
+		"metadata": {"eventTypes": ["Transaction"], "facets": ["appName"]}
+	}`
+
+	testCompareWithResponseJSON = `{
+		"currentResults": [{"count": 1204}],
+		"previousResults": [{"count": 1100}],
+		"metadata": {"eventTypes": ["Transaction"], "timeWindow": {"compareWith": "1 WEEKS AGO"}}
+	}`
+
+	testCompareWithTimeseriesResponseJSON = `{
+		"results": [
+			{"beginTimeSeconds": 1600000000, "endTimeSeconds": 1600000060, "comparison": "current", "count": 20},
+			{"beginTimeSeconds": 1600000000, "endTimeSeconds": 1600000060, "comparison": "previous", "count": 18}
+		],
+		"metadata": {"eventTypes": ["Transaction"], "timeWindow": {"compareWith": "1 DAYS AGO"}}
+	}`
+)
+
+func unmarshalContainer(t *testing.T, response string) *NRDBResultContainer {
+	var container NRDBResultContainer
+	require.NoError(t, json.Unmarshal([]byte(response), &container))
+
+	return &container
+}
+
+func TestDecodeResults(t *testing.T) {
+	t.Parallel()
+
+	type aggregate struct {
+		Duration float64   `nrdb:"average.duration"`
+		Count    int       `nrdb:"count"`
+		P95      *float64  `nrdb:"percentile.duration.95"`
+		P50      *float64  `nrdb:"percentile.duration.50"`
+		Hosts    []string  `nrdb:"uniques.host"`
+		Latest   time.Time `nrdb:"latest.timestamp"`
+		Skipped  string    `nrdb:"-"`
+	}
+
+	var results []aggregate
+	require.NoError(t, unmarshalContainer(t, testAggregateResponseJSON).DecodeResults(&results))
+
+	p95 := 0.081
+	assert.Equal(t, []aggregate{{
+		Duration: 0.0235,
+		Count:    1204,
+		P95:      &p95,
+		Hosts:    []string{"web-1", "web-2"},
+		Latest:   time.Unix(1600000123, 456000000),
+	}}, results)
+
+	type bucket struct {
+		Begin    time.Time `nrdb:"beginTimeSeconds,seconds"`
+		Duration *float64  `nrdb:"average.duration"`
+	}
+
+	var buckets []bucket
+	require.NoError(t, unmarshalContainer(t, testTimeseriesResponseJSON).DecodeResults(&buckets))
+	require.Len(t, buckets, 3)
+	assert.Equal(t, time.Unix(1600000060, 0), buckets[1].Begin)
+	assert.Nil(t, buckets[1].Duration)
+
+	type facet struct {
+		Facet   []string
+		AppName string `nrdb:"appName"`
+		Count   uint16 `nrdb:"count"`
+	}
+
+	var facets []facet
+	require.NoError(t, unmarshalContainer(t, testFacetResponseJSON).DecodeResults(&facets))
+	assert.Equal(t, facet{Facet: []string{"search", "web-1"}, AppName: "search", Count: 41}, facets[2])
+}
+
+func TestDecodeResultErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		result  NRDBResult
+		out     interface{}
+		message string
+	}{
+		"not a pointer": {
+			result:  NRDBResult{},
+			out:     struct{}{},
+			message: "nrdb: can't decode a result into struct {}, a struct pointer is required",
+		},
+		"fraction into int": {
+			result:  NRDBResult{"count": 1.5},
+			out:     &struct{ Count int8 }{},
+			message: `nrdb: can't decode "count" into Count: 1.5 doesn't fit in int8`,
+		},
+		"overflow": {
+			result:  NRDBResult{"count": 300.0},
+			out:     &struct{ Count uint8 }{},
+			message: `nrdb: can't decode "count" into Count: 300 doesn't fit in uint8`,
+		},
+		"string into number": {
+			result:  NRDBResult{"name": "web"},
+			out:     &struct{ Name float64 }{},
+			message: `nrdb: can't decode "name" into Name: strconv.ParseFloat: parsing "web": invalid syntax`,
+		},
+		"number into string": {
+			result:  NRDBResult{"name": 1.0},
+			out:     &struct{ Name string }{},
+			message: `nrdb: can't decode "name" into Name: expected a string, got float64`,
+		},
+	}
+
+	for name, tc := range cases {
+		err := tc.result.Decode(tc.out)
+		require.Error(t, err, name)
+		assert.Equal(t, tc.message, err.Error(), name)
+	}
+
+	var notSlice []int
+	assert.Error(t, unmarshalContainer(t, testAggregateResponseJSON).DecodeResults(notSlice))
+}
+
+func TestTimeseries(t *testing.T) {
+	t.Parallel()
+
+	points, err := unmarshalContainer(t, testTimeseriesResponseJSON).Timeseries("average.duration")
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+
+	assert.Equal(t, TimeseriesPoint{BeginTime: time.Unix(1600000000, 0), EndTime: time.Unix(1600000060, 0), Value: 0.021}, points[0])
+	assert.True(t, math.IsNaN(points[1].Value))
+	assert.Equal(t, 0.034, points[2].Value)
+
+	_, err = unmarshalContainer(t, testTimeseriesResponseJSON).Timeseries("count")
+	assert.EqualError(t, err, `nrdb: result has no "count"`)
+
+	_, err = unmarshalContainer(t, testFacetResponseJSON).Timeseries("count")
+	assert.EqualError(t, err, "nrdb: result 0 isn't a TIMESERIES result")
+
+	_, err = unmarshalContainer(t, testCompareWithTimeseriesResponseJSON).Timeseries("count")
+	assert.EqualError(t, err, "nrdb: result 0 is a COMPARE WITH result, use ComparisonTimeseries")
+
+	points, err = unmarshalContainer(t, `{"results": [
+		{"beginTimeSeconds": 1600000060, "endTimeSeconds": 1600000120, "count": 2},
+		{"beginTimeSeconds": 1600000000, "endTimeSeconds": 1600000060, "count": 1}
+	]}`).Timeseries("count")
+	require.NoError(t, err)
+	assert.Equal(t, []TimeseriesPoint{
+		{BeginTime: time.Unix(1600000000, 0), EndTime: time.Unix(1600000060, 0), Value: 1},
+		{BeginTime: time.Unix(1600000060, 0), EndTime: time.Unix(1600000120, 0), Value: 2},
+	}, points)
+}
+
+func TestComparisonTimeseries(t *testing.T) {
+	t.Parallel()
+
+	current, previous, err := unmarshalContainer(t, testCompareWithTimeseriesResponseJSON).ComparisonTimeseries("count")
+	require.NoError(t, err)
+	assert.Equal(t, []TimeseriesPoint{{BeginTime: time.Unix(1600000000, 0), EndTime: time.Unix(1600000060, 0), Value: 20}}, current)
+	assert.Equal(t, []TimeseriesPoint{{BeginTime: time.Unix(1600000000, 0), EndTime: time.Unix(1600000060, 0), Value: 18}}, previous)
+
+	_, _, err = unmarshalContainer(t, testCompareWithResponseJSON).ComparisonTimeseries("count")
+	assert.EqualError(t, err, "nrdb: result 0 isn't a TIMESERIES result")
+}
+
+func TestFacets(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		response   string
+		attributes []string
+		values     map[string]float64
+	}{
+		"two attributes": {
+			response:   testFacetResponseJSON,
+			attributes: []string{"appName", "host"},
+			values:     map[string]float64{"checkout, web-1": 312, "checkout, web-2": 298, "search, web-1": 41},
+		},
+		"one attribute": {
+			response:   `{"results": [{"facet": "checkout", "count": 3}, {"facet": 404, "count": 1}], "metadata": {"facets": ["appName"]}}`,
+			attributes: []string{"appName"},
+			values:     map[string]float64{"checkout": 3, "404": 1},
+		},
+	}
+
+	for name, tc := range cases {
+		container := unmarshalContainer(t, tc.response)
+
+		assert.Equal(t, tc.attributes, container.FacetAttributes(), name)
+
+		values, err := container.FacetValues("count")
+		require.NoError(t, err, name)
+		assert.Equal(t, tc.values, values, name)
+	}
+
+	container := unmarshalContainer(t, testFacetResponseJSON)
+	facets := container.FacetResults()
+	assert.Equal(t, "web-2", facets["checkout, web-2"]["host"])
+	assert.Equal(t, []string{"checkout", "web-2"}, facets["checkout, web-2"].Facet())
+	assert.Equal(t, NRDBResult{"count": 7.0}, container.OtherResult)
+}
+
+func TestFacetTimeseries(t *testing.T) {
+	t.Parallel()
+
+	series, err := unmarshalContainer(t, testFacetTimeseriesResponseJSON).FacetTimeseries("count")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]TimeseriesPoint{
+		"checkout": {
+			{BeginTime: time.Unix(1600000000, 0), EndTime: time.Unix(1600000060, 0), Value: 10},
+			{BeginTime: time.Unix(1600000060, 0), EndTime: time.Unix(1600000120, 0), Value: 12},
+		},
+		"search": {
+			{BeginTime: time.Unix(1600000000, 0), EndTime: time.Unix(1600000060, 0), Value: 3},
+			{BeginTime: time.Unix(1600000060, 0), EndTime: time.Unix(1600000120, 0), Value: 0},
+		},
+	}, series)
+}
+
+func TestComparison(t *testing.T) {
+	t.Parallel()
+
+	type count struct {
+		Count int `nrdb:"count"`
+	}
+
+	cases := map[string]struct {
+		response string
+		current  []count
+		previous []count
+	}{
+		"aggregate": {
+			response: testCompareWithResponseJSON,
+			current:  []count{{1204}},
+			previous: []count{{1100}},
+		},
+		"timeseries": {
+			response: testCompareWithTimeseriesResponseJSON,
+			current:  []count{{20}},
+			previous: []count{{18}},
+		},
+	}
+
+	for name, tc := range cases {
+		var current, previous []count
+		require.NoError(t, unmarshalContainer(t, tc.response).DecodeComparison(&current, &previous), name)
+		assert.Equal(t, tc.current, current, name)
+		assert.Equal(t, tc.previous, previous, name)
+	}
+
+	current, previous := unmarshalContainer(t, testCompareWithTimeseriesResponseJSON).Comparison()
+	begin, _, ok := current[0].TimeWindow()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1600000000, 0), begin)
+	assert.Equal(t, "previous", previous[0][ResultComparison])
+}
@@ -0,0 +1,857 @@
+package nrdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrql"
+)
+
+// Query is a NRQL query assembled clause by clause, so that attribute names
+// and literals are always quoted correctly.  Start one with Select, and turn
+// it into NRQL with the Build method, which reports anything that can't be
+// written as NRQL:
+//
+//	query, err := nrdb.Select(nrdb.Average(nrdb.Attr("duration"))).
+//		From("Transaction").
+//		Where(nrdb.Eq("appName", "Bob's app")).
+//		Since(nrdb.Ago(1, nrdb.Hour)).
+//		Timeseries().
+//		Build()
+//	if err != nil {
+//		return err
+//	}
+//
+//	resp, err := client.Query(accountID, query)
+type Query struct {
+	selects     []Expr
+	from        []string
+	where       Expr
+	facets      []Expr
+	limit       string
+	since       *Moment
+	until       *Moment
+	compareWith *Moment
+	timeseries  *string
+	err         error
+}
+
+// Select starts a query selecting the given attributes or functions.
+func Select(exprs ...Expr) *Query {
+	return &Query{selects: exprs}
+}
+
+// From sets the event types the query reads from.
+func (q *Query) From(eventTypes ...string) *Query {
+	for _, eventType := range eventTypes {
+		q.fail(checkIdentifier(eventType))
+	}
+
+	q.from = append(q.from, eventTypes...)
+	return q
+}
+
+// Where filters the events the query reads.  Calling Where more than once
+// requires all of the conditions to match.
+func (q *Query) Where(condition Expr) *Query {
+	if q.where == nil {
+		q.where = condition
+	} else {
+		q.where = And(q.where, condition)
+	}
+
+	return q
+}
+
+// Facet groups the results by the given attributes or functions.
+func (q *Query) Facet(exprs ...Expr) *Query {
+	q.facets = append(q.facets, exprs...)
+	return q
+}
+
+// Limit sets the maximum number of results, or facets, returned.  It must be
+// at least 1.
+func (q *Query) Limit(limit int) *Query {
+	if limit < 1 {
+		q.fail(fmt.Errorf("nrdb: invalid limit %d, it must be at least 1", limit))
+	}
+
+	q.limit = strconv.Itoa(limit)
+	return q
+}
+
+// LimitMax returns as many results, or facets, as NRDB allows.
+func (q *Query) LimitMax() *Query {
+	q.limit = "MAX"
+	return q
+}
+
+// Since sets the start of the time range queried.
+func (q *Query) Since(moment Moment) *Query {
+	q.since = &moment
+	return q
+}
+
+// Until sets the end of the time range queried.
+func (q *Query) Until(moment Moment) *Query {
+	q.until = &moment
+	return q
+}
+
+// CompareWith compares the results with the same time range, starting at the
+// given moment.
+func (q *Query) CompareWith(moment Moment) *Query {
+	q.compareWith = &moment
+	return q
+}
+
+// Timeseries returns the results as a time series, with buckets chosen by
+// NRDB.
+func (q *Query) Timeseries() *Query {
+	bucket := ""
+	q.timeseries = &bucket
+	return q
+}
+
+// TimeseriesBy returns the results as a time series, with buckets of the
+// given size.  The count must be at least 1.
+func (q *Query) TimeseriesBy(count int, unit TimeUnit) *Query {
+	bucket := period{count: count, unit: unit}
+	q.fail(bucket.check())
+
+	text := bucket.String()
+	q.timeseries = &text
+	return q
+}
+
+// TimeseriesAuto returns the results as a time series, with buckets sized
+// for the time range queried.
+func (q *Query) TimeseriesAuto() *Query {
+	bucket := "AUTO"
+	q.timeseries = &bucket
+	return q
+}
+
+// Validate reports the first part of the query that can't be written as
+// NRQL, such as an empty SELECT, a LIMIT below 1, a NaN literal or an
+// attribute name containing a backtick.
+func (q *Query) Validate() error {
+	if q.err != nil {
+		return q.err
+	}
+
+	if len(q.selects) == 0 {
+		return errors.New("nrdb: query selects nothing")
+	}
+
+	exprs := append(append([]Expr{}, q.selects...), q.facets...)
+	if q.where != nil {
+		exprs = append(exprs, q.where)
+	}
+
+	if err := checkExprs(exprs); err != nil {
+		return err
+	}
+
+	for _, moment := range []*Moment{q.since, q.until, q.compareWith} {
+		if moment != nil && moment.err != nil {
+			return moment.err
+		}
+	}
+
+	return nil
+}
+
+// Build validates the query, then returns it as NRQL.
+func (q *Query) Build() (NRQL, error) {
+	if err := q.Validate(); err != nil {
+		return "", err
+	}
+
+	return q.NRQL(), nil
+}
+
+// fail records the first error found while the query is built.
+func (q *Query) fail(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+// String returns the query as NRQL, without validating it.
+func (q *Query) String() string {
+	clauses := []string{"SELECT " + joinExprs(q.selects)}
+
+	if len(q.from) > 0 {
+		eventTypes := make([]string, len(q.from))
+		for i, eventType := range q.from {
+			eventTypes[i] = quoteIdentifier(eventType)
+		}
+
+		clauses = append(clauses, "FROM "+strings.Join(eventTypes, ", "))
+	}
+
+	if q.where != nil {
+		clauses = append(clauses, "WHERE "+q.where.String())
+	}
+
+	if len(q.facets) > 0 {
+		clauses = append(clauses, "FACET "+joinExprs(q.facets))
+	}
+
+	if q.limit != "" {
+		clauses = append(clauses, "LIMIT "+q.limit)
+	}
+
+	if q.since != nil {
+		clauses = append(clauses, "SINCE "+q.since.String())
+	}
+
+	if q.until != nil {
+		clauses = append(clauses, "UNTIL "+q.until.String())
+	}
+
+	if q.compareWith != nil {
+		clauses = append(clauses, "COMPARE WITH "+q.compareWith.String())
+	}
+
+	if q.timeseries != nil {
+		clauses = append(clauses, strings.TrimSpace("TIMESERIES "+*q.timeseries))
+	}
+
+	return strings.Join(clauses, " ")
+}
+
+// NRQL returns the query as NRQL, without validating it.  Use Build to
+// catch queries that can't be run.
+func (q *Query) NRQL() NRQL {
+	return NRQL(q.String())
+}
+
+// TimeUnit is a unit of time used by SINCE, UNTIL, COMPARE WITH and
+// TIMESERIES.
+type TimeUnit string
+
+// The units of time NRQL understands.
+const (
+	Second TimeUnit = "second"
+	Minute TimeUnit = "minute"
+	Hour   TimeUnit = "hour"
+	Day    TimeUnit = "day"
+	Week   TimeUnit = "week"
+	Month  TimeUnit = "month"
+)
+
+// Moment is a point in time used by SINCE, UNTIL and COMPARE WITH.
+type Moment struct {
+	text string
+	err  error
+}
+
+// Ago is the moment the given amount of time before now.  The count must be
+// at least 1.
+func Ago(count int, unit TimeUnit) Moment {
+	p := period{count: count, unit: unit}
+	return Moment{text: p.String() + " ago", err: p.check()}
+}
+
+// At is the given moment, with millisecond precision.
+func At(t time.Time) Moment {
+	return Moment{text: strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)}
+}
+
+// Now is the moment the query runs.
+func Now() Moment {
+	return Moment{text: "now"}
+}
+
+// String returns the moment as NRQL.
+func (m Moment) String() string {
+	return m.text
+}
+
+// Expr is an attribute, literal, function or condition in a query.  Exprs
+// are only made by this package, so they are always quoted correctly.  Those
+// that can't be written as NRQL are reported by Query.Validate.
+type Expr interface {
+	String() string
+	isExpr()
+	check() error
+}
+
+// Attr is the attribute with the given name.  Names that need it are quoted
+// with backticks.  Names can't contain a backtick, as NRQL can't quote them.
+func Attr(name string) Expr {
+	return attribute(name)
+}
+
+// Lit is the given value as a NRQL literal.  Strings are quoted and escaped,
+// nil is NULL, and expressions are returned as they are.  NaN and infinite
+// numbers have no NRQL literal, so they are reported by Query.Validate.
+func Lit(value interface{}) Expr {
+	switch value := value.(type) {
+	case Expr:
+		return value
+	case nil:
+		return literal("NULL")
+	case string:
+		return literal(quoteString(value))
+	case bool:
+		return literal(strings.ToUpper(strconv.FormatBool(value)))
+	case int:
+		return literal(strconv.FormatInt(int64(value), 10))
+	case int8:
+		return literal(strconv.FormatInt(int64(value), 10))
+	case int16:
+		return literal(strconv.FormatInt(int64(value), 10))
+	case int32:
+		return literal(strconv.FormatInt(int64(value), 10))
+	case int64:
+		return literal(strconv.FormatInt(value, 10))
+	case uint:
+		return literal(strconv.FormatUint(uint64(value), 10))
+	case uint8:
+		return literal(strconv.FormatUint(uint64(value), 10))
+	case uint16:
+		return literal(strconv.FormatUint(uint64(value), 10))
+	case uint32:
+		return literal(strconv.FormatUint(uint64(value), 10))
+	case uint64:
+		return literal(strconv.FormatUint(value, 10))
+	case float32:
+		return float(float64(value), 32)
+	case float64:
+		return float(value, 64)
+	case json.Number:
+		if _, err := strconv.ParseFloat(value.String(), 64); err != nil {
+			return literal(quoteString(value.String()))
+		}
+
+		return literal(value.String())
+	case time.Time:
+		return literal(strconv.FormatInt(value.UnixNano()/int64(time.Millisecond), 10))
+	default:
+		return literal(quoteString(fmt.Sprint(value)))
+	}
+}
+
+// Star is the * used by SELECT * and count(*).
+func Star() Expr {
+	return star{}
+}
+
+// Func is a call to the named NRQL function.  Arguments which aren't
+// expressions are converted with Lit.
+func Func(name string, args ...interface{}) Expr {
+	exprs := make([]Expr, len(args))
+	for i, arg := range args {
+		exprs[i] = Lit(arg)
+	}
+
+	return call{name: name, args: exprs}
+}
+
+// Count is count(*).
+func Count() Expr {
+	return Func("count", Star())
+}
+
+// Average is average(expr).
+func Average(expr Expr) Expr {
+	return Func("average", expr)
+}
+
+// Sum is sum(expr).
+func Sum(expr Expr) Expr {
+	return Func("sum", expr)
+}
+
+// Min is min(expr).
+func Min(expr Expr) Expr {
+	return Func("min", expr)
+}
+
+// Max is max(expr).
+func Max(expr Expr) Expr {
+	return Func("max", expr)
+}
+
+// Latest is latest(expr).
+func Latest(expr Expr) Expr {
+	return Func("latest", expr)
+}
+
+// UniqueCount is uniqueCount(exprs...).
+func UniqueCount(exprs ...Expr) Expr {
+	return call{name: "uniqueCount", args: exprs}
+}
+
+// Uniques is uniques(expr).
+func Uniques(expr Expr) Expr {
+	return Func("uniques", expr)
+}
+
+// Percentile is percentile(expr, percentiles...).
+func Percentile(expr Expr, percentiles ...float64) Expr {
+	args := []Expr{expr}
+	for _, percentile := range percentiles {
+		args = append(args, Lit(percentile))
+	}
+
+	return call{name: "percentile", args: args}
+}
+
+// Filter is filter(expr, WHERE condition).
+func Filter(expr Expr, condition Expr) Expr {
+	return call{name: "filter", args: []Expr{expr, whereArg{condition: condition}}}
+}
+
+// Percentage is percentage(expr, WHERE condition).
+func Percentage(expr Expr, condition Expr) Expr {
+	return call{name: "percentage", args: []Expr{expr, whereArg{condition: condition}}}
+}
+
+// Rate is rate(expr, count unit).
+func Rate(expr Expr, count int, unit TimeUnit) Expr {
+	return call{name: "rate", args: []Expr{expr, period{count: count, unit: unit}}}
+}
+
+// As names a selected or faceted expression in the results.
+func As(expr Expr, name string) Expr {
+	return alias{expr: expr, name: name}
+}
+
+// Eq is attribute = value.
+func Eq(attributeName string, value interface{}) Expr {
+	return binary{op: "=", left: Attr(attributeName), right: Lit(value)}
+}
+
+// NotEq is attribute != value.
+func NotEq(attributeName string, value interface{}) Expr {
+	return binary{op: "!=", left: Attr(attributeName), right: Lit(value)}
+}
+
+// Lt is attribute < value.
+func Lt(attributeName string, value interface{}) Expr {
+	return binary{op: "<", left: Attr(attributeName), right: Lit(value)}
+}
+
+// Lte is attribute <= value.
+func Lte(attributeName string, value interface{}) Expr {
+	return binary{op: "<=", left: Attr(attributeName), right: Lit(value)}
+}
+
+// Gt is attribute > value.
+func Gt(attributeName string, value interface{}) Expr {
+	return binary{op: ">", left: Attr(attributeName), right: Lit(value)}
+}
+
+// Gte is attribute >= value.
+func Gte(attributeName string, value interface{}) Expr {
+	return binary{op: ">=", left: Attr(attributeName), right: Lit(value)}
+}
+
+// Like is attribute LIKE pattern.
+func Like(attributeName string, pattern string) Expr {
+	return binary{op: "LIKE", left: Attr(attributeName), right: Lit(pattern)}
+}
+
+// NotLike is attribute NOT LIKE pattern.
+func NotLike(attributeName string, pattern string) Expr {
+	return binary{op: "NOT LIKE", left: Attr(attributeName), right: Lit(pattern)}
+}
+
+// In is attribute IN (values...).
+func In(attributeName string, values ...interface{}) Expr {
+	return inList{expr: Attr(attributeName), values: literals(values)}
+}
+
+// NotIn is attribute NOT IN (values...).
+func NotIn(attributeName string, values ...interface{}) Expr {
+	return inList{expr: Attr(attributeName), not: true, values: literals(values)}
+}
+
+// IsNull is attribute IS NULL.
+func IsNull(attributeName string) Expr {
+	return isValue{expr: Attr(attributeName), value: "NULL"}
+}
+
+// IsNotNull is attribute IS NOT NULL.
+func IsNotNull(attributeName string) Expr {
+	return isValue{expr: Attr(attributeName), not: true, value: "NULL"}
+}
+
+// And requires all of the conditions to match.
+func And(conditions ...Expr) Expr {
+	return chain("AND", conditions)
+}
+
+// Or requires any of the conditions to match.
+func Or(conditions ...Expr) Expr {
+	return chain("OR", conditions)
+}
+
+// Not requires the condition not to match.
+func Not(condition Expr) Expr {
+	return unary{op: "NOT", expr: condition}
+}
+
+// Arithmetic is left op right, where op is one of +, -, * or /.
+func Arithmetic(left Expr, op string, right Expr) Expr {
+	return binary{op: op, left: left, right: right}
+}
+
+func float(value float64, bitSize int) Expr {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return invalid{err: fmt.Errorf("nrdb: %v has no NRQL literal", value)}
+	}
+
+	return literal(strconv.FormatFloat(value, 'f', -1, bitSize))
+}
+
+func literals(values []interface{}) []Expr {
+	exprs := make([]Expr, len(values))
+	for i, value := range values {
+		exprs[i] = Lit(value)
+	}
+
+	return exprs
+}
+
+func chain(op string, conditions []Expr) Expr {
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	expr := conditions[0]
+	for _, condition := range conditions[1:] {
+		expr = binary{op: op, left: expr, right: condition}
+	}
+
+	return expr
+}
+
+// checkExprs returns the first error found in the expressions.
+func checkExprs(exprs []Expr) error {
+	for _, expr := range exprs {
+		if expr == nil {
+			return errors.New("nrdb: missing expression")
+		}
+
+		if err := expr.check(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func joinExprs(exprs []Expr) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = expr.String()
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// identifierPattern matches the attribute names and event types that don't
+// need quoting.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.:]*$`)
+
+// checkIdentifier rejects names NRQL can't quote.  NRQL has no escape for
+// a backtick inside a backtick quoted name.
+func checkIdentifier(name string) error {
+	if name == "" {
+		return errors.New("nrdb: empty attribute name or event type")
+	}
+
+	if strings.Contains(name, "`") {
+		return fmt.Errorf("nrdb: %q contains a backtick, which NRQL can't quote", name)
+	}
+
+	return nil
+}
+
+func quoteIdentifier(name string) string {
+	if identifierPattern.MatchString(name) && !nrql.IsKeyword(name) {
+		return name
+	}
+
+	return "`" + name + "`"
+}
+
+func quoteString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+
+	return "'" + value + "'"
+}
+
+// Operator precedence, loosest first.
+const (
+	precedenceOr = iota + 1
+	precedenceAnd
+	precedenceNot
+	precedenceComparison
+	precedenceAdditive
+	precedenceMultiplicative
+	precedenceUnary
+	precedencePrimary
+)
+
+func precedence(expr Expr) int {
+	switch expr := expr.(type) {
+	case binary:
+		return binaryPrecedence(expr.op)
+	case unary:
+		if expr.op == "NOT" {
+			return precedenceNot
+		}
+
+		return precedenceUnary
+	case inList, isValue:
+		return precedenceComparison
+	default:
+		return precedencePrimary
+	}
+}
+
+func binaryPrecedence(op string) int {
+	switch op {
+	case "OR":
+		return precedenceOr
+	case "AND":
+		return precedenceAnd
+	case "+", "-":
+		return precedenceAdditive
+	case "*", "/":
+		return precedenceMultiplicative
+	default:
+		return precedenceComparison
+	}
+}
+
+// wrap parenthesizes expr when it binds looser than minimum.
+func wrap(expr Expr, minimum int) string {
+	if precedence(expr) < minimum {
+		return "(" + expr.String() + ")"
+	}
+
+	return expr.String()
+}
+
+type attribute string
+
+func (attribute) isExpr() {}
+
+func (a attribute) String() string {
+	return quoteIdentifier(string(a))
+}
+
+func (a attribute) check() error {
+	return checkIdentifier(string(a))
+}
+
+type literal string
+
+func (literal) isExpr() {}
+
+func (l literal) String() string {
+	return string(l)
+}
+
+func (literal) check() error {
+	return nil
+}
+
+// invalid is a value that can't be written as NRQL.
+type invalid struct {
+	err error
+}
+
+func (invalid) isExpr() {}
+
+func (invalid) String() string {
+	return "NULL"
+}
+
+func (i invalid) check() error {
+	return i.err
+}
+
+type star struct{}
+
+func (star) isExpr() {}
+
+func (star) String() string {
+	return "*"
+}
+
+func (star) check() error {
+	return nil
+}
+
+type call struct {
+	name string
+	args []Expr
+}
+
+func (call) isExpr() {}
+
+func (c call) String() string {
+	return c.name + "(" + joinExprs(c.args) + ")"
+}
+
+func (c call) check() error {
+	return checkExprs(c.args)
+}
+
+type alias struct {
+	expr Expr
+	name string
+}
+
+func (alias) isExpr() {}
+
+func (a alias) String() string {
+	return a.expr.String() + " AS " + quoteString(a.name)
+}
+
+func (a alias) check() error {
+	return checkExprs([]Expr{a.expr})
+}
+
+type whereArg struct {
+	condition Expr
+}
+
+func (whereArg) isExpr() {}
+
+func (w whereArg) String() string {
+	return "WHERE " + w.condition.String()
+}
+
+func (w whereArg) check() error {
+	return checkExprs([]Expr{w.condition})
+}
+
+type period struct {
+	count int
+	unit  TimeUnit
+}
+
+func (period) isExpr() {}
+
+func (p period) String() string {
+	text := strconv.Itoa(p.count) + " " + string(p.unit)
+	if p.count != 1 {
+		text += "s"
+	}
+
+	return text
+}
+
+func (p period) check() error {
+	if p.count < 1 {
+		return fmt.Errorf("nrdb: invalid period %d %s, the count must be at least 1", p.count, p.unit)
+	}
+
+	return nil
+}
+
+type binary struct {
+	op    string
+	left  Expr
+	right Expr
+}
+
+func (binary) isExpr() {}
+
+func (b binary) String() string {
+	p := binaryPrecedence(b.op)
+
+	// Comparisons don't chain, so a comparison on either side needs
+	// parentheses.  Everything else is left associative.
+	left := p
+	if p == precedenceComparison {
+		left++
+	}
+
+	return wrap(b.left, left) + " " + b.op + " " + wrap(b.right, p+1)
+}
+
+func (b binary) check() error {
+	return checkExprs([]Expr{b.left, b.right})
+}
+
+type unary struct {
+	op   string
+	expr Expr
+}
+
+func (unary) isExpr() {}
+
+func (u unary) String() string {
+	if u.op == "NOT" {
+		return "NOT " + wrap(u.expr, precedenceNot)
+	}
+
+	return u.op + wrap(u.expr, precedenceUnary)
+}
+
+func (u unary) check() error {
+	return checkExprs([]Expr{u.expr})
+}
+
+type inList struct {
+	expr   Expr
+	not    bool
+	values []Expr
+}
+
+func (inList) isExpr() {}
+
+func (i inList) String() string {
+	op := " IN "
+	if i.not {
+		op = " NOT IN "
+	}
+
+	return wrap(i.expr, precedenceAdditive) + op + "(" + joinExprs(i.values) + ")"
+}
+
+func (i inList) check() error {
+	if len(i.values) == 0 {
+		return errors.New("nrdb: IN needs at least one value")
+	}
+
+	return checkExprs(append([]Expr{i.expr}, i.values...))
+}
+
+// isValue is expr IS [NOT] NULL, TRUE or FALSE.
+type isValue struct {
+	expr  Expr
+	not   bool
+	value string
+}
+
+func (isValue) isExpr() {}
+
+func (i isValue) String() string {
+	if i.not {
+		return wrap(i.expr, precedenceAdditive) + " IS NOT " + i.value
+	}
+
+	return wrap(i.expr, precedenceAdditive) + " IS " + i.value
+}
+
+func (i isValue) check() error {
+	return checkExprs([]Expr{i.expr})
+}
@@ -0,0 +1,266 @@
+//go:build unit
+// +build unit
+
+package nrdb
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrql"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query    *Query
+		expected NRQL
+	}{
+		"timeseries": {
+			query: Select(Average(Attr("duration"))).
+				From("Transaction").
+				Where(Eq("appName", "Example application")).
+				Since(Ago(1, Hour)).
+				Timeseries(),
+			expected: "SELECT average(duration) FROM Transaction WHERE appName = 'Example application' SINCE 1 hour ago TIMESERIES",
+		},
+		"facets": {
+			query: Select(Count(), As(Percentile(Attr("duration"), 95, 99.5), "latency")).
+				From("Transaction", "PageView").
+				Where(In("host", "web-1", "web-2")).
+				Where(Or(Gt("duration", 1.5), IsNotNull("error.message"))).
+				Facet(Attr("appName"), Attr("request.uri")).
+				LimitMax().
+				Since(Ago(1, Day)).
+				CompareWith(Ago(1, Week)),
+			expected: "SELECT count(*), percentile(duration, 95, 99.5) AS 'latency' FROM Transaction, PageView " +
+				"WHERE host IN ('web-1', 'web-2') AND (duration > 1.5 OR error.message IS NOT NULL) " +
+				"FACET appName, request.uri LIMIT MAX SINCE 1 day ago COMPARE WITH 1 week ago",
+		},
+		"quoting": {
+			query: Select(Uniques(Attr("user name")), Latest(Attr("from"))).
+				From("My Events").
+				Where(And(Eq("name", `Bob's "app" \ prod`), NotLike("path", "%/health%"), Not(Eq("enabled", true)))).
+				Limit(10),
+			expected: "SELECT uniques(`user name`), latest(`from`) FROM `My Events` " +
+				`WHERE name = 'Bob\'s "app" \\ prod' AND path NOT LIKE '%/health%' AND NOT enabled = TRUE LIMIT 10`,
+		},
+		"functions": {
+			query: Select(
+				Filter(Count(), Eq("error", true)),
+				Percentage(Count(), NotEq("response.status", "200")),
+				Rate(Sum(Attr("bytes")), 1, Minute),
+				Arithmetic(Max(Attr("duration")), "*", Lit(1000)),
+				Func("histogram", Attr("duration"), 10, 20),
+			).
+				From("Transaction").
+				Where(Lte("duration", json.Number("2.5"))).
+				Since(At(time.Unix(1600000000, 0))).
+				Until(Now()).
+				TimeseriesBy(5, Minute),
+			expected: "SELECT filter(count(*), WHERE error = TRUE), percentage(count(*), WHERE response.status != '200'), " +
+				"rate(sum(bytes), 1 minute), max(duration) * 1000, histogram(duration, 10, 20) FROM Transaction " +
+				"WHERE duration <= 2.5 SINCE 1600000000000 UNTIL now TIMESERIES 5 minutes",
+		},
+		"literals": {
+			query: Select(Star()).
+				From("Log").
+				Where(And(Eq("a", nil), Gte("b", int64(-3)), Lt("c", uint8(7)), Eq("d", json.Number("1 OR 1=1")), IsNull("e"))).
+				TimeseriesAuto(),
+			expected: "SELECT * FROM Log WHERE a = NULL AND b >= -3 AND c < 7 AND d = '1 OR 1=1' AND e IS NULL TIMESERIES AUTO",
+		},
+	}
+
+	for name, tc := range cases {
+		query, err := tc.query.Build()
+		require.NoError(t, err, name)
+		assert.Equal(t, tc.expected, query, name)
+
+		parsed, err := ParseQuery(query)
+		require.NoError(t, err, name)
+		assert.Equal(t, tc.query.String(), parsed.String(), name)
+	}
+}
+
+func TestQueryBuilderValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query    *Query
+		expected string
+	}{
+		"no select": {
+			query:    Select().From("Transaction"),
+			expected: "selects nothing",
+		},
+		"backtick attribute": {
+			query:    Select(Latest(Attr("x`y"))).From("Transaction"),
+			expected: "backtick",
+		},
+		"backtick event type": {
+			query:    Select(Count()).From("a`b"),
+			expected: "backtick",
+		},
+		"backtick condition": {
+			query:    Select(Count()).From("Transaction").Where(Not(Eq("x`y", 1))),
+			expected: "backtick",
+		},
+		"empty attribute": {
+			query:    Select(Count()).From("Transaction").Facet(Attr("")),
+			expected: "empty attribute",
+		},
+		"NaN": {
+			query:    Select(Count()).From("Transaction").Where(Gt("duration", math.NaN())),
+			expected: "NaN has no NRQL literal",
+		},
+		"infinity": {
+			query:    Select(Max(Arithmetic(Attr("duration"), "*", Lit(float32(math.Inf(-1)))))).From("Transaction"),
+			expected: "-Inf has no NRQL literal",
+		},
+		"empty IN": {
+			query:    Select(Count()).From("Transaction").Where(In("host")),
+			expected: "at least one value",
+		},
+		"empty condition": {
+			query:    Select(Count()).From("Transaction").Where(Eq("a", 1)).Where(And()),
+			expected: "missing expression",
+		},
+		"negative limit": {
+			query:    Select(Count()).From("Transaction").Limit(-5),
+			expected: "invalid limit -5",
+		},
+		"zero timeseries": {
+			query:    Select(Count()).From("Transaction").TimeseriesBy(0, Minute),
+			expected: "invalid period 0 minute",
+		},
+		"negative since": {
+			query:    Select(Count()).From("Transaction").Since(Ago(-1, Hour)),
+			expected: "invalid period -1 hour",
+		},
+		"zero rate": {
+			query:    Select(Rate(Count(), 0, Second)).From("Transaction"),
+			expected: "invalid period 0 second",
+		},
+	}
+
+	for name, tc := range cases {
+		query, err := tc.query.Build()
+		assert.Empty(t, query, name)
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), tc.expected, name)
+	}
+}
+
+func TestParseQueryCorpus(t *testing.T) {
+	t.Parallel()
+
+	// Queries as they're written in dashboards and alert conditions, with the
+	// canonical NRQL they're parsed into.
+	corpus := map[NRQL]NRQL{
+		"SELECT average(duration) FROM Transaction TIMESERIES WHERE appName = 'Example application'": "SELECT average(duration) FROM Transaction WHERE appName = 'Example application' TIMESERIES",
+
+		"SELECT count(*) FROM Transaction": "SELECT count(*) FROM Transaction",
+
+		"select count(*) from Transaction where appName='checkout' and httpResponseCode like '5%' since 30 minutes ago": "SELECT count(*) FROM Transaction WHERE appName = 'checkout' AND httpResponseCode LIKE '5%' SINCE 30 minutes ago",
+
+		"FROM Metric SELECT rate(sum(apm.service.transaction.duration), 1 minute) WHERE appName = 'api' FACET host LIMIT 20 TIMESERIES 1 minute": "SELECT rate(sum(apm.service.transaction.duration), 1 minute) FROM Metric WHERE appName = 'api' FACET host LIMIT 20 TIMESERIES 1 minute",
+
+		"SELECT percentage(count(*), WHERE error IS true) AS 'Error rate' FROM Transaction SINCE 1 day ago COMPARE WITH 1 week ago": "SELECT percentage(count(*), WHERE error IS TRUE) AS 'Error rate' FROM Transaction SINCE 1 day ago COMPARE WITH 1 week ago",
+
+		"SELECT count(*) FROM PageView FACET CASES(WHERE pageUrl LIKE '%/cart%' AS 'Cart', WHERE pageUrl LIKE '%/checkout%' AS Checkout)": "SELECT count(*) FROM PageView FACET CASES(WHERE pageUrl LIKE '%/cart%' AS 'Cart', WHERE pageUrl LIKE '%/checkout%' AS 'Checkout')",
+
+		"SELECT uniqueCount(session) FROM PageView WHERE countryCode NOT IN ('US', 'CA') AND `user-agent` IS NOT NULL SINCE '2021-01-01 00:00:00' UNTIL '2021-01-02 00:00:00'": "SELECT uniqueCount(session) FROM PageView WHERE countryCode NOT IN ('US', 'CA') AND `user-agent` IS NOT NULL SINCE '2021-01-01 00:00:00' UNTIL '2021-01-02 00:00:00'",
+
+		"SELECT (sum(provider.errors) / sum(provider.requests)) * 100 FROM LoadBalancerSample WHERE NOT (env = \"prod\" OR env = 'stage') LIMIT MAX": "SELECT sum(provider.errors) / sum(provider.requests) * 100 FROM LoadBalancerSample WHERE NOT (env = 'prod' OR env = 'stage') LIMIT MAX",
+
+		"SELECT latest(cpuPercent) - 10, max(memoryUsedBytes) / 1024 FROM SystemSample WHERE hostname RLIKE 'web-.*' FACET hostname SINCE 5 minutes ago TIMESERIES AUTO": "SELECT latest(cpuPercent) - 10, max(memoryUsedBytes) / 1024 FROM SystemSample WHERE hostname RLIKE 'web-.*' FACET hostname SINCE 5 minutes ago TIMESERIES AUTO",
+
+		"SELECT * FROM Log WHERE message LIKE '%can\\'t connect%' AND level <> 'debug' SINCE 1 hour ago UNTIL now LIMIT 100": "SELECT * FROM Log WHERE message LIKE '%can\\'t connect%' AND level != 'debug' LIMIT 100 SINCE 1 hour ago UNTIL now",
+
+		"SELECT funnel(session, WHERE pageUrl = '/' AS 'Home', WHERE pageUrl = '/done' AS 'Done') FROM PageView SINCE yesterday": "SELECT funnel(session, WHERE pageUrl = '/' AS 'Home', WHERE pageUrl = '/done' AS 'Done') FROM PageView SINCE yesterday",
+
+		"SELECT count(*) FROM K8sContainerSample WHERE status != 'Running' AND restartCount >= 3 FACET k8s.podName, `k8s.namespace` TIMESERIES MAX": "SELECT count(*) FROM K8sContainerSample WHERE status != 'Running' AND restartCount >= 3 FACET k8s.podName, k8s.namespace TIMESERIES MAX",
+	}
+
+	for raw, canonical := range corpus {
+		parsed, err := ParseQuery(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, canonical, parsed.NRQL(), raw)
+
+		// The canonical form parses to itself.
+		reparsed, err := ParseQuery(parsed.NRQL())
+		require.NoError(t, err, raw)
+		assert.Equal(t, canonical, reparsed.NRQL(), raw)
+	}
+}
+
+func TestParseQueryChanges(t *testing.T) {
+	t.Parallel()
+
+	query, err := ParseQuery("SELECT count(*) FROM Transaction WHERE appName = 'web'")
+	require.NoError(t, err)
+
+	query.Where(Eq("host", "web-1")).Facet(Attr("name")).Since(Ago(2, Hour))
+
+	assert.Equal(t, NRQL("SELECT count(*) FROM Transaction WHERE appName = 'web' AND host = 'web-1' FACET name SINCE 2 hours ago"), query.NRQL())
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[NRQL]struct {
+		offset  int
+		message string
+	}{
+		"SELECT count(*)":                                                       {0, "missing FROM clause"},
+		"FROM Transaction":                                                      {0, "missing SELECT clause"},
+		"SELECT count(* FROM Transaction":                                       {15, `expected ")", found "FROM"`},
+		"SELECT count(*) FROM Transaction WHERE name = 'x":                      {46, "unterminated string"},
+		"SELECT count(*) FROM Transaction SINCE 1 hour":                         {45, "expected AGO, found end of query"},
+		"SELECT count(*) FROM Transaction SINCE 1 hour ago WITH TIMEZONE 'UTC'": {50, "the WITH clause is not supported"},
+		"SELECT count(*) FROM Transaction LIMIT 10 LIMIT 20":                    {42, "duplicate LIMIT clause"},
+		"SELECT count(*) FROM Transaction WHERE a = 1 b":                        {45, `expected a clause, found "b"`},
+		"SELECT count(*) FROM Transaction WHERE a ; 1":                          {41, "unexpected character ';'"},
+		"SELECT from FROM Transaction":                                          {7, "unexpected keyword FROM"},
+		"SELECT count(*) FROM Transaction WHERE a IS 1":                         {44, `expected NULL, TRUE or FALSE, found "1"`},
+	}
+
+	for query, expected := range cases {
+		_, err := ParseQuery(query)
+
+		var syntaxErr *nrql.SyntaxError
+		require.True(t, errors.As(err, &syntaxErr), string(query))
+		assert.Equal(t, expected.offset, syntaxErr.Pos.Offset, string(query))
+		assert.Equal(t, expected.message, syntaxErr.Message, string(query))
+	}
+}
+
+// unknownExpr is a NRQL expression of a type the builder doesn't know.
+type unknownExpr struct {
+	nrql.Expr
+}
+
+func TestConvertExprUnsupported(t *testing.T) {
+	t.Parallel()
+
+	_, err := convertExpr(&nrql.Binary{Op: "AND", Left: &nrql.Ident{Name: "a"}, Right: unknownExpr{}})
+	assert.EqualError(t, err, "nrdb: unsupported NRQL expression nrdb.unknownExpr")
+}
+
+func TestParseQuerySubqueriesAndRawStrings(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseQuery("SELECT count(*) FROM Log WHERE host IN (SELECT uniques(host) FROM Span)")
+	assert.EqualError(t, err, "nrdb: unsupported NRQL expression *nrql.Subquery")
+
+	query, err := ParseQuery(`SELECT count(*) FROM Log WHERE message RLIKE r'\d+'`)
+	require.NoError(t, err)
+	assert.Equal(t, NRQL(`SELECT count(*) FROM Log WHERE message RLIKE '\\d+'`), query.NRQL())
+}
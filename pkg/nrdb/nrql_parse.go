@@ -0,0 +1,203 @@
+package nrdb
+
+import (
+	"fmt"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrql"
+)
+
+// ParseQuery parses NRQL into a Query, which can then be changed with the
+// builder's methods.  The query is parsed with nrql.Parse, and is converted
+// if it only uses the clauses the builder can produce, so
+// ParseQuery(query.NRQL()) returns a query with the same String.  Syntax
+// errors, and clauses the builder doesn't support, are returned as a
+// *nrql.SyntaxError, and expressions it doesn't support as an error naming the
+// expression's type.
+func ParseQuery(query NRQL) (*Query, error) {
+	parsed, err := nrql.Parse(string(query))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case parsed.Offset != nil:
+		return nil, unsupportedClause("OFFSET", parsed.Offset.At)
+	case parsed.SlideBy != nil:
+		return nil, unsupportedClause("SLIDE", parsed.SlideBy.At)
+	case parsed.WithTimezone != nil:
+		return nil, unsupportedClause("WITH", parsed.WithTimezone.At)
+	case parsed.Extrapolate != nil:
+		return nil, unsupportedClause("EXTRAPOLATE", parsed.Extrapolate.At)
+	case parsed.Facet != nil && parsed.Facet.OrderBy != nil:
+		return nil, unsupportedClause("ORDER", parsed.Facet.OrderBy.At)
+	}
+
+	selects, err := convertExprs(parsed.Select.Exprs)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{
+		selects: selects,
+	}
+
+	for _, eventType := range parsed.From.EventTypes {
+		q.from = append(q.from, eventType.Name)
+	}
+
+	if parsed.Where != nil {
+		if q.where, err = convertExpr(parsed.Where.Condition); err != nil {
+			return nil, err
+		}
+	}
+
+	if parsed.Facet != nil {
+		if q.facets, err = convertExprs(parsed.Facet.Exprs); err != nil {
+			return nil, err
+		}
+	}
+
+	if parsed.Limit != nil {
+		if parsed.Limit.Max {
+			q.LimitMax()
+		} else {
+			q.Limit(parsed.Limit.Count)
+		}
+	}
+
+	if parsed.Since != nil {
+		q.Since(convertTime(parsed.Since.Time))
+	}
+
+	if parsed.Until != nil {
+		q.Until(convertTime(parsed.Until.Time))
+	}
+
+	if parsed.CompareWith != nil {
+		q.CompareWith(convertTime(parsed.CompareWith.Time))
+	}
+
+	if parsed.Timeseries != nil {
+		bucket := ""
+
+		if b := parsed.Timeseries.Bucket; b != nil {
+			bucket = b.Keyword
+			if b.Period != nil {
+				bucket = convertPeriod(b.Period).String()
+			}
+		}
+
+		q.timeseries = &bucket
+	}
+
+	return q, nil
+}
+
+func unsupportedClause(keyword string, at nrql.Pos) error {
+	return &nrql.SyntaxError{Pos: at, Message: fmt.Sprintf("the %s clause is not supported", keyword)}
+}
+
+func convertExprs(exprs []nrql.Expr) ([]Expr, error) {
+	converted := make([]Expr, len(exprs))
+	for i, expr := range exprs {
+		var err error
+		if converted[i], err = convertExpr(expr); err != nil {
+			return nil, err
+		}
+	}
+
+	return converted, nil
+}
+
+func convertExpr(expr nrql.Expr) (Expr, error) {
+	switch expr := expr.(type) {
+	case *nrql.Ident:
+		return attribute(expr.Name), nil
+	case *nrql.Literal:
+		if expr.Kind == nrql.LiteralString {
+			return literal(quoteString(expr.Value)), nil
+		}
+
+		return literal(expr.Value), nil
+	case *nrql.Star:
+		return star{}, nil
+	case *nrql.Call:
+		args, err := convertExprs(expr.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		return call{name: expr.Name, args: args}, nil
+	case *nrql.Alias:
+		inner, err := convertExpr(expr.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		return alias{expr: inner, name: expr.Name}, nil
+	case *nrql.WhereArg:
+		condition, err := convertExpr(expr.Condition)
+		if err != nil {
+			return nil, err
+		}
+
+		return whereArg{condition: condition}, nil
+	case *nrql.Period:
+		return convertPeriod(expr), nil
+	case *nrql.Binary:
+		left, err := convertExpr(expr.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := convertExpr(expr.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		return binary{op: expr.Op, left: left, right: right}, nil
+	case *nrql.Unary:
+		inner, err := convertExpr(expr.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		return unary{op: expr.Op, expr: inner}, nil
+	case *nrql.In:
+		inner, err := convertExpr(expr.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := convertExprs(expr.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		return inList{expr: inner, not: expr.Not, values: values}, nil
+	case *nrql.Is:
+		inner, err := convertExpr(expr.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		return isValue{expr: inner, not: expr.Not, value: expr.Value}, nil
+	default:
+		return nil, fmt.Errorf("nrdb: unsupported NRQL expression %T", expr)
+	}
+}
+
+func convertPeriod(p *nrql.Period) period {
+	return period{count: p.Count, unit: TimeUnit(p.Unit)}
+}
+
+func convertTime(t *nrql.Time) Moment {
+	switch t.Kind {
+	case nrql.TimeAgo:
+		return Ago(t.Period.Count, TimeUnit(t.Period.Unit))
+	case nrql.TimeString:
+		return Moment{text: quoteString(t.Text)}
+	default:
+		return Moment{text: t.Text}
+	}
+}
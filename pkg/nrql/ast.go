@@ -0,0 +1,311 @@
+package nrql
+
+import "fmt"
+
+// Pos is a position in a query.  Lines and columns start at 1, and columns
+// count bytes.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Query is a parsed NRQL query.  Clauses missing from the query are nil.
+type Query struct {
+	Select       *SelectClause
+	From         *FromClause
+	Where        *WhereClause
+	Facet        *FacetClause
+	Limit        *LimitClause
+	Offset       *OffsetClause
+	Since        *TimeClause
+	Until        *TimeClause
+	CompareWith  *TimeClause
+	Timeseries   *TimeseriesClause
+	SlideBy      *SlideByClause
+	WithTimezone *WithTimezoneClause
+	Extrapolate  *ExtrapolateClause
+}
+
+// SelectClause is SELECT expr, ...
+type SelectClause struct {
+	At    Pos
+	Exprs []Expr
+}
+
+// FromClause is FROM eventType, ...
+type FromClause struct {
+	At         Pos
+	EventTypes []*Ident
+}
+
+// WhereClause is WHERE condition.
+type WhereClause struct {
+	At        Pos
+	Condition Expr
+}
+
+// FacetClause is FACET expr, ... [ORDER BY expr [ASC|DESC]].
+type FacetClause struct {
+	At      Pos
+	Exprs   []Expr
+	OrderBy *OrderBy
+}
+
+// OrderBy is the ORDER BY of a FACET clause.
+type OrderBy struct {
+	At         Pos
+	Expr       Expr
+	Descending bool
+}
+
+// LimitClause is LIMIT count or LIMIT MAX.
+type LimitClause struct {
+	At    Pos
+	Max   bool
+	Count int
+}
+
+// OffsetClause is OFFSET count.
+type OffsetClause struct {
+	At    Pos
+	Count int
+}
+
+// TimeClause is SINCE time, UNTIL time or COMPARE WITH time.
+type TimeClause struct {
+	At      Pos
+	Keyword string
+	Time    *Time
+}
+
+// TimeseriesClause is TIMESERIES [bucket].  Bucket is nil for the default
+// bucket size.
+type TimeseriesClause struct {
+	At     Pos
+	Bucket *Bucket
+}
+
+// SlideByClause is SLIDE BY bucket.
+type SlideByClause struct {
+	At     Pos
+	Bucket *Bucket
+}
+
+// WithTimezoneClause is WITH TIMEZONE 'zone'.
+type WithTimezoneClause struct {
+	At   Pos
+	Zone string
+}
+
+// ExtrapolateClause is EXTRAPOLATE.
+type ExtrapolateClause struct {
+	At Pos
+}
+
+// Bucket is the size of TIMESERIES and SLIDE BY buckets: a period, AUTO or
+// MAX.
+type Bucket struct {
+	At      Pos
+	Keyword string
+	Period  *Period
+}
+
+// TimeKind is how a Time is written.
+type TimeKind int
+
+// The ways of writing a time.
+const (
+	// TimeAgo is a period before now, as in 1 hour ago.
+	TimeAgo TimeKind = iota
+	// TimeEpoch is milliseconds since the epoch.
+	TimeEpoch
+	// TimeString is a quoted date and time.
+	TimeString
+	// TimeKeyword is now, today, yesterday, or this or last followed by a
+	// unit of time.
+	TimeKeyword
+)
+
+// Time is a point in time in SINCE, UNTIL and COMPARE WITH.  Period is set
+// for TimeAgo, and Text for the other kinds.
+type Time struct {
+	At     Pos
+	Kind   TimeKind
+	Period *Period
+	Text   string
+}
+
+// Node is a part of a parsed query.
+type Node interface {
+	Position() Pos
+}
+
+// Expr is an expression: an attribute, literal, function call, operation or
+// condition.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Ident is an attribute name or event type.
+type Ident struct {
+	At     Pos
+	Name   string
+	Quoted bool
+}
+
+// LiteralKind is the type of a Literal.
+type LiteralKind int
+
+// The types of literal.
+const (
+	LiteralString LiteralKind = iota
+	LiteralNumber
+	LiteralBoolean
+	LiteralNull
+)
+
+// Literal is a string, number, boolean or null.  Value holds the unquoted
+// string, the number as written, TRUE, FALSE or NULL.  Raw is set for raw
+// strings, as in RLIKE r'\d+', whose backslashes are part of the value.
+type Literal struct {
+	At    Pos
+	Kind  LiteralKind
+	Value string
+	Raw   bool
+}
+
+// Star is the * of SELECT * and count(*).
+type Star struct {
+	At Pos
+}
+
+// Call is a function call.
+type Call struct {
+	At   Pos
+	Name string
+	Args []Expr
+}
+
+// Alias names an expression with AS.
+type Alias struct {
+	At   Pos
+	Expr Expr
+	Name string
+}
+
+// WhereArg is a WHERE condition passed to a function, as in filter().
+type WhereArg struct {
+	At        Pos
+	Condition Expr
+}
+
+// Period is a length of time, as in 5 minutes.  Unit is singular and lower
+// case.
+type Period struct {
+	At    Pos
+	Count int
+	Unit  string
+}
+
+// Binary is left op right.  Op is upper case, and one of OR, AND, =, !=, <,
+// <=, >, >=, LIKE, NOT LIKE, RLIKE, NOT RLIKE, +, -, * or /.
+type Binary struct {
+	At    Pos
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// Unary is NOT expr or -expr.
+type Unary struct {
+	At   Pos
+	Op   string
+	Expr Expr
+}
+
+// In is expr [NOT] IN (values, ...).
+type In struct {
+	At     Pos
+	Expr   Expr
+	Not    bool
+	Values []Expr
+}
+
+// Subquery is a query nested in another, as in x IN (SELECT y FROM T) or
+// x > (SELECT average(x) FROM T).
+type Subquery struct {
+	At    Pos
+	Query *Query
+}
+
+// Is is expr IS [NOT] NULL, TRUE or FALSE.
+type Is struct {
+	At    Pos
+	Expr  Expr
+	Not   bool
+	Value string
+}
+
+func (n *Ident) Position() Pos    { return n.At }
+func (n *Literal) Position() Pos  { return n.At }
+func (n *Star) Position() Pos     { return n.At }
+func (n *Call) Position() Pos     { return n.At }
+func (n *Alias) Position() Pos    { return n.At }
+func (n *WhereArg) Position() Pos { return n.At }
+func (n *Period) Position() Pos   { return n.At }
+func (n *Binary) Position() Pos   { return n.At }
+func (n *Unary) Position() Pos    { return n.At }
+func (n *In) Position() Pos       { return n.At }
+func (n *Is) Position() Pos       { return n.At }
+func (n *Subquery) Position() Pos { return n.At }
+
+func (*Ident) exprNode()    {}
+func (*Literal) exprNode()  {}
+func (*Star) exprNode()     {}
+func (*Call) exprNode()     {}
+func (*Alias) exprNode()    {}
+func (*WhereArg) exprNode() {}
+func (*Period) exprNode()   {}
+func (*Binary) exprNode()   {}
+func (*Unary) exprNode()    {}
+func (*In) exprNode()       {}
+func (*Is) exprNode()       {}
+func (*Subquery) exprNode() {}
+
+// Walk calls fn for expr and each expression within it, parents first.
+// Returning false from fn skips the expressions within.  Walk doesn't enter the
+// clauses of subqueries.
+func Walk(expr Expr, fn func(Expr) bool) {
+	if expr == nil || !fn(expr) {
+		return
+	}
+
+	switch expr := expr.(type) {
+	case *Call:
+		for _, arg := range expr.Args {
+			Walk(arg, fn)
+		}
+	case *Alias:
+		Walk(expr.Expr, fn)
+	case *WhereArg:
+		Walk(expr.Condition, fn)
+	case *Binary:
+		Walk(expr.Left, fn)
+		Walk(expr.Right, fn)
+	case *Unary:
+		Walk(expr.Expr, fn)
+	case *In:
+		Walk(expr.Expr, fn)
+		for _, value := range expr.Values {
+			Walk(value, fn)
+		}
+	case *Is:
+		Walk(expr.Expr, fn)
+	}
+}
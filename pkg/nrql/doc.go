@@ -0,0 +1,18 @@
+/*
+Package nrql parses NRQL, the New Relic Query Language, into a syntax tree and
+checks queries for mistakes the API would otherwise reject, or silently
+accept.  It needs no API keys and makes no network calls, so it can run in CI
+before queries are used in dashboards or alert conditions.
+
+Parse returns the syntax tree of a query, or a *SyntaxError with the line and
+column of the problem.  Lint parses a query and runs the lint rules for where
+it will be used:
+
+	problems, err := nrql.Lint(query, nrql.TargetAlertCondition)
+
+Details on the New Relic Query Language are available here:
+
+https://docs.newrelic.com/docs/query-data/nrql-new-relic-query-language/getting-started/introduction-nrql
+
+*/
+package nrql
@@ -0,0 +1,35 @@
+//go:build integration
+// +build integration
+
+package nrql
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/newrelic/newrelic-client-go/pkg/alerts"
+)
+
+func Example_lint() {
+	// Check an alert condition's query before creating the condition.
+	query := alerts.NrqlConditionCreateQuery{
+		Query: "SELECT count(*) FROM Transaction WHERE error IS TRUE SINCE 5 minutes ago",
+	}
+
+	problems, err := Lint(query.Query, TargetAlertCondition)
+	if err != nil {
+		log.Fatal("error parsing query: ", err)
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if HasErrors(problems) {
+		fmt.Println("the condition would be rejected")
+	}
+
+	// Output:
+	// 1:54: error: alert condition queries can't use SINCE; the condition's settings control it (alert-unsupported-clause)
+	// the condition would be rejected
+}
@@ -0,0 +1,195 @@
+package nrql
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Target is where a query is used, which decides the lint rules that apply.
+type Target string
+
+// The places queries are used.
+const (
+	// TargetQuery is a query run on its own, with NerdGraph or the nrdb
+	// package.
+	TargetQuery Target = "query"
+	// TargetDashboard is the query of a dashboard widget.
+	TargetDashboard Target = "dashboard"
+	// TargetAlertCondition is the query of a NRQL alert condition.
+	TargetAlertCondition Target = "alertCondition"
+)
+
+// Severity is how serious a Problem is.
+type Severity string
+
+// The severities of problems.
+const (
+	// SeverityError problems make the API reject the query.
+	SeverityError Severity = "error"
+	// SeverityWarning problems are accepted by the API, but the query
+	// probably doesn't do what was meant.
+	SeverityWarning Severity = "warning"
+)
+
+// The names of the lint rules.
+const (
+	// RuleAlertUnsupportedClause reports clauses NRQL alert conditions don't
+	// allow, such as SINCE and TIMESERIES.
+	RuleAlertUnsupportedClause = "alert-unsupported-clause"
+	// RuleMissingSince reports dashboard queries without a SINCE clause,
+	// which only cover the last hour.
+	RuleMissingSince = "missing-since"
+	// RuleUnboundedFacet reports FACET clauses without a LIMIT, which only
+	// return the top 10 facets.
+	RuleUnboundedFacet = "unbounded-facet"
+	// RuleLimitMax reports LIMIT MAX, which can return thousands of rows.
+	RuleLimitMax = "limit-max"
+)
+
+// Problem is a mistake found in a query by Lint.
+type Problem struct {
+	Rule     string
+	Severity Severity
+	Pos      Pos
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", p.Pos, p.Severity, p.Message, p.Rule)
+}
+
+// Lint parses a query and checks it with the lint rules for the target.  A
+// query which doesn't parse returns a *SyntaxError.
+func Lint(query string, target Target) ([]Problem, error) {
+	q, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return LintQuery(q, target), nil
+}
+
+// LintQuery checks a parsed query with the lint rules for the target.
+// Problems are returned in the order they appear in the query.
+func LintQuery(q *Query, target Target) []Problem {
+	problems := []Problem{}
+
+	for _, rule := range rules {
+		problems = append(problems, rule(q, target)...)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Pos.Offset < problems[j].Pos.Offset
+	})
+
+	return problems
+}
+
+// HasErrors reports whether any of the problems is an error.
+func HasErrors(problems []Problem) bool {
+	for _, problem := range problems {
+		if problem.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+var rules = []func(*Query, Target) []Problem{
+	lintAlertUnsupportedClauses,
+	lintMissingSince,
+	lintUnboundedFacet,
+	lintLimitMax,
+}
+
+func lintAlertUnsupportedClauses(q *Query, target Target) []Problem {
+	if target != TargetAlertCondition {
+		return nil
+	}
+
+	problems := []Problem{}
+
+	unsupported := func(keyword string, at Pos) {
+		problems = append(problems, Problem{
+			Rule:     RuleAlertUnsupportedClause,
+			Severity: SeverityError,
+			Pos:      at,
+			Message:  fmt.Sprintf("alert condition queries can't use %s; the condition's settings control it", keyword),
+		})
+	}
+
+	if q.Since != nil {
+		unsupported("SINCE", q.Since.At)
+	}
+
+	if q.Until != nil {
+		unsupported("UNTIL", q.Until.At)
+	}
+
+	if q.CompareWith != nil {
+		unsupported("COMPARE WITH", q.CompareWith.At)
+	}
+
+	if q.Timeseries != nil {
+		unsupported("TIMESERIES", q.Timeseries.At)
+	}
+
+	if q.Limit != nil {
+		unsupported("LIMIT", q.Limit.At)
+	}
+
+	if q.Offset != nil {
+		unsupported("OFFSET", q.Offset.At)
+	}
+
+	if q.SlideBy != nil {
+		unsupported("SLIDE BY", q.SlideBy.At)
+	}
+
+	if q.WithTimezone != nil {
+		unsupported("WITH TIMEZONE", q.WithTimezone.At)
+	}
+
+	return problems
+}
+
+func lintMissingSince(q *Query, target Target) []Problem {
+	if target != TargetDashboard || q.Since != nil {
+		return nil
+	}
+
+	return []Problem{{
+		Rule:     RuleMissingSince,
+		Severity: SeverityWarning,
+		Pos:      q.Select.At,
+		Message:  "query has no SINCE clause, so it only covers the last hour",
+	}}
+}
+
+func lintUnboundedFacet(q *Query, target Target) []Problem {
+	// Alert conditions can't use LIMIT, so there's nothing to suggest.
+	if target == TargetAlertCondition || q.Facet == nil || q.Limit != nil {
+		return nil
+	}
+
+	return []Problem{{
+		Rule:     RuleUnboundedFacet,
+		Severity: SeverityWarning,
+		Pos:      q.Facet.At,
+		Message:  "FACET has no LIMIT, so only the top 10 facets are returned",
+	}}
+}
+
+func lintLimitMax(q *Query, target Target) []Problem {
+	if target == TargetAlertCondition || q.Limit == nil || !q.Limit.Max {
+		return nil
+	}
+
+	return []Problem{{
+		Rule:     RuleLimitMax,
+		Severity: SeverityWarning,
+		Pos:      q.Limit.At,
+		Message:  "LIMIT MAX can return thousands of rows; set the limit needed instead",
+	}}
+}
@@ -0,0 +1,117 @@
+//go:build unit
+// +build unit
+
+package nrql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		query    string
+		target   Target
+		expected []string
+	}{
+		{
+			query:    "SELECT count(*) FROM Transaction WHERE error IS TRUE",
+			target:   TargetAlertCondition,
+			expected: []string{},
+		},
+		{
+			query:  "SELECT count(*) FROM Transaction SINCE 5 minutes ago LIMIT 10 TIMESERIES",
+			target: TargetAlertCondition,
+			expected: []string{
+				"1:34: error: alert condition queries can't use SINCE; the condition's settings control it (alert-unsupported-clause)",
+				"1:54: error: alert condition queries can't use LIMIT; the condition's settings control it (alert-unsupported-clause)",
+				"1:63: error: alert condition queries can't use TIMESERIES; the condition's settings control it (alert-unsupported-clause)",
+			},
+		},
+		{
+			query:  "SELECT count(*) FROM Transaction UNTIL now COMPARE WITH 1 day ago SLIDE BY 1 minute WITH TIMEZONE 'UTC' OFFSET 5",
+			target: TargetAlertCondition,
+			expected: []string{
+				"1:34: error: alert condition queries can't use UNTIL; the condition's settings control it (alert-unsupported-clause)",
+				"1:44: error: alert condition queries can't use COMPARE WITH; the condition's settings control it (alert-unsupported-clause)",
+				"1:67: error: alert condition queries can't use SLIDE BY; the condition's settings control it (alert-unsupported-clause)",
+				"1:85: error: alert condition queries can't use WITH TIMEZONE; the condition's settings control it (alert-unsupported-clause)",
+				"1:105: error: alert condition queries can't use OFFSET; the condition's settings control it (alert-unsupported-clause)",
+			},
+		},
+		{
+			// The clauses of subqueries aren't the alert condition's.
+			query:    "SELECT count(*) FROM Log WHERE message RLIKE r'\\d+' AND host IN (SELECT uniques(host) FROM Log SINCE 1 day ago)",
+			target:   TargetAlertCondition,
+			expected: []string{},
+		},
+		{
+			// Alert conditions can facet without a limit.
+			query:    "SELECT average(duration) FROM Transaction FACET appName",
+			target:   TargetAlertCondition,
+			expected: []string{},
+		},
+		{
+			query:  "FROM Transaction SELECT count(*) FACET appName",
+			target: TargetDashboard,
+			expected: []string{
+				"1:18: warning: query has no SINCE clause, so it only covers the last hour (missing-since)",
+				"1:34: warning: FACET has no LIMIT, so only the top 10 facets are returned (unbounded-facet)",
+			},
+		},
+		{
+			query:  "SELECT count(*) FROM Transaction FACET appName LIMIT MAX SINCE 1 day ago",
+			target: TargetDashboard,
+			expected: []string{
+				"1:48: warning: LIMIT MAX can return thousands of rows; set the limit needed instead (limit-max)",
+			},
+		},
+		{
+			query:  "SELECT count(*) FROM Transaction FACET appName",
+			target: TargetQuery,
+			expected: []string{
+				"1:34: warning: FACET has no LIMIT, so only the top 10 facets are returned (unbounded-facet)",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		problems, err := Lint(tc.query, tc.target)
+		require.NoError(t, err, tc.query)
+
+		actual := []string{}
+		for _, problem := range problems {
+			actual = append(actual, problem.String())
+		}
+
+		assert.Equal(t, tc.expected, actual, tc.query)
+	}
+}
+
+func TestLintSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	_, err := Lint("SELECT count(* FROM Transaction", TargetDashboard)
+
+	var syntaxErr *SyntaxError
+	require.True(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, `nrql: syntax error at 1:16: expected ")", found "FROM"`, err.Error())
+}
+
+func TestHasErrors(t *testing.T) {
+	t.Parallel()
+
+	warnings, err := Lint("SELECT count(*) FROM Transaction LIMIT MAX", TargetDashboard)
+	require.NoError(t, err)
+	assert.Len(t, warnings, 2)
+	assert.False(t, HasErrors(warnings))
+
+	errs, err := Lint("SELECT count(*) FROM Transaction LIMIT MAX", TargetAlertCondition)
+	require.NoError(t, err)
+	assert.True(t, HasErrors(errs))
+}
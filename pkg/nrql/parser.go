@@ -0,0 +1,1012 @@
+package nrql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError is returned by Parse for queries that aren't valid NRQL.
+type SyntaxError struct {
+	Pos     Pos
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("nrql: syntax error at %s: %s", e.Pos, e.Message)
+}
+
+// keywords are the NRQL words that must be quoted with backticks to be used
+// as attribute names or event types.
+var keywords = map[string]bool{
+	"ago": true, "and": true, "as": true, "by": true, "compare": true,
+	"extrapolate": true, "facet": true, "false": true, "from": true,
+	"in": true, "is": true, "like": true, "limit": true, "not": true,
+	"null": true, "offset": true, "or": true, "order": true, "rlike": true,
+	"select": true, "show": true, "since": true, "slide": true,
+	"timeseries": true, "true": true, "until": true, "where": true,
+	"with": true,
+}
+
+// IsKeyword reports whether word is a NRQL keyword, which must be quoted with
+// backticks to be used as an attribute name or event type.
+func IsKeyword(word string) bool {
+	return keywords[strings.ToLower(word)]
+}
+
+// Parse parses a NRQL query.
+func Parse(query string) (*Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+
+	return p.parseQuery()
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenString
+	tokenRawString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+// symbols are the operators and punctuation, longest first.
+var symbols = []string{"!=", "<>", "<=", ">=", "=", "<", ">", "+", "-", "*", "/", "(", ")", ","}
+
+func lex(input string) ([]token, error) {
+	tokens := []token{}
+	line, lineStart := 1, 0
+
+	position := func(offset int) Pos {
+		return Pos{Offset: offset, Line: line, Column: offset - lineStart + 1}
+	}
+
+	for i := 0; i < len(input); {
+		c := input[i]
+		start := position(i)
+
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+			continue
+
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+
+		case strings.HasPrefix(input[i:], "--") || strings.HasPrefix(input[i:], "//"):
+			// A comment to the end of the line.
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+			continue
+
+		case strings.HasPrefix(input[i:], "/*"):
+			end := strings.Index(input[i+2:], "*/")
+			if end < 0 {
+				return nil, &SyntaxError{Pos: start, Message: "unterminated comment"}
+			}
+
+			for _, b := range []byte(input[i : i+end+4]) {
+				i++
+				if b == '\n' {
+					line, lineStart = line+1, i
+				}
+			}
+			continue
+
+		case (c == 'r' || c == 'R') && i+1 < len(input) && (input[i+1] == '\'' || input[i+1] == '"'):
+			// A raw string, whose backslashes aren't escapes.
+			quote := input[i+1]
+			closed := false
+
+			for i += 2; i < len(input); i++ {
+				if input[i] == quote {
+					closed = true
+					i++
+					break
+				}
+
+				if input[i] == '\n' {
+					line, lineStart = line+1, i+1
+				}
+			}
+
+			if !closed {
+				return nil, &SyntaxError{Pos: start, Message: "unterminated string"}
+			}
+
+			tokens = append(tokens, token{kind: tokenRawString, text: input[start.Offset+2 : i-1], pos: start})
+
+		case isIdentStart(c):
+			for i < len(input) && isIdentPart(input[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: input[start.Offset:i], pos: start})
+
+		case isDigit(c) || (c == '.' && i+1 < len(input) && isDigit(input[i+1])):
+			i = lexNumber(input, i)
+
+			// A number can't run into another number or a name, as in 1.2.3.
+			if i < len(input) && (input[i] == '.' || isIdentPart(input[i])) {
+				for i < len(input) && isIdentPart(input[i]) {
+					i++
+				}
+
+				return nil, &SyntaxError{Pos: start, Message: fmt.Sprintf("invalid number %q", input[start.Offset:i])}
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: input[start.Offset:i], pos: start})
+
+		case c == '`':
+			end := strings.IndexByte(input[i+1:], '`')
+			if end < 0 {
+				return nil, &SyntaxError{Pos: start, Message: "unterminated backtick quote"}
+			}
+
+			i += end + 2
+			tokens = append(tokens, token{kind: tokenQuotedIdent, text: input[start.Offset+1 : i-1], pos: start})
+
+		case c == '\'' || c == '"':
+			var text strings.Builder
+			closed := false
+
+			for i++; i < len(input); i++ {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				} else if input[i] == c {
+					closed = true
+					i++
+					break
+				}
+
+				if input[i] == '\n' {
+					line, lineStart = line+1, i+1
+				}
+
+				text.WriteByte(input[i])
+			}
+
+			if !closed {
+				return nil, &SyntaxError{Pos: start, Message: "unterminated string"}
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), pos: start})
+
+		default:
+			symbol := ""
+			for _, s := range symbols {
+				if strings.HasPrefix(input[i:], s) {
+					symbol = s
+					break
+				}
+			}
+
+			if symbol == "" {
+				return nil, &SyntaxError{Pos: start, Message: fmt.Sprintf("unexpected character %q", c)}
+			}
+
+			i += len(symbol)
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol, pos: start})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: position(len(input))}), nil
+}
+
+// lexNumber returns the end of the number starting at i, with digits, an
+// optional fraction and an optional exponent, as in 1.5e3.
+func lexNumber(input string, i int) int {
+	digits := func(i int) int {
+		for i < len(input) && isDigit(input[i]) {
+			i++
+		}
+		return i
+	}
+
+	i = digits(i)
+	if i < len(input) && input[i] == '.' {
+		i = digits(i + 1)
+	}
+
+	if i < len(input) && (input[i] == 'e' || input[i] == 'E') {
+		j := i + 1
+		if j < len(input) && (input[j] == '+' || input[j] == '-') {
+			j++
+		}
+
+		if j < len(input) && isDigit(input[j]) {
+			i = digits(j)
+		}
+	}
+
+	return i
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.' || c == ':'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.peekAt(0)
+}
+
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) isWord(word string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && strings.EqualFold(t.text, word)
+}
+
+func (p *parser) acceptWord(word string) bool {
+	if p.isWord(word) {
+		p.next()
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expectWord(word string) error {
+	if !p.acceptWord(word) {
+		return p.errorf(p.peek(), "expected %s, found %s", word, describe(p.peek()))
+	}
+
+	return nil
+}
+
+func (p *parser) isSymbol(symbol string) bool {
+	t := p.peek()
+	return t.kind == tokenSymbol && t.text == symbol
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	if p.isSymbol(symbol) {
+		p.next()
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.errorf(p.peek(), "expected %q, found %s", symbol, describe(p.peek()))
+	}
+
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: t.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func describe(t token) string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	return p.parseClauses(Pos{Line: 1, Column: 1}, false)
+}
+
+// parseSubquery parses the query nested in parentheses after open, up to the
+// closing parenthesis.
+func (p *parser) parseSubquery(open token) (Expr, error) {
+	q, err := p.parseClauses(open.pos, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Subquery{At: open.pos, Query: q}, nil
+}
+
+// isSubquery reports whether the next token starts a subquery.
+func (p *parser) isSubquery() bool {
+	return p.isWord("SELECT") || p.isWord("FROM")
+}
+
+// parseClauses parses the clauses of a query at the given position, up to the
+// end of the query or, for a subquery, a closing parenthesis.
+func (p *parser) parseClauses(at Pos, nested bool) (*Query, error) {
+	q := &Query{}
+	seen := map[string]bool{}
+
+	for p.peek().kind != tokenEOF && !(nested && p.isSymbol(")")) {
+		t := p.next()
+		clause := strings.ToUpper(t.text)
+
+		if t.kind != tokenIdent || !isClause(clause) {
+			return nil, p.errorf(t, "expected a clause, found %s", describe(t))
+		}
+
+		if seen[clause] {
+			return nil, p.errorf(t, "duplicate %s clause", clause)
+		}
+		seen[clause] = true
+
+		var err error
+
+		switch clause {
+		case "SELECT":
+			q.Select = &SelectClause{At: t.pos}
+			q.Select.Exprs, err = p.parseList()
+		case "FROM":
+			q.From = &FromClause{At: t.pos}
+			q.From.EventTypes, err = p.parseEventTypes()
+		case "WHERE":
+			q.Where = &WhereClause{At: t.pos}
+			q.Where.Condition, err = p.parseExpr()
+		case "FACET":
+			q.Facet, err = p.parseFacet(t)
+		case "LIMIT":
+			q.Limit, err = p.parseLimit(t)
+		case "OFFSET":
+			q.Offset = &OffsetClause{At: t.pos}
+			q.Offset.Count, err = p.parseCount()
+		case "SINCE":
+			q.Since = &TimeClause{At: t.pos, Keyword: "SINCE"}
+			q.Since.Time, err = p.parseTime()
+		case "UNTIL":
+			q.Until = &TimeClause{At: t.pos, Keyword: "UNTIL"}
+			q.Until.Time, err = p.parseTime()
+		case "COMPARE":
+			q.CompareWith = &TimeClause{At: t.pos, Keyword: "COMPARE WITH"}
+			if err = p.expectWord("WITH"); err == nil {
+				q.CompareWith.Time, err = p.parseTime()
+			}
+		case "TIMESERIES":
+			q.Timeseries = &TimeseriesClause{At: t.pos}
+			if p.peek().kind == tokenNumber || p.isWord("AUTO") || p.isWord("MAX") {
+				q.Timeseries.Bucket, err = p.parseBucket()
+			}
+		case "SLIDE":
+			q.SlideBy = &SlideByClause{At: t.pos}
+			if err = p.expectWord("BY"); err == nil {
+				q.SlideBy.Bucket, err = p.parseBucket()
+			}
+		case "WITH":
+			q.WithTimezone = &WithTimezoneClause{At: t.pos}
+			q.WithTimezone.Zone, err = p.parseTimezone()
+		case "EXTRAPOLATE":
+			q.Extrapolate = &ExtrapolateClause{At: t.pos}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !seen["SELECT"] {
+		return nil, &SyntaxError{Pos: at, Message: "missing SELECT clause"}
+	}
+
+	if !seen["FROM"] {
+		return nil, &SyntaxError{Pos: at, Message: "missing FROM clause"}
+	}
+
+	return q, nil
+}
+
+func isClause(word string) bool {
+	switch word {
+	case "SELECT", "FROM", "WHERE", "FACET", "LIMIT", "OFFSET", "SINCE", "UNTIL",
+		"COMPARE", "TIMESERIES", "SLIDE", "WITH", "EXTRAPOLATE":
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *parser) parseEventTypes() ([]*Ident, error) {
+	eventTypes := []*Ident{}
+
+	for {
+		t := p.next()
+		if (t.kind != tokenIdent || IsKeyword(t.text)) && t.kind != tokenQuotedIdent {
+			return nil, p.errorf(t, "expected an event type, found %s", describe(t))
+		}
+
+		eventTypes = append(eventTypes, &Ident{At: t.pos, Name: t.text, Quoted: t.kind == tokenQuotedIdent})
+
+		if !p.acceptSymbol(",") {
+			return eventTypes, nil
+		}
+	}
+}
+
+func (p *parser) parseFacet(t token) (*FacetClause, error) {
+	exprs, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+
+	facet := &FacetClause{At: t.pos, Exprs: exprs}
+
+	if order := p.peek(); p.acceptWord("ORDER") {
+		if err := p.expectWord("BY"); err != nil {
+			return nil, err
+		}
+
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		facet.OrderBy = &OrderBy{At: order.pos, Expr: expr}
+
+		if !p.acceptWord("ASC") {
+			facet.OrderBy.Descending = p.acceptWord("DESC")
+		}
+	}
+
+	return facet, nil
+}
+
+func (p *parser) parseLimit(t token) (*LimitClause, error) {
+	if p.acceptWord("MAX") {
+		return &LimitClause{At: t.pos, Max: true}, nil
+	}
+
+	if p.peek().kind != tokenNumber {
+		return nil, p.errorf(p.peek(), "expected a limit, found %s", describe(p.peek()))
+	}
+
+	count, err := p.parseCount()
+	if err != nil {
+		return nil, err
+	}
+
+	return &LimitClause{At: t.pos, Count: count}, nil
+}
+
+func (p *parser) parseCount() (int, error) {
+	t := p.next()
+
+	count, err := strconv.Atoi(t.text)
+	if t.kind != tokenNumber || err != nil {
+		return 0, p.errorf(t, "expected a whole number, found %s", describe(t))
+	}
+
+	return count, nil
+}
+
+func (p *parser) parseTime() (*Time, error) {
+	t := p.peek()
+
+	switch {
+	case t.kind == tokenNumber:
+		count, err := p.parseCount()
+		if err != nil {
+			return nil, err
+		}
+
+		unit, ok := timeUnit(p.peek())
+		if !ok {
+			// A bare number is milliseconds since the epoch.
+			return &Time{At: t.pos, Kind: TimeEpoch, Text: t.text}, nil
+		}
+
+		p.next()
+
+		if err := p.expectWord("AGO"); err != nil {
+			return nil, err
+		}
+
+		return &Time{At: t.pos, Kind: TimeAgo, Period: &Period{At: t.pos, Count: count, Unit: unit}}, nil
+
+	case t.kind == tokenString:
+		p.next()
+		return &Time{At: t.pos, Kind: TimeString, Text: t.text}, nil
+
+	case t.kind == tokenIdent:
+		switch word := strings.ToLower(t.text); word {
+		case "now", "today", "yesterday":
+			p.next()
+			return &Time{At: t.pos, Kind: TimeKeyword, Text: word}, nil
+
+		case "this", "last":
+			if unit, ok := timeUnit(p.peekAt(1)); ok {
+				p.next()
+				p.next()
+
+				return &Time{At: t.pos, Kind: TimeKeyword, Text: word + " " + unit}, nil
+			}
+		}
+	}
+
+	return nil, p.errorf(t, "expected a time, found %s", describe(t))
+}
+
+func (p *parser) parseBucket() (*Bucket, error) {
+	t := p.peek()
+
+	switch {
+	case p.acceptWord("AUTO"):
+		return &Bucket{At: t.pos, Keyword: "AUTO"}, nil
+	case p.acceptWord("MAX"):
+		return &Bucket{At: t.pos, Keyword: "MAX"}, nil
+	}
+
+	count, err := p.parseCount()
+	if err != nil {
+		return nil, err
+	}
+
+	unit, ok := timeUnit(p.peek())
+	if !ok {
+		return nil, p.errorf(p.peek(), "expected a unit of time, found %s", describe(p.peek()))
+	}
+
+	p.next()
+
+	return &Bucket{At: t.pos, Period: &Period{At: t.pos, Count: count, Unit: unit}}, nil
+}
+
+func (p *parser) parseTimezone() (string, error) {
+	if err := p.expectWord("TIMEZONE"); err != nil {
+		return "", err
+	}
+
+	t := p.next()
+	if t.kind != tokenString {
+		return "", p.errorf(t, "expected a time zone, found %s", describe(t))
+	}
+
+	return t.text, nil
+}
+
+// timeUnit returns the singular unit of time a token names, if it names one.
+func timeUnit(t token) (string, bool) {
+	if t.kind != tokenIdent {
+		return "", false
+	}
+
+	unit := strings.TrimSuffix(strings.ToLower(t.text), "s")
+
+	switch unit {
+	case "second", "minute", "hour", "day", "week", "month", "quarter", "year":
+		return unit, true
+	default:
+		return "", false
+	}
+}
+
+// parseList parses the expressions, which may be named with AS, of SELECT
+// and FACET.
+func (p *parser) parseList() ([]Expr, error) {
+	exprs := []Expr{}
+
+	for {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if expr, err = p.parseAlias(expr); err != nil {
+			return nil, err
+		}
+
+		exprs = append(exprs, expr)
+
+		if !p.acceptSymbol(",") {
+			return exprs, nil
+		}
+	}
+}
+
+func (p *parser) parseAlias(expr Expr) (Expr, error) {
+	if !p.acceptWord("AS") {
+		return expr, nil
+	}
+
+	t := p.next()
+	if t.kind != tokenString && t.kind != tokenIdent && t.kind != tokenQuotedIdent {
+		return nil, p.errorf(t, "expected a name, found %s", describe(t))
+	}
+
+	return &Alias{At: expr.Position(), Expr: expr, Name: t.text}, nil
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptWord("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Binary{At: left.Position(), Op: "OR", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptWord("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Binary{At: left.Position(), Op: "AND", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	t := p.peek()
+	if !p.acceptWord("NOT") {
+		return p.parseComparison()
+	}
+
+	expr, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Unary{At: t.pos, Op: "NOT", Expr: expr}, nil
+}
+
+// comparisonOperators are the symbols comparing two values.
+var comparisonOperators = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	not := false
+
+	if p.isWord("NOT") {
+		following := p.peekAt(1)
+		if following.kind != tokenIdent {
+			return left, nil
+		}
+
+		switch strings.ToUpper(following.text) {
+		case "LIKE", "RLIKE", "IN":
+			p.next()
+			not = true
+			t = p.peek()
+		default:
+			return left, nil
+		}
+	}
+
+	switch {
+	case t.kind == tokenSymbol && comparisonOperators[t.text]:
+		p.next()
+
+		op := t.text
+		if op == "<>" {
+			op = "!="
+		}
+
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Binary{At: left.Position(), Op: op, Left: left, Right: right}, nil
+
+	case p.isWord("LIKE") || p.isWord("RLIKE"):
+		p.next()
+
+		op := strings.ToUpper(t.text)
+		if not {
+			op = "NOT " + op
+		}
+
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Binary{At: left.Position(), Op: op, Left: left, Right: right}, nil
+
+	case p.acceptWord("IN"):
+		open := p.peek()
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+
+		values, err := p.parseInValues(open)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+
+		return &In{At: left.Position(), Expr: left, Not: not, Values: values}, nil
+
+	case p.acceptWord("IS"):
+		not := p.acceptWord("NOT")
+
+		value := p.next()
+		switch word := strings.ToUpper(value.text); {
+		case value.kind == tokenIdent && (word == "NULL" || word == "TRUE" || word == "FALSE"):
+			return &Is{At: left.Position(), Expr: left, Not: not, Value: word}, nil
+		default:
+			return nil, p.errorf(value, "expected NULL, TRUE or FALSE, found %s", describe(value))
+		}
+	}
+
+	return left, nil
+}
+
+// parseInValues parses the values of IN after the opening parenthesis, which
+// are either a list of expressions or a subquery.
+func (p *parser) parseInValues(open token) ([]Expr, error) {
+	if p.isSubquery() {
+		subquery, err := p.parseSubquery(open)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Expr{subquery}, nil
+	}
+
+	values := []Expr{}
+
+	for {
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+
+		if !p.acceptSymbol(",") {
+			return values, nil
+		}
+	}
+}
+
+func (p *parser) parseAdditive() (Expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isSymbol("+") || p.isSymbol("-") {
+		op := p.next().text
+
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Binary{At: left.Position(), Op: op, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isSymbol("*") || p.isSymbol("/") {
+		op := p.next().text
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Binary{At: left.Position(), Op: op, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.peek()
+	if !p.acceptSymbol("-") {
+		return p.parsePrimary()
+	}
+
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Unary{At: t.pos, Op: "-", Expr: expr}, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenNumber:
+		if _, err := strconv.ParseFloat(t.text, 64); err != nil {
+			return nil, p.errorf(t, "invalid number %s", describe(t))
+		}
+
+		return &Literal{At: t.pos, Kind: LiteralNumber, Value: t.text}, nil
+
+	case tokenString:
+		return &Literal{At: t.pos, Kind: LiteralString, Value: t.text}, nil
+
+	case tokenRawString:
+		return &Literal{At: t.pos, Kind: LiteralString, Value: t.text, Raw: true}, nil
+
+	case tokenQuotedIdent:
+		return &Ident{At: t.pos, Name: t.text, Quoted: true}, nil
+
+	case tokenSymbol:
+		switch t.text {
+		case "*":
+			return &Star{At: t.pos}, nil
+		case "(":
+			var expr Expr
+			var err error
+
+			if p.isSubquery() {
+				expr, err = p.parseSubquery(t)
+			} else {
+				expr, err = p.parseExpr()
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+
+			return expr, nil
+		}
+
+	case tokenIdent:
+		if p.acceptSymbol("(") {
+			return p.parseCall(t)
+		}
+
+		switch word := strings.ToUpper(t.text); word {
+		case "TRUE", "FALSE":
+			return &Literal{At: t.pos, Kind: LiteralBoolean, Value: word}, nil
+		case "NULL":
+			return &Literal{At: t.pos, Kind: LiteralNull, Value: word}, nil
+		}
+
+		if IsKeyword(t.text) {
+			return nil, p.errorf(t, "unexpected keyword %s", strings.ToUpper(t.text))
+		}
+
+		return &Ident{At: t.pos, Name: t.text}, nil
+	}
+
+	return nil, p.errorf(t, "expected an expression, found %s", describe(t))
+}
+
+func (p *parser) parseCall(name token) (Expr, error) {
+	c := &Call{At: name.pos, Name: name.text, Args: []Expr{}}
+
+	if p.acceptSymbol(")") {
+		return c, nil
+	}
+
+	for {
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Args = append(c.Args, arg)
+
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// parseArg parses a function argument, which may also be a WHERE condition,
+// as in filter(), or a period of time, as in rate().
+func (p *parser) parseArg() (Expr, error) {
+	var arg Expr
+
+	t := p.peek()
+
+	if p.acceptWord("WHERE") {
+		condition, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		arg = &WhereArg{At: t.pos, Condition: condition}
+	} else if unit, ok := timeUnit(p.peekAt(1)); ok && t.kind == tokenNumber {
+		count, err := p.parseCount()
+		if err != nil {
+			return nil, err
+		}
+
+		p.next()
+		arg = &Period{At: t.pos, Count: count, Unit: unit}
+	} else {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		arg = expr
+	}
+
+	return p.parseAlias(arg)
+}
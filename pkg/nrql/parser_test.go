@@ -0,0 +1,250 @@
+//go:build unit
+// +build unit
+
+package nrql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	q, err := Parse("SELECT count(*) AS 'total', percentile(duration, 95)\n" +
+		"FROM Transaction, `My Events`\n" +
+		"WHERE appName = 'api' AND (error IS true OR host NOT IN ('a', 'b'))\n" +
+		"FACET CASES(WHERE name LIKE '%x%' AS 'x') ORDER BY count(*) DESC\n" +
+		"LIMIT 20 OFFSET 10 SINCE 1 day ago UNTIL now COMPARE WITH 1 week ago\n" +
+		"TIMESERIES 5 minutes SLIDE BY AUTO WITH TIMEZONE 'America/Los_Angeles' EXTRAPOLATE")
+	require.NoError(t, err)
+
+	require.Len(t, q.Select.Exprs, 2)
+	total := q.Select.Exprs[0].(*Alias)
+	assert.Equal(t, "total", total.Name)
+	assert.Equal(t, &Call{At: Pos{Offset: 7, Line: 1, Column: 8}, Name: "count", Args: []Expr{&Star{At: Pos{Offset: 13, Line: 1, Column: 14}}}}, total.Expr)
+
+	assert.Equal(t, Pos{Offset: 53, Line: 2, Column: 1}, q.From.At)
+	assert.Equal(t, []*Ident{
+		{At: Pos{Offset: 58, Line: 2, Column: 6}, Name: "Transaction"},
+		{At: Pos{Offset: 71, Line: 2, Column: 19}, Name: "My Events", Quoted: true},
+	}, q.From.EventTypes)
+
+	and := q.Where.Condition.(*Binary)
+	assert.Equal(t, "AND", and.Op)
+	assert.Equal(t, "=", and.Left.(*Binary).Op)
+	or := and.Right.(*Binary)
+	assert.Equal(t, "OR", or.Op)
+	assert.Equal(t, "TRUE", or.Left.(*Is).Value)
+	assert.True(t, or.Right.(*In).Not)
+
+	cases := q.Facet.Exprs[0].(*Call)
+	assert.Equal(t, "CASES", cases.Name)
+	assert.IsType(t, &WhereArg{}, cases.Args[0].(*Alias).Expr)
+	assert.True(t, q.Facet.OrderBy.Descending)
+
+	assert.Equal(t, 20, q.Limit.Count)
+	assert.Equal(t, 10, q.Offset.Count)
+	assert.Equal(t, &Period{At: q.Since.Time.At, Count: 1, Unit: "day"}, q.Since.Time.Period)
+	assert.Equal(t, TimeKeyword, q.Until.Time.Kind)
+	assert.Equal(t, "COMPARE WITH", q.CompareWith.Keyword)
+	assert.Equal(t, "week", q.CompareWith.Time.Period.Unit)
+	assert.Equal(t, 5, q.Timeseries.Bucket.Period.Count)
+	assert.Equal(t, "AUTO", q.SlideBy.Bucket.Keyword)
+	assert.Equal(t, "America/Los_Angeles", q.WithTimezone.Zone)
+	assert.NotNil(t, q.Extrapolate)
+	assert.Equal(t, 6, q.Extrapolate.At.Line)
+}
+
+func TestParseTimes(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]Time{
+		"3 hours ago":           {Kind: TimeAgo, Period: &Period{Count: 3, Unit: "hour"}},
+		"1600000000000":         {Kind: TimeEpoch, Text: "1600000000000"},
+		"'2021-01-01 00:00:00'": {Kind: TimeString, Text: "2021-01-01 00:00:00"},
+		"Yesterday":             {Kind: TimeKeyword, Text: "yesterday"},
+		"this quarter":          {Kind: TimeKeyword, Text: "this quarter"},
+		"last week":             {Kind: TimeKeyword, Text: "last week"},
+	}
+
+	for since, expected := range cases {
+		q, err := Parse("SELECT count(*) FROM Log SINCE " + since)
+		require.NoError(t, err, since)
+
+		actual := *q.Since.Time
+		actual.At = Pos{}
+		if actual.Period != nil {
+			actual.Period.At = Pos{}
+		}
+
+		assert.Equal(t, expected, actual, since)
+	}
+}
+
+func TestParseSubqueries(t *testing.T) {
+	t.Parallel()
+
+	q, err := Parse("SELECT count(*) FROM Transaction\n" +
+		"WHERE entity.guid IN (SELECT uniques(entity.guid) FROM Span WHERE name = 'x' LIMIT MAX)\n" +
+		"AND duration > (SELECT average(duration) FROM Transaction SINCE 1 day ago)\n" +
+		"SINCE 1 hour ago")
+	require.NoError(t, err)
+
+	and := q.Where.Condition.(*Binary)
+
+	in := and.Left.(*In)
+	require.Len(t, in.Values, 1)
+	subquery := in.Values[0].(*Subquery)
+	assert.Equal(t, Pos{Offset: 54, Line: 2, Column: 22}, subquery.At)
+	assert.Equal(t, "Span", subquery.Query.From.EventTypes[0].Name)
+	assert.True(t, subquery.Query.Limit.Max)
+	assert.Nil(t, subquery.Query.Since)
+
+	scalar := and.Right.(*Binary).Right.(*Subquery)
+	assert.Equal(t, "average", scalar.Query.Select.Exprs[0].(*Call).Name)
+	assert.Equal(t, "day", scalar.Query.Since.Time.Period.Unit)
+
+	assert.Equal(t, "hour", q.Since.Time.Period.Unit)
+}
+
+func TestParseRawStrings(t *testing.T) {
+	t.Parallel()
+
+	q, err := Parse(`SELECT count(*) FROM Log WHERE message RLIKE r'\d+ (ms|s)' OR host NOT RLIKE R"web-\w'"`)
+	require.NoError(t, err)
+
+	or := q.Where.Condition.(*Binary)
+
+	rlike := or.Left.(*Binary)
+	assert.Equal(t, "RLIKE", rlike.Op)
+	assert.Equal(t, &Literal{At: Pos{Offset: 45, Line: 1, Column: 46}, Kind: LiteralString, Value: `\d+ (ms|s)`, Raw: true}, rlike.Right)
+
+	notRlike := or.Right.(*Binary)
+	assert.Equal(t, "NOT RLIKE", notRlike.Op)
+	assert.Equal(t, `web-\w'`, notRlike.Right.(*Literal).Value)
+
+	// r is still an attribute name when it isn't followed by a quote.
+	q, err = Parse("SELECT r FROM Log WHERE r = 'x'")
+	require.NoError(t, err)
+	assert.Equal(t, "r", q.Select.Exprs[0].(*Ident).Name)
+}
+
+func TestParseComments(t *testing.T) {
+	t.Parallel()
+
+	queries := []string{
+		"SELECT count(*) FROM Transaction WHERE a = 1 -- comment\nSINCE 1 day ago",
+		"SELECT count(*) FROM Transaction WHERE a = 1 // comment\nSINCE 1 day ago",
+		"SELECT count(*) FROM Transaction WHERE a = 1 /* multi-line\ncomment */ SINCE 1 day ago",
+		"-- leading comment\nSELECT count(*) FROM Transaction WHERE /* inline */ a = 1 SINCE 1 day ago // trailing",
+	}
+
+	for _, query := range queries {
+		q, err := Parse(query)
+		require.NoError(t, err, query)
+
+		where := q.Where.Condition.(*Binary)
+		assert.Equal(t, "=", where.Op, query)
+		assert.Equal(t, &Literal{At: where.Right.Position(), Kind: LiteralNumber, Value: "1"}, where.Right, query)
+		assert.Equal(t, "day", q.Since.Time.Period.Unit, query)
+	}
+
+	// Lines are still counted within comments.
+	q, err := Parse("SELECT count(*) /* a\nb */ FROM Transaction\n-- c\nSINCE 1 day ago")
+	require.NoError(t, err)
+	assert.Equal(t, Pos{Offset: 26, Line: 2, Column: 6}, q.From.At)
+	assert.Equal(t, 4, q.Since.At.Line)
+}
+
+func TestParseNumbers(t *testing.T) {
+	t.Parallel()
+
+	for _, number := range []string{"1", "1.5", ".5", "1e3", "1.5E-3", "2e+10"} {
+		q, err := Parse("SELECT count(*) FROM Transaction WHERE a > " + number)
+		require.NoError(t, err, number)
+		assert.Equal(t, number, q.Where.Condition.(*Binary).Right.(*Literal).Value, number)
+	}
+}
+
+func TestParseSyntaxErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		line    int
+		column  int
+		message string
+	}{
+		"SELECT count(*)":                                                   {1, 1, "missing FROM clause"},
+		"SELECT count(*\nFROM Transaction":                                  {2, 1, `expected ")", found "FROM"`},
+		"SELECT count(*)\nFROM Transaction\nWHERE name = 'x":                {3, 14, "unterminated string"},
+		"SELECT count(*) FROM Transaction SINCE 1 hour":                     {1, 46, "expected AGO, found end of query"},
+		"SELECT count(*) FROM Transaction SINCE last":                       {1, 40, `expected a time, found "last"`},
+		"SELECT count(*) FROM Transaction WITH TIMEZONE UTC":                {1, 48, `expected a time zone, found "UTC"`},
+		"SELECT count(*) FROM Transaction TIMESERIES 5":                     {1, 46, "expected a unit of time, found end of query"},
+		"SELECT count(*) FROM Transaction FACET name ORDER count":           {1, 51, `expected BY, found "count"`},
+		"SELECT count(*) FROM Transaction SINCE 1 day ago SINCE 1 hour ago": {1, 50, "duplicate SINCE clause"},
+		"SELECT count(*) FROM `Transaction":                                 {1, 22, "unterminated backtick quote"},
+		"SELECT count(*) FROM WHERE":                                        {1, 22, `expected an event type, found "WHERE"`},
+		"SELECT count(*) FROM Transaction LIMIT 1.5":                        {1, 40, `expected a whole number, found "1.5"`},
+		"SELECT 1.2.3 FROM Transaction":                                     {1, 8, `invalid number "1.2.3"`},
+		"SELECT 2e FROM Transaction":                                        {1, 8, `invalid number "2e"`},
+		"SELECT count(*) FROM Transaction /* since":                         {1, 34, "unterminated comment"},
+		"SELECT count(*) FROM Log WHERE message RLIKE r'x":                  {1, 46, "unterminated string"},
+		"SELECT count(*) FROM Log WHERE a IN (SELECT a WHERE b = 1)":        {1, 37, "missing FROM clause"},
+		"SELECT count(*) FROM Log WHERE a IN (SELECT a FROM Span":           {1, 56, `expected ")", found end of query`},
+		"SELECT count(*) FROM Log WHERE a IN (SELECT a FROM Span) )":        {1, 58, `expected a clause, found ")"`},
+	}
+
+	for query, expected := range cases {
+		_, err := Parse(query)
+
+		var syntaxErr *SyntaxError
+		require.True(t, errors.As(err, &syntaxErr), query)
+		assert.Equal(t, expected.line, syntaxErr.Pos.Line, query)
+		assert.Equal(t, expected.column, syntaxErr.Pos.Column, query)
+		assert.Equal(t, expected.message, syntaxErr.Message, query)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	q, err := Parse("SELECT filter(sum(a), WHERE b IN (1, c)) + -d FROM T")
+	require.NoError(t, err)
+
+	idents := []string{}
+	Walk(q.Select.Exprs[0], func(expr Expr) bool {
+		if ident, ok := expr.(*Ident); ok {
+			idents = append(idents, ident.Name)
+		}
+
+		return true
+	})
+
+	assert.Equal(t, []string{"a", "b", "c", "d"}, idents)
+
+	// Returning false skips the expressions within.
+	calls := 0
+	Walk(q.Select.Exprs[0], func(expr Expr) bool {
+		if _, ok := expr.(*Call); ok {
+			calls++
+			return false
+		}
+
+		return true
+	})
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestIsKeyword(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsKeyword("FROM"))
+	assert.True(t, IsKeyword("since"))
+	assert.False(t, IsKeyword("duration"))
+}
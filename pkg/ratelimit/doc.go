@@ -0,0 +1,15 @@
+/*
+Package ratelimit provides a token bucket rate limiter for requests made to
+the New Relic APIs.
+
+A Limiter can be shared by every client built from the same configuration so
+that the combined request rate of all API packages stays within a budget.
+
+	limiter := ratelimit.NewLimiter(10, 5) // 10 requests per second, bursts of 5
+
+	client, err := newrelic.New(
+		newrelic.ConfigPersonalAPIKey(os.Getenv("NEW_RELIC_API_KEY")),
+		newrelic.ConfigRateLimiter(config.EndpointFamilies.NerdGraph, limiter),
+	)
+*/
+package ratelimit
@@ -0,0 +1,114 @@
+//go:build unit
+// +build unit
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterAllowBurst(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(1, 3)
+
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+}
+
+func TestLimiterInvalidBurst(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(1, 0)
+
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+}
+
+func TestLimiterRefill(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(10, 1)
+	now := l.last
+
+	assert.Equal(t, time.Duration(0), l.reserve(now))
+	assert.Equal(t, 100*time.Millisecond, l.reserve(now))
+
+	// Tokens accrue at the configured rate, but never beyond the burst size
+	l.refill(now.Add(time.Hour))
+	assert.Equal(t, float64(1), l.tokens)
+}
+
+func TestLimiterWait(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(50, 1)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.Wait(context.Background()))
+	}
+
+	// The first request uses the burst, the remaining four wait 20ms each
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(70*time.Millisecond))
+}
+
+func TestLimiterWaitConcurrent(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(100, 5)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i := 0; i < 15; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, l.Wait(context.Background()))
+		}()
+	}
+
+	wg.Wait()
+
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(90*time.Millisecond))
+}
+
+func TestLimiterWaitContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(0.1, 1)
+	require.True(t, l.Allow())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := l.Wait(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	// The abandoned reservation is returned to the bucket
+	assert.InDelta(t, 0, l.tokens, 0.01)
+}
+
+func TestLimiterWaitContextAlreadyDone(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, l.Wait(ctx), context.Canceled)
+	assert.True(t, l.Allow())
+}
@@ -22,10 +22,12 @@ type Region struct {
 	infrastructureBaseURL string
 	insightsBaseURL       string
 	logsBaseURL           string
+	metricsBaseURL        string
 	nerdGraphBaseURL      string
 	restBaseURL           string
 	syntheticsBaseURL     string
 	insightsKeysBaseURL   string
+	tracesBaseURL         string
 }
 
 // String returns a human readable value for the specified Region Name
@@ -160,6 +162,16 @@ func (r *Region) SetInsightsBaseURL(url string) {
 	}
 }
 
+// InsightsBaseURL returns the base URL for Insights custom insert API calls
+func (r *Region) InsightsBaseURL() string {
+	if r == nil {
+		log.Errorf("call to nil region.InsightsBaseURL")
+		return ""
+	}
+
+	return r.insightsBaseURL
+}
+
 // InsightsURL returns the Full URL for Insights custom insert API calls
 func (r *Region) InsightsURL(accountID int) string {
 	if r == nil {
@@ -234,3 +246,45 @@ func (r *Region) LogsURL() string {
 
 	return r.logsBaseURL
 }
+
+//
+// Metrics
+//
+
+// SetMetricsBaseURL Allows overriding the Metrics Base URL
+func (r *Region) SetMetricsBaseURL(url string) {
+	if r != nil && url != "" {
+		r.metricsBaseURL = url
+	}
+}
+
+// MetricsURL returns the Full URL for the Metric API
+func (r *Region) MetricsURL() string {
+	if r == nil {
+		log.Errorf("call to nil region.MetricsURL")
+		return ""
+	}
+
+	return r.metricsBaseURL
+}
+
+//
+// Traces
+//
+
+// SetTracesBaseURL Allows overriding the Traces Base URL
+func (r *Region) SetTracesBaseURL(url string) {
+	if r != nil && url != "" {
+		r.tracesBaseURL = url
+	}
+}
+
+// TracesURL returns the Full URL for the Trace API
+func (r *Region) TracesURL() string {
+	if r == nil {
+		log.Errorf("call to nil region.TracesURL")
+		return ""
+	}
+
+	return r.tracesBaseURL
+}
@@ -27,9 +27,11 @@ var Regions = map[Name]*Region{
 		insightsBaseURL:       "https://insights-collector.newrelic.com/v1",
 		insightsKeysBaseURL:   "https://insights.newrelic.com/internal_api/1",
 		logsBaseURL:           "https://log-api.newrelic.com/log/v1",
+		metricsBaseURL:        "https://metric-api.newrelic.com/metric/v1",
 		nerdGraphBaseURL:      "https://api.newrelic.com/graphql",
 		restBaseURL:           "https://api.newrelic.com/v2",
 		syntheticsBaseURL:     "https://synthetics.newrelic.com/synthetics/api",
+		tracesBaseURL:         "https://trace-api.newrelic.com/trace/v1",
 	},
 	EU: {
 		name:                  "EU",
@@ -37,9 +39,11 @@ var Regions = map[Name]*Region{
 		insightsBaseURL:       "https://insights-collector.eu01.nr-data.net/v1",
 		insightsKeysBaseURL:   "https://insights.eu.newrelic.com/internal_api/1",
 		logsBaseURL:           "https://log-api.eu.newrelic.com/log/v1",
+		metricsBaseURL:        "https://metric-api.eu.newrelic.com/metric/v1",
 		nerdGraphBaseURL:      "https://api.eu.newrelic.com/graphql",
 		restBaseURL:           "https://api.eu.newrelic.com/v2",
 		syntheticsBaseURL:     "https://synthetics.eu.newrelic.com/synthetics/api",
+		tracesBaseURL:         "https://trace-api.eu.newrelic.com/trace/v1",
 	},
 	Staging: {
 		name:                  "Staging",
@@ -47,9 +51,11 @@ var Regions = map[Name]*Region{
 		insightsBaseURL:       "https://staging-insights-collector.newrelic.com/v1",
 		insightsKeysBaseURL:   "https://staging-insights.newrelic.com/internal_api/1",
 		logsBaseURL:           "https://staging-log-api.newrelic.com/log/v1",
+		metricsBaseURL:        "https://staging-metric-api.newrelic.com/metric/v1",
 		nerdGraphBaseURL:      "https://staging-api.newrelic.com/graphql",
 		restBaseURL:           "https://staging-api.newrelic.com/v2",
 		syntheticsBaseURL:     "https://staging-synthetics.newrelic.com/synthetics/api",
+		tracesBaseURL:         "https://staging-trace-api.newrelic.com/trace/v1",
 	},
 	Local: {
 		name:                  "Local",
@@ -57,9 +63,11 @@ var Regions = map[Name]*Region{
 		insightsBaseURL:       "http://localhost:3000/v1",
 		insightsKeysBaseURL:   "http://localhost:3000/internal_api/1",
 		logsBaseURL:           "http://localhost:3000/log/v1",
+		metricsBaseURL:        "http://localhost:3000/metric/v1",
 		nerdGraphBaseURL:      "http://localhost:3000/graphql",
 		restBaseURL:           "http://localhost:3000/v2",
 		syntheticsBaseURL:     "http://localhost:3000/synthetics/api",
+		tracesBaseURL:         "http://localhost:3000/trace/v1",
 	},
 }
 
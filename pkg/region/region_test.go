@@ -131,6 +131,52 @@ func TestLogsURLs(t *testing.T) {
 	}
 }
 
+func TestMetricsURLs(t *testing.T) {
+	t.Parallel()
+
+	pairs := map[Name]string{
+		US:      "https://metric-api.newrelic.com/metric/v1",
+		EU:      "https://metric-api.eu.newrelic.com/metric/v1",
+		Staging: "https://staging-metric-api.newrelic.com/metric/v1",
+		Local:   "http://localhost:3000/metric/v1",
+	}
+
+	for k, v := range pairs {
+		assert.Equal(t, v, Regions[k].MetricsURL())
+	}
+}
+
+func TestTracesURLs(t *testing.T) {
+	t.Parallel()
+
+	pairs := map[Name]string{
+		US:      "https://trace-api.newrelic.com/trace/v1",
+		EU:      "https://trace-api.eu.newrelic.com/trace/v1",
+		Staging: "https://staging-trace-api.newrelic.com/trace/v1",
+		Local:   "http://localhost:3000/trace/v1",
+	}
+
+	for k, v := range pairs {
+		assert.Equal(t, v, Regions[k].TracesURL())
+	}
+}
+
+func TestInsightsURLs(t *testing.T) {
+	t.Parallel()
+
+	pairs := map[Name]string{
+		US:      "https://insights-collector.newrelic.com/v1",
+		EU:      "https://insights-collector.eu01.nr-data.net/v1",
+		Staging: "https://staging-insights-collector.newrelic.com/v1",
+		Local:   "http://localhost:3000/v1",
+	}
+
+	for k, v := range pairs {
+		assert.Equal(t, v, Regions[k].InsightsBaseURL())
+		assert.Equal(t, v+"/accounts/12345/events", Regions[k].InsightsURL(12345))
+	}
+}
+
 func TestNerdgraphURLs(t *testing.T) {
 	t.Parallel()
 
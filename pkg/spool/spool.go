@@ -0,0 +1,596 @@
+// Package spool provides a disk-backed write-ahead queue, used to persist
+// the data queued by the batching clients until it has been sent.
+//
+// Records are appended to segment files in a directory, and acknowledged once
+// they have been sent.  A segment file is removed once every record in it has
+// been acknowledged.  Records that could not be sent, such as during an
+// outage, are released to be replayed while the process runs, and records left
+// unacknowledged when a process exits are replayed when the spool is next
+// opened, so delivery is at least once.
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSegmentSize is the default size in bytes at which a new
+	// segment file is started.
+	DefaultMaxSegmentSize = 8 << 20
+
+	// DefaultMaxSize is the default maximum size in bytes of the segment
+	// files in a spool.
+	DefaultMaxSize = 256 << 20
+
+	// DefaultSyncInterval is the default interval at which segment files
+	// are synced to disk with SyncInterval.
+	DefaultSyncInterval = time.Second
+
+	// DefaultReplayInterval is the default interval at which released
+	// records are replayed.
+	DefaultReplayInterval = 5 * time.Second
+
+	// DefaultMaxReplayInterval is the default interval the replay interval
+	// backs off to while released records keep failing.
+	DefaultMaxReplayInterval = 5 * time.Minute
+)
+
+// segmentExt is the extension of segment files.
+const segmentExt = ".seg"
+
+// recordHeaderSize is the size of the header each record is written with,
+// holding the length and CRC-32 checksum of its data.
+const recordHeaderSize = 8
+
+var (
+	// ErrFull is returned when appending a record would take the spool
+	// over its maximum size.
+	ErrFull = errors.New("spool: maximum size reached")
+
+	// ErrClosed is returned when appending to a closed spool.
+	ErrClosed = errors.New("spool: closed")
+)
+
+// SyncPolicy is when records appended to a spool are synced to disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs every record to disk as it is appended.
+	SyncAlways SyncPolicy = iota
+
+	// SyncInterval syncs records to disk when appending, at most once per
+	// SyncInterval, and when the spool is closed.
+	SyncInterval
+
+	// SyncNever leaves syncing records to disk to the operating system.
+	SyncNever
+)
+
+// Config is the configuration of a spool.
+type Config struct {
+	// Dir is the directory the segment files are written to.  It is created
+	// if it doesn't exist, and must only be used by one spool at a time.
+	Dir string
+
+	// MaxSegmentSize is the size in bytes at which a new segment file is
+	// started.  Defaults to DefaultMaxSegmentSize.
+	MaxSegmentSize int64
+
+	// MaxSize is the maximum size in bytes of the segment files in the
+	// spool.  Records are rejected with ErrFull once it is reached, until
+	// enough records are acknowledged.  Defaults to DefaultMaxSize.
+	MaxSize int64
+
+	// Sync is when records are synced to disk.  Defaults to SyncAlways.
+	Sync SyncPolicy
+
+	// SyncInterval is the interval records are synced to disk at with
+	// SyncInterval.  Defaults to DefaultSyncInterval.
+	SyncInterval time.Duration
+
+	// ReplayInterval is how long the batching clients wait before replaying
+	// released records.  Defaults to DefaultReplayInterval.
+	ReplayInterval time.Duration
+
+	// MaxReplayInterval is how long the wait between replays grows to,
+	// doubling each time released records are replayed and fail again.
+	// Defaults to DefaultMaxReplayInterval.
+	MaxReplayInterval time.Duration
+}
+
+// Record identifies a record appended to a spool, to acknowledge it with.
+type Record struct {
+	segment uint64
+	offset  int64
+}
+
+// Spool is a disk-backed write-ahead queue.  It is safe for concurrent use.
+type Spool struct {
+	mu       sync.Mutex
+	cfg      Config
+	segments map[uint64]*segment
+	replay   []*segment
+	released map[Record]bool
+	active   *segment
+	file     *os.File
+	nextSeq  uint64
+	size     int64
+	lastSync time.Time
+	closed   bool
+}
+
+type segment struct {
+	seq     uint64
+	path    string
+	size    int64
+	records int
+	acked   map[int64]bool
+	sealed  bool
+}
+
+// Open opens the spool in the configured directory, recovering the records
+// left in it to be replayed.
+func Open(cfg Config) (*Spool, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("spool: no directory specified")
+	}
+	if cfg.MaxSegmentSize == 0 {
+		cfg.MaxSegmentSize = DefaultMaxSegmentSize
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = DefaultMaxSize
+	}
+	if cfg.SyncInterval == 0 {
+		cfg.SyncInterval = DefaultSyncInterval
+	}
+	if cfg.ReplayInterval == 0 {
+		cfg.ReplayInterval = DefaultReplayInterval
+	}
+	if cfg.MaxReplayInterval == 0 {
+		cfg.MaxReplayInterval = DefaultMaxReplayInterval
+	}
+	if cfg.MaxSegmentSize < 0 || cfg.MaxSize < cfg.MaxSegmentSize {
+		return nil, errors.New("spool: invalid size specified")
+	}
+	if cfg.Sync < SyncAlways || cfg.Sync > SyncNever || cfg.SyncInterval < 0 {
+		return nil, errors.New("spool: invalid sync policy specified")
+	}
+	if cfg.ReplayInterval < 0 || cfg.MaxReplayInterval < cfg.ReplayInterval {
+		return nil, errors.New("spool: invalid replay interval specified")
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		cfg:      cfg,
+		segments: map[uint64]*segment{},
+		released: map[Record]bool{},
+		nextSeq:  1,
+	}
+
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// recover loads the segment files left in the directory to be replayed,
+// truncating any record left partially written.
+func (s *Spool) recover() error {
+	files, err := ioutil.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), segmentExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		seg := &segment{
+			seq:    seq,
+			path:   filepath.Join(s.cfg.Dir, f.Name()),
+			acked:  map[int64]bool{},
+			sealed: true,
+		}
+
+		data, err := ioutil.ReadFile(seg.path)
+		if err != nil {
+			return err
+		}
+
+		records, valid := readRecords(data)
+		if valid < int64(len(data)) {
+			if err := os.Truncate(seg.path, valid); err != nil {
+				return err
+			}
+		}
+
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+
+		if len(records) == 0 {
+			if err := os.Remove(seg.path); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		seg.size = valid
+		seg.records = len(records)
+
+		s.segments[seq] = seg
+		s.replay = append(s.replay, seg)
+		s.size += seg.size
+	}
+
+	sort.Slice(s.replay, func(i, j int) bool {
+		return s.replay[i].seq < s.replay[j].seq
+	})
+
+	return nil
+}
+
+// Replay calls fn with each record recovered when the spool was opened, then
+// with each record released since the last replay, in the order they were
+// appended, until fn returns false.  Records must be acknowledged once they
+// have been sent, as with appended records.  Records not passed to fn, or
+// passed to fn when it returns false, are replayed by the next call.
+func (s *Spool) Replay(fn func(data []byte, record Record) bool) error {
+	s.mu.Lock()
+	segments := s.replay
+	s.replay = nil
+	s.mu.Unlock()
+
+	for i, seg := range segments {
+		data, err := ioutil.ReadFile(seg.path)
+		if err != nil {
+			s.requeue(segments[i:])
+			return err
+		}
+
+		records, _ := readRecords(data)
+		for j, r := range records {
+			if !fn(r.data, Record{segment: seg.seq, offset: r.offset}) {
+				for _, r := range records[j:] {
+					s.Release(Record{segment: seg.seq, offset: r.offset})
+				}
+
+				s.requeue(segments[i+1:])
+				return nil
+			}
+		}
+	}
+
+	released := s.takeReleased()
+
+	for i, record := range released {
+		data, err := s.readRecord(record)
+		if err != nil {
+			s.Release(released[i:]...)
+			return err
+		}
+
+		// The record was acknowledged since it was released.
+		if data == nil {
+			continue
+		}
+
+		if !fn(data, record) {
+			s.Release(released[i:]...)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// requeue puts back the recovered segments a replay didn't get to, for the
+// next replay to start with.
+func (s *Spool) requeue(segments []*segment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.replay = append(segments, s.replay...)
+}
+
+// Release returns records that could not be sent to the spool, to be
+// replayed by the next call to Replay.  Records released more than once are
+// replayed once, and acknowledged records are not replayed.
+func (s *Spool) Release(records ...Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range records {
+		if seg, ok := s.segments[r.segment]; ok && !seg.acked[r.offset] {
+			s.released[r] = true
+		}
+	}
+}
+
+// Released returns the number of records released since the last replay.
+func (s *Spool) Released() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.released)
+}
+
+// takeReleased returns the records released since the last replay, in the
+// order they were appended.
+func (s *Spool) takeReleased() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]Record, 0, len(s.released))
+	for r := range s.released {
+		records = append(records, r)
+	}
+	s.released = map[Record]bool{}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].segment != records[j].segment {
+			return records[i].segment < records[j].segment
+		}
+
+		return records[i].offset < records[j].offset
+	})
+
+	return records
+}
+
+// readRecord reads the data of a released record from its segment file.  It
+// returns nil when the record has been acknowledged.
+func (s *Spool) readRecord(record Record) ([]byte, error) {
+	s.mu.Lock()
+	seg, ok := s.segments[record.segment]
+	if !ok || seg.acked[record.offset] {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	path := seg.path
+	s.mu.Unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, recordHeaderSize)
+	if _, err := f.ReadAt(header, record.offset); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(header[0:4]))
+	if _, err := f.ReadAt(data, record.offset+recordHeaderSize); err != nil {
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, fmt.Errorf("spool: record at %d in %s is corrupt", record.offset, path)
+	}
+
+	return data, nil
+}
+
+// Config returns the configuration of the spool, with defaults applied.
+func (s *Spool) Config() Config {
+	return s.cfg
+}
+
+// Append writes a record to the spool.  The Record returned is used to
+// acknowledge it once it has been sent.
+func (s *Spool) Append(data []byte) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return Record{}, ErrClosed
+	}
+
+	size := int64(recordHeaderSize + len(data))
+	if s.size+size > s.cfg.MaxSize {
+		return Record{}, ErrFull
+	}
+
+	if s.active == nil || (s.active.records > 0 && s.active.size+size > s.cfg.MaxSegmentSize) {
+		if err := s.rotate(); err != nil {
+			return Record{}, err
+		}
+	}
+
+	offset := s.active.size
+
+	buf := make([]byte, size)
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(data))
+	copy(buf[recordHeaderSize:], data)
+
+	if _, err := s.file.Write(buf); err != nil {
+		return Record{}, err
+	}
+
+	s.active.size += size
+	s.active.records++
+	s.size += size
+
+	if err := s.sync(false); err != nil {
+		return Record{}, err
+	}
+
+	return Record{segment: s.active.seq, offset: offset}, nil
+}
+
+// Ack acknowledges records that have been sent, removing each segment file
+// once every record in it is acknowledged.  Acknowledging a record more than
+// once has no effect.
+func (s *Spool) Ack(records ...Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range records {
+		seg, ok := s.segments[r.segment]
+		if !ok || seg.acked[r.offset] {
+			continue
+		}
+
+		seg.acked[r.offset] = true
+		delete(s.released, r)
+		s.removeIfAcked(seg)
+	}
+}
+
+// Size returns the size in bytes of the segment files in the spool.
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.size
+}
+
+// Close syncs and closes the segment file being written.  Records that
+// have not been acknowledged are replayed when the spool is next opened.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	s.closed = true
+
+	return s.seal()
+}
+
+// rotate seals the segment being written and starts a new one.
+func (s *Spool) rotate() error {
+	if err := s.seal(); err != nil {
+		return err
+	}
+
+	seg := &segment{
+		seq:   s.nextSeq,
+		path:  filepath.Join(s.cfg.Dir, fmt.Sprintf("%020d%s", s.nextSeq, segmentExt)),
+		acked: map[int64]bool{},
+	}
+
+	file, err := os.OpenFile(seg.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	s.nextSeq++
+	s.segments[seg.seq] = seg
+	s.active = seg
+	s.file = file
+
+	return nil
+}
+
+// seal syncs and closes the segment being written, if any.
+func (s *Spool) seal() error {
+	if s.active == nil {
+		return nil
+	}
+
+	err := s.sync(true)
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	s.active.sealed = true
+	s.removeIfAcked(s.active)
+
+	s.active = nil
+	s.file = nil
+
+	return err
+}
+
+// sync syncs the segment being written to disk as the sync policy requires,
+// or always when sealing it, unless the policy is to never sync.
+func (s *Spool) sync(sealing bool) error {
+	switch s.cfg.Sync {
+	case SyncNever:
+		return nil
+	case SyncInterval:
+		if !sealing && time.Since(s.lastSync) < s.cfg.SyncInterval {
+			return nil
+		}
+	}
+
+	s.lastSync = time.Now()
+
+	return s.file.Sync()
+}
+
+// removeIfAcked removes a sealed segment once every record in it has been
+// acknowledged.
+func (s *Spool) removeIfAcked(seg *segment) {
+	if !seg.sealed || len(seg.acked) < seg.records {
+		return
+	}
+
+	delete(s.segments, seg.seq)
+	s.size -= seg.size
+
+	// A segment file that can't be removed is replayed when the spool is
+	// next opened, which delivery at least once allows for.
+	_ = os.Remove(seg.path)
+}
+
+// storedRecord is a record read from a segment file, along with its offset.
+type storedRecord struct {
+	offset int64
+	data   []byte
+}
+
+// readRecords returns the records in a segment file's data, along with the
+// size of the data holding complete records.
+func readRecords(data []byte) ([]storedRecord, int64) {
+	records := []storedRecord{}
+	offset := 0
+
+	for len(data)-offset >= recordHeaderSize {
+		length := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		checksum := binary.BigEndian.Uint32(data[offset+4 : offset+8])
+
+		start := offset + recordHeaderSize
+		if length > len(data)-start {
+			break
+		}
+
+		record := data[start : start+length]
+		if crc32.ChecksumIEEE(record) != checksum {
+			break
+		}
+
+		records = append(records, storedRecord{offset: int64(offset), data: record})
+		offset = start + length
+	}
+
+	return records, int64(offset)
+}
@@ -0,0 +1,272 @@
+//go:build unit
+// +build unit
+
+package spool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+
+	return files
+}
+
+func replayAll(t *testing.T, s *Spool) ([]string, []Record) {
+	data := []string{}
+	records := []Record{}
+
+	require.NoError(t, s.Replay(func(d []byte, r Record) bool {
+		data = append(data, string(d))
+		records = append(records, r)
+		return true
+	}))
+
+	return data, records
+}
+
+func TestSpoolReplaysUnacknowledgedRecords(t *testing.T) {
+	t.Parallel()
+
+	dir := testDir(t)
+
+	s, err := Open(Config{Dir: dir})
+	require.NoError(t, err)
+
+	_, err = s.Append([]byte("one"))
+	require.NoError(t, err)
+	two, err := s.Append([]byte("two"))
+	require.NoError(t, err)
+	_, err = s.Append([]byte("three"))
+	require.NoError(t, err)
+
+	s.Ack(two)
+	require.NoError(t, s.Close())
+
+	s, err = Open(Config{Dir: dir})
+	require.NoError(t, err)
+
+	// Records are replayed per segment, so acknowledged records sharing a
+	// segment with unacknowledged ones are replayed too.
+	data, records := replayAll(t, s)
+	assert.Equal(t, []string{"one", "two", "three"}, data)
+
+	s.Ack(records...)
+	assert.Empty(t, segmentFiles(t, dir))
+	assert.Zero(t, s.Size())
+
+	require.NoError(t, s.Close())
+
+	s, err = Open(Config{Dir: dir})
+	require.NoError(t, err)
+
+	data, _ = replayAll(t, s)
+	assert.Empty(t, data)
+	require.NoError(t, s.Close())
+}
+
+func TestSpoolRemovesAcknowledgedSegments(t *testing.T) {
+	t.Parallel()
+
+	dir := testDir(t)
+
+	// Each record takes a segment of its own.
+	s, err := Open(Config{Dir: dir, MaxSegmentSize: recordHeaderSize + 3, Sync: SyncNever})
+	require.NoError(t, err)
+
+	one, err := s.Append([]byte("one"))
+	require.NoError(t, err)
+	_, err = s.Append([]byte("two"))
+	require.NoError(t, err)
+	three, err := s.Append([]byte("three"))
+	require.NoError(t, err)
+
+	assert.Len(t, segmentFiles(t, dir), 3)
+	assert.Equal(t, int64(3*recordHeaderSize+11), s.Size())
+
+	s.Ack(one)
+	assert.Len(t, segmentFiles(t, dir), 2)
+
+	// The segment being written is kept until the spool is closed.
+	s.Ack(three)
+	assert.Len(t, segmentFiles(t, dir), 2)
+
+	require.NoError(t, s.Close())
+	assert.Len(t, segmentFiles(t, dir), 1)
+
+	s, err = Open(Config{Dir: dir})
+	require.NoError(t, err)
+
+	data, _ := replayAll(t, s)
+	assert.Equal(t, []string{"two"}, data)
+	require.NoError(t, s.Close())
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(Config{Dir: testDir(t), MaxSegmentSize: 2 * (recordHeaderSize + 3), MaxSize: 2 * (recordHeaderSize + 3)})
+	require.NoError(t, err)
+
+	one, err := s.Append([]byte("one"))
+	require.NoError(t, err)
+	_, err = s.Append([]byte("two"))
+	require.NoError(t, err)
+
+	_, err = s.Append([]byte("six"))
+	assert.Equal(t, ErrFull, err)
+
+	// Acknowledged records only free space once their segment is removed.
+	s.Ack(one)
+	_, err = s.Append([]byte("six"))
+	assert.Equal(t, ErrFull, err)
+
+	require.NoError(t, s.Close())
+
+	_, err = s.Append([]byte("six"))
+	assert.Equal(t, ErrClosed, err)
+	assert.Equal(t, ErrClosed, s.Close())
+}
+
+func TestSpoolTruncatesPartialRecords(t *testing.T) {
+	t.Parallel()
+
+	dir := testDir(t)
+
+	s, err := Open(Config{Dir: dir})
+	require.NoError(t, err)
+
+	_, err = s.Append([]byte("one"))
+	require.NoError(t, err)
+	_, err = s.Append([]byte("two"))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	// Simulate a crash while the last record was being written.
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 1)
+	require.NoError(t, os.Truncate(files[0], 2*recordHeaderSize+5))
+
+	s, err = Open(Config{Dir: dir})
+	require.NoError(t, err)
+
+	data, _ := replayAll(t, s)
+	assert.Equal(t, []string{"one"}, data)
+	assert.Equal(t, int64(recordHeaderSize+3), s.Size())
+
+	// New records are written to a new segment after the recovered ones.
+	_, err = s.Append([]byte("three"))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = Open(Config{Dir: dir})
+	require.NoError(t, err)
+
+	data, _ = replayAll(t, s)
+	assert.Equal(t, []string{"one", "three"}, data)
+	require.NoError(t, s.Close())
+}
+
+func TestSpoolConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := Open(Config{})
+	assert.Error(t, err)
+
+	_, err = Open(Config{Dir: testDir(t), MaxSegmentSize: 10, MaxSize: 5})
+	assert.Error(t, err)
+
+	_, err = Open(Config{Dir: testDir(t), Sync: SyncNever + 1})
+	assert.Error(t, err)
+
+	_, err = Open(Config{Dir: testDir(t), ReplayInterval: time.Minute, MaxReplayInterval: time.Second})
+	assert.Error(t, err)
+
+	s, err := Open(Config{Dir: filepath.Join(testDir(t), "nested"), Sync: SyncInterval})
+	require.NoError(t, err)
+
+	_, err = s.Append([]byte("one"))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+}
+
+func TestSpoolIgnoresDuplicateAcks(t *testing.T) {
+	t.Parallel()
+
+	dir := testDir(t)
+
+	s, err := Open(Config{Dir: dir, MaxSegmentSize: 2 * (recordHeaderSize + 3)})
+	require.NoError(t, err)
+
+	one, err := s.Append([]byte("one"))
+	require.NoError(t, err)
+	_, err = s.Append([]byte("two"))
+	require.NoError(t, err)
+
+	// Sealing the segment by starting the next one.
+	_, err = s.Append([]byte("six"))
+	require.NoError(t, err)
+	require.Len(t, segmentFiles(t, dir), 2)
+
+	// Acknowledging one record twice doesn't count for the other.
+	s.Ack(one)
+	s.Ack(one)
+	assert.Len(t, segmentFiles(t, dir), 2)
+
+	require.NoError(t, s.Close())
+}
+
+func TestSpoolReplaysReleasedRecords(t *testing.T) {
+	t.Parallel()
+
+	s, err := Open(Config{Dir: testDir(t)})
+	require.NoError(t, err)
+
+	records := []Record{}
+	for _, data := range []string{"one", "two", "three", "four"} {
+		r, err := s.Append([]byte(data))
+		require.NoError(t, err)
+		records = append(records, r)
+	}
+
+	// Nothing is replayed until records are released.
+	data, _ := replayAll(t, s)
+	assert.Empty(t, data)
+
+	s.Release(records[3], records[1], records[1], records[2])
+	s.Ack(records[2])
+	assert.Equal(t, 2, s.Released())
+
+	data, replayed := replayAll(t, s)
+	assert.Equal(t, []string{"two", "four"}, data)
+	assert.Equal(t, []Record{records[1], records[3]}, replayed)
+	assert.Zero(t, s.Released())
+
+	// Records not taken by a replay are replayed by the next one.
+	s.Release(records[1], records[3])
+	require.NoError(t, s.Replay(func(d []byte, r Record) bool {
+		return false
+	}))
+
+	data, _ = replayAll(t, s)
+	assert.Equal(t, []string{"two", "four"}, data)
+
+	require.NoError(t, s.Close())
+}
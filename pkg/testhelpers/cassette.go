@@ -0,0 +1,421 @@
+package testhelpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/newrelic-client-go/internal/utils"
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/region"
+)
+
+// CassetteModeEnv is the environment variable that selects the mode cassettes
+// created by NewCassetteTestConfig run in, either "record" or "replay".
+const CassetteModeEnv = "NEW_RELIC_CASSETTE_MODE"
+
+// CassetteMode is the mode a Cassette runs in.
+type CassetteMode string
+
+// CassetteModes specifies the modes a Cassette can run in.
+var CassetteModes = struct {
+	// Record performs requests against New Relic and records the interactions.
+	Record CassetteMode
+	// Replay responds to requests with previously recorded interactions.
+	Replay CassetteMode
+}{
+	Record: "record",
+	Replay: "replay",
+}
+
+// Cassette is an http.RoundTripper that records requests and their responses
+// to a file, then replays them in later test runs, allowing integration tests
+// to run offline without a New Relic account.
+//
+// Recorded interactions are matched on the request method, URL and body.  For
+// NerdGraph requests the body is the GraphQL query, with whitespace collapsed,
+// and its variables.  Interactions that match the same request are replayed in
+// the order they were recorded, with the last one repeated once exhausted.
+//
+// API keys are obfuscated in recorded headers, and any secrets added to the
+// cassette are obfuscated wherever they appear in recorded URLs and bodies.
+type Cassette struct {
+	path      string
+	mode      CassetteMode
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	data     cassetteData
+	secrets  []string
+	replayed map[string]int
+}
+
+type cassetteData struct {
+	Region       string            `json:"region,omitempty"`
+	Env          map[string]string `json:"env,omitempty"`
+	Interactions []Interaction     `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded HTTP request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded HTTP response.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// NewCassette returns a Cassette stored in the file at path.  In replay mode,
+// the file is loaded and requests are answered from it.  In record mode,
+// requests are performed using the transport, or http.DefaultTransport if it is
+// nil, and the file is written when the test completes.
+func NewCassette(t *testing.T, path string, mode CassetteMode, transport http.RoundTripper) *Cassette {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	c := &Cassette{
+		path:      path,
+		mode:      mode,
+		transport: transport,
+		data:      cassetteData{Env: map[string]string{}},
+		replayed:  map[string]int{},
+	}
+
+	switch mode {
+	case CassetteModes.Replay:
+		b, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(b, &c.data))
+	case CassetteModes.Record:
+		t.Cleanup(func() {
+			require.NoError(t, c.Save())
+		})
+	default:
+		t.Fatalf("invalid cassette mode %q", mode)
+	}
+
+	return c
+}
+
+// NewCassetteTestConfig returns a configuration for an integration test whose
+// requests go through the cassette stored at path, in the mode given by the
+// NEW_RELIC_CASSETTE_MODE environment variable, defaulting to replay.  In
+// record mode, the configuration is the one from NewIntegrationTestConfig.  In
+// replay mode no credentials are needed.  If nothing has been recorded yet, the
+// test runs live against New Relic with the configuration from
+// NewIntegrationTestConfig, and a nil Cassette, skipping without credentials.
+func NewCassetteTestConfig(t *testing.T, path string) (config.Config, *Cassette) {
+	mode := CassetteMode(os.Getenv(CassetteModeEnv))
+	if mode == "" {
+		mode = CassetteModes.Replay
+	}
+
+	if mode == CassetteModes.Record {
+		cfg := NewIntegrationTestConfig(t)
+
+		c := NewCassette(t, path, mode, cfg.HTTPTransport)
+		c.AddSecret(cfg.PersonalAPIKey, cfg.AdminAPIKey, cfg.InsightsInsertKey, cfg.LicenseKey)
+		c.data.Region = cfg.Region().String()
+		cfg.HTTPTransport = c
+
+		return cfg, c
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return NewIntegrationTestConfig(t), nil
+	}
+
+	c := NewCassette(t, path, mode, nil)
+
+	cfg := NewTestConfig(t, nil)
+
+	if c.data.Region != "" {
+		regName, err := region.Parse(c.data.Region)
+		require.NoError(t, err)
+
+		reg, err := region.Get(regName)
+		require.NoError(t, err)
+		require.NoError(t, cfg.SetRegion(reg))
+	}
+
+	cfg.HTTPTransport = c
+
+	return cfg, c
+}
+
+// AddSecret adds values, such as API keys, that are obfuscated wherever they
+// appear in recorded URLs, headers and bodies.
+func (c *Cassette) AddSecret(secrets ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range secrets {
+		if s != "" {
+			c.secrets = append(c.secrets, s)
+		}
+	}
+}
+
+// Getenv returns the value of an environment variable, such as a test account
+// ID.  Values are read from the environment and recorded in record mode, and
+// read from the cassette in replay mode.  A nil Cassette, as returned when a
+// test runs live, reads from the environment.
+func (c *Cassette) Getenv(name string) string {
+	if c == nil {
+		return os.Getenv(name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.mode == CassetteModes.Replay {
+		return c.data.Env[name]
+	}
+
+	value := os.Getenv(name)
+	c.data.Env[name] = value
+
+	return value
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.mode == CassetteModes.Replay {
+		return c.replay(req, body)
+	}
+
+	return c.record(req, body)
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := interactionKey(req.Method, req.URL.String(), body)
+
+	matches := []Interaction{}
+	for _, i := range c.data.Interactions {
+		if interactionKey(i.Request.Method, i.Request.URL, []byte(i.Request.Body)) == key {
+			matches = append(matches, i)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("cassette %s: no interaction recorded for %s %s", c.path, req.Method, req.URL)
+	}
+
+	n := c.replayed[key]
+	if n >= len(matches) {
+		n = len(matches) - 1
+	}
+	c.replayed[key]++
+
+	recorded := matches[n].Response
+
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data.Interactions = append(c.data.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    c.scrub(req.URL.String()),
+			Header: c.scrubHeader(req.Header),
+			Body:   c.scrub(string(body)),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     c.scrubHeader(resp.Header),
+			Body:       c.scrub(string(respBody)),
+		},
+	})
+
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the cassette file.  It is called
+// automatically when a test recording a cassette completes.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := json.MarshalIndent(c.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.path, append(b, '\n'), 0644)
+}
+
+func (c *Cassette) scrub(s string) string {
+	for _, secret := range c.secrets {
+		s = strings.ReplaceAll(s, secret, utils.Obfuscate(secret))
+	}
+
+	return s
+}
+
+// scrubHeader returns a copy of the header with API key headers obfuscated
+// and secrets obfuscated wherever they appear in other values.
+func (c *Cassette) scrubHeader(header http.Header) http.Header {
+	h := utils.ObfuscateHeader(header)
+
+	for k, values := range h {
+		scrubbed := make([]string, len(values))
+		for i, v := range values {
+			scrubbed[i] = c.scrub(v)
+		}
+
+		h[k] = scrubbed
+	}
+
+	return h
+}
+
+// requestBody reads the body of a request, leaving it in place to be sent,
+// and returns it decompressed if it was gzipped.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		return gunzip(body)
+	}
+
+	return body, nil
+}
+
+// responseBody reads the body of a response, replacing it with a decompressed
+// copy if it was gzipped.
+func responseBody(resp *http.Response) ([]byte, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		if body, err = gunzip(body); err != nil {
+			return nil, err
+		}
+
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = int64(len(body))
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+func gunzip(body []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// interactionKey identifies the requests an interaction matches, from the
+// method, the URL with its query parameters sorted, and the body.  GraphQL
+// bodies are reduced to the query, with whitespace collapsed, and the
+// variables, and other JSON bodies are compacted.
+func interactionKey(method, rawURL string, body []byte) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		u.RawQuery = u.Query().Encode()
+		rawURL = u.String()
+	}
+
+	var gql struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+
+	var normalized string
+	if err := json.Unmarshal(body, &gql); err == nil && gql.Query != "" {
+		vars, _ := json.Marshal(gql.Variables)
+		normalized = strings.Join(strings.Fields(gql.Query), " ") + "\n" + string(vars)
+	} else {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			b, _ := json.Marshal(v)
+			normalized = string(b)
+		} else {
+			normalized = string(body)
+		}
+	}
+
+	return method + " " + rawURL + "\n" + normalized
+}
@@ -0,0 +1,167 @@
+//go:build unit
+// +build unit
+
+package testhelpers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCassetteSecret = "NRAK-0123456789ABCDEF"
+
+func cassetteRequest(t *testing.T, client *http.Client, url, body string) (int, string) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Api-Key", testCassetteSecret)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, string(b)
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"data":{"call":` + string(rune('0'+calls)) + `,"key":"` + testCassetteSecret + `"}}`))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "test.json")
+
+	recorder := NewCassette(t, path, CassetteModes.Record, nil)
+	recorder.AddSecret(testCassetteSecret)
+	client := &http.Client{Transport: recorder}
+
+	query := `{"query":"query($id: Int!) {\n  actor {\n    account(id: $id) { name }\n  }\n}","variables":{"id":1,"name":"a"}}`
+	status, body := cassetteRequest(t, client, ts.URL+"/graphql?b=2&a=1", query)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Contains(t, body, testCassetteSecret)
+
+	_, _ = cassetteRequest(t, client, ts.URL+"/graphql?b=2&a=1", query)
+	require.NoError(t, recorder.Save())
+	require.Equal(t, 2, calls)
+
+	recorded, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(recorded), testCassetteSecret)
+	assert.Contains(t, string(recorded), "NRAK-012*************")
+
+	player := NewCassette(t, path, CassetteModes.Replay, nil)
+	client = &http.Client{Transport: player}
+
+	// Whitespace in the query, the order of variables and the order of query
+	// parameters don't affect matching.
+	equivalent := `{"variables":{"name":"a","id":1},"query":"query($id: Int!) { actor { account(id: $id) { name } } }"}`
+
+	status, body = cassetteRequest(t, client, ts.URL+"/graphql?a=1&b=2", equivalent)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.JSONEq(t, `{"data":{"call":1,"key":"NRAK-012*************"}}`, body)
+
+	_, body = cassetteRequest(t, client, ts.URL+"/graphql?a=1&b=2", equivalent)
+	assert.Contains(t, body, `"call":2`)
+
+	// The last matching interaction is repeated once exhausted.
+	_, body = cassetteRequest(t, client, ts.URL+"/graphql?a=1&b=2", equivalent)
+	assert.Contains(t, body, `"call":2`)
+
+	assert.Equal(t, 2, calls)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/graphql", strings.NewReader(`{"query":"{ other }"}`))
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no interaction recorded for POST")
+}
+
+func TestCassetteRecordHeaders(t *testing.T) {
+	t.Parallel()
+
+	const licenseKey = "0123456789abcdef0123456789abcdefNRAL"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Echo", r.Header.Get("X-Trace"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "test.json")
+
+	recorder := NewCassette(t, path, CassetteModes.Record, nil)
+	recorder.AddSecret(testCassetteSecret, licenseKey)
+	client := &http.Client{Transport: recorder}
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/log/v1", strings.NewReader(`[]`))
+	require.NoError(t, err)
+	req.Header.Set("X-License-Key", licenseKey)
+	req.Header.Set("X-Trace", "key="+testCassetteSecret)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.NoError(t, recorder.Save())
+
+	recorded, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(recorded), licenseKey)
+	assert.NotContains(t, string(recorded), testCassetteSecret)
+	assert.Contains(t, string(recorded), "01234567****")
+	assert.Contains(t, string(recorded), "key=NRAK-012*************")
+}
+
+func TestCassetteGetenv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.json")
+	os.Setenv("NEW_RELIC_TEST_CASSETTE_ENV", "12345")
+	defer os.Unsetenv("NEW_RELIC_TEST_CASSETTE_ENV")
+
+	recorder := NewCassette(t, path, CassetteModes.Record, nil)
+	assert.Equal(t, "12345", recorder.Getenv("NEW_RELIC_TEST_CASSETTE_ENV"))
+	require.NoError(t, recorder.Save())
+
+	os.Unsetenv("NEW_RELIC_TEST_CASSETTE_ENV")
+
+	player := NewCassette(t, path, CassetteModes.Replay, nil)
+	assert.Equal(t, "12345", player.Getenv("NEW_RELIC_TEST_CASSETTE_ENV"))
+}
+
+func TestNewCassetteTestConfig_live(t *testing.T) {
+	t.Setenv(CassetteModeEnv, "")
+	t.Setenv("NEW_RELIC_API_KEY", testCassetteSecret)
+	t.Setenv("NEW_RELIC_TEST_CASSETTE_ENV", "12345")
+
+	cfg, c := NewCassetteTestConfig(t, filepath.Join(t.TempDir(), "missing.json"))
+	assert.Nil(t, c)
+	assert.Equal(t, testCassetteSecret, cfg.PersonalAPIKey)
+	assert.Nil(t, cfg.HTTPTransport)
+	assert.Equal(t, "12345", c.Getenv("NEW_RELIC_TEST_CASSETTE_ENV"))
+}
+
+func TestInteractionKey(t *testing.T) {
+	t.Parallel()
+
+	a := interactionKey("POST", "https://api.newrelic.com/v2/x.json?b=1&a=2", []byte(`{"b": 1, "a": [1, 2]}`))
+	b := interactionKey("POST", "https://api.newrelic.com/v2/x.json?a=2&b=1", []byte(`{"a":[1,2],"b":1}`))
+	assert.Equal(t, a, b)
+
+	assert.NotEqual(t, a, interactionKey("PUT", "https://api.newrelic.com/v2/x.json?a=2&b=1", []byte(`{"a":[1,2],"b":1}`)))
+	assert.NotEqual(t, a, interactionKey("POST", "https://api.newrelic.com/v2/x.json?a=2&b=1", []byte(`{"a":[1,2],"b":2}`)))
+}
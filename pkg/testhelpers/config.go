@@ -37,6 +37,8 @@ func NewTestConfig(t *testing.T, testServer *httptest.Server) config.Config {
 		cfg.Region().SetRestBaseURL(testServer.URL)
 		cfg.Region().SetSyntheticsBaseURL(testServer.URL)
 		cfg.Region().SetLogsBaseURL(testServer.URL)
+		cfg.Region().SetMetricsBaseURL(testServer.URL)
+		cfg.Region().SetTracesBaseURL(testServer.URL)
 	}
 
 	return cfg
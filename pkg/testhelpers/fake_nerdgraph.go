@@ -0,0 +1,380 @@
+package testhelpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+)
+
+// FakeNerdGraphHandler resolves a field of a NerdGraph operation.  The value
+// returned is marshaled to JSON as the field's value, so the types of the
+// package under test can be returned as is.  Returning an error sets the
+// field to null and adds the error to the response's errors.
+type FakeNerdGraphHandler func(req *FakeNerdGraphRequest) (interface{}, error)
+
+// FakeNerdGraph is a fake NerdGraph server for unit tests.  Requests are parsed
+// and every selected field is dispatched to the handler registered for its
+// path, e.g. "actor.entitySearch" or "dashboardCreate".  Fields without a
+// handler of their own, such as "actor", are resolved by dispatching their
+// selected fields in turn.
+//
+// Handlers share a FakeStore, so create, read, update and delete handlers can
+// be written against the same in-memory state.
+type FakeNerdGraph struct {
+	// Server is the underlying test server.
+	Server *httptest.Server
+
+	// Store is the in-memory state shared by the handlers.
+	Store *FakeStore
+
+	mu       sync.Mutex
+	handlers map[string]FakeNerdGraphHandler
+	requests []FakeNerdGraphRequest
+}
+
+// FakeNerdGraphRequest is the request a FakeNerdGraphHandler resolves a field for.
+type FakeNerdGraphRequest struct {
+	// Operation is the type of the operation, "query" or "mutation".
+	Operation string
+
+	// OperationName is the name of the operation, if it is named.
+	OperationName string
+
+	// Field is the path of the field being resolved, e.g. "actor.entitySearch".
+	Field string
+
+	// Arguments are the field's arguments, with variables resolved.
+	Arguments map[string]interface{}
+
+	// Query is the GraphQL document sent.
+	Query string
+
+	// Variables are the variables sent with the query.
+	Variables map[string]interface{}
+
+	// Header is the header of the HTTP request.
+	Header http.Header
+
+	// Store is the in-memory state shared by the handlers.
+	Store *FakeStore
+}
+
+// DecodeArgument decodes the value of an argument into v, which would
+// usually be the input type the client sends for it.
+func (r *FakeNerdGraphRequest) DecodeArgument(name string, v interface{}) error {
+	value, ok := r.Arguments[name]
+	if !ok {
+		return fmt.Errorf("argument %q not given for %s", name, r.Field)
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// FakeNerdGraphError is an error returned by a FakeNerdGraphHandler with
+// extensions, such as an errorClass, included in the response.
+type FakeNerdGraphError struct {
+	Message    string
+	Extensions map[string]interface{}
+}
+
+func (e *FakeNerdGraphError) Error() string {
+	return e.Message
+}
+
+type fakeNerdGraphError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// NewFakeNerdGraph starts a fake NerdGraph server, which is closed when the
+// test completes.
+func NewFakeNerdGraph(t *testing.T) *FakeNerdGraph {
+	f := &FakeNerdGraph{
+		Store:    NewFakeStore(),
+		handlers: map[string]FakeNerdGraphHandler{},
+	}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Server.Close)
+
+	return f
+}
+
+// Config returns a test configuration whose NerdGraph requests are sent to
+// the fake server.
+func (f *FakeNerdGraph) Config(t *testing.T) config.Config {
+	cfg := NewTestConfig(t, nil)
+	cfg.Region().SetNerdGraphBaseURL(f.Server.URL)
+
+	return cfg
+}
+
+// Handle registers the handler for the field at the given path, replacing any
+// handler registered for it before.
+func (f *FakeNerdGraph) Handle(field string, handler FakeNerdGraphHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.handlers[field] = handler
+}
+
+// Requests returns the requests handlers have been called with, in order.
+func (f *FakeNerdGraph) Requests() []FakeNerdGraphRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeNerdGraphRequest(nil), f.requests...)
+}
+
+func (f *FakeNerdGraph) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{}
+	errs := []fakeNerdGraphError{}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil && r.Header.Get("Content-Encoding") == "gzip" {
+		body, err = gunzip(body)
+	}
+
+	var gql struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	var op *graphQLOperation
+	if err == nil {
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		err = d.Decode(&gql)
+	}
+
+	if err == nil {
+		op, err = parseGraphQLOperation(gql.Query, gql.OperationName)
+	}
+
+	if err != nil {
+		errs = append(errs, fakeNerdGraphError{Message: err.Error()})
+	} else {
+		req := FakeNerdGraphRequest{
+			Operation:     op.Type,
+			OperationName: op.Name,
+			Query:         gql.Query,
+			Variables:     gql.Variables,
+			Header:        r.Header,
+			Store:         f.Store,
+		}
+
+		response["data"] = f.resolve(&req, op.Selections, "", []interface{}{}, &errs)
+	}
+
+	if len(errs) > 0 {
+		response["errors"] = errs
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// resolve returns the values of the selected fields, dispatching each one to
+// its handler, or resolving its own selected fields if it has none.
+func (f *FakeNerdGraph) resolve(req *FakeNerdGraphRequest, fields []*graphQLField, parent string, path []interface{}, errs *[]fakeNerdGraphError) map[string]interface{} {
+	result := map[string]interface{}{}
+
+	for _, field := range fields {
+		fieldPath := field.Name
+		if parent != "" {
+			fieldPath = parent + "." + field.Name
+		}
+
+		key := field.ResponseKey()
+		responsePath := append(append([]interface{}{}, path...), key)
+
+		f.mu.Lock()
+		handler, ok := f.handlers[fieldPath]
+		nested := f.hasNestedHandlers(fieldPath)
+		f.mu.Unlock()
+
+		switch {
+		case ok:
+			fieldReq := *req
+			fieldReq.Field = fieldPath
+			fieldReq.Arguments = resolveGraphQLValue(field.Arguments, req.Variables).(map[string]interface{})
+
+			f.mu.Lock()
+			f.requests = append(f.requests, fieldReq)
+			f.mu.Unlock()
+
+			value, err := handler(&fieldReq)
+			if err != nil {
+				*errs = append(*errs, newFakeNerdGraphError(err, responsePath))
+				result[key] = nil
+				continue
+			}
+
+			result[key] = value
+		case nested && len(field.Selections) > 0:
+			result[key] = f.resolve(req, field.Selections, fieldPath, responsePath, errs)
+		default:
+			*errs = append(*errs, fakeNerdGraphError{
+				Message: fmt.Sprintf("no fake NerdGraph handler registered for %q", fieldPath),
+				Path:    responsePath,
+			})
+			result[key] = nil
+		}
+	}
+
+	return result
+}
+
+// hasNestedHandlers reports whether any handlers are registered for fields
+// selected within the field at the given path.
+func (f *FakeNerdGraph) hasNestedHandlers(field string) bool {
+	for k := range f.handlers {
+		if strings.HasPrefix(k, field+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newFakeNerdGraphError(err error, path []interface{}) fakeNerdGraphError {
+	e := fakeNerdGraphError{Message: err.Error(), Path: path}
+
+	if fakeErr, ok := err.(*FakeNerdGraphError); ok {
+		e.Extensions = fakeErr.Extensions
+	}
+
+	return e
+}
+
+// FakeStore is an in-memory store of the entities a fake server manages, in
+// named collections of values keyed by ID.  It is safe for concurrent use.
+type FakeStore struct {
+	mu          sync.Mutex
+	lastID      int
+	collections map[string]*fakeCollection
+}
+
+type fakeCollection struct {
+	ids    []string
+	values map[string]interface{}
+}
+
+// NewFakeStore returns an empty FakeStore.
+func NewFakeStore() *FakeStore {
+	return &FakeStore{collections: map[string]*fakeCollection{}}
+}
+
+// NextID returns a new ID, unique within the store.
+func (s *FakeStore) NextID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+
+	return s.lastID
+}
+
+// NextIDString returns a new ID, unique within the store, as a string.
+func (s *FakeStore) NextIDString() string {
+	return strconv.Itoa(s.NextID())
+}
+
+// Put stores the value with the given ID in a collection, replacing any
+// value stored with the ID before.
+func (s *FakeStore) Put(collection, id string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collections[collection]
+	if !ok {
+		c = &fakeCollection{values: map[string]interface{}{}}
+		s.collections[collection] = c
+	}
+
+	if _, ok := c.values[id]; !ok {
+		c.ids = append(c.ids, id)
+	}
+
+	c.values[id] = value
+}
+
+// Get returns the value with the given ID in a collection.
+func (s *FakeStore) Get(collection, id string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collections[collection]
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := c.values[id]
+
+	return value, ok
+}
+
+// Delete removes the value with the given ID from a collection, and reports
+// whether it was present.
+func (s *FakeStore) Delete(collection, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collections[collection]
+	if !ok {
+		return false
+	}
+
+	if _, ok := c.values[id]; !ok {
+		return false
+	}
+
+	delete(c.values, id)
+
+	for i := range c.ids {
+		if c.ids[i] == id {
+			c.ids = append(c.ids[:i], c.ids[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// List returns the values in a collection, in the order they were first stored.
+func (s *FakeStore) List(collection string) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := []interface{}{}
+
+	c, ok := s.collections[collection]
+	if !ok {
+		return values
+	}
+
+	for _, id := range c.ids {
+		values = append(values, c.values[id])
+	}
+
+	return values
+}
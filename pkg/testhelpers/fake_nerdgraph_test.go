@@ -0,0 +1,170 @@
+//go:build unit
+// +build unit
+
+package testhelpers_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/newrelic-client-go/pkg/common"
+	"github.com/newrelic/newrelic-client-go/pkg/dashboards"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+type fakeDashboardEntity struct {
+	Typename    string            `json:"__typename"`
+	AccountID   int               `json:"accountId"`
+	GUID        common.EntityGUID `json:"guid"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+}
+
+func newFakeDashboards(t *testing.T) (*testhelpers.FakeNerdGraph, dashboards.Dashboards) {
+	fake := testhelpers.NewFakeNerdGraph(t)
+
+	fake.Handle("dashboardCreate", func(req *testhelpers.FakeNerdGraphRequest) (interface{}, error) {
+		var accountID int
+		var input dashboards.DashboardInput
+
+		if err := req.DecodeArgument("accountId", &accountID); err != nil {
+			return nil, err
+		}
+
+		if err := req.DecodeArgument("dashboard", &input); err != nil {
+			return nil, err
+		}
+
+		guid := common.EntityGUID(fmt.Sprintf("dashboard-%d", req.Store.NextID()))
+		req.Store.Put("dashboards", string(guid), fakeDashboardEntity{
+			Typename:    "DashboardEntity",
+			AccountID:   accountID,
+			GUID:        guid,
+			Name:        input.Name,
+			Description: input.Description,
+		})
+
+		return dashboards.DashboardCreateResult{
+			EntityResult: dashboards.DashboardEntityResult{
+				AccountID:   accountID,
+				GUID:        guid,
+				Name:        input.Name,
+				Description: input.Description,
+			},
+		}, nil
+	})
+
+	fake.Handle("actor.entity", func(req *testhelpers.FakeNerdGraphRequest) (interface{}, error) {
+		var guid string
+		if err := req.DecodeArgument("guid", &guid); err != nil {
+			return nil, err
+		}
+
+		dashboard, ok := req.Store.Get("dashboards", guid)
+		if !ok {
+			return nil, nil
+		}
+
+		return dashboard, nil
+	})
+
+	fake.Handle("dashboardDelete", func(req *testhelpers.FakeNerdGraphRequest) (interface{}, error) {
+		var guid string
+		if err := req.DecodeArgument("guid", &guid); err != nil {
+			return nil, err
+		}
+
+		if !req.Store.Delete("dashboards", guid) {
+			return nil, &testhelpers.FakeNerdGraphError{
+				Message:    "dashboard not found",
+				Extensions: map[string]interface{}{"errorClass": "NOT_FOUND"},
+			}
+		}
+
+		return dashboards.DashboardDeleteResult{Status: dashboards.DashboardDeleteResultStatusTypes.SUCCESS}, nil
+	})
+
+	return fake, dashboards.New(fake.Config(t))
+}
+
+func TestFakeNerdGraph(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeDashboards(t)
+
+	created, err := client.DashboardCreate(12345, dashboards.DashboardInput{
+		Name:        "Fake dashboard",
+		Description: "created in memory",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 12345, created.EntityResult.AccountID)
+	assert.Equal(t, "Fake dashboard", created.EntityResult.Name)
+
+	guid := created.EntityResult.GUID
+
+	dashboard, err := client.GetDashboardEntity(guid)
+	require.NoError(t, err)
+	assert.Equal(t, guid, dashboard.GUID)
+	assert.Equal(t, "created in memory", dashboard.Description)
+
+	deleted, err := client.DashboardDelete(guid)
+	require.NoError(t, err)
+	assert.Equal(t, dashboards.DashboardDeleteResultStatusTypes.SUCCESS, deleted.Status)
+
+	_, err = client.GetDashboardEntity(guid)
+	assert.Error(t, err)
+
+	_, err = client.DashboardDelete(guid)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dashboard not found")
+
+	requests := fake.Requests()
+	require.Len(t, requests, 5)
+	assert.Equal(t, "mutation", requests[0].Operation)
+	assert.Equal(t, "dashboardCreate", requests[0].Field)
+	assert.Equal(t, "query", requests[1].Operation)
+	assert.Equal(t, "actor.entity", requests[1].Field)
+	assert.Equal(t, string(guid), requests[1].Arguments["guid"])
+}
+
+func TestFakeNerdGraphUnhandledField(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeDashboards(t)
+
+	_, err := client.DashboardUpdate(dashboards.DashboardInput{Name: "x"}, "guid")
+	require.Error(t, err)
+
+	var gqlErrs *errors.GraphQLErrors
+	require.ErrorAs(t, err, &gqlErrs)
+	assert.Contains(t, err.Error(), `no fake NerdGraph handler registered for "dashboardUpdate"`)
+}
+
+func TestFakeStore(t *testing.T) {
+	t.Parallel()
+
+	store := testhelpers.NewFakeStore()
+
+	store.Put("things", "a", 1)
+	store.Put("things", "b", 2)
+	store.Put("things", "a", 3)
+
+	value, ok := store.Get("things", "a")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+	assert.Equal(t, []interface{}{3, 2}, store.List("things"))
+
+	assert.True(t, store.Delete("things", "a"))
+	assert.False(t, store.Delete("things", "a"))
+	assert.Equal(t, []interface{}{2}, store.List("things"))
+
+	_, ok = store.Get("other", "a")
+	assert.False(t, ok)
+	assert.Empty(t, store.List("other"))
+
+	assert.NotEqual(t, store.NextID(), store.NextID())
+}
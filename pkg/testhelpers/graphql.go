@@ -0,0 +1,643 @@
+package testhelpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// graphQLOperation is a parsed GraphQL operation, reduced to what the fake
+// NerdGraph server needs to dispatch it: its type and selected fields.
+type graphQLOperation struct {
+	Type       string
+	Name       string
+	Selections []*graphQLField
+}
+
+// graphQLField is a field selected in a GraphQL operation.  Argument values
+// are literals, or graphQLVariable references resolved against the variables
+// sent with the request.  Until fragments are expanded, a selection may also
+// be a spread of the named fragment.
+type graphQLField struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]interface{}
+	Selections []*graphQLField
+
+	spread string
+}
+
+// ResponseKey returns the key the field's value is returned under.
+func (f *graphQLField) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+
+	return f.Name
+}
+
+// graphQLVariable is a reference to an operation variable.
+type graphQLVariable string
+
+// parseGraphQLOperation parses a GraphQL document and returns the operation
+// with the given name, or the first operation if name is empty.
+func parseGraphQLOperation(query, name string) (*graphQLOperation, error) {
+	p := &graphQLParser{lexer: graphQLLexer{src: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	operations := []*graphQLOperation{}
+	fragments := map[string][]*graphQLField{}
+
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.is(tokenPunct, "{"):
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+
+			operations = append(operations, &graphQLOperation{Type: "query", Selections: selections})
+		case p.is(tokenName, "query"), p.is(tokenName, "mutation"), p.is(tokenName, "subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+
+			operations = append(operations, op)
+		case p.is(tokenName, "fragment"):
+			fragmentName, selections, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+
+			fragments[fragmentName] = selections
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	var op *graphQLOperation
+	for _, o := range operations {
+		if name == "" || o.Name == name {
+			op = o
+			break
+		}
+	}
+
+	if op == nil {
+		if name != "" {
+			return nil, fmt.Errorf("unknown operation named %q", name)
+		}
+
+		return nil, fmt.Errorf("no operation found in document")
+	}
+
+	selections, err := expandFragments(op.Selections, fragments, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = selections
+
+	return op, nil
+}
+
+// expandFragments replaces fragment spreads with the fields they select.
+func expandFragments(fields []*graphQLField, fragments map[string][]*graphQLField, expanding map[string]bool) ([]*graphQLField, error) {
+	result := []*graphQLField{}
+
+	for _, f := range fields {
+		if f.spread != "" {
+			name := f.spread
+
+			selections, ok := fragments[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %q", name)
+			}
+
+			if expanding[name] {
+				return nil, fmt.Errorf("fragment %q spreads itself", name)
+			}
+
+			expanding[name] = true
+			expanded, err := expandFragments(selections, fragments, expanding)
+			delete(expanding, name)
+
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, expanded...)
+			continue
+		}
+
+		selections, err := expandFragments(f.Selections, fragments, expanding)
+		if err != nil {
+			return nil, err
+		}
+		f.Selections = selections
+
+		result = append(result, f)
+	}
+
+	return result, nil
+}
+
+// resolveGraphQLValue replaces variable references in a parsed argument value
+// with the values of the variables.
+func resolveGraphQLValue(value interface{}, variables map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case graphQLVariable:
+		return variables[string(v)]
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = resolveGraphQLValue(item, variables)
+		}
+
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[k] = resolveGraphQLValue(item, variables)
+		}
+
+		return result
+	default:
+		return v
+	}
+}
+
+type graphQLParser struct {
+	lexer graphQLLexer
+	tok   graphQLToken
+}
+
+func (p *graphQLParser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+
+	return nil
+}
+
+func (p *graphQLParser) is(kind graphQLTokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *graphQLParser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("syntax error: unexpected end of document")
+	}
+
+	return fmt.Errorf("syntax error: unexpected %q at offset %d", p.tok.value, p.tok.pos)
+}
+
+func (p *graphQLParser) expect(kind graphQLTokenKind, value string) error {
+	if p.tok.kind != kind || (value != "" && p.tok.value != value) {
+		return p.unexpected()
+	}
+
+	return p.advance()
+}
+
+func (p *graphQLParser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+
+	name := p.tok.value
+
+	return name, p.advance()
+}
+
+func (p *graphQLParser) parseOperation() (*graphQLOperation, error) {
+	op := &graphQLOperation{Type: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName {
+		op.Name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Variable definitions only describe the variables sent with the
+	// request, so they are skipped.
+	if p.is(tokenPunct, "(") {
+		if err := p.skipBalanced("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.skipDirectives(); err != nil {
+		return nil, err
+	}
+
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = selections
+
+	return op, nil
+}
+
+func (p *graphQLParser) parseFragment() (string, []*graphQLField, error) {
+	if err := p.advance(); err != nil {
+		return "", nil, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err = p.expect(tokenName, "on"); err != nil {
+		return "", nil, err
+	}
+
+	if _, err = p.name(); err != nil {
+		return "", nil, err
+	}
+
+	if err = p.skipDirectives(); err != nil {
+		return "", nil, err
+	}
+
+	selections, err := p.parseSelectionSet()
+
+	return name, selections, err
+}
+
+func (p *graphQLParser) parseSelectionSet() ([]*graphQLField, error) {
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+
+	selections := []*graphQLField{}
+
+	for !p.is(tokenPunct, "}") {
+		if p.is(tokenPunct, "...") {
+			fields, err := p.parseFragmentSelection()
+			if err != nil {
+				return nil, err
+			}
+
+			selections = append(selections, fields...)
+			continue
+		}
+
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+
+		selections = append(selections, field)
+	}
+
+	return selections, p.advance()
+}
+
+// parseFragmentSelection parses an inline fragment, whose fields are merged
+// into the enclosing selection set, or a fragment spread.
+func (p *graphQLParser) parseFragmentSelection() ([]*graphQLField, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		spread := &graphQLField{spread: p.tok.value}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		return []*graphQLField{spread}, p.skipDirectives()
+	}
+
+	if p.is(tokenName, "on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		if _, err := p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.skipDirectives(); err != nil {
+		return nil, err
+	}
+
+	return p.parseSelectionSet()
+}
+
+func (p *graphQLParser) parseField() (*graphQLField, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	field := &graphQLField{Name: name, Arguments: map[string]interface{}{}}
+
+	if p.is(tokenPunct, ":") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+
+		field.Alias = name
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is(tokenPunct, "(") {
+		if field.Arguments, err = p.parseArguments(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.skipDirectives(); err != nil {
+		return nil, err
+	}
+
+	if p.is(tokenPunct, "{") {
+		if field.Selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return field, nil
+}
+
+func (p *graphQLParser) parseArguments() (map[string]interface{}, error) {
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return nil, err
+	}
+
+	args := map[string]interface{}{}
+
+	for !p.is(tokenPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		if err = p.expect(tokenPunct, ":"); err != nil {
+			return nil, err
+		}
+
+		if args[name], err = p.parseValue(); err != nil {
+			return nil, err
+		}
+	}
+
+	return args, p.advance()
+}
+
+func (p *graphQLParser) parseValue() (interface{}, error) {
+	tok := p.tok
+
+	switch {
+	case p.is(tokenPunct, "$"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		name, err := p.name()
+
+		return graphQLVariable(name), err
+	case p.is(tokenPunct, "["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		list := []interface{}{}
+		for !p.is(tokenPunct, "]") {
+			item, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, item)
+		}
+
+		return list, p.advance()
+	case p.is(tokenPunct, "{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		object := map[string]interface{}{}
+		for !p.is(tokenPunct, "}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+
+			if err = p.expect(tokenPunct, ":"); err != nil {
+				return nil, err
+			}
+
+			if object[name], err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+
+		return object, p.advance()
+	case tok.kind == tokenNumber:
+		return json.Number(tok.value), p.advance()
+	case tok.kind == tokenString:
+		return tok.value, p.advance()
+	case tok.kind == tokenName:
+		var value interface{}
+
+		switch tok.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			// Enum values are returned as their names.
+			value = tok.value
+		}
+
+		return value, p.advance()
+	default:
+		return nil, p.unexpected()
+	}
+}
+
+func (p *graphQLParser) skipDirectives() error {
+	for p.is(tokenPunct, "@") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+
+		if _, err := p.name(); err != nil {
+			return err
+		}
+
+		if p.is(tokenPunct, "(") {
+			if _, err := p.parseArguments(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *graphQLParser) skipBalanced(open, close string) error {
+	depth := 0
+
+	for {
+		switch {
+		case p.tok.kind == tokenEOF:
+			return p.unexpected()
+		case p.is(tokenPunct, open):
+			depth++
+		case p.is(tokenPunct, close):
+			depth--
+		}
+
+		if err := p.advance(); err != nil {
+			return err
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+type graphQLTokenKind int
+
+const (
+	tokenEOF graphQLTokenKind = iota
+	tokenPunct
+	tokenName
+	tokenNumber
+	tokenString
+)
+
+type graphQLToken struct {
+	kind  graphQLTokenKind
+	value string
+	pos   int
+}
+
+// graphQLLexer splits a GraphQL document into tokens, skipping whitespace,
+// commas and comments, which are insignificant.
+type graphQLLexer struct {
+	src string
+	pos int
+}
+
+func (l *graphQLLexer) next() (graphQLToken, error) {
+	l.skipIgnored()
+
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return graphQLToken{kind: tokenEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return graphQLToken{kind: tokenPunct, value: "...", pos: start}, nil
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		l.pos++
+		return graphQLToken{kind: tokenPunct, value: string(c), pos: start}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+
+		return graphQLToken{kind: tokenName, value: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		l.pos++
+		for l.pos < len(l.src) && strings.IndexByte("0123456789.eE+-", l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+
+		return graphQLToken{kind: tokenNumber, value: l.src[start:l.pos], pos: start}, nil
+	case c == '"':
+		value, err := l.readString()
+		return graphQLToken{kind: tokenString, value: value, pos: start}, err
+	default:
+		return graphQLToken{}, fmt.Errorf("syntax error: unexpected character %q at offset %d", c, start)
+	}
+}
+
+func (l *graphQLLexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case ' ', '\t', '\n', '\r', ',':
+			l.pos++
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *graphQLLexer) readString() (string, error) {
+	start := l.pos
+
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return "", fmt.Errorf("syntax error: unterminated string at offset %d", start)
+		}
+
+		l.pos += end + 6
+
+		return l.src[start+3 : l.pos-3], nil
+	}
+
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+		case '"':
+			l.pos++
+
+			// GraphQL string escapes are the same as JSON's.
+			var value string
+			if err := json.Unmarshal([]byte(l.src[start:l.pos]), &value); err != nil {
+				return "", fmt.Errorf("syntax error: invalid string at offset %d: %s", start, err)
+			}
+
+			return value, nil
+		case '\n':
+			return "", fmt.Errorf("syntax error: unterminated string at offset %d", start)
+		default:
+			l.pos++
+		}
+	}
+
+	return "", fmt.Errorf("syntax error: unterminated string at offset %d", start)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
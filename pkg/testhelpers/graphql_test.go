@@ -0,0 +1,110 @@
+//go:build unit
+// +build unit
+
+package testhelpers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGraphQLOperation(t *testing.T) {
+	t.Parallel()
+
+	query := `
+	# Comments, commas and directives are ignored.
+	query Search($query: String!, $limit: Int = 10,) {
+		actor {
+			results: entitySearch(query: $query, options: { limit: $limit, tags: ["a", "b"] }) @include(if: true) {
+				...searchFields
+			}
+			account(id: 12345) { name }
+		}
+	}
+
+	fragment searchFields on EntitySearch {
+		count
+		results {
+			entities {
+				guid
+				... on DashboardEntityOutline { dashboardParentGuid }
+			}
+		}
+	}
+
+	mutation Other { dashboardDelete(guid: "abc", force: false, status: SUCCESS, value: null, ratio: -1.5e3) { status } }
+	`
+
+	op, err := parseGraphQLOperation(query, "")
+	require.NoError(t, err)
+	assert.Equal(t, "query", op.Type)
+	assert.Equal(t, "Search", op.Name)
+
+	require.Len(t, op.Selections, 1)
+	actor := op.Selections[0]
+	assert.Equal(t, "actor", actor.Name)
+
+	require.Len(t, actor.Selections, 2)
+	search := actor.Selections[0]
+	assert.Equal(t, "entitySearch", search.Name)
+	assert.Equal(t, "results", search.ResponseKey())
+
+	args := resolveGraphQLValue(search.Arguments, map[string]interface{}{"query": "name = 'x'", "limit": 5})
+	assert.Equal(t, map[string]interface{}{
+		"query": "name = 'x'",
+		"options": map[string]interface{}{
+			"limit": 5,
+			"tags":  []interface{}{"a", "b"},
+		},
+	}, args)
+
+	// The fragment spread is expanded, and the inline fragment merged.
+	require.Len(t, search.Selections, 2)
+	assert.Equal(t, "count", search.Selections[0].Name)
+	entities := search.Selections[1].Selections[0]
+	require.Len(t, entities.Selections, 2)
+	assert.Equal(t, "dashboardParentGuid", entities.Selections[1].Name)
+
+	assert.Equal(t, json.Number("12345"), actor.Selections[1].Arguments["id"])
+
+	op, err = parseGraphQLOperation(query, "Other")
+	require.NoError(t, err)
+	assert.Equal(t, "mutation", op.Type)
+	assert.Equal(t, map[string]interface{}{
+		"guid":   "abc",
+		"force":  false,
+		"status": "SUCCESS",
+		"value":  nil,
+		"ratio":  json.Number("-1.5e3"),
+	}, op.Selections[0].Arguments)
+
+	op, err = parseGraphQLOperation(`{ actor { user { name } } }`, "")
+	require.NoError(t, err)
+	assert.Equal(t, "query", op.Type)
+}
+
+func TestParseGraphQLOperationErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		`query { actor { `:                        "unexpected end of document",
+		`query { actor(id: ) { name } }`:          `unexpected ")"`,
+		`query { ...missing }`:                    `unknown fragment "missing"`,
+		`query { ...a } fragment a on T { ...a }`: `fragment "a" spreads itself`,
+		`query { actor(name: "unterminated) }`:    "unterminated string",
+		`query { actor % }`:                       "unexpected character",
+		``:                                        "no operation found",
+	}
+
+	for query, expected := range cases {
+		_, err := parseGraphQLOperation(query, "")
+		require.Error(t, err, query)
+		assert.Contains(t, err.Error(), expected, query)
+	}
+
+	_, err := parseGraphQLOperation(`query A { a }`, "B")
+	assert.EqualError(t, err, `unknown operation named "B"`)
+}
@@ -0,0 +1,79 @@
+package testhelpers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// IngestServer is a test server for the ingest APIs, such as the Metric and
+// Trace APIs, which records the JSON payloads sent to it.  Gzipped payloads
+// are decompressed.
+type IngestServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	payloads []json.RawMessage
+	header   http.Header
+}
+
+// NewIngestServer starts an IngestServer responding with the given status
+// code, which is closed when the test ends.
+func NewIngestServer(t *testing.T, status int) *IngestServer {
+	s := &IngestServer{status: status}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if !assert.NoError(t, err) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+
+		data, err := ioutil.ReadAll(body)
+		if !assert.NoError(t, err) || !assert.True(t, json.Valid(data), "payload isn't JSON: %s", data) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.payloads = append(s.payloads, data)
+		s.header = r.Header
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(s.status)
+		_, _ = w.Write([]byte(`{"requestId":"abc"}`))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Payloads returns the payloads received, in order.
+func (s *IngestServer) Payloads() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]json.RawMessage(nil), s.payloads...)
+}
+
+// Header returns the headers of the last request received.
+func (s *IngestServer) Header() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.header
+}
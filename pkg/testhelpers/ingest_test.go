@@ -0,0 +1,45 @@
+//go:build unit
+// +build unit
+
+package testhelpers_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+func TestIngestServer(t *testing.T) {
+	t.Parallel()
+
+	server := testhelpers.NewIngestServer(t, http.StatusAccepted)
+
+	resp, err := http.Post(server.URL, "application/json", bytes.NewBufferString(`[{"a":1}]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err = gz.Write([]byte(`[{"b":2}]`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, []json.RawMessage{json.RawMessage(`[{"a":1}]`), json.RawMessage(`[{"b":2}]`)}, server.Payloads())
+	assert.Equal(t, "gzip", server.Header().Get("Content-Encoding"))
+}
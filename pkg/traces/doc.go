@@ -0,0 +1,17 @@
+/*
+Package traces provides a programmatic API for sending distributed tracing
+spans to New Relic's Trace API.
+
+Spans can be sent in the New Relic JSON format, or in the Zipkin JSON v2
+format, either directly with CreateSpans, or queued in batch mode.
+
+Authentication
+
+You will need a valid License key, or an Insights insert key, to communicate
+with the backend New Relic API that provides this functionality.  See the API
+key documentation below for more information on how to locate these keys:
+
+https://docs.newrelic.com/docs/apis/get-started/intro-apis/types-new-relic-api-keys
+
+*/
+package traces
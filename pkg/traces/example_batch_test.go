@@ -0,0 +1,51 @@
+//go:build integration
+// +build integration
+
+package traces
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+)
+
+func Example_batch() {
+	// Initialize the client configuration.  A New Relic License Key is
+	// required to communicate with the backend API.
+	cfg := config.New()
+	cfg.LicenseKey = os.Getenv("NEW_RELIC_LICENSE_KEY")
+
+	// Initialize the client.
+	client := New(cfg)
+
+	// Start batch mode, with attributes common to every span queued.
+	common := BatchConfigCommonAttributes(map[string]interface{}{
+		"service.name": "example",
+	})
+
+	if err := client.BatchMode(context.Background(), common); err != nil {
+		log.Fatal("error starting batch mode:", err)
+	}
+
+	// Queue the spans of a trace.
+	start := time.Now()
+
+	spans := []Span{
+		{ID: "1", TraceID: "example-trace", Name: "checkout", Timestamp: start, Duration: 120 * time.Millisecond},
+		{ID: "2", TraceID: "example-trace", ParentID: "1", Name: "charge card", Timestamp: start.Add(10 * time.Millisecond), Duration: 80 * time.Millisecond},
+	}
+
+	for _, span := range spans {
+		if err := client.EnqueueSpan(context.Background(), span); err != nil {
+			log.Fatal("error queueing span:", err)
+		}
+	}
+
+	// Send the queued spans and leave batch mode.
+	if err := client.Close(context.Background()); err != nil {
+		log.Fatal("error closing traces batch mode:", err)
+	}
+}
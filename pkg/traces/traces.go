@@ -0,0 +1,378 @@
+package traces
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	nrhttp "github.com/newrelic/newrelic-client-go/internal/http"
+	"github.com/newrelic/newrelic-client-go/pkg/config"
+	"github.com/newrelic/newrelic-client-go/pkg/errors"
+	"github.com/newrelic/newrelic-client-go/pkg/logging"
+)
+
+const (
+	DefaultBatchSize    = 1000
+	DefaultBatchTimeout = 5 * time.Second
+)
+
+// MaxPayloadSize is the maximum size in bytes of a payload accepted by the
+// Trace API.  It limits the size of the payloads spans are sent in.
+const MaxPayloadSize = 1000000
+
+// DataFormat is the format spans are sent to the Trace API in.
+type DataFormat string
+
+const (
+	// DataFormatNewRelic is the New Relic JSON format.
+	DataFormatNewRelic DataFormat = "newrelic"
+
+	// DataFormatZipkin is the Zipkin JSON v2 format.
+	DataFormatZipkin DataFormat = "zipkin"
+)
+
+// version returns the version of the data format, sent in the
+// Data-Format-Version header.
+func (f DataFormat) version() (string, error) {
+	switch f {
+	case DataFormatNewRelic:
+		return "1", nil
+	case DataFormatZipkin:
+		return "2", nil
+	default:
+		return "", errors.NewInvalidInputf("traces: unknown data format %q", string(f))
+	}
+}
+
+// Traces is used to send spans to the Trace API.
+type Traces struct {
+	client nrhttp.Client
+	config config.Config
+	logger logging.Logger
+
+	// For queue based span handling
+	spanQueue  chan queuedSpan
+	spanTimer  *time.Timer
+	flushQueue chan bool
+	stats      *batchStats
+	commonData []byte
+
+	// For closing batch mode
+	batchMu  *sync.Mutex
+	enqueues *sync.WaitGroup
+	workers  *sync.WaitGroup
+	stopping chan struct{}
+	draining chan struct{}
+	stopped  chan struct{}
+
+	// These have defaults
+	batchFormat         DataFormat
+	batchSize           int
+	batchTimeout        time.Duration
+	batchMaxPayloadSize int
+	batchCommon         *Common
+	batchErrorHandler   BatchErrorHandler
+}
+
+// New is used to create a new Traces client instance.
+func New(cfg config.Config) Traces {
+	cfg.Compression = config.Compression.Gzip
+
+	client := nrhttp.NewClient(cfg)
+	if cfg.InsightsInsertKey != "" {
+		client.SetAuthStrategy(&nrhttp.LogsInsertKeyAuthorizer{})
+	} else {
+		client.SetAuthStrategy(&nrhttp.LicenseKeyAuthorizer{})
+	}
+
+	pkg := Traces{
+		client:              client,
+		config:              cfg,
+		logger:              cfg.GetLogger(),
+		batchMu:             &sync.Mutex{},
+		batchFormat:         DataFormatNewRelic,
+		batchSize:           DefaultBatchSize,
+		batchTimeout:        DefaultBatchTimeout,
+		batchMaxPayloadSize: MaxPayloadSize,
+	}
+
+	return pkg
+}
+
+// Span is a unit of work in a distributed trace.
+type Span struct {
+	// ID is the unique ID of the span.
+	ID string
+
+	// TraceID is the ID of the trace the span is part of.
+	TraceID string
+
+	// ParentID is the ID of the span's parent, if it has one.
+	ParentID string
+
+	// Name is the name of the span's operation.
+	Name string
+
+	// ServiceName is the name of the service that did the work.
+	ServiceName string
+
+	// Kind is the kind of span, such as "client" or "server".
+	Kind string
+
+	// Timestamp is when the span started.
+	Timestamp time.Time
+
+	// Duration is how long the span took.
+	Duration time.Duration
+
+	// Attributes are the attributes of the span, sent as tags in the Zipkin
+	// format.
+	Attributes map[string]interface{}
+}
+
+// Common holds the attributes shared by the spans sent in a payload, which
+// are sent in the common block of the New Relic format, and merged into the
+// tags of each span in the Zipkin format.
+type Common struct {
+	Attributes map[string]interface{}
+}
+
+type newRelicSpanJSON struct {
+	ID         string                 `json:"id"`
+	TraceID    string                 `json:"trace.id"`
+	Timestamp  int64                  `json:"timestamp,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type newRelicCommonJSON struct {
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type zipkinSpanJSON struct {
+	TraceID       string              `json:"traceId"`
+	ID            string              `json:"id"`
+	ParentID      string              `json:"parentId,omitempty"`
+	Name          string              `json:"name,omitempty"`
+	Kind          string              `json:"kind,omitempty"`
+	Timestamp     int64               `json:"timestamp,omitempty"`
+	Duration      int64               `json:"duration,omitempty"`
+	LocalEndpoint *zipkinEndpointJSON `json:"localEndpoint,omitempty"`
+	Tags          map[string]string   `json:"tags,omitempty"`
+}
+
+type zipkinEndpointJSON struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// marshalSpan validates the span and converts it into a JSON []byte in the
+// given format.
+func marshalSpan(format DataFormat, span Span, common *Common) ([]byte, error) {
+	if span.ID == "" || span.TraceID == "" {
+		return nil, errors.NewInvalidInput("traces: span ID and trace ID are required")
+	}
+
+	var data []byte
+	var err error
+
+	switch format {
+	case DataFormatNewRelic:
+		data, err = json.Marshal(newRelicSpan(span))
+	case DataFormatZipkin:
+		data, err = json.Marshal(zipkinSpan(span, common))
+	default:
+		_, err = format.version()
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, errors.NewInvalidInputf("traces: error marshaling span %q: %s", span.ID, err.Error()).WithCause(err)
+	}
+
+	return data, nil
+}
+
+// newRelicSpan converts a span to the New Relic format, where its fields other
+// than its IDs and timestamp are attributes.
+func newRelicSpan(span Span) newRelicSpanJSON {
+	attributes := make(map[string]interface{}, len(span.Attributes)+5)
+	for k, v := range span.Attributes {
+		attributes[k] = v
+	}
+
+	if span.ParentID != "" {
+		attributes["parent.id"] = span.ParentID
+	}
+	if span.Name != "" {
+		attributes["name"] = span.Name
+	}
+	if span.ServiceName != "" {
+		attributes["service.name"] = span.ServiceName
+	}
+	if span.Kind != "" {
+		attributes["span.kind"] = span.Kind
+	}
+	if span.Duration > 0 {
+		attributes["duration.ms"] = float64(span.Duration) / float64(time.Millisecond)
+	}
+
+	var timestamp int64
+	if !span.Timestamp.IsZero() {
+		timestamp = span.Timestamp.UnixNano() / int64(time.Millisecond)
+	}
+
+	return newRelicSpanJSON{
+		ID:         span.ID,
+		TraceID:    span.TraceID,
+		Timestamp:  timestamp,
+		Attributes: attributes,
+	}
+}
+
+// zipkinSpan converts a span to the Zipkin format, where its attributes and
+// the common attributes are tags.
+func zipkinSpan(span Span, common *Common) zipkinSpanJSON {
+	tags := map[string]string{}
+	if common != nil {
+		for k, v := range common.Attributes {
+			tags[k] = fmt.Sprint(v)
+		}
+	}
+	for k, v := range span.Attributes {
+		tags[k] = fmt.Sprint(v)
+	}
+
+	z := zipkinSpanJSON{
+		TraceID:  span.TraceID,
+		ID:       span.ID,
+		ParentID: span.ParentID,
+		Name:     span.Name,
+		Kind:     strings.ToUpper(span.Kind),
+		Duration: span.Duration.Microseconds(),
+	}
+
+	if !span.Timestamp.IsZero() {
+		z.Timestamp = span.Timestamp.UnixNano() / int64(time.Microsecond)
+	}
+	if span.ServiceName != "" {
+		z.LocalEndpoint = &zipkinEndpointJSON{ServiceName: span.ServiceName}
+	}
+	if len(tags) > 0 {
+		z.Tags = tags
+	}
+
+	return z
+}
+
+// marshalCommon converts the common block into a JSON []byte, or nil when
+// there is none or the format doesn't have one.
+func marshalCommon(format DataFormat, common *Common) ([]byte, error) {
+	if common == nil || format != DataFormatNewRelic {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(newRelicCommonJSON{Attributes: common.Attributes})
+	if err != nil {
+		return nil, errors.NewInvalidInputf("traces: error marshaling common block: %s", err.Error()).WithCause(err)
+	}
+
+	return data, nil
+}
+
+// buildPayload joins the spans, already marshaled to JSON, into a payload in
+// the given format, along with the common block.
+func buildPayload(format DataFormat, common []byte, spans [][]byte) []byte {
+	var buf bytes.Buffer
+
+	if format == DataFormatZipkin {
+		buf.WriteString(`[`)
+		buf.Write(bytes.Join(spans, []byte(",")))
+		buf.WriteString(`]`)
+
+		return buf.Bytes()
+	}
+
+	buf.WriteString(`[{`)
+	if common != nil {
+		buf.WriteString(`"common":`)
+		buf.Write(common)
+		buf.WriteString(`,`)
+	}
+	buf.WriteString(`"spans":[`)
+	buf.Write(bytes.Join(spans, []byte(",")))
+	buf.WriteString(`]}]`)
+
+	return buf.Bytes()
+}
+
+// emptyPayloadSize returns the size of a payload without any spans.
+func emptyPayloadSize(format DataFormat, common []byte) int {
+	return len(buildPayload(format, common, nil))
+}
+
+// CreateSpans sends spans to New Relic in the given format, along with the
+// common block shared by them, which may be nil.
+func (t *Traces) CreateSpans(format DataFormat, common *Common, spans ...Span) error {
+	return t.CreateSpansWithContext(context.Background(), format, common, spans...)
+}
+
+// CreateSpansWithContext sends spans to New Relic in the given format, along
+// with the common block shared by them, which may be nil.
+func (t *Traces) CreateSpansWithContext(ctx context.Context, format DataFormat, common *Common, spans ...Span) error {
+	if len(spans) == 0 {
+		return errors.NewInvalidInput("traces: CreateSpans: no spans, nothing to do")
+	}
+
+	commonData, err := marshalCommon(format, common)
+	if err != nil {
+		return err
+	}
+
+	data := make([][]byte, len(spans))
+	for i, span := range spans {
+		if data[i], err = marshalSpan(format, span, common); err != nil {
+			return err
+		}
+	}
+
+	payload := buildPayload(format, commonData, data)
+	if len(payload) > MaxPayloadSize {
+		return errors.NewPayloadTooLarge(len(payload), MaxPayloadSize)
+	}
+
+	return t.sendPayload(ctx, format, payload)
+}
+
+type createSpansResponse struct {
+	RequestID string `json:"requestId"`
+}
+
+func (t *Traces) sendPayload(ctx context.Context, format DataFormat, payload []byte) error {
+	version, err := format.version()
+	if err != nil {
+		return err
+	}
+
+	resp := &createSpansResponse{}
+
+	req, err := t.client.NewRequest(http.MethodPost, t.config.Region().TracesURL(), nil, payload, resp)
+	if err != nil {
+		return err
+	}
+
+	req.SetHeader("Data-Format", string(format))
+	req.SetHeader("Data-Format-Version", version)
+	req.WithContext(ctx)
+
+	if _, err = t.client.Do(req); err != nil {
+		return err
+	}
+
+	t.logger.Trace("spans sent", "requestId", resp.RequestID)
+
+	return nil
+}
@@ -0,0 +1,459 @@
+package traces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+)
+
+// BatchMode enables the Traces client to accept, queue, and post spans on
+// behalf of the consuming application.  Batch mode runs until ctx is done,
+// discarding any spans still queued, or until Close is called, which sends
+// them first.  Once closed, batch mode can be enabled again.
+func (t *Traces) BatchMode(ctx context.Context, opts ...BatchConfigOption) (err error) {
+	t.batchMu.Lock()
+	defer t.batchMu.Unlock()
+
+	if t.spanQueue != nil {
+		return errors.New("the Traces client is already in batch mode")
+	}
+
+	// Loop through config options
+	for _, fn := range opts {
+		if nil != fn {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+	}
+
+	if t.commonData, err = marshalCommon(t.batchFormat, t.batchCommon); err != nil {
+		return err
+	}
+
+	t.spanQueue = make(chan queuedSpan, t.batchSize)
+	t.flushQueue = make(chan bool, 1)
+	t.spanTimer = time.NewTimer(t.batchTimeout)
+	t.stats = &batchStats{}
+	t.enqueues = &sync.WaitGroup{}
+	t.workers = &sync.WaitGroup{}
+	t.stopping = make(chan struct{})
+	t.draining = make(chan struct{})
+	t.stopped = make(chan struct{})
+
+	t.workers.Add(1)
+	go func() {
+		defer t.workers.Done()
+
+		err := t.batchWorker(ctx)
+		if err != nil {
+			t.logger.Error("batch worker returned error", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Close stops batch mode once every queued span has been sent.  Spans can no
+// longer be queued once Close is called, the spans already queued are sent,
+// and Close waits for them to be sent, or for ctx to be done.  An error is
+// returned if any spans could not be sent.  Batch mode can be enabled again
+// once closed.
+func (t *Traces) Close(ctx context.Context) error {
+	t.batchMu.Lock()
+
+	if t.spanQueue == nil {
+		t.batchMu.Unlock()
+		return errors.New("queueing not enabled for this client")
+	}
+
+	stats, stopped := t.stats, t.stopped
+
+	select {
+	case <-t.stopping:
+	default:
+		t.logger.Debug("closing batch mode")
+		close(t.stopping)
+		go t.shutdown()
+	}
+
+	t.batchMu.Unlock()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	failed := atomic.LoadInt64(&stats.failed)
+	dropped := atomic.LoadInt64(&stats.dropped)
+
+	if failed > 0 || dropped > 0 {
+		return fmt.Errorf("traces: %d spans could not be sent and %d were dropped", failed, dropped)
+	}
+
+	return nil
+}
+
+// shutdown waits for spans being queued, has the worker send the spans left
+// in the queue, then leaves batch mode.
+func (t *Traces) shutdown() {
+	t.enqueues.Wait()
+	close(t.draining)
+
+	t.workers.Wait()
+
+	// A worker stopped by its context being done may have left spans.
+	t.dropSpans(t.drainQueue())
+	t.spanTimer.Stop()
+
+	t.batchMu.Lock()
+	defer t.batchMu.Unlock()
+
+	t.spanQueue = nil
+	t.flushQueue = nil
+	close(t.stopped)
+}
+
+type BatchConfigOption func(*Traces) error
+
+// BatchConfigDataFormat is the format spans queued in batch mode are sent in.
+// The New Relic format is the default.
+func BatchConfigDataFormat(format DataFormat) BatchConfigOption {
+	return func(t *Traces) error {
+		if _, err := format.version(); err != nil {
+			return errors.New("traces: invalid data format specified")
+		}
+
+		t.batchFormat = format
+		return nil
+	}
+}
+
+// BatchConfigQueueSize is how many spans to queue before sending to New
+// Relic.  If this limit is hit before the Timeout, the queue is flushed.
+func BatchConfigQueueSize(size int) BatchConfigOption {
+	return func(t *Traces) error {
+		if size <= 0 {
+			return errors.New("traces: invalid queue size specified")
+		}
+
+		t.batchSize = size
+		return nil
+	}
+}
+
+// BatchConfigTimeout is the maximum amount of time to queue spans before
+// sending to New Relic.  If this is reached before the Size limit, the queue
+// is flushed.
+func BatchConfigTimeout(seconds int) BatchConfigOption {
+	return func(t *Traces) error {
+		if seconds <= 0 {
+			return errors.New("traces: invalid timeout specified")
+		}
+
+		t.batchTimeout = time.Duration(seconds) * time.Second
+		return nil
+	}
+}
+
+// BatchConfigCommonAttributes sets the attributes shared by every span queued
+// in batch mode.
+func BatchConfigCommonAttributes(attributes map[string]interface{}) BatchConfigOption {
+	return func(t *Traces) error {
+		if _, err := marshalCommon(DataFormatNewRelic, &Common{Attributes: attributes}); err != nil {
+			return fmt.Errorf("traces: invalid common attributes specified: %w", err)
+		}
+
+		t.batchCommon = &Common{Attributes: attributes}
+		return nil
+	}
+}
+
+// BatchConfigMaxPayloadSize is the maximum size in bytes of the payload a
+// batch of spans is sent in, before compression.  If adding a span to a batch
+// would take it over this size, the batch is sent first.  Spans too large to
+// be sent on their own are rejected when queued.  It can't exceed
+// MaxPayloadSize, which is the default.
+func BatchConfigMaxPayloadSize(size int) BatchConfigOption {
+	return func(t *Traces) error {
+		if size <= 0 || size > MaxPayloadSize {
+			return errors.New("traces: invalid max payload size specified")
+		}
+
+		t.batchMaxPayloadSize = size
+		return nil
+	}
+}
+
+// BatchErrorHandler is called with the spans of a batch that could not be
+// sent to New Relic, and the error for the attempt to send them.
+type BatchErrorHandler func(spans []Span, err error)
+
+// BatchConfigErrorHandler sets the handler called with batches of spans that
+// could not be sent to New Relic.
+func BatchConfigErrorHandler(handler BatchErrorHandler) BatchConfigOption {
+	return func(t *Traces) error {
+		t.batchErrorHandler = handler
+		return nil
+	}
+}
+
+// BatchStats are delivery statistics for the spans queued in batch mode.
+type BatchStats struct {
+	// Queued is the number of spans waiting to be sent, including spans in
+	// the batch being sent.
+	Queued int64
+
+	// Sent is the number of spans sent to New Relic.
+	Sent int64
+
+	// Failed is the number of spans in batches that could not be sent, which
+	// were passed to the error handler.
+	Failed int64
+
+	// Dropped is the number of spans discarded without being sent, such as
+	// spans still queued when batch mode's context is done.
+	Dropped int64
+}
+
+type batchStats struct {
+	queued  int64
+	sent    int64
+	failed  int64
+	dropped int64
+}
+
+// BatchStats returns the delivery statistics for the spans queued in batch mode.
+func (t *Traces) BatchStats() BatchStats {
+	t.batchMu.Lock()
+	stats := t.stats
+	t.batchMu.Unlock()
+
+	if stats == nil {
+		return BatchStats{}
+	}
+
+	return BatchStats{
+		Queued:  atomic.LoadInt64(&stats.queued),
+		Sent:    atomic.LoadInt64(&stats.sent),
+		Failed:  atomic.LoadInt64(&stats.failed),
+		Dropped: atomic.LoadInt64(&stats.dropped),
+	}
+}
+
+// EnqueueSpan queues a span to be sent in batch mode.  It blocks until the
+// span can be queued, returning an error if ctx is done first.
+func (t *Traces) EnqueueSpan(ctx context.Context, span Span) (err error) {
+	t.batchMu.Lock()
+
+	if t.spanQueue == nil {
+		t.batchMu.Unlock()
+		return errors.New("queueing not enabled for this client")
+	}
+
+	data, err := marshalSpan(t.batchFormat, span, t.batchCommon)
+	if err != nil {
+		t.batchMu.Unlock()
+		return err
+	}
+
+	// A span that can't fit in a batch on its own can never be sent.
+	if size := emptyPayloadSize(t.batchFormat, t.commonData) + len(data); size > t.batchMaxPayloadSize {
+		t.batchMu.Unlock()
+		return nrErrors.NewPayloadTooLarge(size, t.batchMaxPayloadSize)
+	}
+
+	select {
+	case <-t.stopping:
+		t.batchMu.Unlock()
+		return errors.New("traces: batch mode is closing")
+	default:
+	}
+
+	queue, stopping, stats := t.spanQueue, t.stopping, t.stats
+	t.enqueues.Add(1)
+	defer t.enqueues.Done()
+
+	t.batchMu.Unlock()
+
+	atomic.AddInt64(&stats.queued, 1)
+
+	select {
+	case queue <- queuedSpan{span: span, data: data}:
+		return nil
+	case <-stopping:
+		err = errors.New("traces: batch mode is closing")
+	case <-ctx.Done():
+		t.logger.Trace("EnqueueSpan: exiting per context Done")
+		err = ctx.Err()
+	}
+
+	atomic.AddInt64(&stats.queued, -1)
+
+	return err
+}
+
+// Flush gives the user a way to manually send the queued spans in the
+// background.  This is also done when the timeout expires.
+func (t *Traces) Flush() error {
+	t.batchMu.Lock()
+	flushQueue := t.flushQueue
+	t.batchMu.Unlock()
+
+	if flushQueue == nil {
+		return errors.New("queueing not enabled for this client")
+	}
+
+	t.logger.Debug("flushing spans")
+
+	// A worker with a flush pending doesn't need another.
+	select {
+	case flushQueue <- true:
+	default:
+	}
+
+	return nil
+}
+
+// queuedSpan is a span in the queue, along with its JSON in the batch's format.
+type queuedSpan struct {
+	span Span
+	data []byte
+}
+
+// spanBatch is the batch of spans the worker is building, along with the size
+// of the payload it is sent in.
+type spanBatch struct {
+	spans []queuedSpan
+	size  int
+}
+
+// batchWorker reads spans from the queue into a batch, sending it once it is
+// full, when flushed, or when the timeout expires, until batch mode is closing
+// or ctx is done.
+func (t *Traces) batchWorker(ctx context.Context) error {
+	emptySize := emptyPayloadSize(t.batchFormat, t.commonData)
+	batch := &spanBatch{size: emptySize}
+
+	for {
+		select {
+		case item := <-t.spanQueue:
+			t.batchSpan(ctx, batch, item)
+		case <-t.flushQueue:
+			t.sendBatch(ctx, batch)
+		case <-t.spanTimer.C:
+			t.logger.Debug("Timeout expired, flushing queued spans")
+			t.sendBatch(ctx, batch)
+			t.spanTimer.Reset(t.batchTimeout)
+		case <-t.draining:
+			// Spans queued when ctx is done are discarded, even when closing.
+			if err := ctx.Err(); err != nil {
+				t.dropSpans(len(batch.spans) + t.drainQueue())
+				return err
+			}
+
+			t.logger.Trace("batchWorker draining queue")
+			for {
+				select {
+				case item := <-t.spanQueue:
+					t.batchSpan(ctx, batch, item)
+				default:
+					t.sendBatch(ctx, batch)
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			t.logger.Trace("batchWorker exiting per context Done")
+			t.dropSpans(len(batch.spans) + t.drainQueue())
+			return ctx.Err()
+		}
+	}
+}
+
+// batchSpan adds a span to the worker's batch.  The batch is sent first if the
+// span would take its payload over the maximum size, and is sent once it
+// holds the maximum number of spans.
+func (t *Traces) batchSpan(ctx context.Context, batch *spanBatch, item queuedSpan) {
+	if len(batch.spans) > 0 && batch.size+len(",")+len(item.data) > t.batchMaxPayloadSize {
+		t.sendBatch(ctx, batch)
+	}
+
+	if len(batch.spans) > 0 {
+		batch.size += len(",")
+	}
+
+	batch.spans = append(batch.spans, item)
+	batch.size += len(item.data)
+
+	if len(batch.spans) >= t.batchSize {
+		t.sendBatch(ctx, batch)
+	}
+}
+
+// sendBatch sends the spans in the worker's batch, leaving it empty, and
+// accounts for the result, passing batches that could not be sent to the
+// error handler.
+func (t *Traces) sendBatch(ctx context.Context, batch *spanBatch) {
+	if len(batch.spans) == 0 {
+		return
+	}
+
+	spans := make([]Span, len(batch.spans))
+	data := make([][]byte, len(batch.spans))
+	for i, item := range batch.spans {
+		spans[i] = item.span
+		data[i] = item.data
+	}
+
+	batch.spans = nil
+	batch.size = emptyPayloadSize(t.batchFormat, t.commonData)
+
+	count := int64(len(spans))
+	err := t.sendPayload(ctx, t.batchFormat, buildPayload(t.batchFormat, t.commonData, data))
+
+	atomic.AddInt64(&t.stats.queued, -count)
+
+	if err != nil {
+		atomic.AddInt64(&t.stats.failed, count)
+		t.logger.Error("failed to send spans", "error", err, "count", count)
+
+		if t.batchErrorHandler != nil {
+			t.batchErrorHandler(spans, err)
+		}
+
+		return
+	}
+
+	atomic.AddInt64(&t.stats.sent, count)
+}
+
+// drainQueue discards the spans left in the queue, returning how many there were.
+func (t *Traces) drainQueue() int {
+	count := 0
+
+	for {
+		select {
+		case <-t.spanQueue:
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+// dropSpans accounts for queued spans discarded without being sent.
+func (t *Traces) dropSpans(count int) {
+	if count == 0 {
+		return
+	}
+
+	atomic.AddInt64(&t.stats.queued, -int64(count))
+	atomic.AddInt64(&t.stats.dropped, int64(count))
+	t.logger.Warn("dropped queued spans", "count", count)
+}
@@ -0,0 +1,211 @@
+//go:build unit
+// +build unit
+
+package traces
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+func testSpans(count int) []Span {
+	spans := make([]Span, count)
+	for i := range spans {
+		spans[i] = Span{ID: fmt.Sprintf("span-%d", i), TraceID: "trace-1", Timestamp: time.Unix(1600000000, 0)}
+	}
+
+	return spans
+}
+
+func TestBatchSpans(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx,
+		BatchConfigQueueSize(2),
+		BatchConfigCommonAttributes(map[string]interface{}{"service.name": "test"}),
+	))
+
+	for _, span := range testSpans(3) {
+		require.NoError(t, client.EnqueueSpan(ctx, span))
+	}
+
+	require.NoError(t, client.Close(ctx))
+	assert.Equal(t, BatchStats{Sent: 3}, client.BatchStats())
+
+	payloads := testPayloads(t, server)
+	require.Len(t, payloads, 2)
+	assert.Equal(t, map[string]interface{}{
+		"attributes": map[string]interface{}{"service.name": "test"},
+	}, payloads[0].([]interface{})[0].(map[string]interface{})["common"])
+	assert.Len(t, receivedSpans(t, server), 3)
+
+	assert.Error(t, client.EnqueueSpan(ctx, testSpan))
+	assert.Error(t, client.Flush())
+	assert.Error(t, client.Close(ctx))
+
+	// Batch mode can be enabled again once closed.
+	require.NoError(t, client.BatchMode(ctx))
+	require.NoError(t, client.EnqueueSpan(ctx, testSpan))
+	require.NoError(t, client.Close(ctx))
+	assert.Len(t, server.Payloads(), 3)
+}
+
+func TestBatchZipkin(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx,
+		BatchConfigDataFormat(DataFormatZipkin),
+		BatchConfigCommonAttributes(map[string]interface{}{"service.name": "test"}),
+	))
+
+	for _, span := range testSpans(2) {
+		require.NoError(t, client.EnqueueSpan(ctx, span))
+	}
+
+	require.NoError(t, client.Close(ctx))
+
+	payloads := testPayloads(t, server)
+	require.Len(t, payloads, 1)
+	require.Len(t, payloads[0], 2)
+	assert.Equal(t, map[string]interface{}{"service.name": "test"}, payloads[0].([]interface{})[0].(map[string]interface{})["tags"])
+	assert.Equal(t, "zipkin", server.Header().Get("Data-Format"))
+}
+
+func TestBatchFlush(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+	ctx := context.Background()
+
+	require.NoError(t, client.BatchMode(ctx, BatchConfigTimeout(3600)))
+	require.NoError(t, client.EnqueueSpan(ctx, testSpan))
+
+	assert.Eventually(t, func() bool {
+		return client.BatchStats().Queued == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.Flush())
+
+	assert.Eventually(t, func() bool {
+		return client.BatchStats().Sent == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Nothing is left to send.
+	require.NoError(t, client.Close(ctx))
+	assert.Len(t, server.Payloads(), 1)
+}
+
+func TestBatchSplitByPayloadSize(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+	ctx := context.Background()
+
+	spans := testSpans(5)
+	data, err := marshalSpan(DataFormatNewRelic, spans[0], nil)
+	require.NoError(t, err)
+
+	// Exactly two spans fit in a payload.
+	require.NoError(t, client.BatchMode(ctx,
+		BatchConfigTimeout(3600),
+		BatchConfigMaxPayloadSize(emptyPayloadSize(DataFormatNewRelic, nil)+2*len(data)+len(",")),
+	))
+
+	for _, span := range spans {
+		require.NoError(t, client.EnqueueSpan(ctx, span))
+	}
+
+	oversized := Span{ID: "span-10", TraceID: "trace-1", Name: strings.Repeat("a", 3*len(data))}
+	assert.Error(t, client.EnqueueSpan(ctx, oversized))
+
+	require.NoError(t, client.Close(ctx))
+
+	payloads := testPayloads(t, server)
+	require.Len(t, payloads, 3)
+	assert.Len(t, receivedSpans(t, server), 5)
+	assert.Equal(t, BatchStats{Sent: 5}, client.BatchStats())
+}
+
+func TestBatchErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newTestClient(t, http.StatusBadRequest)
+	ctx := context.Background()
+
+	var failed []Span
+	require.NoError(t, client.BatchMode(ctx,
+		BatchConfigErrorHandler(func(spans []Span, err error) {
+			assert.Error(t, err)
+			failed = append(failed, spans...)
+		}),
+	))
+
+	for _, span := range testSpans(2) {
+		require.NoError(t, client.EnqueueSpan(ctx, span))
+	}
+
+	err := client.Close(ctx)
+	require.Error(t, err)
+	assert.Equal(t, "traces: 2 spans could not be sent and 0 were dropped", err.Error())
+	assert.Equal(t, testSpans(2), failed)
+}
+
+func TestBatchDroppedOnCancel(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, client.BatchMode(ctx, BatchConfigTimeout(3600)))
+	for _, span := range testSpans(2) {
+		require.NoError(t, client.EnqueueSpan(ctx, span))
+	}
+
+	cancel()
+
+	err := client.Close(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "traces: 0 spans could not be sent and 2 were dropped", err.Error())
+	assert.Empty(t, server.Payloads())
+}
+
+func TestBatchConfigOptions(t *testing.T) {
+	t.Parallel()
+
+	tr := New(mock.NewTestConfig(t, nil))
+
+	assert.Error(t, BatchConfigDataFormat("jaeger")(&tr))
+	assert.NoError(t, BatchConfigDataFormat(DataFormatZipkin)(&tr))
+	assert.Error(t, BatchConfigQueueSize(0)(&tr))
+	assert.Error(t, BatchConfigTimeout(0)(&tr))
+	assert.Error(t, BatchConfigMaxPayloadSize(0)(&tr))
+	assert.Error(t, BatchConfigMaxPayloadSize(MaxPayloadSize+1)(&tr))
+	assert.NoError(t, BatchConfigMaxPayloadSize(MaxPayloadSize)(&tr))
+	assert.Error(t, BatchConfigCommonAttributes(map[string]interface{}{"bad": func() {}})(&tr))
+
+	assert.Error(t, tr.EnqueueSpan(context.Background(), testSpan))
+	assert.Error(t, tr.Flush())
+	assert.Error(t, tr.Close(context.Background()))
+	assert.Equal(t, BatchStats{}, tr.BatchStats())
+
+	require.NoError(t, tr.BatchMode(context.Background()))
+	assert.Error(t, tr.BatchMode(context.Background()))
+	assert.Error(t, tr.EnqueueSpan(context.Background(), Span{}))
+	require.NoError(t, tr.Close(context.Background()))
+}
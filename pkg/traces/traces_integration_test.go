@@ -0,0 +1,40 @@
+//go:build integration
+// +build integration
+
+// Requires NEW_RELIC_LICENSE_KEY envvar (APM License Key)
+
+package traces
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	nr "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+func TestIntegrationTraces(t *testing.T) {
+	t.Parallel()
+
+	client := newIntegrationTestClient(t)
+
+	common := &Common{Attributes: map[string]interface{}{"test": "TestIntegrationTraces"}}
+	span := Span{
+		ID:          "1",
+		TraceID:     "newrelic-client-go-test",
+		Name:        "TestIntegrationTraces",
+		ServiceName: "newrelic-client-go",
+		Timestamp:   time.Now(),
+		Duration:    time.Millisecond,
+	}
+
+	assert.NoError(t, client.CreateSpans(DataFormatNewRelic, common, span))
+	assert.NoError(t, client.CreateSpans(DataFormatZipkin, common, span))
+}
+
+func newIntegrationTestClient(t *testing.T) Traces {
+	tc := nr.NewIntegrationTestConfig(t)
+
+	return New(tc)
+}
@@ -0,0 +1,188 @@
+//go:build unit
+// +build unit
+
+package traces
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nrErrors "github.com/newrelic/newrelic-client-go/pkg/errors"
+	mock "github.com/newrelic/newrelic-client-go/pkg/testhelpers"
+)
+
+func newTestClient(t *testing.T, status int) (Traces, *mock.IngestServer) {
+	server := mock.NewIngestServer(t, status)
+	cfg := mock.NewTestConfig(t, server.Server)
+
+	retryMax := 0
+	cfg.RetryMax = &retryMax
+
+	return New(cfg), server
+}
+
+// testPayloads decodes the payloads received by the test server.
+func testPayloads(t *testing.T, server *mock.IngestServer) []interface{} {
+	payloads := []interface{}{}
+
+	for _, data := range server.Payloads() {
+		var payload interface{}
+		require.NoError(t, json.Unmarshal(data, &payload))
+		payloads = append(payloads, payload)
+	}
+
+	return payloads
+}
+
+// receivedSpans returns the spans of every payload in the New Relic format
+// received by the test server.
+func receivedSpans(t *testing.T, server *mock.IngestServer) []map[string]interface{} {
+	spans := []map[string]interface{}{}
+
+	for _, payload := range testPayloads(t, server) {
+		for _, span := range payload.([]interface{})[0].(map[string]interface{})["spans"].([]interface{}) {
+			spans = append(spans, span.(map[string]interface{}))
+		}
+	}
+
+	return spans
+}
+
+var testSpan = Span{
+	ID:          "span-1",
+	TraceID:     "trace-1",
+	ParentID:    "span-0",
+	Name:        "GET /checkout",
+	ServiceName: "checkout",
+	Kind:        "server",
+	Timestamp:   time.Unix(1600000000, 0),
+	Duration:    1500 * time.Microsecond,
+	Attributes:  map[string]interface{}{"http.status_code": 200},
+}
+
+func TestCreateSpansNewRelic(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	common := &Common{Attributes: map[string]interface{}{"host.name": "test"}}
+
+	require.NoError(t, client.CreateSpans(DataFormatNewRelic, common, testSpan))
+
+	payloads := testPayloads(t, server)
+	require.Len(t, payloads, 1)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"common": map[string]interface{}{
+				"attributes": map[string]interface{}{"host.name": "test"},
+			},
+			"spans": []interface{}{
+				map[string]interface{}{
+					"id":        "span-1",
+					"trace.id":  "trace-1",
+					"timestamp": float64(1600000000000),
+					"attributes": map[string]interface{}{
+						"parent.id":        "span-0",
+						"name":             "GET /checkout",
+						"service.name":     "checkout",
+						"span.kind":        "server",
+						"duration.ms":      1.5,
+						"http.status_code": float64(200),
+					},
+				},
+			},
+		},
+	}, payloads[0])
+
+	assert.Equal(t, "newrelic", server.Header().Get("Data-Format"))
+	assert.Equal(t, "1", server.Header().Get("Data-Format-Version"))
+	assert.Equal(t, mock.LicenseKey, server.Header().Get("X-License-Key"))
+}
+
+func TestCreateSpansZipkin(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	common := &Common{Attributes: map[string]interface{}{"host.name": "test"}}
+
+	require.NoError(t, client.CreateSpans(DataFormatZipkin, common, testSpan))
+
+	payloads := testPayloads(t, server)
+	require.Len(t, payloads, 1)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"traceId":       "trace-1",
+			"id":            "span-1",
+			"parentId":      "span-0",
+			"name":          "GET /checkout",
+			"kind":          "SERVER",
+			"timestamp":     float64(1600000000000000),
+			"duration":      float64(1500),
+			"localEndpoint": map[string]interface{}{"serviceName": "checkout"},
+			"tags":          map[string]interface{}{"host.name": "test", "http.status_code": "200"},
+		},
+	}, payloads[0])
+
+	assert.Equal(t, "zipkin", server.Header().Get("Data-Format"))
+	assert.Equal(t, "2", server.Header().Get("Data-Format-Version"))
+}
+
+func TestCreateSpansInsertKey(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	cfg := client.config
+	cfg.InsightsInsertKey = "insertKey"
+	client = New(cfg)
+
+	require.NoError(t, client.CreateSpans(DataFormatNewRelic, nil, testSpan))
+
+	assert.Equal(t, "insertKey", server.Header().Get("Api-Key"))
+	assert.Empty(t, server.Header().Get("X-Insert-Key"))
+}
+
+func TestCreateSpansInvalid(t *testing.T) {
+	t.Parallel()
+
+	client, server := newTestClient(t, http.StatusAccepted)
+
+	cases := map[string][]Span{
+		"no spans":   {},
+		"no id":      {{TraceID: "trace-1"}},
+		"no trace":   {{ID: "span-1"}},
+		"attributes": {{ID: "span-1", TraceID: "trace-1", Attributes: map[string]interface{}{"bad": func() {}}}},
+	}
+
+	for name, spans := range cases {
+		err := client.CreateSpans(DataFormatNewRelic, nil, spans...)
+		assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput), name)
+	}
+
+	err := client.CreateSpans(DataFormat("jaeger"), nil, testSpan)
+	assert.True(t, errors.Is(err, nrErrors.ErrInvalidInput))
+
+	err = client.CreateSpans(DataFormatNewRelic, nil, Span{ID: "span-1", TraceID: "trace-1", Name: strings.Repeat("a", MaxPayloadSize)})
+	assert.True(t, errors.Is(err, nrErrors.ErrPayloadTooLarge))
+
+	assert.Empty(t, server.Payloads())
+}
+
+func TestCreateSpansError(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newTestClient(t, http.StatusForbidden)
+
+	err := client.CreateSpans(DataFormatNewRelic, nil, testSpan)
+	assert.True(t, errors.Is(err, nrErrors.ErrForbidden))
+}